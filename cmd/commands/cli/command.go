@@ -19,6 +19,7 @@ package cli
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
 	"path/filepath"
 	"strconv"
@@ -124,6 +125,8 @@ func (c *cliApp) handleActions(line string) {
 		{"ip", c.ip},
 		{"disconnect", c.disconnect},
 		{"stop", c.stopClient},
+		{"wireguard-cleanup", c.wireguardCleanup},
+		{"exports", c.exports},
 	}
 
 	argCmds := []struct {
@@ -131,12 +134,15 @@ func (c *cliApp) handleActions(line string) {
 		handler func(argsString string)
 	}{
 		{command: "connect", handler: c.connect},
+		{command: "export-stop", handler: c.exportStop},
+		{command: "export", handler: c.export},
 		{command: "unlock", handler: c.unlock},
 		{command: "identities", handler: c.identities},
 		{command: "version", handler: c.version},
 		{command: "license", handler: c.license},
 		{command: "registration", handler: c.registration},
 		{command: "proposals", handler: c.proposals},
+		{command: "providers", handler: c.providers},
 	}
 
 	for _, cmd := range staticCmds {
@@ -276,6 +282,9 @@ func (c *cliApp) healthcheck() {
 	info(fmt.Sprintf("Version: %v", healthcheck.Version))
 	buildString := metadata.FormatString(healthcheck.BuildInfo.Commit, healthcheck.BuildInfo.Branch, healthcheck.BuildInfo.BuildNumber)
 	info(buildString)
+	if healthcheck.Wireguard != nil {
+		info(fmt.Sprintf("Wireguard mode: %v", healthcheck.Wireguard.Mode))
+	}
 }
 
 func (c *cliApp) proposals(filter string) {
@@ -394,6 +403,57 @@ func (c *cliApp) identities(argsString string) {
 	}
 }
 
+func (c *cliApp) providers(argsString string) {
+	const usage = "providers command:\n    <consumer-identity> list\n    <consumer-identity> favourite <provider-identity>\n" +
+		"    <consumer-identity> block <provider-identity>\n    <consumer-identity> remove <provider-identity>"
+	args := strings.Fields(argsString)
+	if len(args) < 2 {
+		info(usage)
+		return
+	}
+
+	consumerID, action := args[0], args[1]
+	switch action {
+	case "list":
+		preferences, err := c.tequilapi.ProviderPreferences(consumerID)
+		if err != nil {
+			warn(err)
+			return
+		}
+		if len(preferences) == 0 {
+			info("No favourite or blocked providers")
+			return
+		}
+		for _, preference := range preferences {
+			status("+", preference.ProviderID, preference.Kind)
+		}
+	case "favourite", "block", "remove":
+		if len(args) != 3 {
+			info(usage)
+			return
+		}
+		providerID := args[2]
+
+		var err error
+		switch action {
+		case "favourite":
+			err = c.tequilapi.SetProviderPreference(consumerID, providerID, "favourite")
+		case "block":
+			err = c.tequilapi.SetProviderPreference(consumerID, providerID, "blocked")
+		default:
+			err = c.tequilapi.RemoveProviderPreference(consumerID, providerID)
+		}
+		if err != nil {
+			warn(err)
+			return
+		}
+		success("Provider preferences updated.")
+	default:
+		warnf("Unknown sub-command '%s'\n", action)
+		fmt.Println(usage)
+	}
+}
+
 func (c *cliApp) registration(argsString string) {
 	if argsString == "" {
 		warn("Please supply identity")
@@ -431,6 +491,81 @@ func (c *cliApp) stopClient() {
 	success("Client stopped")
 }
 
+func (c *cliApp) wireguardCleanup() {
+	report, err := c.tequilapi.WireguardCleanup()
+	if err != nil {
+		warn("Cannot clean up wireguard interfaces:", err)
+		return
+	}
+
+	for _, iface := range report.Interfaces {
+		info("Removed interface:", iface)
+	}
+	for _, rule := range report.NATRules {
+		info("Removed NAT rule:", rule)
+	}
+	success(fmt.Sprintf("Wireguard cleanup removed %d interfaces and %d NAT rules", len(report.Interfaces), len(report.NATRules)))
+}
+
+func (c *cliApp) export(argsString string) {
+	options := strings.Fields(argsString)
+
+	if len(options) < 3 {
+		info("Please type in the provider identity. export <consumer-identity> <provider-identity> <service-type> [file]")
+		return
+	}
+
+	consumerID, providerID, serviceType := options[0], options[1], options[2]
+
+	status("EXPORTING", "from:", consumerID, "to:", providerID)
+
+	exported, err := c.tequilapi.ExportSession(consumerID, providerID, serviceType)
+	if err != nil {
+		warn(err)
+		return
+	}
+
+	if len(options) > 3 {
+		if err := ioutil.WriteFile(options[3], []byte(exported.Config), 0600); err != nil {
+			warn("Cannot write exported config:", err)
+			return
+		}
+		info(fmt.Sprintf("%s config written to: %s", exported.Format, options[3]))
+	} else {
+		fmt.Println(exported.Config)
+	}
+	success("Session exported, SID:", exported.SessionID)
+}
+
+func (c *cliApp) exports() {
+	exported, err := c.tequilapi.ExportedSessions()
+	if err != nil {
+		warn(err)
+		return
+	}
+
+	if len(exported.Sessions) == 0 {
+		info("No exported sessions")
+		return
+	}
+	for _, session := range exported.Sessions {
+		info(fmt.Sprintf("SID: %s, provider: %s, service: %s, format: %s", session.SessionID, session.ProviderID, session.ServiceType, session.Format))
+	}
+}
+
+func (c *cliApp) exportStop(argsString string) {
+	if argsString == "" {
+		info("Please type in the session id. export-stop <session-id>")
+		return
+	}
+
+	if err := c.tequilapi.StopExportedSession(argsString); err != nil {
+		warn(err)
+		return
+	}
+	success("Exported session stopped.")
+}
+
 func (c *cliApp) version(argsString string) {
 	fmt.Println(versionSummary)
 }
@@ -495,6 +630,28 @@ func newAutocompleter(tequilapi *tequilapi_client.Client, proposals []tequilapi_
 		readline.PcItem("help"),
 		readline.PcItem("quit"),
 		readline.PcItem("stop"),
+		readline.PcItem("wireguard-cleanup"),
+		readline.PcItem(
+			"export",
+			readline.PcItemDynamic(
+				getIdentityOptionList(tequilapi),
+				readline.PcItemDynamic(
+					getProposalOptionList(proposals),
+				),
+			),
+		),
+		readline.PcItem("exports"),
+		readline.PcItem(
+			"providers",
+			readline.PcItemDynamic(
+				getIdentityOptionList(tequilapi),
+				readline.PcItem("list"),
+				readline.PcItem("favourite", readline.PcItemDynamic(getProposalOptionList(proposals))),
+				readline.PcItem("block", readline.PcItemDynamic(getProposalOptionList(proposals))),
+				readline.PcItem("remove"),
+			),
+		),
+		readline.PcItem("export-stop"),
 		readline.PcItem(
 			"unlock",
 			readline.PcItemDynamic(
@@ -115,6 +115,10 @@ func (c *serviceCommand) Run(ctx *cli.Context) (err error) {
 		return err
 	}
 
+	if err := c.di.StartSettlement(providerID); err != nil {
+		return err
+	}
+
 	// We need a small buffer for the error channel as we'll have quite a few concurrent reporters
 	// The buffer size is determined as follows:
 	// 1 for the signal callback
@@ -33,5 +33,7 @@ func (di *Dependencies) registerConnections(nodeOptions node.Options) {
 
 func (di *Dependencies) registerWireguardConnection() {
 	wireguard.Bootstrap()
-	di.ConnectionRegistry.Register(wireguard.ServiceType, wireguard_connection.NewConnectionCreator())
+	connectionFactory := wireguard_connection.NewConnectionCreator(di.WireguardModeSelector)
+	di.ConnectionRegistry.Register(wireguard.ServiceType, connectionFactory)
+	di.ConnectionRegistry.RegisterExporter(wireguard.ServiceType, connectionFactory)
 }
@@ -19,10 +19,13 @@ package cmd
 
 import (
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/asaskevich/EventBus"
 	log "github.com/cihub/seelog"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -30,7 +33,9 @@ import (
 	"github.com/mysteriumnetwork/node/communication"
 	nats_dialog "github.com/mysteriumnetwork/node/communication/nats/dialog"
 	nats_discovery "github.com/mysteriumnetwork/node/communication/nats/discovery"
+	"github.com/mysteriumnetwork/node/consumer/preferences"
 	consumer_session "github.com/mysteriumnetwork/node/consumer/session"
+	"github.com/mysteriumnetwork/node/consumer/spending"
 	"github.com/mysteriumnetwork/node/consumer/statistics"
 	"github.com/mysteriumnetwork/node/core/connection"
 	"github.com/mysteriumnetwork/node/core/ip"
@@ -46,6 +51,9 @@ import (
 	market_metrics "github.com/mysteriumnetwork/node/market/metrics"
 	"github.com/mysteriumnetwork/node/market/metrics/oracle"
 	"github.com/mysteriumnetwork/node/market/mysterium"
+	proposals_broker "github.com/mysteriumnetwork/node/market/proposals/broker"
+	proposals_registry "github.com/mysteriumnetwork/node/market/proposals/registry"
+	proposals_repository "github.com/mysteriumnetwork/node/market/proposals/repository"
 	"github.com/mysteriumnetwork/node/metadata"
 	"github.com/mysteriumnetwork/node/metrics"
 	"github.com/mysteriumnetwork/node/money"
@@ -53,22 +61,28 @@ import (
 	service_noop "github.com/mysteriumnetwork/node/services/noop"
 	service_openvpn "github.com/mysteriumnetwork/node/services/openvpn"
 	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
+	wireguard_endpoint "github.com/mysteriumnetwork/node/services/wireguard/endpoint"
 	"github.com/mysteriumnetwork/node/session"
 	"github.com/mysteriumnetwork/node/session/balance"
 	balance_provider "github.com/mysteriumnetwork/node/session/balance/provider"
+	"github.com/mysteriumnetwork/node/session/ledger"
 	session_payment "github.com/mysteriumnetwork/node/session/payment"
 	payment_factory "github.com/mysteriumnetwork/node/session/payment/factory"
 	payments_noop "github.com/mysteriumnetwork/node/session/payment/noop"
 	"github.com/mysteriumnetwork/node/session/promise"
+	"github.com/mysteriumnetwork/node/session/promise/issuers"
+	"github.com/mysteriumnetwork/node/session/promise/settlement"
 	"github.com/mysteriumnetwork/node/session/promise/validators"
 	"github.com/mysteriumnetwork/node/tequilapi"
 	tequilapi_endpoints "github.com/mysteriumnetwork/node/tequilapi/endpoints"
 	"github.com/mysteriumnetwork/node/utils"
+	"github.com/pkg/errors"
 )
 
 // Storage stores persistent objects for future usage
 type Storage interface {
 	Store(issuer string, data interface{}) error
+	StoreAll(bucket string, data ...interface{}) error
 	Delete(issuer string, data interface{}) error
 	Update(bucket string, object interface{}) error
 	GetAllFrom(bucket string, data interface{}) error
@@ -85,34 +99,49 @@ type Dependencies struct {
 	NetworkDefinition    metadata.NetworkDefinition
 	MysteriumAPI         *mysterium.MysteriumAPI
 	MysteriumMorqaClient market_metrics.QualityOracle
+	ProposalRegistry     proposals_registry.ProposalRegistry
+	ProposalFetcher      proposals_repository.Fetcher
+	ProposalBroker       *proposals_broker.Backend
+	ProposalRepository   *proposals_repository.Repository
 	EtherClient          *ethclient.Client
 
 	NATService           nat.NATService
 	Storage              Storage
 	Keystore             *keystore.KeyStore
 	PromiseStorage       *promise.Storage
+	IssuedPromiseStorage *promise.IssuedStorage
+	Settler              *settlement.Settler
+	Ledger               *ledger.Ledger
+	SpendingTracker      *spending.Tracker
 	IdentityManager      identity.Manager
 	SignerFactory        identity.SignerFactory
 	IdentityRegistry     identity_registry.IdentityRegistry
 	IdentityRegistration identity_registry.RegistrationDataProvider
+	IdentityStatuses     *identity_registry.StatusCache
 
 	IPResolver       ip.Resolver
 	LocationResolver location.Resolver
 	LocationDetector location.Detector
 	LocationOriginal location.Cache
 
-	StatisticsTracker  *statistics.SessionStatisticsTracker
-	StatisticsReporter *statistics.SessionStatisticsReporter
-	SessionStorage     *consumer_session.Storage
+	StatisticsTracker   *statistics.SessionStatisticsTracker
+	StatisticsReporter  *statistics.SessionStatisticsReporter
+	SessionStorage      *consumer_session.Storage
+	ProviderPreferences *preferences.Storage
 
 	EventBus EventBus.Bus
 
 	ConnectionManager  connection.Manager
+	SessionExporter    *connection.SessionExporter
 	ConnectionRegistry *connection.Registry
 
 	ServicesManager       *service.Manager
 	ServiceRegistry       *service.Registry
 	ServiceSessionStorage *session.StorageMemory
+
+	WireguardModeSelector *wireguard_endpoint.ModeSelector
+	wireguardCleaner      tequilapi_endpoints.WireguardCleaner
+	wireguardCleanerLock  sync.Mutex
 }
 
 // Bootstrap initiates all container dependencies
@@ -140,6 +169,7 @@ func (di *Dependencies) Bootstrap(nodeOptions node.Options) error {
 
 	di.bootstrapIdentityComponents(nodeOptions)
 	di.bootstrapLocationComponents(nodeOptions.Location, nodeOptions.Directories.Config)
+	di.WireguardModeSelector = wireguard_endpoint.NewModeSelector(wireguard_endpoint.Mode(nodeOptions.Wireguard.Mode))
 	di.bootstrapNodeComponents(nodeOptions)
 
 	di.registerConnections(nodeOptions)
@@ -167,6 +197,7 @@ func (di *Dependencies) registerOpenvpnConnection(nodeOptions node.Options) {
 		di.SignerFactory,
 	)
 	di.ConnectionRegistry.Register(service_openvpn.ServiceType, connectionFactory)
+	di.ConnectionRegistry.RegisterExporter(service_openvpn.ServiceType, connectionFactory)
 }
 
 func (di *Dependencies) registerNoopConnection() {
@@ -186,6 +217,21 @@ func (di *Dependencies) Shutdown() (err error) {
 		}
 	}()
 
+	if di.ProposalRepository != nil {
+		di.ProposalRepository.Stop()
+	}
+	if di.ProposalBroker != nil {
+		di.ProposalBroker.Stop()
+	}
+	if di.SessionExporter != nil {
+		di.SessionExporter.StopAll()
+	}
+	if di.Settler != nil {
+		di.Settler.Stop()
+	}
+	if di.IdentityStatuses != nil {
+		di.IdentityStatuses.Stop()
+	}
 	if di.ServicesManager != nil {
 		if err := di.ServicesManager.Kill(); err != nil {
 			errs = append(errs, err)
@@ -242,6 +288,10 @@ func (di *Dependencies) subscribeEventConsumers() error {
 	if err != nil {
 		return err
 	}
+	err = di.EventBus.Subscribe(connection.SessionEventTopic, di.Ledger.ConsumeSessionEvent)
+	if err != nil {
+		return err
+	}
 
 	// statistics events
 	err = di.EventBus.Subscribe(connection.StatisticsEventTopic, di.StatisticsTracker.ConsumeStatisticsEvent)
@@ -267,25 +317,67 @@ func (di *Dependencies) bootstrapNodeComponents(nodeOptions node.Options) {
 		time.Minute,
 	)
 	di.SessionStorage = consumer_session.NewSessionStorage(di.Storage, di.StatisticsTracker)
+	di.ProviderPreferences = preferences.NewStorage(di.Storage)
+	di.ProposalRepository = proposals_repository.NewRepository(di.ProposalFetcher, di.Storage, time.Minute)
+	di.ProposalRepository.Start()
 	di.PromiseStorage = promise.NewStorage(di.Storage)
+	di.IssuedPromiseStorage = promise.NewIssuedStorage(di.Storage)
+	di.Ledger = ledger.NewLedger(di.Storage)
+	if nodeOptions.ExperimentPayments {
+		// promises are settled rarely, as every clearing transaction costs gas
+		di.Settler = settlement.NewSettler(di.PromiseStorage, settlement.NewStorage(di.Storage), di.newPromiseClearer, time.Hour)
+	}
+	di.SpendingTracker = spending.NewTracker(di.Storage, spending.Limits{
+		PerSession:  nodeOptions.Spending.MaxPerSession,
+		PerProvider: nodeOptions.Spending.MaxPerProvider,
+		PerDay:      nodeOptions.Spending.MaxPerDay,
+		PerMonth:    nodeOptions.Spending.MaxPerMonth,
+	})
 	di.EventBus = EventBus.New()
 
+	newBudget := func(consumer, provider identity.Identity, proposal market.ServiceProposal, limits spending.Limits) (connection.SpendingGuard, error) {
+		return di.SpendingTracker.NewBudget(consumer, provider, ledger.CurrencyOf(proposal), limits)
+	}
+	newSpendingRecorder := func(consumer, provider identity.Identity, proposal market.ServiceProposal) promise.AmountRecorder {
+		return di.Ledger.Spending(consumer, provider, ledger.CurrencyOf(proposal))
+	}
 	di.ConnectionRegistry = connection.NewRegistry()
 	di.ConnectionManager = connection.NewManager(
 		dialogFactory,
-		payment_factory.PaymentIssuerFactoryFunc(nodeOptions, di.SignerFactory),
+		payment_factory.PaymentIssuerFactoryFunc(nodeOptions, di.SignerFactory, di.IssuedPromiseStorage, newSpendingRecorder),
+		di.IssuedPromiseStorage,
+		newBudget,
 		di.ConnectionRegistry.CreateConnection,
 		di.EventBus,
 	)
+	di.SessionExporter = connection.NewSessionExporter(
+		dialogFactory,
+		payment_factory.PaymentIssuerFactoryFunc(nodeOptions, di.SignerFactory, di.IssuedPromiseStorage, newSpendingRecorder),
+		di.IssuedPromiseStorage,
+		newBudget,
+		di.ConnectionRegistry.CreateExporter,
+	)
 
-	router := tequilapi.NewAPIRouter()
+	wireguardMode := func() string {
+		return string(di.WireguardModeSelector.Resolved())
+	}
+	router := tequilapi.NewAPIRouter(wireguardMode)
 	tequilapi_endpoints.AddRouteForStop(router, utils.SoftKiller(di.Shutdown))
 	tequilapi_endpoints.AddRoutesForIdentities(router, di.IdentityManager, di.SignerFactory)
-	tequilapi_endpoints.AddRoutesForConnection(router, di.ConnectionManager, di.IPResolver, di.StatisticsTracker, di.MysteriumAPI)
+	tequilapi_endpoints.AddRoutesForConnection(router, di.ConnectionManager, di.IPResolver, di.StatisticsTracker, di.ProposalRepository, di.ProviderPreferences)
+	tequilapi_endpoints.AddRoutesForExports(router, di.SessionExporter, di.ProposalRepository, di.ProviderPreferences)
+	tequilapi_endpoints.AddRoutesForLedger(router, di.Ledger)
 	tequilapi_endpoints.AddRoutesForLocation(router, di.ConnectionManager, di.LocationDetector, di.LocationOriginal)
-	tequilapi_endpoints.AddRoutesForProposals(router, di.MysteriumAPI, di.MysteriumMorqaClient)
+	tequilapi_endpoints.AddRoutesForProposals(router, di.ProposalRepository, di.MysteriumMorqaClient, di.ProviderPreferences)
+	tequilapi_endpoints.AddRoutesForProviderPreferences(router, di.ProviderPreferences)
 	tequilapi_endpoints.AddRoutesForSession(router, di.SessionStorage)
+	tequilapi_endpoints.AddRoutesForSpending(router, di.SpendingTracker)
+	tequilapi_endpoints.AddRoutesForWireguard(router, di.cleanupWireguard)
+	if di.Settler != nil {
+		tequilapi_endpoints.AddRoutesForSettlement(router, di.Settler)
+	}
 	identity_registry.AddIdentityRegistrationEndpoint(router, di.IdentityRegistration, di.IdentityRegistry)
+	identity_registry.AddIdentityStatusEndpoints(router, di.IdentityStatuses)
 
 	httpAPIServer := tequilapi.NewServer(nodeOptions.TequilapiAddress, nodeOptions.TequilapiPort, router)
 	metricsSender := metrics.CreateSender(nodeOptions.DisableMetrics, nodeOptions.MetricsAddress)
@@ -293,29 +385,67 @@ func (di *Dependencies) bootstrapNodeComponents(nodeOptions node.Options) {
 	di.Node = node.NewNode(di.ConnectionManager, httpAPIServer, di.LocationOriginal, metricsSender)
 }
 
+// StartSettlement starts settlement of promises received by the given provider identity, if payments are enabled
+func (di *Dependencies) StartSettlement(providerID identity.Identity) error {
+	if di.Settler == nil {
+		return nil
+	}
+	return di.Settler.Start(providerID)
+}
+
+// newPromiseClearer clears promises with the payments contract, paying for transactions from the receiver account
+func (di *Dependencies) newPromiseClearer(receiver identity.Identity) (settlement.Clearer, error) {
+	account := accounts.Account{Address: common.HexToAddress(receiver.Address)}
+	transactor, err := bind.NewKeyStoreTransactor(di.Keystore, account)
+	if err != nil {
+		return nil, err
+	}
+
+	clearer, err := settlement.NewContractClearer(
+		di.EtherClient,
+		di.NetworkDefinition.PaymentsContractAddress,
+		transactor,
+		issuers.NewPaymentsSigner(di.SignerFactory(receiver)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return clearer, nil
+}
+
+func (di *Dependencies) setWireguardCleaner(cleaner tequilapi_endpoints.WireguardCleaner) {
+	di.wireguardCleanerLock.Lock()
+	defer di.wireguardCleanerLock.Unlock()
+
+	di.wireguardCleaner = cleaner
+}
+
+func (di *Dependencies) cleanupWireguard() (tequilapi_endpoints.WireguardCleanupDTO, error) {
+	di.wireguardCleanerLock.Lock()
+	cleaner := di.wireguardCleaner
+	di.wireguardCleanerLock.Unlock()
+
+	if cleaner == nil {
+		return tequilapi_endpoints.WireguardCleanupDTO{}, errors.New("wireguard service is not running")
+	}
+	return cleaner()
+}
+
 func newSessionManagerFactory(
 	proposal market.ServiceProposal,
 	sessionStorage *session.StorageMemory,
 	promiseStorage session_payment.PromiseStorage,
+	promiseLedger *ledger.Ledger,
+	freeCredit session_payment.FreeCreditKeeper,
 	nodeOptions node.Options,
 ) session.ManagerFactory {
 	return func(dialog communication.Dialog) *session.Manager {
-		providerBalanceTrackerFactory := func(consumer, provider, issuer identity.Identity) (session.BalanceTracker, error) {
+		providerBalanceTrackerFactory := func(sessionID session.ID, consumer, provider, issuer identity.Identity) (session.BalanceTracker, error) {
 			// if the flag ain't set, just return a noop balance tracker
 			if !nodeOptions.ExperimentPayments {
 				return payments_noop.NewSessionBalance(), nil
 			}
 
-			timeTracker := session.NewTracker(time.Now)
-			// TODO: set the time and proper payment info
-			payment := dto.PaymentPerTime{
-				Price: money.Money{
-					Currency: money.CURRENCY_MYST,
-					Amount:   uint64(10),
-				},
-				Duration: time.Minute,
-			}
-			amountCalc := session.AmountCalc{PaymentDef: payment}
 			sender := balance.NewBalanceSender(dialog)
 			promiseChan := make(chan promise.Message, 1)
 			listener := promise.NewListener(promiseChan)
@@ -324,10 +454,13 @@ func newSessionManagerFactory(
 				return nil, err
 			}
 
-			// TODO: the ints and times here need to be passed in as well, or defined as constants
-			tracker := balance_provider.NewBalanceTracker(&timeTracker, amountCalc, 0)
+			tracker, err := newProviderBalanceTracker(proposal, sessionStorage, sessionID)
+			if err != nil {
+				return nil, err
+			}
 			validator := validators.NewIssuedPromiseValidator(consumer, provider, issuer)
-			return session_payment.NewSessionBalance(sender, tracker, promiseChan, time.Second*5, time.Second*1, validator, promiseStorage, issuer), nil
+			earnings := promiseLedger.Earnings(provider, consumer, string(sessionID), ledger.CurrencyOf(proposal))
+			return session_payment.NewSessionBalance(sender, tracker, promiseChan, time.Second*5, time.Second*1, validator, promiseStorage, issuer, earnings, freeCredit, ledger.CurrencyOf(proposal)), nil
 		}
 		return session.NewManager(
 			proposal,
@@ -338,6 +471,60 @@ func newSessionManagerFactory(
 	}
 }
 
+// newPaymentInfoProvider tells consumers the last promise they issued to this provider, so that they can detect any discrepancies,
+// and the free credit they have left
+func newPaymentInfoProvider(promiseStorage session_payment.PromiseStorage, freeCredit session_payment.FreeCreditKeeper, nodeOptions node.Options) session.PaymentInfoProvider {
+	if !nodeOptions.ExperimentPayments {
+		return nil
+	}
+	return func(issuerID identity.Identity) *session.PaymentInfo {
+		paymentInfo := &session.PaymentInfo{}
+		if lastPromise, err := promiseStorage.GetLastPromise(issuerID); err == nil {
+			paymentInfo.LastPromise.SequenceID = lastPromise.SequenceID
+			if lastPromise.Message != nil {
+				paymentInfo.LastPromise.Amount = lastPromise.Message.Amount
+			}
+		}
+
+		remaining, err := freeCredit.Remaining(issuerID)
+		if err != nil {
+			log.Warn("Failed to get free credit of consumer ", issuerID.Address, ": ", err)
+			return paymentInfo
+		}
+		// payment info is still sent in uint64 units
+		if paymentInfo.FreeCredit, err = remaining.Uint64(); err != nil {
+			log.Warn("Failed to send free credit of consumer ", issuerID.Address, ": ", err)
+		}
+		return paymentInfo
+	}
+}
+
+// newProviderBalanceTracker charges exactly the price, rounding and minimum charge advertised in the proposal:
+// for the transferred data when proposal is priced per bytes and for the time when it is priced per time
+func newProviderBalanceTracker(proposal market.ServiceProposal, sessionStorage *session.StorageMemory, sessionID session.ID) (*balance_provider.BalanceTracker, error) {
+	switch payment := proposal.PaymentMethod.(type) {
+	case dto.PaymentPerBytes:
+		calc, err := session.NewTrafficAmountCalc(payment)
+		if err != nil {
+			return nil, err
+		}
+		trafficTracker := session.NewTrafficTracker(sessionStorage, sessionID)
+		return balance_provider.NewTrafficBalanceTracker(trafficTracker, calc, money.Money{}), nil
+	case dto.PaymentPerTime:
+		calc, err := session.NewAmountCalc(payment)
+		if err != nil {
+			return nil, err
+		}
+		timeTracker := session.NewTracker(time.Now)
+		return balance_provider.NewBalanceTracker(&timeTracker, calc, money.Money{}), nil
+	default:
+		// services without metered payment method are not charged
+		timeTracker := session.NewTracker(time.Now)
+		free := dto.PaymentPerTime{Price: money.NewMoney(0, money.CURRENCY_MYST), Duration: time.Minute}
+		return balance_provider.NewBalanceTracker(&timeTracker, session.AmountCalc{PaymentDef: free}, money.Money{}), nil
+	}
+}
+
 // function decides on network definition combined from testnet/localnet flags and possible overrides
 func (di *Dependencies) bootstrapNetworkComponents(options node.OptionsNetwork) (err error) {
 	network := metadata.DefaultNetwork
@@ -369,6 +556,19 @@ func (di *Dependencies) bootstrapNetworkComponents(options node.OptionsNetwork)
 
 	di.NetworkDefinition = network
 	di.MysteriumAPI = mysterium.NewClient(network.DiscoveryAPIAddress)
+
+	switch options.DiscoveryType {
+	case "", node.DiscoveryTypeAPI:
+		di.ProposalRegistry = di.MysteriumAPI
+		di.ProposalFetcher = di.MysteriumAPI
+	case node.DiscoveryTypeBroker:
+		if err = di.bootstrapBrokerDiscovery(network.BrokerAddress); err != nil {
+			return err
+		}
+	default:
+		return errors.Errorf("unknown discovery type: %s", options.DiscoveryType)
+	}
+
 	di.MysteriumMorqaClient = oracle.NewMorqaClient(network.QualityOracle)
 
 	log.Info("Using Eth endpoint: ", network.EtherClientRPC)
@@ -385,6 +585,37 @@ func (di *Dependencies) bootstrapNetworkComponents(options node.OptionsNetwork)
 		di.IdentityRegistry = &identity_registry.FakeRegistry{Registered: true, RegistrationEventExists: true}
 	}
 
+	balances, err := identity_registry.NewContractBalance(di.EtherClient, network.PaymentsContractAddress)
+	if err != nil {
+		return err
+	}
+	di.IdentityStatuses = identity_registry.NewStatusCache(balances, di.IdentityRegistry, time.Minute)
+	di.IdentityStatuses.Start()
+
+	return nil
+}
+
+func (di *Dependencies) bootstrapBrokerDiscovery(brokerAddress string) error {
+	address, err := nats_discovery.NewAddressFromHost(brokerAddress, proposals_broker.SubjectProposals)
+	if err != nil {
+		return err
+	}
+	if err = address.Connect(); err != nil {
+		return errors.Wrap(err, "failed to connect to broker for proposal discovery")
+	}
+
+	log.Info("Using broker for proposal discovery: ", brokerAddress)
+	verifierFactory := func(id identity.Identity) identity.Verifier {
+		return identity.NewVerifierIdentity(id)
+	}
+	// Providers ping their proposals every minute, so a few missed pings are tolerated before proposal expires
+	di.ProposalBroker = proposals_broker.NewBackend(address.GetConnection(), verifierFactory, 3*time.Minute)
+	if err = di.ProposalBroker.Start(); err != nil {
+		return err
+	}
+
+	di.ProposalRegistry = di.ProposalBroker
+	di.ProposalFetcher = di.ProposalBroker
 	return nil
 }
 
@@ -43,6 +43,11 @@ var (
 		Usage: "Enables experimental payments check",
 	}
 
+	discoveryTypeFlag = cli.StringFlag{
+		Name:  "discovery.type",
+		Usage: "Proposal discovery backend: 'api' (discovery service) or 'broker' (broadcasts over message broker)",
+		Value: node.DiscoveryTypeAPI,
+	}
 	discoveryAddressFlag = cli.StringFlag{
 		Name:  "discovery-address",
 		Usage: "`URL` of discovery service",
@@ -79,7 +84,7 @@ func RegisterFlagsNetwork(flags *[]cli.Flag) {
 		testFlag, localnetFlag,
 		identityCheckFlag,
 		paymentCheckFlag,
-		discoveryAddressFlag, brokerAddressFlag,
+		discoveryTypeFlag, discoveryAddressFlag, brokerAddressFlag,
 		etherRPCFlag, etherContractPaymentsFlag,
 		qualityOracleFlag,
 	)
@@ -94,6 +99,7 @@ func ParseFlagsNetwork(ctx *cli.Context) node.OptionsNetwork {
 		ctx.GlobalBool(identityCheckFlag.Name),
 		ctx.GlobalBool(paymentCheckFlag.Name),
 
+		ctx.GlobalString(discoveryTypeFlag.Name),
 		ctx.GlobalString(discoveryAddressFlag.Name),
 		ctx.GlobalString(brokerAddressFlag.Name),
 
@@ -39,6 +39,12 @@ var (
 		Usage: "Determines the scrypt memory complexity. If set to true, will use 4MB blocks instead of the standard 256MB ones",
 	}
 
+	wireguardModeFlag = cli.StringFlag{
+		Name:  "wireguard.mode",
+		Usage: "Wireguard implementation to use: 'kernelspace', 'userspace' or 'auto' to fall back to userspace if kernel module is not available",
+		Value: "auto",
+	}
+
 	metricsDisableFlag = cli.BoolFlag{
 		Name:  "metrics.disable",
 		Usage: "Opt-out from sending usage metrics",
@@ -57,6 +63,13 @@ func ParseKeystoreFlags(ctx *cli.Context) node.OptionsKeystore {
 	}
 }
 
+// ParseWireguardFlags parses the wireguard options for node
+func ParseWireguardFlags(ctx *cli.Context) node.OptionsWireguard {
+	return node.OptionsWireguard{
+		Mode: ctx.GlobalString(wireguardModeFlag.Name),
+	}
+}
+
 // RegisterFlagsNode function register node flags to flag list
 func RegisterFlagsNode(flags *[]cli.Flag) error {
 	if err := RegisterFlagsDirectory(flags); err != nil {
@@ -64,11 +77,12 @@ func RegisterFlagsNode(flags *[]cli.Flag) error {
 	}
 
 	*flags = append(*flags, tequilapiAddressFlag, tequilapiPortFlag, keystoreLightweightFlag, metricsDisableFlag,
-		metricsAddressFlag)
+		metricsAddressFlag, wireguardModeFlag)
 
 	RegisterFlagsNetwork(flags)
 	openvpn_core.RegisterFlags(flags)
 	RegisterFlagsLocation(flags)
+	RegisterFlagsSpending(flags)
 
 	return nil
 }
@@ -86,8 +100,11 @@ func ParseFlagsNode(ctx *cli.Context) node.Options {
 
 		Keystore: ParseKeystoreFlags(ctx),
 
+		Wireguard: ParseWireguardFlags(ctx),
+
 		Openvpn:        wrapper{nodeOptions: openvpn_core.ParseFlags(ctx)},
 		Location:       ParseFlagsLocation(ctx),
+		Spending:       ParseFlagsSpending(ctx),
 		OptionsNetwork: ParseFlagsNetwork(ctx),
 	}
 }
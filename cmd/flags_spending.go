@@ -0,0 +1,65 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"github.com/mysteriumnetwork/node/core/node"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/urfave/cli"
+)
+
+var (
+	spendingMaxPerSessionFlag = cli.Float64Flag{
+		Name:  "spending.max-per-session",
+		Usage: "Maximum amount of MYST to spend in a single session, 0 means no limit",
+	}
+	spendingMaxPerProviderFlag = cli.Float64Flag{
+		Name:  "spending.max-per-provider",
+		Usage: "Maximum amount of MYST to spend with a single provider, 0 means no limit",
+	}
+	spendingMaxPerDayFlag = cli.Float64Flag{
+		Name:  "spending.max-per-day",
+		Usage: "Maximum amount of MYST to spend per day, 0 means no limit",
+	}
+	spendingMaxPerMonthFlag = cli.Float64Flag{
+		Name:  "spending.max-per-month",
+		Usage: "Maximum amount of MYST to spend per month, 0 means no limit",
+	}
+)
+
+// RegisterFlagsSpending function register spending limit flags to flag list
+func RegisterFlagsSpending(flags *[]cli.Flag) {
+	*flags = append(*flags, spendingMaxPerSessionFlag, spendingMaxPerProviderFlag, spendingMaxPerDayFlag, spendingMaxPerMonthFlag)
+}
+
+// ParseFlagsSpending function fills in spending limit options from CLI context
+func ParseFlagsSpending(ctx *cli.Context) node.OptionsSpending {
+	return node.OptionsSpending{
+		MaxPerSession:  mystAmount(ctx.GlobalFloat64(spendingMaxPerSessionFlag.Name)),
+		MaxPerProvider: mystAmount(ctx.GlobalFloat64(spendingMaxPerProviderFlag.Name)),
+		MaxPerDay:      mystAmount(ctx.GlobalFloat64(spendingMaxPerDayFlag.Name)),
+		MaxPerMonth:    mystAmount(ctx.GlobalFloat64(spendingMaxPerMonthFlag.Name)),
+	}
+}
+
+func mystAmount(value float64) money.Money {
+	if value <= 0 {
+		return money.Money{}
+	}
+	return money.NewMoney(value, money.CURRENCY_MYST)
+}
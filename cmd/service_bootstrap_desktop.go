@@ -37,6 +37,7 @@ import (
 	openvpn_discovery "github.com/mysteriumnetwork/node/services/openvpn/discovery"
 	openvpn_service "github.com/mysteriumnetwork/node/services/openvpn/service"
 	"github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/session/freecredit"
 )
 
 const logPrefix = "[service bootstrap] "
@@ -73,20 +74,29 @@ func (di *Dependencies) bootstrapServiceOpenvpn(nodeOptions node.Options) {
 			return nil, market.ServiceProposal{}, err
 		}
 
-		currentLocation := market.Location{Country: location.Country}
+		currentLocation := market.Location{
+			Country: location.Country,
+			NATType: market.NATTypeFor(location.PubIP, location.OutIP),
+		}
 		transportOptions := serviceOptions.(openvpn_service.Options)
 
-		mapPort := func() func() {
+		mapPort := func(protocol string) func() {
 			return mapping.GetPortMappingFunc(
 				location.PubIP,
 				location.OutIP,
-				transportOptions.OpenvpnProtocol,
+				protocol,
 				transportOptions.OpenvpnPort,
 				"Myst node OpenVPN port mapping")
 		}
 
-		proposal := openvpn_discovery.NewServiceProposalWithLocation(currentLocation, transportOptions.OpenvpnProtocol)
-		return openvpn_service.NewManager(nodeOptions, transportOptions, location, di.ServiceSessionStorage, di.NATService, mapPort), proposal, nil
+		manager, err := openvpn_service.NewManager(nodeOptions, transportOptions, location, di.ServiceSessionStorage, di.NATService, mapPort, di.EventBus)
+		if err != nil {
+			return nil, market.ServiceProposal{}, err
+		}
+
+		proposal := openvpn_discovery.NewServiceProposalWithLocation(currentLocation, transportOptions.Transports(), transportOptions.CryptoProfile, transportOptions.Payment())
+		proposal.FreeCredit = transportOptions.FreeCredit()
+		return manager, proposal, nil
 	}
 	di.ServiceRegistry.Register(service_openvpn.ServiceType, createService)
 }
@@ -106,13 +116,16 @@ func (di *Dependencies) bootstrapServiceNoop(nodeOptions node.Options) {
 }
 
 // bootstrapServiceComponents initiates ServicesManager dependency
-func (di *Dependencies) bootstrapServiceComponents(nodeOptions node.Options) {
+func (di *Dependencies) bootstrapServiceComponents(nodeOptions node.Options) error {
 	di.NATService = nat.NewService()
 	if err := di.NATService.Enable(); err != nil {
 		log.Warn(logPrefix, "Failed to enable NAT forwarding: ", err)
 	}
 	di.ServiceRegistry = service.NewRegistry()
 	di.ServiceSessionStorage = session.NewStorageMemory()
+	if err := di.EventBus.Subscribe(session.StatisticsEventTopic, di.ServiceSessionStorage.ConsumeStatisticsEvent); err != nil {
+		return err
+	}
 
 	newDialogWaiter := func(providerID identity.Identity, serviceType string) (communication.DialogWaiter, error) {
 		address, err := nats_discovery.NewAddressFromHostAndID(di.NetworkDefinition.BrokerAddress, providerID, serviceType)
@@ -127,11 +140,16 @@ func (di *Dependencies) bootstrapServiceComponents(nodeOptions node.Options) {
 		), nil
 	}
 	newDialogHandler := func(proposal market.ServiceProposal, configProvider session.ConfigNegotiator) communication.DialogHandler {
-		sessionManagerFactory := newSessionManagerFactory(proposal, di.ServiceSessionStorage, di.PromiseStorage, nodeOptions)
-		return session.NewDialogHandler(sessionManagerFactory, configProvider.ProvideConfig)
+		freeCredit := freecredit.NewTracker(di.Storage, proposal)
+		sessionManagerFactory := newSessionManagerFactory(proposal, di.ServiceSessionStorage, di.PromiseStorage, di.Ledger, freeCredit, nodeOptions)
+		var configUpdater session.ConfigUpdater
+		if updateNegotiator, ok := configProvider.(session.ConfigUpdateNegotiator); ok {
+			configUpdater = updateNegotiator.UpdateConfig
+		}
+		return session.NewDialogHandler(sessionManagerFactory, configProvider.ProvideConfig, configUpdater, newPaymentInfoProvider(di.PromiseStorage, freeCredit, nodeOptions))
 	}
 	newDiscovery := func() *registry.Discovery {
-		return registry.NewService(di.IdentityRegistry, di.IdentityRegistration, di.MysteriumAPI, di.SignerFactory)
+		return registry.NewService(di.IdentityRegistry, di.IdentityRegistration, di.ProposalRegistry, di.SignerFactory)
 	}
 	di.ServicesManager = service.NewManager(
 		di.ServiceRegistry,
@@ -139,4 +157,5 @@ func (di *Dependencies) bootstrapServiceComponents(nodeOptions node.Options) {
 		newDialogHandler,
 		newDiscovery,
 	)
+	return nil
 }
@@ -20,17 +20,22 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/mysteriumnetwork/node/core/node"
 	"github.com/mysteriumnetwork/node/core/service"
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/nat/mapping"
 	"github.com/mysteriumnetwork/node/services/wireguard"
 	wireguard_service "github.com/mysteriumnetwork/node/services/wireguard/service"
+	tequilapi_endpoints "github.com/mysteriumnetwork/node/tequilapi/endpoints"
 )
 
 // BootstrapServices loads all the components required for running services
 func (di *Dependencies) BootstrapServices(nodeOptions node.Options) error {
-	di.bootstrapServiceComponents(nodeOptions)
+	if err := di.bootstrapServiceComponents(nodeOptions); err != nil {
+		return err
+	}
 
 	di.bootstrapServiceOpenvpn(nodeOptions)
 	di.bootstrapServiceNoop(nodeOptions)
@@ -49,6 +54,9 @@ func (di *Dependencies) bootstrapServiceWireguard(nodeOptions node.Options) {
 			}
 
 			wgOptions := serviceOptions.(wireguard_service.Options)
+			if err := wgOptions.Validate(); err != nil {
+				return nil, market.ServiceProposal{}, err
+			}
 
 			mapPort := func(port int) func() {
 				return mapping.GetPortMappingFunc(
@@ -59,8 +67,35 @@ func (di *Dependencies) bootstrapServiceWireguard(nodeOptions node.Options) {
 					"Myst node wireguard(tm) port mapping")
 			}
 
-			return wireguard_service.NewManager(location, di.NATService, mapPort, wgOptions),
-				wireguard_service.GetProposal(location.Country), nil
+			manager, err := wireguard_service.NewManager(location, di.NATService, mapPort, wgOptions, di.EventBus, di.ServiceSessionStorage, di.WireguardModeSelector)
+			if err != nil {
+				return nil, market.ServiceProposal{}, err
+			}
+			di.setWireguardCleaner(func() (tequilapi_endpoints.WireguardCleanupDTO, error) {
+				return wireguardCleanupDTO(manager.Cleanup())
+			})
+
+			proposal := wireguard_service.GetProposal(market.Location{
+				Country: location.Country,
+				NATType: market.NATTypeFor(location.PubIP, location.OutIP),
+			}, wgOptions.Payment())
+			proposal.FreeCredit = wgOptions.FreeCredit()
+			return manager, proposal, nil
 		},
 	)
 }
+
+func wireguardCleanupDTO(report wireguard_service.CleanupReport, err error) (tequilapi_endpoints.WireguardCleanupDTO, error) {
+	if err != nil {
+		return tequilapi_endpoints.WireguardCleanupDTO{}, err
+	}
+
+	dto := tequilapi_endpoints.WireguardCleanupDTO{
+		Interfaces: report.Interfaces,
+		NATRules:   make([]string, 0, len(report.NATRules)),
+	}
+	for _, rule := range report.NATRules {
+		dto.NATRules = append(dto.NATRules, fmt.Sprintf("%s -> %s", rule.SourceAddress, rule.TargetIP))
+	}
+	return dto, nil
+}
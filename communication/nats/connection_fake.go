@@ -20,6 +20,7 @@ package nats
 import (
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/nats-io/go-nats"
@@ -44,7 +45,9 @@ func StartConnectionFake() *connectionFake {
 }
 
 type connectionFake struct {
-	subscriptions map[string][]nats.MsgHandler
+	subscriptions     map[string][]nats.MsgHandler
+	subscriptionsLock sync.RWMutex
+
 	queue         chan *nats.Msg
 	queueShutdown chan bool
 
@@ -145,15 +148,16 @@ func (conn *connectionFake) Close() {
 }
 
 func (conn *connectionFake) subscriptionAdd(subject string, handler nats.MsgHandler) {
-	subscriptions, exist := conn.subscriptions[subject]
-	if exist {
-		subscriptions = append(subscriptions, handler)
-	} else {
-		conn.subscriptions[subject] = []nats.MsgHandler{handler}
-	}
+	conn.subscriptionsLock.Lock()
+	defer conn.subscriptionsLock.Unlock()
+
+	conn.subscriptions[subject] = append(conn.subscriptions[subject], handler)
 }
 
 func (conn *connectionFake) subscriptionsGet(subject string) (*[]nats.MsgHandler, bool) {
+	conn.subscriptionsLock.RLock()
+	defer conn.subscriptionsLock.RUnlock()
+
 	subscriptions, exist := conn.subscriptions[subject]
 	return &subscriptions, exist
 }
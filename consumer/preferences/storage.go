@@ -0,0 +1,151 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package preferences
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+)
+
+const bucketPrefix = "provider-preferences-"
+
+// Kind of consumer preference for a provider
+type Kind string
+
+const (
+	// Favourite providers are flagged in listings and preferred when choosing automatically
+	Favourite = Kind("favourite")
+	// Blocked providers are excluded from listings and automatic selection
+	Blocked = Kind("blocked")
+)
+
+var (
+	// ErrPreferenceNotFound is returned when consumer has no preference for the provider
+	ErrPreferenceNotFound = errors.New("provider preference not found")
+	// ErrUnknownKind is returned for unsupported preference kinds
+	ErrUnknownKind = errors.New("unknown provider preference kind")
+	// errBoltNotFound represents the bolts not found error
+	errBoltNotFound = errors.New("not found")
+)
+
+// Storer allows to store, get and delete provider preferences
+type Storer interface {
+	Store(bucket string, object interface{}) error
+	GetAllFrom(bucket string, array interface{}) error
+	Delete(bucket string, object interface{}) error
+}
+
+// ProviderPreference is a consumer preference for a single provider
+type ProviderPreference struct {
+	ProviderID string `storm:"id"`
+	Kind       Kind
+	AddedAt    time.Time
+}
+
+// Storage keeps favourite and blocked providers of every consumer identity
+type Storage struct {
+	storage Storer
+	lock    sync.Mutex
+}
+
+// NewStorage creates provider preference storage
+func NewStorage(storage Storer) *Storage {
+	return &Storage{
+		storage: storage,
+	}
+}
+
+// List returns all provider preferences of the consumer
+func (s *Storage) List(consumerID identity.Identity) ([]ProviderPreference, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var preferences []ProviderPreference
+	err := s.storage.GetAllFrom(bucketName(consumerID), &preferences)
+	if err != nil && err.Error() != errBoltNotFound.Error() {
+		return nil, err
+	}
+	return preferences, nil
+}
+
+// Set marks provider as favourite or blocked for the consumer, replacing previous preference
+func (s *Storage) Set(consumerID identity.Identity, providerID string, kind Kind) error {
+	if kind != Favourite && kind != Blocked {
+		return ErrUnknownKind
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.storage.Store(bucketName(consumerID), &ProviderPreference{
+		ProviderID: providerID,
+		Kind:       kind,
+		AddedAt:    time.Now().UTC(),
+	})
+}
+
+// Remove forgets consumer preference for the provider
+func (s *Storage) Remove(consumerID identity.Identity, providerID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err := s.storage.Delete(bucketName(consumerID), &ProviderPreference{ProviderID: providerID})
+	if err != nil && err.Error() == errBoltNotFound.Error() {
+		return ErrPreferenceNotFound
+	}
+	return err
+}
+
+// Kinds returns consumer preferences keyed by provider ID
+func (s *Storage) Kinds(consumerID identity.Identity) (map[string]Kind, error) {
+	preferences, err := s.List(consumerID)
+	if err != nil {
+		return nil, err
+	}
+
+	kinds := make(map[string]Kind, len(preferences))
+	for _, preference := range preferences {
+		kinds[preference.ProviderID] = preference.Kind
+	}
+	return kinds, nil
+}
+
+// Prefer drops proposals of blocked providers and moves proposals of favourite ones to the front.
+// It should be applied before any automatic proposal selection.
+func Prefer(proposals []market.ServiceProposal, kinds map[string]Kind) []market.ServiceProposal {
+	preferred := make([]market.ServiceProposal, 0, len(proposals))
+	for _, proposal := range proposals {
+		if kinds[proposal.ProviderID] != Blocked {
+			preferred = append(preferred, proposal)
+		}
+	}
+
+	sort.SliceStable(preferred, func(i, j int) bool {
+		return kinds[preferred[i].ProviderID] == Favourite && kinds[preferred[j].ProviderID] != Favourite
+	})
+	return preferred
+}
+
+func bucketName(consumerID identity.Identity) string {
+	return bucketPrefix + consumerID.Address
+}
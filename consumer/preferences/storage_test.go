@@ -0,0 +1,114 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package preferences
+
+import (
+	"testing"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/stretchr/testify/assert"
+)
+
+var consumerID = identity.FromAddress("0xconsumer")
+
+type storerFake struct {
+	buckets map[string]map[string]ProviderPreference
+}
+
+func newStorerFake() *storerFake {
+	return &storerFake{buckets: make(map[string]map[string]ProviderPreference)}
+}
+
+func (sf *storerFake) Store(bucket string, object interface{}) error {
+	preference := object.(*ProviderPreference)
+	if sf.buckets[bucket] == nil {
+		sf.buckets[bucket] = make(map[string]ProviderPreference)
+	}
+	sf.buckets[bucket][preference.ProviderID] = *preference
+	return nil
+}
+
+func (sf *storerFake) GetAllFrom(bucket string, array interface{}) error {
+	preferences := array.(*[]ProviderPreference)
+	for _, preference := range sf.buckets[bucket] {
+		*preferences = append(*preferences, preference)
+	}
+	return nil
+}
+
+func (sf *storerFake) Delete(bucket string, object interface{}) error {
+	preference := object.(*ProviderPreference)
+	if _, found := sf.buckets[bucket][preference.ProviderID]; !found {
+		return errBoltNotFound
+	}
+	delete(sf.buckets[bucket], preference.ProviderID)
+	return nil
+}
+
+func TestStorage_SetReplacesPreference(t *testing.T) {
+	storage := NewStorage(newStorerFake())
+
+	assert.NoError(t, storage.Set(consumerID, "0xprovider", Favourite))
+	assert.NoError(t, storage.Set(consumerID, "0xprovider", Blocked))
+
+	kinds, err := storage.Kinds(consumerID)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Kind{"0xprovider": Blocked}, kinds)
+
+	kinds, err = storage.Kinds(identity.FromAddress("0xother"))
+	assert.NoError(t, err)
+	assert.Empty(t, kinds)
+}
+
+func TestStorage_SetRejectsUnknownKind(t *testing.T) {
+	storage := NewStorage(newStorerFake())
+
+	assert.Equal(t, ErrUnknownKind, storage.Set(consumerID, "0xprovider", Kind("liked")))
+}
+
+func TestStorage_Remove(t *testing.T) {
+	storage := NewStorage(newStorerFake())
+	assert.NoError(t, storage.Set(consumerID, "0xprovider", Favourite))
+
+	assert.NoError(t, storage.Remove(consumerID, "0xprovider"))
+	assert.Equal(t, ErrPreferenceNotFound, storage.Remove(consumerID, "0xprovider"))
+
+	preferences, err := storage.List(consumerID)
+	assert.NoError(t, err)
+	assert.Empty(t, preferences)
+}
+
+func TestPrefer(t *testing.T) {
+	proposals := []market.ServiceProposal{
+		{ProviderID: "0x1"},
+		{ProviderID: "0x2"},
+		{ProviderID: "0x3"},
+		{ProviderID: "0x4"},
+	}
+	kinds := map[string]Kind{
+		"0x2": Blocked,
+		"0x3": Favourite,
+	}
+
+	assert.Equal(
+		t,
+		[]market.ServiceProposal{{ProviderID: "0x3"}, {ProviderID: "0x1"}, {ProviderID: "0x4"}},
+		Prefer(proposals, kinds),
+	)
+}
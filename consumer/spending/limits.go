@@ -0,0 +1,108 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package spending
+
+import (
+	"fmt"
+
+	"github.com/mysteriumnetwork/node/money"
+)
+
+// Limits caps consumer spending. Zero limit means there is no cap.
+type Limits struct {
+	PerSession  money.Money
+	PerProvider money.Money
+	PerDay      money.Money
+	PerMonth    money.Money
+}
+
+// Merge returns the stricter of every limit, so that neither of them is exceeded.
+// Limits in different currencies can not be compared, so they are not merged.
+func (l Limits) Merge(other Limits) (Limits, error) {
+	var merged Limits
+	var err error
+	if merged.PerSession, err = stricter(l.PerSession, other.PerSession); err != nil {
+		return merged, err
+	}
+	if merged.PerProvider, err = stricter(l.PerProvider, other.PerProvider); err != nil {
+		return merged, err
+	}
+	if merged.PerDay, err = stricter(l.PerDay, other.PerDay); err != nil {
+		return merged, err
+	}
+	merged.PerMonth, err = stricter(l.PerMonth, other.PerMonth)
+	return merged, err
+}
+
+func stricter(a, b money.Money) (money.Money, error) {
+	if a.IsZero() {
+		return b, nil
+	}
+	if b.IsZero() {
+		return a, nil
+	}
+	cmp, err := b.Cmp(a)
+	if err != nil {
+		return money.Money{}, err
+	}
+	if cmp < 0 {
+		return b, nil
+	}
+	return a, nil
+}
+
+// LimitError is returned when spending would exceed one of the limits
+type LimitError struct {
+	Limit string
+	Max   money.Money
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("spending limit %s of %v reached", e.Limit, e.Max.Units())
+}
+
+// Usage describes how much was spent against a single limit
+type Usage struct {
+	Spent money.Money
+	Limit money.Money
+}
+
+// Remaining returns how much can still be spent, false if there is no limit.
+// Nothing remains once the spent amount reaches the limit.
+func (u Usage) Remaining() (money.Money, bool) {
+	if u.Limit.IsZero() {
+		return money.Money{}, false
+	}
+	remaining, err := u.Limit.Sub(u.Spent)
+	if err != nil {
+		return money.Money{Currency: u.Limit.Currency}, true
+	}
+	return remaining, true
+}
+
+func (u Usage) allows(amount money.Money) (bool, error) {
+	if u.Limit.IsZero() {
+		return true, nil
+	}
+	total, err := u.Spent.Add(amount)
+	if err != nil {
+		return false, err
+	}
+	cmp, err := total.Cmp(u.Limit)
+	return cmp <= 0, err
+}
@@ -0,0 +1,302 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package spending
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/session"
+)
+
+const bucketPrefix = "consumer-spending-"
+
+// errBoltNotFound represents the bolts not found error
+var errBoltNotFound = errors.New("not found")
+
+// Storer allows to store and get spending records
+type Storer interface {
+	StoreAll(bucket string, objects ...interface{}) error
+	GetOneByField(bucket string, fieldName string, key interface{}, to interface{}) error
+}
+
+// Record is the amount consumer spent in a single period or with a single provider
+type Record struct {
+	Key       string `storm:"id"`
+	Amount    money.Money
+	UpdatedAt time.Time
+}
+
+// Summary describes current spending of consumer against its limits
+type Summary struct {
+	Sessions []SessionSummary
+	Day      Usage
+	Month    Usage
+}
+
+// SessionSummary describes spending of a single active session of consumer
+type SessionSummary struct {
+	SessionID  session.ID
+	ProviderID identity.Identity
+	Session    Usage
+	Provider   Usage
+}
+
+// Tracker keeps track of consumer spending and enforces the limits on it
+type Tracker struct {
+	storage Storer
+	limits  Limits
+	now     func() time.Time
+
+	active map[session.ID]*Budget
+	lock   sync.Mutex
+}
+
+// NewTracker creates spending tracker which enforces the given global limits
+func NewTracker(storage Storer, limits Limits) *Tracker {
+	return &Tracker{
+		storage: storage,
+		limits:  limits,
+		now:     time.Now,
+		active:  make(map[session.ID]*Budget),
+	}
+}
+
+// NewBudget starts tracking spending of a new session paid in the given currency. Both the given and the global limits are enforced.
+// Budget is listed in the consumer summary once it is bound to the created session.
+func (t *Tracker) NewBudget(consumer, provider identity.Identity, currency money.Currency, limits Limits) (*Budget, error) {
+	merged, err := t.limits.Merge(limits)
+	if err != nil {
+		return nil, err
+	}
+	return &Budget{
+		tracker:  t,
+		consumer: consumer,
+		provider: provider,
+		limits:   merged,
+		spent:    money.Money{Currency: currency},
+	}, nil
+}
+
+// Summary returns current spending of the consumer, including every active session
+func (t *Tracker) Summary(consumer identity.Identity) (Summary, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := t.now()
+	summary := Summary{Sessions: []SessionSummary{}}
+
+	for sessionID, budget := range t.active {
+		if budget.consumer != consumer {
+			continue
+		}
+
+		spent, err := t.load(consumer, providerKey(budget.provider))
+		if err != nil {
+			return summary, err
+		}
+		summary.Sessions = append(summary.Sessions, SessionSummary{
+			SessionID:  sessionID,
+			ProviderID: budget.provider,
+			Session:    Usage{Spent: budget.spent, Limit: budget.limits.PerSession},
+			Provider:   Usage{Spent: spent.Amount, Limit: budget.limits.PerProvider},
+		})
+	}
+	sort.Slice(summary.Sessions, func(i, j int) bool {
+		return summary.Sessions[i].SessionID < summary.Sessions[j].SessionID
+	})
+
+	day, err := t.load(consumer, dayKey(now))
+	if err != nil {
+		return summary, err
+	}
+	summary.Day = Usage{Spent: day.Amount, Limit: t.limits.PerDay}
+
+	month, err := t.load(consumer, monthKey(now))
+	if err != nil {
+		return summary, err
+	}
+	summary.Month = Usage{Spent: month.Amount, Limit: t.limits.PerMonth}
+
+	return summary, nil
+}
+
+func (t *Tracker) spend(budget *Budget, amount money.Money) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	session := Usage{Spent: budget.spent, Limit: budget.limits.PerSession}
+	allowed, err := session.allows(amount)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return &LimitError{Limit: "per session", Max: session.Limit}
+	}
+
+	now := t.now()
+	checks := []struct {
+		key   string
+		name  string
+		limit money.Money
+	}{
+		{providerKey(budget.provider), "per provider", budget.limits.PerProvider},
+		{dayKey(now), "per day", budget.limits.PerDay},
+		{monthKey(now), "per month", budget.limits.PerMonth},
+	}
+
+	records := make([]interface{}, len(checks))
+	for i, check := range checks {
+		record, err := t.load(budget.consumer, check.key)
+		if err != nil {
+			return err
+		}
+		usage := Usage{Spent: record.Amount, Limit: check.limit}
+		allowed, err := usage.allows(amount)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return &LimitError{Limit: check.name, Max: usage.Limit}
+		}
+		if record.Amount, err = record.Amount.Add(amount); err != nil {
+			return err
+		}
+		record.UpdatedAt = now.UTC()
+		records[i] = &record
+	}
+
+	spent, err := budget.spent.Add(amount)
+	if err != nil {
+		return err
+	}
+	if err := t.storage.StoreAll(bucketName(budget.consumer), records...); err != nil {
+		return err
+	}
+	budget.spent = spent
+	budget.spentAt = now
+	return nil
+}
+
+// refund takes the amount back from the session and from the periods it was last spent in
+func (t *Tracker) refund(budget *Budget, amount money.Money) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	spent, err := budget.spent.Sub(amount)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{providerKey(budget.provider), dayKey(budget.spentAt), monthKey(budget.spentAt)}
+	records := make([]interface{}, len(keys))
+	for i, key := range keys {
+		record, err := t.load(budget.consumer, key)
+		if err != nil {
+			return err
+		}
+		if record.Amount, err = record.Amount.Sub(amount); err != nil {
+			return err
+		}
+		record.UpdatedAt = t.now().UTC()
+		records[i] = &record
+	}
+
+	if err := t.storage.StoreAll(bucketName(budget.consumer), records...); err != nil {
+		return err
+	}
+	budget.spent = spent
+	return nil
+}
+
+func (t *Tracker) track(budget *Budget, sessionID session.ID) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	budget.sessionID = sessionID
+	t.active[sessionID] = budget
+}
+
+func (t *Tracker) close(budget *Budget) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.active[budget.sessionID] == budget {
+		delete(t.active, budget.sessionID)
+	}
+}
+
+func (t *Tracker) load(consumer identity.Identity, key string) (Record, error) {
+	record := Record{}
+	err := t.storage.GetOneByField(bucketName(consumer), "Key", key, &record)
+	if err != nil && err.Error() == errBoltNotFound.Error() {
+		return Record{Key: key}, nil
+	}
+	return record, err
+}
+
+// Budget tracks spending of a single session
+type Budget struct {
+	tracker   *Tracker
+	sessionID session.ID
+	consumer  identity.Identity
+	provider  identity.Identity
+	limits    Limits
+	spent     money.Money
+	spentAt   time.Time
+}
+
+// Spend records the given amount of promise units as spent, unless it would exceed any of the limits
+func (b *Budget) Spend(amount uint64) error {
+	return b.tracker.spend(b, money.NewUnits(amount, b.spent.Currency))
+}
+
+// Refund takes back the amount which was spent, but not promised in the end
+func (b *Budget) Refund(amount uint64) error {
+	return b.tracker.refund(b, money.NewUnits(amount, b.spent.Currency))
+}
+
+// Track binds the budget to the created session, so its spending is listed in the consumer summary
+func (b *Budget) Track(sessionID session.ID) {
+	b.tracker.track(b, sessionID)
+}
+
+// Close stops tracking the session, already spent amounts stay recorded
+func (b *Budget) Close() {
+	b.tracker.close(b)
+}
+
+func bucketName(consumer identity.Identity) string {
+	return bucketPrefix + consumer.Address
+}
+
+func providerKey(provider identity.Identity) string {
+	return "provider-" + provider.Address
+}
+
+func dayKey(now time.Time) string {
+	return "day-" + now.UTC().Format("2006-01-02")
+}
+
+func monthKey(now time.Time) string {
+	return "month-" + now.UTC().Format("2006-01")
+}
@@ -0,0 +1,256 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package spending
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	consumerID = identity.FromAddress("0xconsumer")
+	providerID = identity.FromAddress("0xprovider")
+)
+
+type storerFake struct {
+	buckets map[string]map[string]Record
+	err     error
+}
+
+func newStorerFake() *storerFake {
+	return &storerFake{buckets: make(map[string]map[string]Record)}
+}
+
+func (sf *storerFake) StoreAll(bucket string, objects ...interface{}) error {
+	if sf.err != nil {
+		return sf.err
+	}
+	if sf.buckets[bucket] == nil {
+		sf.buckets[bucket] = make(map[string]Record)
+	}
+	for _, object := range objects {
+		record := object.(*Record)
+		sf.buckets[bucket][record.Key] = *record
+	}
+	return nil
+}
+
+func (sf *storerFake) GetOneByField(bucket string, fieldName string, key interface{}, to interface{}) error {
+	record, found := sf.buckets[bucket][key.(string)]
+	if !found {
+		return errBoltNotFound
+	}
+	*to.(*Record) = record
+	return nil
+}
+
+func newTestTracker(storage Storer, limits Limits, now time.Time) *Tracker {
+	tracker := NewTracker(storage, limits)
+	tracker.now = func() time.Time { return now }
+	return tracker
+}
+
+func myst(units uint64) money.Money {
+	return money.NewUnits(units, money.CURRENCY_MYST)
+}
+
+func newBudget(t *testing.T, tracker *Tracker, consumer, provider identity.Identity, limits Limits) *Budget {
+	budget, err := tracker.NewBudget(consumer, provider, money.CURRENCY_MYST, limits)
+	assert.NoError(t, err)
+	return budget
+}
+
+func TestBudget_SpendsUntilSessionLimit(t *testing.T) {
+	tracker := newTestTracker(newStorerFake(), Limits{}, time.Now())
+	budget := newBudget(t, tracker, consumerID, providerID, Limits{PerSession: myst(250)})
+	budget.Track("session-1")
+
+	assert.NoError(t, budget.Spend(100))
+	assert.NoError(t, budget.Spend(100))
+	assert.Equal(t, &LimitError{Limit: "per session", Max: myst(250)}, budget.Spend(100))
+
+	summary, err := tracker.Summary(consumerID)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		[]SessionSummary{{
+			SessionID:  "session-1",
+			ProviderID: providerID,
+			Session:    Usage{Spent: myst(200), Limit: myst(250)},
+			Provider:   Usage{Spent: myst(200)},
+		}},
+		summary.Sessions,
+	)
+	assert.Equal(t, Usage{Spent: myst(200)}, summary.Day)
+	assert.Equal(t, Usage{Spent: myst(200)}, summary.Month)
+}
+
+func TestBudget_ProviderLimitSpansSessions(t *testing.T) {
+	tracker := newTestTracker(newStorerFake(), Limits{PerProvider: myst(150)}, time.Now())
+
+	assert.NoError(t, newBudget(t, tracker, consumerID, providerID, Limits{}).Spend(100))
+	budget := newBudget(t, tracker, consumerID, providerID, Limits{})
+	assert.Equal(t, &LimitError{Limit: "per provider", Max: myst(150)}, budget.Spend(100))
+
+	other := newBudget(t, tracker, consumerID, identity.FromAddress("0xother"), Limits{})
+	assert.NoError(t, other.Spend(100))
+}
+
+func TestBudget_DailyAndMonthlyLimitsArePersisted(t *testing.T) {
+	storage := newStorerFake()
+	day := time.Date(2019, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	tracker := newTestTracker(storage, Limits{PerDay: myst(100), PerMonth: myst(150)}, day)
+	assert.NoError(t, newBudget(t, tracker, consumerID, providerID, Limits{}).Spend(100))
+
+	restarted := newTestTracker(storage, Limits{PerDay: myst(100), PerMonth: myst(150)}, day)
+	budget := newBudget(t, restarted, consumerID, providerID, Limits{})
+	assert.Equal(t, &LimitError{Limit: "per day", Max: myst(100)}, budget.Spend(1))
+
+	nextDay := newTestTracker(storage, Limits{PerDay: myst(100), PerMonth: myst(150)}, day.Add(24*time.Hour))
+	budget = newBudget(t, nextDay, consumerID, providerID, Limits{})
+	assert.NoError(t, budget.Spend(50))
+	assert.Equal(t, &LimitError{Limit: "per month", Max: myst(150)}, budget.Spend(1))
+
+	nextMonth := newTestTracker(storage, Limits{PerDay: myst(100), PerMonth: myst(150)}, day.AddDate(0, 1, 0))
+	assert.NoError(t, newBudget(t, nextMonth, consumerID, providerID, Limits{}).Spend(100))
+}
+
+func TestBudget_RefundTakesBackSpending(t *testing.T) {
+	storage := newStorerFake()
+	tracker := newTestTracker(storage, Limits{PerDay: myst(150)}, time.Now())
+	budget := newBudget(t, tracker, consumerID, providerID, Limits{PerSession: myst(150)})
+	budget.Track("session-1")
+
+	assert.NoError(t, budget.Spend(100))
+	assert.NoError(t, budget.Refund(100))
+	assert.NoError(t, budget.Spend(150))
+
+	summary, err := tracker.Summary(consumerID)
+	assert.NoError(t, err)
+	assert.Equal(t, Usage{Spent: myst(150), Limit: myst(150)}, summary.Sessions[0].Session)
+	assert.Equal(t, Usage{Spent: myst(150)}, summary.Sessions[0].Provider)
+	assert.Equal(t, Usage{Spent: myst(150), Limit: myst(150)}, summary.Day)
+}
+
+func TestTracker_SummaryWithoutActiveSession(t *testing.T) {
+	tracker := newTestTracker(newStorerFake(), Limits{PerDay: myst(300)}, time.Now())
+	budget := newBudget(t, tracker, consumerID, providerID, Limits{})
+	budget.Track("session-1")
+	assert.NoError(t, budget.Spend(100))
+	budget.Close()
+
+	summary, err := tracker.Summary(consumerID)
+	assert.NoError(t, err)
+	assert.Len(t, summary.Sessions, 0)
+	assert.Equal(t, Usage{Spent: myst(100), Limit: myst(300)}, summary.Day)
+
+	remaining, limited := summary.Day.Remaining()
+	assert.True(t, limited)
+	assert.Equal(t, myst(200), remaining)
+
+	_, limited = summary.Month.Remaining()
+	assert.False(t, limited)
+}
+
+func TestTracker_SummaryListsEverySessionOfConsumer(t *testing.T) {
+	otherProviderID := identity.FromAddress("0xother")
+	tracker := newTestTracker(newStorerFake(), Limits{}, time.Now())
+
+	connected := newBudget(t, tracker, consumerID, providerID, Limits{})
+	connected.Track("session-1")
+	assert.NoError(t, connected.Spend(100))
+	exported := newBudget(t, tracker, consumerID, otherProviderID, Limits{PerSession: myst(500)})
+	exported.Track("session-2")
+	assert.NoError(t, exported.Spend(50))
+	foreign := newBudget(t, tracker, identity.FromAddress("0xforeign"), providerID, Limits{})
+	foreign.Track("session-3")
+	assert.NoError(t, foreign.Spend(10))
+
+	summary, err := tracker.Summary(consumerID)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		[]SessionSummary{
+			{SessionID: "session-1", ProviderID: providerID, Session: Usage{Spent: myst(100)}, Provider: Usage{Spent: myst(100)}},
+			{SessionID: "session-2", ProviderID: otherProviderID, Session: Usage{Spent: myst(50), Limit: myst(500)}, Provider: Usage{Spent: myst(50)}},
+		},
+		summary.Sessions,
+	)
+
+	connected.Close()
+	summary, err = tracker.Summary(consumerID)
+	assert.NoError(t, err)
+	assert.Len(t, summary.Sessions, 1)
+	assert.Equal(t, session.ID("session-2"), summary.Sessions[0].SessionID)
+}
+
+func TestBudget_FailedStoreDoesNotCountSpending(t *testing.T) {
+	storage := newStorerFake()
+	tracker := newTestTracker(storage, Limits{}, time.Now())
+	budget := newBudget(t, tracker, consumerID, providerID, Limits{PerSession: myst(100)})
+
+	storage.err = errors.New("disk full")
+	assert.EqualError(t, budget.Spend(100), "disk full")
+
+	storage.err = nil
+	assert.NoError(t, budget.Spend(100))
+}
+
+func TestLimits_MergePicksStricter(t *testing.T) {
+	global := Limits{PerSession: myst(100), PerDay: myst(1000)}
+	params := Limits{PerSession: myst(200), PerProvider: myst(50), PerDay: myst(500)}
+
+	merged, err := global.Merge(params)
+	assert.NoError(t, err)
+	assert.Equal(t, Limits{PerSession: myst(100), PerProvider: myst(50), PerDay: myst(500)}, merged)
+
+	merged, err = global.Merge(Limits{})
+	assert.NoError(t, err)
+	assert.Equal(t, global, merged)
+
+	_, err = global.Merge(Limits{PerSession: money.NewUnits(200, money.Currency("BTC"))})
+	assert.Equal(t, money.ErrCurrencyMismatch, err)
+}
+
+func TestBudget_SpendsBeyondUint64(t *testing.T) {
+	tracker := newTestTracker(newStorerFake(), Limits{}, time.Now())
+	budget := newBudget(t, tracker, consumerID, providerID, Limits{})
+
+	assert.NoError(t, budget.Spend(math.MaxUint64))
+	assert.NoError(t, budget.Spend(1))
+
+	summary, err := tracker.Summary(consumerID)
+	assert.NoError(t, err)
+	assert.Equal(t, "18446744073709551616", summary.Day.Spent.Units().String())
+}
+
+func TestBudget_RejectsLimitsInOtherCurrency(t *testing.T) {
+	tracker := newTestTracker(newStorerFake(), Limits{}, time.Now())
+	budget, err := tracker.NewBudget(consumerID, providerID, money.Currency("BTC"), Limits{PerSession: myst(100)})
+	assert.NoError(t, err)
+
+	assert.Equal(t, money.ErrCurrencyMismatch, budget.Spend(10))
+}
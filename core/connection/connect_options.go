@@ -18,6 +18,7 @@
 package connection
 
 import (
+	"github.com/mysteriumnetwork/node/consumer/spending"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/session"
@@ -27,6 +28,8 @@ import (
 type ConnectParams struct {
 	// kill switch option restricting communication only through VPN
 	DisableKillSwitch bool
+	// SpendingLimits caps spending of the connection, global limits are enforced as well
+	SpendingLimits spending.Limits
 }
 
 // ConnectOptions represents the params we need to ensure a successful connection
@@ -36,4 +39,6 @@ type ConnectOptions struct {
 	Proposal      market.ServiceProposal
 	SessionID     session.ID
 	SessionConfig []byte
+	// UpdateSession sends updated consumer config of the running session to the provider
+	UpdateSession func(config ConsumerConfig) error
 }
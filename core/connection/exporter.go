@@ -0,0 +1,238 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"errors"
+	"sync"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/consumer/spending"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/metadata"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/session/balance"
+)
+
+const exporterLogPrefix = "[session-exporter] "
+
+// ErrNoExportedSession error indicates that there is no exported session with the given id
+var ErrNoExportedSession = errors.New("no exported session exists")
+
+// ExportedConfig is session configuration rendered for a standard tunnel client
+type ExportedConfig struct {
+	// Format of the configuration, e.g. ovpn or wg-quick
+	Format  string
+	Content string
+}
+
+// ConfigExporter prepares consumer side of the session for an external tunnel client
+type ConfigExporter interface {
+	// GetConfig returns consumer config for session creation
+	GetConfig() (ConsumerConfig, error)
+	// Export renders self-contained client configuration of the created session
+	Export(options ConnectOptions) (ExportedConfig, error)
+}
+
+// ExporterCreator creates config exporter of given service type
+type ExporterCreator func(serviceType string) (ConfigExporter, error)
+
+// ExportedSession describes session kept alive and paid for on behalf of an external tunnel client
+type ExportedSession struct {
+	SessionID  session.ID
+	ConsumerID identity.Identity
+	Proposal   market.ServiceProposal
+	Config     ExportedConfig
+}
+
+type exportedSession struct {
+	ExportedSession
+	stop func()
+}
+
+// SessionExporter creates sessions with providers without starting local tunnels
+type SessionExporter struct {
+	newDialog            DialogCreator
+	paymentIssuerFactory PaymentIssuerFactory
+	promiseStateLoader   PromiseStateLoader
+	newBudget            BudgetCreator
+	newExporter          ExporterCreator
+
+	sessions map[session.ID]*exportedSession
+	lock     sync.Mutex
+}
+
+// NewSessionExporter creates session exporter with given dependencies
+func NewSessionExporter(
+	dialogCreator DialogCreator,
+	paymentIssuerFactory PaymentIssuerFactory,
+	promiseStateLoader PromiseStateLoader,
+	budgetCreator BudgetCreator,
+	exporterCreator ExporterCreator,
+) *SessionExporter {
+	return &SessionExporter{
+		newDialog:            dialogCreator,
+		paymentIssuerFactory: paymentIssuerFactory,
+		promiseStateLoader:   promiseStateLoader,
+		newBudget:            budgetCreator,
+		newExporter:          exporterCreator,
+		sessions:             make(map[session.ID]*exportedSession),
+	}
+}
+
+// Export creates session with provider of given proposal and returns its configuration for an external tunnel client.
+// The session is kept alive and paid for until it is stopped.
+func (se *SessionExporter) Export(consumerID identity.Identity, proposal market.ServiceProposal) (exported ExportedSession, err error) {
+	var cancel []func()
+	stop := func() {
+		for i := range cancel {
+			cancel[len(cancel)-i-1]()
+		}
+	}
+	defer func() {
+		if err != nil {
+			stop()
+		}
+	}()
+
+	exporter, err := se.newExporter(proposal.ServiceType)
+	if err != nil {
+		return
+	}
+
+	sessionCreateConfig, err := exporter.GetConfig()
+	if err != nil {
+		return
+	}
+
+	providerID := identity.FromAddress(proposal.ProviderID)
+	dialog, err := se.newDialog(consumerID, providerID, proposal.ProviderContacts[0])
+	if err != nil {
+		return
+	}
+	cancel = append(cancel, func() { dialog.Close() })
+
+	promiseState, err := se.promiseStateLoader.Load(consumerID, providerID)
+	if err != nil {
+		return
+	}
+
+	messageChan := make(chan balance.Message, 1)
+	budget, err := se.newBudget(consumerID, providerID, proposal, spending.Limits{})
+	if err != nil {
+		return
+	}
+	cancel = append(cancel, budget.Close)
+
+	// traffic of exported sessions does not pass through the node, so it can not be counted
+	payments, err := se.paymentIssuerFactory(promiseState, messageChan, dialog, consumerID, providerID, proposal, budget, nil)
+	if err != nil {
+		return
+	}
+	cancel = append(cancel, payments.Stop)
+
+	consumerInfo := session.ConsumerInfo{
+		IssuerID:          consumerID,
+		MystClientVersion: metadata.VersionAsString(),
+	}
+	sessionID, sessionConfig, paymentInfo, err := session.RequestSessionCreate(dialog, proposal.ID, sessionCreateConfig, consumerInfo)
+	if err != nil {
+		return
+	}
+	cancel = append(cancel, func() { session.RequestSessionDestroy(dialog, sessionID) })
+	budget.Track(sessionID)
+
+	if err = validatePaymentInfo(promiseState, paymentInfo); err != nil {
+		return
+	}
+	applyFreeCredit(payments, paymentInfo)
+
+	config, err := exporter.Export(ConnectOptions{
+		SessionID:     sessionID,
+		SessionConfig: sessionConfig,
+		ConsumerID:    consumerID,
+		ProviderID:    providerID,
+		Proposal:      proposal,
+	})
+	if err != nil {
+		return
+	}
+
+	exported = ExportedSession{
+		SessionID:  sessionID,
+		ConsumerID: consumerID,
+		Proposal:   proposal,
+		Config:     config,
+	}
+
+	se.lock.Lock()
+	se.sessions[sessionID] = &exportedSession{ExportedSession: exported, stop: stop}
+	se.lock.Unlock()
+
+	go se.payForSession(sessionID, payments)
+
+	log.Info(exporterLogPrefix, "session exported: ", sessionID)
+	return exported, nil
+}
+
+// Sessions returns all exported sessions which are still alive
+func (se *SessionExporter) Sessions() []ExportedSession {
+	se.lock.Lock()
+	defer se.lock.Unlock()
+
+	sessions := make([]ExportedSession, 0, len(se.sessions))
+	for _, exported := range se.sessions {
+		sessions = append(sessions, exported.ExportedSession)
+	}
+	return sessions
+}
+
+// Stop destroys exported session and stops paying for it
+func (se *SessionExporter) Stop(sessionID session.ID) error {
+	se.lock.Lock()
+	exported, found := se.sessions[sessionID]
+	delete(se.sessions, sessionID)
+	se.lock.Unlock()
+
+	if !found {
+		return ErrNoExportedSession
+	}
+
+	exported.stop()
+	log.Info(exporterLogPrefix, "exported session stopped: ", sessionID)
+	return nil
+}
+
+// StopAll destroys all exported sessions
+func (se *SessionExporter) StopAll() {
+	for _, exported := range se.Sessions() {
+		if err := se.Stop(exported.SessionID); err != nil && err != ErrNoExportedSession {
+			log.Warn(exporterLogPrefix, "failed to stop exported session: ", err)
+		}
+	}
+}
+
+func (se *SessionExporter) payForSession(sessionID session.ID, payments PaymentIssuer) {
+	if err := payments.Start(); err != nil {
+		log.Error(exporterLogPrefix, "payment error, stopping exported session: ", err)
+		if err := se.Stop(sessionID); err != nil && err != ErrNoExportedSession {
+			log.Error(exporterLogPrefix, "failed to stop exported session: ", err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/session/balance"
+	"github.com/mysteriumnetwork/node/session/promise"
+	"github.com/stretchr/testify/assert"
+)
+
+type configExporterFake struct {
+	exportError error
+	options     ConnectOptions
+}
+
+func (ce *configExporterFake) GetConfig() (ConsumerConfig, error) {
+	return nil, nil
+}
+
+func (ce *configExporterFake) Export(options ConnectOptions) (ExportedConfig, error) {
+	ce.options = options
+	return ExportedConfig{Format: "fake", Content: "config"}, ce.exportError
+}
+
+func newTestSessionExporter(configExporter *configExporterFake) (*SessionExporter, *fakeDialog, *MockPaymentIssuer) {
+	dialog := &fakeDialog{sessionID: establishedSessionID}
+	payments := &MockPaymentIssuer{stopChan: make(chan struct{})}
+
+	exporter := NewSessionExporter(
+		func(consumer, provider identity.Identity, contact market.Contact) (communication.Dialog, error) {
+			return dialog, nil
+		},
+		func(initialState promise.State, messageChan chan balance.Message, dialog communication.Dialog, consumer, provider identity.Identity, proposal market.ServiceProposal, spendingGuard SpendingGuard, traffic TrafficCounter) (PaymentIssuer, error) {
+			return payments, nil
+		},
+		&promiseStateLoaderFake{},
+		newBudgetFake,
+		func(serviceType string) (ConfigExporter, error) {
+			return configExporter, nil
+		},
+	)
+	return exporter, dialog, payments
+}
+
+func TestSessionExporter_Export(t *testing.T) {
+	configExporter := &configExporterFake{}
+	exporter, dialog, _ := newTestSessionExporter(configExporter)
+
+	exported, err := exporter.Export(consumerID, activeProposal)
+	assert.NoError(t, err)
+	assert.Equal(t, establishedSessionID, exported.SessionID)
+	assert.Equal(t, ExportedConfig{Format: "fake", Content: "config"}, exported.Config)
+	assert.Equal(t, establishedSessionID, configExporter.options.SessionID)
+	assert.Equal(t, activeProviderID, configExporter.options.ProviderID)
+	assert.False(t, dialog.closed)
+	assert.Equal(t, []ExportedSession{exported}, exporter.Sessions())
+}
+
+func TestSessionExporter_ExportFailureReleasesSession(t *testing.T) {
+	configExporter := &configExporterFake{exportError: errors.New("render failed")}
+	exporter, dialog, payments := newTestSessionExporter(configExporter)
+
+	_, err := exporter.Export(consumerID, activeProposal)
+	assert.EqualError(t, err, "render failed")
+	assert.True(t, dialog.closed)
+	assert.True(t, payments.StopCalled())
+	assert.Empty(t, exporter.Sessions())
+}
+
+func TestSessionExporter_Stop(t *testing.T) {
+	exporter, dialog, payments := newTestSessionExporter(&configExporterFake{})
+
+	exported, err := exporter.Export(consumerID, activeProposal)
+	assert.NoError(t, err)
+
+	assert.NoError(t, exporter.Stop(exported.SessionID))
+	assert.True(t, dialog.closed)
+	assert.True(t, payments.StopCalled())
+	assert.Empty(t, exporter.Sessions())
+
+	assert.Equal(t, ErrNoExportedSession, exporter.Stop(exported.SessionID))
+}
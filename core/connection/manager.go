@@ -25,6 +25,7 @@ import (
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/consumer"
+	"github.com/mysteriumnetwork/node/consumer/spending"
 	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
@@ -68,15 +69,35 @@ type Publisher interface {
 type PaymentIssuer interface {
 	Start() error
 	Stop()
+	SetFreeCredit(amount uint64)
 }
 
-// PaymentIssuerFactory creates a new payment issuer from the given params
-type PaymentIssuerFactory func(initialState promise.State, messageChan chan balance.Message, dialog communication.Dialog, consumer, provider identity.Identity) (PaymentIssuer, error)
+// PaymentIssuerFactory creates a new payment issuer from the given params.
+// Traffic counts the data of the session only, it is nil when the traffic does not pass through the node.
+type PaymentIssuerFactory func(initialState promise.State, messageChan chan balance.Message, dialog communication.Dialog, consumer, provider identity.Identity, proposal market.ServiceProposal, spendingGuard SpendingGuard, traffic TrafficCounter) (PaymentIssuer, error)
+
+// SpendingGuard approves spending of a single session
+type SpendingGuard interface {
+	Spend(amount uint64) error
+	Refund(amount uint64) error
+	Track(sessionID session.ID)
+	Close()
+}
+
+// BudgetCreator starts tracking spending of a new session of the given proposal within the given limits
+type BudgetCreator func(consumer, provider identity.Identity, proposal market.ServiceProposal, limits spending.Limits) (SpendingGuard, error)
+
+// PromiseStateLoader loads the state of promises consumer issued to provider during previous sessions
+type PromiseStateLoader interface {
+	Load(consumer, provider identity.Identity) (promise.State, error)
+}
 
 type connectionManager struct {
 	//these are passed on creation
 	newDialog            DialogCreator
 	paymentIssuerFactory PaymentIssuerFactory
+	promiseStateLoader   PromiseStateLoader
+	newBudget            BudgetCreator
 	newConnection        Creator
 	eventPublisher       Publisher
 
@@ -94,12 +115,16 @@ type connectionManager struct {
 func NewManager(
 	dialogCreator DialogCreator,
 	paymentIssuerFactory PaymentIssuerFactory,
+	promiseStateLoader PromiseStateLoader,
+	budgetCreator BudgetCreator,
 	connectionCreator Creator,
 	eventPublisher Publisher,
 ) *connectionManager {
 	return &connectionManager{
 		newDialog:            dialogCreator,
 		paymentIssuerFactory: paymentIssuerFactory,
+		promiseStateLoader:   promiseStateLoader,
+		newBudget:            budgetCreator,
 		newConnection:        connectionCreator,
 		status:               statusNotConnected(),
 		cleanConnection:      warnOnClean,
@@ -166,15 +191,24 @@ func (manager *connectionManager) startConnection(consumerID identity.Identity,
 
 	messageChan := make(chan balance.Message, 1)
 
-	// TODO: load initial promise state
-	payments, err := manager.paymentIssuerFactory(promise.State{}, messageChan, dialog, consumerID, providerID)
+	promiseState, err := manager.promiseStateLoader.Load(consumerID, providerID)
 	if err != nil {
 		return err
 	}
 
-	cancel = append(cancel, func() { payments.Stop() })
+	budget, err := manager.newBudget(consumerID, providerID, proposal, params.SpendingLimits)
+	if err != nil {
+		return err
+	}
+	cancel = append(cancel, budget.Close)
 
-	go manager.payForService(payments)
+	traffic := &sessionTraffic{}
+	payments, err := manager.paymentIssuerFactory(promiseState, messageChan, dialog, consumerID, providerID, proposal, budget, traffic.Consumed)
+	if err != nil {
+		return err
+	}
+
+	cancel = append(cancel, func() { payments.Stop() })
 
 	consumerInfo := session.ConsumerInfo{
 		// TODO: once we're supporting payments from another identity make the changes accordingly
@@ -182,12 +216,21 @@ func (manager *connectionManager) startConnection(consumerID identity.Identity,
 		MystClientVersion: metadata.VersionAsString(),
 	}
 
-	sessionID, sessionConfig, err := session.RequestSessionCreate(dialog, proposal.ID, sessionCreateConfig, consumerInfo)
+	sessionID, sessionConfig, paymentInfo, err := session.RequestSessionCreate(dialog, proposal.ID, sessionCreateConfig, consumerInfo)
 	if err != nil {
 		return err
 	}
 
 	cancel = append(cancel, func() { session.RequestSessionDestroy(dialog, sessionID) })
+	budget.Track(sessionID)
+
+	if err = validatePaymentInfo(promiseState, paymentInfo); err != nil {
+		return err
+	}
+	applyFreeCredit(payments, paymentInfo)
+
+	// balance messages wait in messageChan until payments start, so free credit is known before the first one is checked
+	go manager.payForService(payments)
 
 	// set the session info for future use
 	manager.sessionInfo = SessionInfo{
@@ -214,6 +257,9 @@ func (manager *connectionManager) startConnection(consumerID identity.Identity,
 		ConsumerID:    consumerID,
 		ProviderID:    providerID,
 		Proposal:      proposal,
+		UpdateSession: func(config ConsumerConfig) error {
+			return session.RequestSessionUpdate(dialog, sessionID, config)
+		},
 	}
 
 	if err = connection.Start(connectOptions); err != nil {
@@ -222,7 +268,7 @@ func (manager *connectionManager) startConnection(consumerID identity.Identity,
 	cancel = append(cancel, connection.Stop)
 
 	//consume statistics right after start - openvpn3 will publish them even before connected state
-	go manager.consumeStats(statisticsChannel)
+	go manager.consumeStats(statisticsChannel, traffic)
 	err = manager.waitForConnectedState(stateChannel, sessionID)
 	if err != nil {
 		return err
@@ -253,6 +299,11 @@ func (manager *connectionManager) setStatus(cs Status) {
 }
 
 func (manager *connectionManager) Disconnect() error {
+	return manager.disconnect("")
+}
+
+// disconnect closes established connection, leaving the given reason in status
+func (manager *connectionManager) disconnect(reason string) error {
 	manager.discoLock.Lock()
 	defer manager.discoLock.Unlock()
 
@@ -262,7 +313,7 @@ func (manager *connectionManager) Disconnect() error {
 
 	manager.setStatus(statusDisconnecting())
 	manager.cleanConnection()
-	manager.setStatus(statusNotConnected())
+	manager.setStatus(statusDisconnected(reason))
 
 	return nil
 }
@@ -271,7 +322,7 @@ func (manager *connectionManager) payForService(payments PaymentIssuer) {
 	err := payments.Start()
 	if err != nil {
 		log.Error(managerLogPrefix, "payment error: ", err)
-		err = manager.Disconnect()
+		err = manager.disconnect(err.Error())
 		if err != nil {
 			log.Error(managerLogPrefix, "could not disconnect gracefully:", err)
 		}
@@ -323,8 +374,9 @@ func (manager *connectionManager) consumeConnectionStates(stateChannel <-chan St
 	logDisconnectError(manager.Disconnect())
 }
 
-func (manager *connectionManager) consumeStats(statisticsChannel <-chan consumer.SessionStatistics) {
+func (manager *connectionManager) consumeStats(statisticsChannel <-chan consumer.SessionStatistics, traffic *sessionTraffic) {
 	for stats := range statisticsChannel {
+		traffic.consume(stats)
 		manager.eventPublisher.Publish(StatisticsEventTopic, stats)
 	}
 }
@@ -348,3 +400,29 @@ func logDisconnectError(err error) {
 		log.Error(managerLogPrefix, "Disconnect error", err)
 	}
 }
+
+// applyFreeCredit lets payment issuer use the free credit which provider offered for the session
+func applyFreeCredit(payments PaymentIssuer, paymentInfo *session.PaymentInfo) {
+	if paymentInfo == nil || paymentInfo.FreeCredit == 0 {
+		return
+	}
+	log.Info(managerLogPrefix, "Provider offered free credit: ", paymentInfo.FreeCredit)
+	payments.SetFreeCredit(paymentInfo.FreeCredit)
+}
+
+// validatePaymentInfo checks that provider does not claim promises which consumer never issued to it
+func validatePaymentInfo(issued promise.State, paymentInfo *session.PaymentInfo) error {
+	if paymentInfo == nil {
+		return nil
+	}
+
+	claimed := promise.State{
+		Seq:    paymentInfo.LastPromise.SequenceID,
+		Amount: paymentInfo.LastPromise.Amount,
+	}
+	if err := promise.ValidateProviderClaim(issued, claimed); err != nil {
+		log.Warn(managerLogPrefix, "Provider claims promise ", claimed, " while issued ", issued)
+		return err
+	}
+	return nil
+}
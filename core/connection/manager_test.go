@@ -25,8 +25,11 @@ import (
 
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/consumer"
+	"github.com/mysteriumnetwork/node/consumer/spending"
+	"github.com/mysteriumnetwork/node/datasize"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/session"
 	"github.com/mysteriumnetwork/node/session/balance"
 	"github.com/mysteriumnetwork/node/session/promise"
@@ -41,7 +44,12 @@ type testContext struct {
 	fakeDialog            *fakeDialog
 	MockPaymentIssuer     *MockPaymentIssuer
 	stubPublisher         *StubPublisher
+	promiseStateLoader    *promiseStateLoaderFake
+	paymentInfo           *session.PaymentInfo
+	initialPromiseState   promise.State
+	budget                *budgetFake
 	mockStatistics        consumer.SessionStatistics
+	traffic               []TrafficCounter
 	sync.RWMutex
 }
 
@@ -64,14 +72,24 @@ func (tc *testContext) SetupTest() {
 	defer tc.Unlock()
 
 	tc.stubPublisher = NewStubPublisher()
+	tc.promiseStateLoader = &promiseStateLoaderFake{}
+	tc.paymentInfo = nil
+	tc.traffic = nil
 	dialogCreator := func(consumer, provider identity.Identity, contact market.Contact) (communication.Dialog, error) {
 		tc.Lock()
 		defer tc.Unlock()
-		tc.fakeDialog = &fakeDialog{sessionID: establishedSessionID}
+		tc.fakeDialog = &fakeDialog{sessionID: establishedSessionID, paymentInfo: tc.paymentInfo}
 		return tc.fakeDialog, nil
 	}
 
-	mockPaymentFactory := func(initialState promise.State, messageChan chan balance.Message, dialog communication.Dialog, consumer, provider identity.Identity) (PaymentIssuer, error) {
+	budgetCreator := func(consumer, provider identity.Identity, proposal market.ServiceProposal, limits spending.Limits) (SpendingGuard, error) {
+		tc.budget = &budgetFake{limits: limits}
+		return tc.budget, nil
+	}
+
+	mockPaymentFactory := func(initialState promise.State, messageChan chan balance.Message, dialog communication.Dialog, consumer, provider identity.Identity, proposal market.ServiceProposal, spendingGuard SpendingGuard, traffic TrafficCounter) (PaymentIssuer, error) {
+		tc.initialPromiseState = initialState
+		tc.traffic = append(tc.traffic, traffic)
 		tc.MockPaymentIssuer = &MockPaymentIssuer{
 			stopChan: make(chan struct{}),
 		}
@@ -110,6 +128,8 @@ func (tc *testContext) SetupTest() {
 	tc.connManager = NewManager(
 		dialogCreator,
 		mockPaymentFactory,
+		tc.promiseStateLoader,
+		budgetCreator,
 		tc.fakeConnectionFactory.CreateConnection,
 		tc.stubPublisher,
 	)
@@ -119,6 +139,64 @@ func (tc *testContext) TestWhenNoConnectionIsMadeStatusIsNotConnected() {
 	assert.Exactly(tc.T(), statusNotConnected(), tc.connManager.Status())
 }
 
+func (tc *testContext) TestPaymentsResumeFromIssuedPromiseState() {
+	tc.promiseStateLoader.state = promise.State{Seq: 2, Amount: 100}
+	tc.paymentInfo = &session.PaymentInfo{LastPromise: session.LastPromise{SequenceID: 2, Amount: 100}}
+
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	assert.Equal(tc.T(), promise.State{Seq: 2, Amount: 100}, tc.initialPromiseState)
+	assert.Equal(tc.T(), statusConnected(establishedSessionID, activeProposal), tc.connManager.Status())
+}
+
+func (tc *testContext) TestFreeCreditOfferedByProviderIsPassedToPaymentIssuer() {
+	tc.paymentInfo = &session.PaymentInfo{FreeCredit: 300}
+
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	assert.Equal(tc.T(), uint64(300), tc.MockPaymentIssuer.FreeCredit())
+}
+
+func (tc *testContext) TestFreeCreditIsSetBeforePaymentIssuerStarts() {
+	tc.paymentInfo = &session.PaymentInfo{FreeCredit: 300}
+
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	waitABit()
+	assert.True(tc.T(), tc.MockPaymentIssuer.StartCalled())
+	assert.Equal(tc.T(), uint64(300), tc.MockPaymentIssuer.FreeCreditOnStart())
+}
+
+func (tc *testContext) TestConnectFailsWhenProviderClaimsMoreThanIssued() {
+	tc.promiseStateLoader.state = promise.State{Seq: 2, Amount: 100}
+	tc.paymentInfo = &session.PaymentInfo{LastPromise: session.LastPromise{SequenceID: 2, Amount: 500}}
+
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{})
+	assert.Equal(tc.T(), promise.ErrProviderOverclaimed, err)
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.True(tc.T(), tc.fakeDialog.closed)
+}
+
+func (tc *testContext) TestSpendingLimitsArePassedToBudget() {
+	limits := spending.Limits{PerSession: money.NewUnits(100, money.CURRENCY_MYST)}
+
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{SpendingLimits: limits}))
+	assert.Equal(tc.T(), limits, tc.budget.limits)
+	assert.Equal(tc.T(), establishedSessionID, tc.budget.sessionID)
+	assert.False(tc.T(), tc.budget.closed)
+
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+	assert.True(tc.T(), tc.budget.closed)
+}
+
+func (tc *testContext) TestPaymentErrorDisconnectsWithReason() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+
+	tc.connManager.payForService(&failingPaymentIssuer{err: &spending.LimitError{Limit: "per session", Max: money.NewUnits(100, money.CURRENCY_MYST)}})
+	assert.Equal(
+		tc.T(),
+		Status{State: NotConnected, Reason: "spending limit per session of 100 reached"},
+		tc.connManager.Status(),
+	)
+}
+
 func (tc *testContext) TestOnConnectErrorStatusIsNotConnected() {
 	tc.fakeConnectionFactory.mockError = errors.New("fatal connection error")
 
@@ -213,6 +291,20 @@ func (tc *testContext) TestTwoConnectDisconnectCyclesReturnNoError() {
 
 }
 
+func (tc *testContext) TestPaymentIssuerCountsTrafficOfItsSessionOnly() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	waitABit()
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+	waitABit()
+
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	waitABit()
+
+	assert.Len(tc.T(), tc.traffic, 2)
+	assert.Equal(tc.T(), 30*datasize.Byte, tc.traffic[0]())
+	assert.Equal(tc.T(), 30*datasize.Byte, tc.traffic[1]())
+}
+
 func (tc *testContext) TestConnectFailsIfConnectionFactoryReturnsError() {
 	tc.fakeConnectionFactory.mockError = errors.New("failed to create connection instance")
 	assert.Error(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
@@ -357,16 +449,19 @@ type fakeServiceDefinition struct{}
 func (fs *fakeServiceDefinition) GetLocation() market.Location { return market.Location{} }
 
 type MockPaymentIssuer struct {
-	startCalled bool
-	stopCalled  bool
-	MockError   error
-	stopChan    chan struct{}
+	startCalled       bool
+	stopCalled        bool
+	freeCredit        uint64
+	freeCreditOnStart uint64
+	MockError         error
+	stopChan          chan struct{}
 	sync.Mutex
 }
 
 func (mpm *MockPaymentIssuer) Start() error {
 	mpm.Lock()
 	mpm.startCalled = true
+	mpm.freeCreditOnStart = mpm.freeCredit
 	mpm.Unlock()
 	<-mpm.stopChan
 	return mpm.MockError
@@ -390,3 +485,21 @@ func (mpm *MockPaymentIssuer) Stop() {
 	mpm.stopCalled = true
 	close(mpm.stopChan)
 }
+
+func (mpm *MockPaymentIssuer) SetFreeCredit(amount uint64) {
+	mpm.Lock()
+	defer mpm.Unlock()
+	mpm.freeCredit = amount
+}
+
+func (mpm *MockPaymentIssuer) FreeCredit() uint64 {
+	mpm.Lock()
+	defer mpm.Unlock()
+	return mpm.freeCredit
+}
+
+func (mpm *MockPaymentIssuer) FreeCreditOnStart() uint64 {
+	mpm.Lock()
+	defer mpm.Unlock()
+	return mpm.freeCreditOnStart
+}
@@ -22,15 +22,22 @@ type Factory interface {
 	Create(stateChannel StateChannel, statisticsChannel StatisticsChannel) (Connection, error)
 }
 
+// ExporterFactory represents a config exporter constructor
+type ExporterFactory interface {
+	CreateExporter() (ConfigExporter, error)
+}
+
 // Registry holds of all plugable connections
 type Registry struct {
-	creators map[string]Factory
+	creators  map[string]Factory
+	exporters map[string]ExporterFactory
 }
 
 // NewRegistry creates registry of plugable connections
 func NewRegistry() *Registry {
 	return &Registry{
-		creators: make(map[string]Factory),
+		creators:  make(map[string]Factory),
+		exporters: make(map[string]ExporterFactory),
 	}
 }
 
@@ -48,3 +55,18 @@ func (registry *Registry) CreateConnection(serviceType string, stateChannel Stat
 
 	return factory.Create(stateChannel, statisticsChannel)
 }
+
+// RegisterExporter registers config exporter for sessions used by external tunnel clients
+func (registry *Registry) RegisterExporter(serviceType string, exporter ExporterFactory) {
+	registry.exporters[serviceType] = exporter
+}
+
+// CreateExporter creates config exporter of given service type
+func (registry *Registry) CreateExporter(serviceType string) (ConfigExporter, error) {
+	factory, exists := registry.exporters[serviceType]
+	if !exists {
+		return nil, ErrUnsupportedServiceType
+	}
+
+	return factory.CreateExporter()
+}
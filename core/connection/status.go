@@ -45,6 +45,8 @@ type Status struct {
 	State     State
 	SessionID session.ID
 	Proposal  market.ServiceProposal
+	// Reason explains why connection was closed without being asked to, if it was
+	Reason string
 }
 
 func statusConnecting() Status {
@@ -52,13 +54,17 @@ func statusConnecting() Status {
 }
 
 func statusConnected(sessionID session.ID, proposal market.ServiceProposal) Status {
-	return Status{Connected, sessionID, proposal}
+	return Status{State: Connected, SessionID: sessionID, Proposal: proposal}
 }
 
 func statusNotConnected() Status {
 	return Status{State: NotConnected}
 }
 
+func statusDisconnected(reason string) Status {
+	return Status{State: NotConnected, Reason: reason}
+}
+
 func statusReconnecting() Status {
 	return Status{State: Reconnecting}
 }
@@ -24,8 +24,11 @@ import (
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/consumer"
+	"github.com/mysteriumnetwork/node/consumer/spending"
 	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/session/promise"
 )
 
 // StubPublisherEvent represents the event in publishers history
@@ -217,8 +220,9 @@ func (foc *connectionMock) StateCallback(callback func(state fakeState)) {
 const fakeDialogLog = "[fake dialog] "
 
 type fakeDialog struct {
-	peerID    identity.Identity
-	sessionID session.ID
+	peerID      identity.Identity
+	sessionID   session.ID
+	paymentInfo *session.PaymentInfo
 
 	closed bool
 	sync.RWMutex
@@ -284,8 +288,57 @@ func (fd *fakeDialog) Request(producer communication.RequestProducer) (responseP
 					ID:     fd.sessionID,
 					Config: []byte("{}"),
 				},
+				PaymentInfo: fd.paymentInfo,
 			},
 			nil
 	}
 	return nil, ErrUnknownRequest
 }
+
+type promiseStateLoaderFake struct {
+	state promise.State
+}
+
+func (loader *promiseStateLoaderFake) Load(consumer, provider identity.Identity) (promise.State, error) {
+	return loader.state, nil
+}
+
+type budgetFake struct {
+	limits    spending.Limits
+	sessionID session.ID
+	closed    bool
+}
+
+func newBudgetFake(consumer, provider identity.Identity, proposal market.ServiceProposal, limits spending.Limits) (SpendingGuard, error) {
+	return &budgetFake{limits: limits}, nil
+}
+
+func (bf *budgetFake) Spend(amount uint64) error {
+	return nil
+}
+
+func (bf *budgetFake) Refund(amount uint64) error {
+	return nil
+}
+
+func (bf *budgetFake) Track(sessionID session.ID) {
+	bf.sessionID = sessionID
+}
+
+func (bf *budgetFake) Close() {
+	bf.closed = true
+}
+
+type failingPaymentIssuer struct {
+	err error
+}
+
+func (fpi *failingPaymentIssuer) Start() error {
+	return fpi.err
+}
+
+func (fpi *failingPaymentIssuer) Stop() {
+}
+
+func (fpi *failingPaymentIssuer) SetFreeCredit(amount uint64) {
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"sync"
+
+	"github.com/mysteriumnetwork/node/consumer"
+	"github.com/mysteriumnetwork/node/datasize"
+)
+
+// TrafficCounter returns the data transferred through the tunnel of the session so far
+type TrafficCounter func() datasize.BitSize
+
+// sessionTraffic counts the data transferred through the tunnel of a single session,
+// as reported by the statistics channel of its connection
+type sessionTraffic struct {
+	last  consumer.SessionStatistics
+	total consumer.SessionStatistics
+	lock  sync.Mutex
+}
+
+// consume adds up the statistics reported by the connection, which may restart counting from zero
+func (st *sessionTraffic) consume(stats consumer.SessionStatistics) {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	st.total = consumer.AddUpStatistics(st.total, st.last.DiffWithNew(stats))
+	st.last = stats
+}
+
+// Consumed returns the data sent and received through the tunnel of the session so far
+func (st *sessionTraffic) Consumed() datasize.BitSize {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	return datasize.BitSize(st.total.BytesSent+st.total.BytesReceived) * datasize.Byte
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"testing"
+
+	"github.com/mysteriumnetwork/node/consumer"
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionTraffic_AddsUpStatisticsOfConnection(t *testing.T) {
+	traffic := &sessionTraffic{}
+	assert.Equal(t, datasize.BitSize(0), traffic.Consumed())
+
+	traffic.consume(consumer.SessionStatistics{BytesSent: 10, BytesReceived: 20})
+	traffic.consume(consumer.SessionStatistics{BytesSent: 15, BytesReceived: 40})
+	assert.Equal(t, 55*datasize.Byte, traffic.Consumed())
+
+	// connection restarted counting, e.g. after reconnect
+	traffic.consume(consumer.SessionStatistics{BytesSent: 5, BytesReceived: 5})
+	assert.Equal(t, 65*datasize.Byte, traffic.Consumed())
+}
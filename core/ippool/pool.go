@@ -0,0 +1,205 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ippool
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// minRoutePrefix is the shortest route prefix considered when looking for conflicts.
+// Broader routes (default gateway, split default routes of an active tunnel) overlap with everything.
+const minRoutePrefix = 8
+
+// ErrPoolExhausted is returned when there are no more free subnets left in the pool
+var ErrPoolExhausted = errors.New("no more free subnets in the pool")
+
+// HostNetworksFunc returns networks which are already in use by the host
+type HostNetworksFunc func() ([]net.IPNet, error)
+
+// Pool hands out non-overlapping IPv4 subnets of a fixed size from the base network.
+// Subnets which collide with host interface addresses or routes are skipped.
+type Pool struct {
+	base         net.IPNet
+	prefix       int
+	used         map[string]struct{}
+	hostNetworks HostNetworksFunc
+	mu           sync.Mutex
+}
+
+// NewPool creates a pool of subnets with the given prefix length carved from the base network
+func NewPool(base net.IPNet, prefix int) *Pool {
+	return &Pool{
+		base:         base,
+		prefix:       prefix,
+		used:         make(map[string]struct{}),
+		hostNetworks: HostNetworks,
+	}
+}
+
+// Base returns the network subnets are allocated from
+func (p *Pool) Base() net.IPNet {
+	return p.base
+}
+
+// Allocate returns the first subnet from the pool, which is not yet allocated and does not collide with host networks
+func (p *Pool) Allocate() (net.IPNet, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	hostNetworks, err := p.hostNetworks()
+	if err != nil {
+		return net.IPNet{}, errors.Wrap(err, "failed to list host networks")
+	}
+
+	baseIP := p.base.IP.To4()
+	baseOnes, bits := p.base.Mask.Size()
+	if baseIP == nil || bits != 8*net.IPv4len || p.prefix < baseOnes || p.prefix > bits {
+		return net.IPNet{}, fmt.Errorf("can not allocate /%d subnets from %s", p.prefix, p.base.String())
+	}
+
+	count := uint64(1) << uint(p.prefix-baseOnes)
+	start := binary.BigEndian.Uint32(baseIP)
+	for i := uint64(0); i < count; i++ {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, start+uint32(i<<uint(bits-p.prefix)))
+		subnet := net.IPNet{IP: ip, Mask: net.CIDRMask(p.prefix, bits)}
+
+		if _, ok := p.used[subnet.String()]; ok {
+			continue
+		}
+		if _, conflict := FindConflict(subnet, hostNetworks); conflict {
+			continue
+		}
+
+		p.used[subnet.String()] = struct{}{}
+		return subnet, nil
+	}
+
+	return net.IPNet{}, ErrPoolExhausted
+}
+
+// Release returns given subnet back to the pool. Any address within the subnet is accepted.
+func (p *Pool) Release(subnet net.IPNet) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	network := net.IPNet{IP: subnet.IP.Mask(subnet.Mask), Mask: subnet.Mask}
+	if _, ok := p.used[network.String()]; !ok {
+		return errors.New("allocated subnet not found")
+	}
+
+	delete(p.used, network.String())
+	return nil
+}
+
+// SelectNetwork returns the configured network if it does not collide with host networks.
+// If nothing is configured, the first candidate free of collisions is returned.
+func SelectNetwork(configured string, candidates []string) (net.IPNet, error) {
+	return selectNetwork(configured, candidates, HostNetworks)
+}
+
+func selectNetwork(configured string, candidates []string, hostNetworksFunc HostNetworksFunc) (net.IPNet, error) {
+	hostNetworks, err := hostNetworksFunc()
+	if err != nil {
+		return net.IPNet{}, errors.Wrap(err, "failed to list host networks")
+	}
+
+	if configured != "" {
+		network, err := parseNetwork(configured)
+		if err != nil {
+			return net.IPNet{}, err
+		}
+		if conflict, ok := FindConflict(network, hostNetworks); ok {
+			return net.IPNet{}, fmt.Errorf("subnet %s conflicts with host network %s", network.String(), conflict.String())
+		}
+		return network, nil
+	}
+
+	for _, candidate := range candidates {
+		network, err := parseNetwork(candidate)
+		if err != nil {
+			return net.IPNet{}, err
+		}
+		if _, ok := FindConflict(network, hostNetworks); !ok {
+			return network, nil
+		}
+	}
+
+	return net.IPNet{}, errors.New("all candidate subnets conflict with host networks")
+}
+
+// FindConflict returns the first host network overlapping with the given subnet
+func FindConflict(subnet net.IPNet, hostNetworks []net.IPNet) (net.IPNet, bool) {
+	for _, hostNetwork := range hostNetworks {
+		if Overlaps(subnet, hostNetwork) {
+			return hostNetwork, true
+		}
+	}
+	return net.IPNet{}, false
+}
+
+// Overlaps reports whether two networks share at least one address
+func Overlaps(a, b net.IPNet) bool {
+	return a.Contains(b.IP.Mask(b.Mask)) || b.Contains(a.IP.Mask(a.Mask))
+}
+
+// HostNetworks returns IPv4 networks assigned to host interfaces and present in the routing table
+func HostNetworks() ([]net.IPNet, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var networks []net.IPNet
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() == nil || ipNet.IP.IsLoopback() {
+			continue
+		}
+		networks = append(networks, net.IPNet{IP: ipNet.IP.Mask(ipNet.Mask), Mask: ipNet.Mask})
+	}
+
+	routes, err := hostRoutes()
+	if err != nil {
+		return nil, err
+	}
+	for _, route := range routes {
+		if ones, _ := route.Mask.Size(); ones < minRoutePrefix {
+			continue
+		}
+		networks = append(networks, route)
+	}
+
+	return networks, nil
+}
+
+func parseNetwork(cidr string) (net.IPNet, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return net.IPNet{}, errors.Wrap(err, "invalid subnet")
+	}
+	if network.IP.To4() == nil {
+		return net.IPNet{}, fmt.Errorf("invalid subnet %s: only IPv4 is supported", cidr)
+	}
+	return *network, nil
+}
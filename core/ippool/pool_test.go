@@ -0,0 +1,125 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ippool
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Pool_AllocateSkipsHostNetworks(t *testing.T) {
+	pool := newTestPool("10.182.0.0/16", 24, "10.182.0.0/24", "10.182.1.128/25")
+
+	subnet, err := pool.Allocate()
+	assert.NoError(t, err)
+	assert.Equal(t, "10.182.2.0/24", subnet.String())
+
+	subnet, err = pool.Allocate()
+	assert.NoError(t, err)
+	assert.Equal(t, "10.182.3.0/24", subnet.String())
+}
+
+func Test_Pool_AllocateReturnsErrorWhenExhausted(t *testing.T) {
+	pool := newTestPool("10.8.0.0/23", 24, "10.8.1.0/24")
+
+	subnet, err := pool.Allocate()
+	assert.NoError(t, err)
+	assert.Equal(t, "10.8.0.0/24", subnet.String())
+
+	_, err = pool.Allocate()
+	assert.Equal(t, ErrPoolExhausted, err)
+}
+
+func Test_Pool_ReleaseAcceptsAnyAddressWithinSubnet(t *testing.T) {
+	pool := newTestPool("10.8.0.0/24", 24)
+
+	subnet, err := pool.Allocate()
+	assert.NoError(t, err)
+
+	subnet.IP[3] = 1
+	assert.NoError(t, pool.Release(subnet))
+	assert.Error(t, pool.Release(subnet))
+
+	subnet, err = pool.Allocate()
+	assert.NoError(t, err)
+	assert.Equal(t, "10.8.0.0/24", subnet.String())
+}
+
+func Test_SelectNetwork(t *testing.T) {
+	hostNetworks := hostNetworksStub("10.182.0.0/16", "192.168.1.0/24")
+	candidates := []string{"10.182.0.0/16", "10.183.0.0/16"}
+
+	var tests = []struct {
+		configured      string
+		expectedNetwork string
+		expectedError   bool
+	}{
+		{"", "10.183.0.0/16", false},
+		{"172.16.0.0/16", "172.16.0.0/16", false},
+		{"192.168.0.0/16", "", true},
+		{"fd00::/64", "", true},
+		{"not-a-subnet", "", true},
+	}
+
+	for _, test := range tests {
+		network, err := selectNetwork(test.configured, candidates, hostNetworks)
+		if test.expectedError {
+			assert.Error(t, err, test.configured)
+			continue
+		}
+		assert.NoError(t, err, test.configured)
+		assert.Equal(t, test.expectedNetwork, network.String(), test.configured)
+	}
+}
+
+func Test_SelectNetworkFailsWhenAllCandidatesConflict(t *testing.T) {
+	_, err := selectNetwork("", []string{"10.8.0.0/24"}, hostNetworksStub("10.0.0.0/8"))
+	assert.Error(t, err)
+}
+
+func Test_Overlaps(t *testing.T) {
+	assert.True(t, Overlaps(mustParseNetwork("10.0.0.0/8"), mustParseNetwork("10.182.3.0/24")))
+	assert.True(t, Overlaps(mustParseNetwork("10.182.3.0/24"), mustParseNetwork("10.0.0.0/8")))
+	assert.False(t, Overlaps(mustParseNetwork("10.182.3.0/24"), mustParseNetwork("10.182.4.0/24")))
+}
+
+func newTestPool(base string, prefix int, hostNetworks ...string) *Pool {
+	pool := NewPool(mustParseNetwork(base), prefix)
+	pool.hostNetworks = hostNetworksStub(hostNetworks...)
+	return pool
+}
+
+func hostNetworksStub(cidrs ...string) HostNetworksFunc {
+	return func() ([]net.IPNet, error) {
+		var networks []net.IPNet
+		for _, cidr := range cidrs {
+			networks = append(networks, mustParseNetwork(cidr))
+		}
+		return networks, nil
+	}
+}
+
+func mustParseNetwork(cidr string) net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return *network
+}
@@ -0,0 +1,85 @@
+// +build linux
+
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ippool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const routeTablePath = "/proc/net/route"
+
+func hostRoutes() ([]net.IPNet, error) {
+	file, err := os.Open(routeTablePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseRouteTable(file)
+}
+
+// parseRouteTable parses routes in the /proc/net/route format, where destination and mask are little endian hex values
+func parseRouteTable(reader io.Reader) ([]net.IPNet, error) {
+	var routes []net.IPNet
+
+	scanner := bufio.NewScanner(reader)
+	for lineNo := 0; scanner.Scan(); lineNo++ {
+		fields := strings.Fields(scanner.Text())
+		if lineNo == 0 || len(fields) < 8 {
+			// skip the header and malformed lines
+			continue
+		}
+
+		destination, err := parseHexIP(fields[1])
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse route destination")
+		}
+		mask, err := parseHexIP(fields[7])
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse route mask")
+		}
+
+		routes = append(routes, net.IPNet{IP: destination, Mask: net.IPMask(mask)})
+	}
+
+	return routes, scanner.Err()
+}
+
+func parseHexIP(value string) (net.IP, error) {
+	decoded, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) != net.IPv4len {
+		return nil, errors.New("unexpected address length: " + value)
+	}
+
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(decoded))
+	return ip, nil
+}
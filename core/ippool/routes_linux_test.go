@@ -0,0 +1,48 @@
+// +build linux
+
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ippool
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const routeTable = `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth0	00000000	0101A8C0	0003	0	0	100	00000000	0	0	0
+eth0	0001A8C0	00000000	0001	0	0	100	00FFFFFF	0	0	0
+myst0	0000B60A	00000000	0001	0	0	0	0000FFFF	0	0	0
+`
+
+func Test_ParseRouteTable(t *testing.T) {
+	routes, err := parseRouteTable(strings.NewReader(routeTable))
+
+	assert.NoError(t, err)
+	assert.Len(t, routes, 3)
+	assert.Equal(t, "0.0.0.0/0", routes[0].String())
+	assert.Equal(t, "192.168.1.0/24", routes[1].String())
+	assert.Equal(t, "10.182.0.0/16", routes[2].String())
+}
+
+func Test_ParseRouteTableFailsOnInvalidDestination(t *testing.T) {
+	_, err := parseRouteTable(strings.NewReader("header\neth0	XYZ	0	0	0	0	0	00FFFFFF\n"))
+	assert.Error(t, err)
+}
@@ -36,8 +36,11 @@ type Options struct {
 
 	Keystore OptionsKeystore
 
+	Wireguard OptionsWireguard
+
 	Openvpn  Openvpn
 	Location OptionsLocation
+	Spending OptionsSpending
 	OptionsNetwork
 }
 
@@ -45,3 +48,8 @@ type Options struct {
 type OptionsKeystore struct {
 	UseLightweight bool
 }
+
+// OptionsWireguard stores the wireguard implementation configuration shared by consumer and provider
+type OptionsWireguard struct {
+	Mode string
+}
@@ -17,6 +17,13 @@
 
 package node
 
+const (
+	// DiscoveryTypeAPI discovers proposals through centralised discovery API
+	DiscoveryTypeAPI = "api"
+	// DiscoveryTypeBroker discovers proposals from broadcasts over message broker
+	DiscoveryTypeBroker = "broker"
+)
+
 // OptionsNetwork describes possible parameters of network configuration
 type OptionsNetwork struct {
 	Testnet  bool
@@ -25,6 +32,7 @@ type OptionsNetwork struct {
 	ExperimentIdentityCheck bool
 	ExperimentPayments      bool
 
+	DiscoveryType       string
 	DiscoveryAPIAddress string
 	BrokerAddress       string
 
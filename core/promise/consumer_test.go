@@ -127,7 +127,7 @@ type fakePayment struct {
 }
 
 func (fp fakePayment) GetPrice() money.Money {
-	return money.Money{Amount: fp.amount}
+	return money.NewUnits(fp.amount, "")
 }
 
 func fakeBlockchain(balance uint64) identity.Balance {
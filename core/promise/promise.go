@@ -98,7 +98,11 @@ func (sp *SignedPromise) Validate(proposal market.ServiceProposal, balance ident
 
 	price := proposal.PaymentMethod.GetPrice()
 	promisedValue := sp.Promise.Amount
-	if promisedValue.Amount < price.Amount {
+	cmp, err := promisedValue.Cmp(price)
+	if err != nil {
+		return err
+	}
+	if cmp < 0 {
 		return errLowAmount
 	}
 
@@ -106,7 +110,7 @@ func (sp *SignedPromise) Validate(proposal market.ServiceProposal, balance ident
 	if err != nil {
 		return err
 	}
-	if issuerBalance < promisedValue.Amount {
+	if cmp, _ = money.NewUnits(issuerBalance, promisedValue.Currency).Cmp(promisedValue); cmp < 0 {
 		return errLowBalance
 	}
 
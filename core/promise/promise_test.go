@@ -32,13 +32,13 @@ func TestNewPromise(t *testing.T) {
 	promise := NewPromise(
 		identity.Identity{Address: "Consumer"},
 		identity.Identity{Address: "Provider"},
-		money.Money{Amount: 123, Currency: CurrencyToken},
+		money.NewUnits(123, CurrencyToken),
 	)
 
 	assert.Equal(t, 1, promise.SerialNumber)
 	assert.Equal(t, "Consumer", promise.IssuerID)
 	assert.Equal(t, "Provider", promise.BenefiterID)
-	assert.Equal(t, uint64(123), promise.Amount.Amount)
+	assert.Equal(t, money.NewUnits(123, CurrencyToken), promise.Amount)
 	assert.Equal(t, CurrencyToken, promise.Amount.Currency)
 }
 
@@ -46,7 +46,7 @@ func TestSignByIssuer(t *testing.T) {
 	promise := NewPromise(
 		identity.Identity{Address: "Consumer"},
 		identity.Identity{Address: "Provider"},
-		money.Money{Amount: 123, Currency: "TEST"},
+		money.NewUnits(123, "TEST"),
 	)
 
 	signedPromise, err := promise.SignByIssuer(&fakeSigner{})
@@ -39,7 +39,7 @@ var (
 type Service interface {
 	Serve(providerID identity.Identity) error
 	Stop() error
-	ProvideConfig(publicKey json.RawMessage) (session.ServiceConfiguration, session.DestroyCallback, error)
+	ProvideConfig(sessionID session.ID, publicKey json.RawMessage) (session.ServiceConfiguration, session.DestroyCallback, error)
 }
 
 // DialogWaiterFactory initiates communication channel which waits for incoming dialogs
@@ -103,7 +103,9 @@ func (manager *Manager) Start(providerID identity.Identity, serviceType string,
 	}
 
 	discovery := manager.discoveryFactory()
-	discovery.Start(providerID, proposal)
+	if err = discovery.Start(providerID, proposal); err != nil {
+		return err
+	}
 
 	err = service.Serve(providerID)
 	if err != nil {
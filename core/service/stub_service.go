@@ -42,6 +42,6 @@ func (service *serviceFake) GetType() string {
 	return "fake"
 }
 
-func (service *serviceFake) ProvideConfig(publicKey json.RawMessage) (session.ServiceConfiguration, session.DestroyCallback, error) {
+func (service *serviceFake) ProvideConfig(sessionID session.ID, publicKey json.RawMessage) (session.ServiceConfiguration, session.DestroyCallback, error) {
 	return struct{}{}, func() {}, nil
 }
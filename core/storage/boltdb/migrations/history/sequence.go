@@ -31,4 +31,10 @@ var Sequence = []migrations.Migration{
 			2018, 12, 04, 12, 00, 00, 0, time.UTC),
 		Migrate: migrations.MigrateSessionToHistory,
 	},
+	{
+		Name: "promise-amount-to-money",
+		Date: time.Date(
+			2019, 05, 20, 12, 00, 00, 0, time.UTC),
+		Migrate: migrations.MigratePromiseAmountToMoney,
+	},
 }
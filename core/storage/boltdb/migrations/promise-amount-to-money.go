@@ -0,0 +1,72 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package migrations
+
+import (
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/session/promise"
+)
+
+const storedPromiseBucketPrefix = "stored-promise-"
+
+// StoredPromise is the stored promise as it was kept before its unconsumed amount became money
+type StoredPromise struct {
+	SequenceID       uint64 `storm:"id"`
+	Message          *promise.Message
+	AddedAt          time.Time
+	UpdatedAt        time.Time
+	UnconsumedAmount uint64
+}
+
+// ToMoney converts the stored promise to keep its unconsumed amount as money.
+// MYST was the only currency promises were issued in.
+func (sp StoredPromise) ToMoney() promise.StoredPromise {
+	return promise.StoredPromise{
+		SequenceID:       sp.SequenceID,
+		Message:          sp.Message,
+		AddedAt:          sp.AddedAt,
+		UpdatedAt:        sp.UpdatedAt,
+		UnconsumedAmount: money.NewUnits(sp.UnconsumedAmount, money.CURRENCY_MYST),
+	}
+}
+
+// MigratePromiseAmountToMoney converts unconsumed amounts of all stored promises to money
+func MigratePromiseAmountToMoney(db *storm.DB) error {
+	tx, err := db.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, bucket := range tx.PrefixScan(storedPromiseBucketPrefix) {
+		var promises []StoredPromise
+		if err := bucket.All(&promises); err != nil {
+			return err
+		}
+		for i := range promises {
+			converted := promises[i].ToMoney()
+			if err := bucket.Save(&converted); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package migrations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/core/storage/boltdb/boltdbtest"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/session/promise"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromiseAmountToMoneyMigrationWithNoData(t *testing.T) {
+	file, db := boltdbtest.CreateDB(t)
+	defer boltdbtest.CleanupDB(t, file, db)
+
+	assert.NoError(t, MigratePromiseAmountToMoney(db))
+}
+
+func TestPromiseAmountToMoneyMigrationWithData(t *testing.T) {
+	file, db := boltdbtest.CreateDB(t)
+	defer boltdbtest.CleanupDB(t, file, db)
+
+	bucket := storedPromiseBucketPrefix + "0x000000000000000000000000000000000000000a"
+	addedAt := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	oldPromises := []StoredPromise{
+		{SequenceID: 1, AddedAt: addedAt},
+		{SequenceID: 2, AddedAt: addedAt, Message: &promise.Message{SequenceID: 2, Amount: 150}, UnconsumedAmount: 20},
+	}
+	for i := range oldPromises {
+		assert.NoError(t, db.From(bucket).Save(&oldPromises[i]))
+	}
+	otherBucket := storedPromiseBucketPrefix + "0x000000000000000000000000000000000000000b"
+	assert.NoError(t, db.From(otherBucket).Save(&StoredPromise{SequenceID: 1, AddedAt: addedAt, UnconsumedAmount: 5}))
+	assert.NoError(t, db.From("session-history").Save(&oldSessionMock))
+
+	assert.NoError(t, MigratePromiseAmountToMoney(db))
+
+	var promises []promise.StoredPromise
+	assert.NoError(t, db.From(bucket).All(&promises))
+	assert.Equal(
+		t,
+		[]promise.StoredPromise{
+			{SequenceID: 1, AddedAt: addedAt, UnconsumedAmount: money.Money{Currency: money.CURRENCY_MYST}},
+			{
+				SequenceID:       2,
+				AddedAt:          addedAt,
+				Message:          &promise.Message{SequenceID: 2, Amount: 150},
+				UnconsumedAmount: money.NewUnits(20, money.CURRENCY_MYST),
+			},
+		},
+		promises,
+	)
+
+	var otherPromises []promise.StoredPromise
+	assert.NoError(t, db.From(otherBucket).All(&otherPromises))
+	assert.Equal(
+		t,
+		[]promise.StoredPromise{{SequenceID: 1, AddedAt: addedAt, UnconsumedAmount: money.NewUnits(5, money.CURRENCY_MYST)}},
+		otherPromises,
+	)
+}
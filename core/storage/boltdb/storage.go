@@ -44,6 +44,22 @@ func (b *Bolt) Store(bucket string, data interface{}) error {
 	return b.db.From(bucket).Save(data)
 }
 
+// StoreAll keeps all the given structs in the bucket, either all of them are stored or none
+func (b *Bolt) StoreAll(bucket string, data ...interface{}) error {
+	tx, err := b.db.From(bucket).Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, object := range data {
+		if err := tx.Save(object); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 // GetAllFrom allows to get all structs from the bucket
 func (b *Bolt) GetAllFrom(bucket string, data interface{}) error {
 	return b.db.From(bucket).All(data)
@@ -69,9 +85,15 @@ func (b *Bolt) GetLast(bucket string, to interface{}) error {
 	return b.db.From(bucket).Select().Reverse().First(to)
 }
 
-// GetBuckets returns a list of buckets
+// GetBuckets returns the names of all top level buckets
 func (b *Bolt) GetBuckets() []string {
-	return b.db.Bucket()
+	nodes := b.db.PrefixScan("")
+	buckets := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		path := node.Bucket()
+		buckets = append(buckets, path[len(path)-1])
+	}
+	return buckets
 }
 
 // Close closes database
@@ -113,3 +113,43 @@ func Test_StorageGetLast_ErrsOnEmpty(t *testing.T) {
 	err = storage.GetLast(bucket, &result)
 	assert.Equal(t, "not found", err.Error())
 }
+
+func Test_StorageStoreAll(t *testing.T) {
+	storage, close, err := createMockStorage(t)
+	assert.Nil(t, err)
+	defer close()
+
+	err = storage.StoreAll(bucket, &myTestType{ID: 1}, &myTestType{ID: 2})
+	assert.Nil(t, err)
+
+	var result []myTestType
+	err = storage.GetAllFrom(bucket, &result)
+	assert.Nil(t, err)
+	assert.Equal(t, []myTestType{{ID: 1}, {ID: 2}}, result)
+}
+
+func Test_StorageStoreAll_StoresNothingOnFailure(t *testing.T) {
+	storage, close, err := createMockStorage(t)
+	assert.Nil(t, err)
+	defer close()
+
+	err = storage.StoreAll(bucket, &myTestType{ID: 1}, myTestType{ID: 2})
+	assert.NotNil(t, err)
+
+	var result myTestType
+	err = storage.GetOneByField(bucket, "ID", int64(1), &result)
+	assert.Equal(t, "not found", err.Error())
+}
+
+func Test_StorageGetBuckets(t *testing.T) {
+	storage, close, err := createMockStorage(t)
+	assert.Nil(t, err)
+	defer close()
+
+	assert.Nil(t, storage.Store(bucket, &myTestType{ID: 1}))
+	assert.Nil(t, storage.Store("other", &myTestType{ID: 1}))
+
+	buckets := storage.GetBuckets()
+	assert.Contains(t, buckets, bucket)
+	assert.Contains(t, buckets, "other")
+}
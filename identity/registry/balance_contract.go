@@ -0,0 +1,116 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package registry
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/payments/contracts/abigen"
+	"github.com/mysteriumnetwork/payments/mysttoken"
+	"github.com/pkg/errors"
+)
+
+// Balance is the amount of MYST identity has on blockchain
+type Balance struct {
+	// Token is the amount of MYST tokens owned by identity
+	Token money.Money
+	// Payments is the amount deposited to the payments contract, which promises are paid from
+	Payments money.Money
+}
+
+// BalanceProvider provides on-chain balance of identity
+type BalanceProvider interface {
+	Balance(id identity.Identity) (Balance, error)
+}
+
+// ContractBalance reads identity balance from MYST token and payments contract
+type ContractBalance struct {
+	backend         bind.ContractBackend
+	contractSession *abigen.IdentityPromisesCallerSession
+
+	token     *mysttoken.MystTokenCaller
+	tokenLock sync.Mutex
+}
+
+// NewContractBalance creates balance provider which uses payments contract at given address.
+// The token address is asked from payments contract on first use.
+func NewContractBalance(contractBackend bind.ContractBackend, paymentsAddress common.Address) (*ContractBalance, error) {
+	contract, err := abigen.NewIdentityPromisesCaller(paymentsAddress, contractBackend)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ContractBalance{
+		backend: contractBackend,
+		contractSession: &abigen.IdentityPromisesCallerSession{
+			Contract: contract,
+			CallOpts: bind.CallOpts{
+				Pending: false,
+			},
+		},
+	}, nil
+}
+
+// Balance returns MYST token and payments contract balances of identity
+func (cb *ContractBalance) Balance(id identity.Identity) (Balance, error) {
+	token, err := cb.tokenCaller()
+	if err != nil {
+		return Balance{}, err
+	}
+
+	address := common.HexToAddress(id.Address)
+	tokenBalance, err := token.BalanceOf(&cb.contractSession.CallOpts, address)
+	if err != nil {
+		return Balance{}, errors.Wrap(err, "failed to get token balance")
+	}
+	paymentsBalance, err := cb.contractSession.Balances(address)
+	if err != nil {
+		return Balance{}, errors.Wrap(err, "failed to get payments balance")
+	}
+
+	return Balance{
+		Token:    money.NewBigUnits(tokenBalance, money.CURRENCY_MYST),
+		Payments: money.NewBigUnits(paymentsBalance, money.CURRENCY_MYST),
+	}, nil
+}
+
+func (cb *ContractBalance) tokenCaller() (*mysttoken.MystTokenCaller, error) {
+	cb.tokenLock.Lock()
+	defer cb.tokenLock.Unlock()
+
+	if cb.token != nil {
+		return cb.token, nil
+	}
+
+	tokenAddress, err := cb.contractSession.Token()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get token address")
+	}
+	token, err := mysttoken.NewMystTokenCaller(tokenAddress, cb.backend)
+	if err != nil {
+		return nil, err
+	}
+	cb.token = token
+	return token, nil
+}
+
+var _ BalanceProvider = &ContractBalance{}
@@ -0,0 +1,141 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package registry
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/payments/contracts/abigen"
+	"github.com/mysteriumnetwork/payments/mysttoken"
+	"github.com/stretchr/testify/assert"
+)
+
+var deployerKey, _ = crypto.GenerateKey()
+
+type deployedContracts struct {
+	backend         *backends.SimulatedBackend
+	transactor      *bind.TransactOpts
+	token           *mysttoken.MystToken
+	paymentsAddress common.Address
+}
+
+func deployContracts(t *testing.T) deployedContracts {
+	transactor := bind.NewKeyedTransactor(deployerKey)
+	backend := backends.NewSimulatedBackend(
+		core.GenesisAlloc{
+			transactor.From: core.GenesisAccount{Balance: big.NewInt(1000000000000000000)},
+		},
+		8000000,
+	)
+
+	tokenAddress, _, token, err := mysttoken.DeployMystToken(transactor, backend)
+	assert.NoError(t, err)
+	backend.Commit()
+
+	paymentsAddress, _, _, err := abigen.DeployIdentityPromises(transactor, backend, tokenAddress, big.NewInt(100))
+	assert.NoError(t, err)
+	backend.Commit()
+
+	return deployedContracts{
+		backend:         backend,
+		transactor:      transactor,
+		token:           token,
+		paymentsAddress: paymentsAddress,
+	}
+}
+
+func TestContractBalance_ReturnsTokenAndPaymentsBalances(t *testing.T) {
+	contracts := deployContracts(t)
+	id := identity.FromAddress("0x000000000000000000000000000000000000beef")
+
+	_, err := contracts.token.Mint(contracts.transactor, common.HexToAddress(id.Address), big.NewInt(1500))
+	assert.NoError(t, err)
+	contracts.backend.Commit()
+
+	balances, err := NewContractBalance(contracts.backend, contracts.paymentsAddress)
+	assert.NoError(t, err)
+
+	balance, err := balances.Balance(id)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		Balance{
+			Token:    money.NewUnits(1500, money.CURRENCY_MYST),
+			Payments: money.NewUnits(0, money.CURRENCY_MYST),
+		},
+		balance,
+	)
+}
+
+func TestContractBalance_ReturnsBalanceAboveUint64(t *testing.T) {
+	contracts := deployContracts(t)
+	id := identity.FromAddress("0x000000000000000000000000000000000000beef")
+
+	minted := new(big.Int).Lsh(big.NewInt(1), 70)
+	_, err := contracts.token.Mint(contracts.transactor, common.HexToAddress(id.Address), minted)
+	assert.NoError(t, err)
+	contracts.backend.Commit()
+
+	balances, err := NewContractBalance(contracts.backend, contracts.paymentsAddress)
+	assert.NoError(t, err)
+
+	balance, err := balances.Balance(id)
+	assert.NoError(t, err)
+	assert.Equal(t, money.NewBigUnits(minted, money.CURRENCY_MYST), balance.Token)
+}
+
+func TestContractBalance_ReturnsZeroForUnknownIdentity(t *testing.T) {
+	contracts := deployContracts(t)
+
+	balances, err := NewContractBalance(contracts.backend, contracts.paymentsAddress)
+	assert.NoError(t, err)
+
+	balance, err := balances.Balance(identity.FromAddress("0x000000000000000000000000000000000000cafe"))
+	assert.NoError(t, err)
+	assert.True(t, balance.Token.IsZero())
+	assert.True(t, balance.Payments.IsZero())
+}
+
+func TestContractBalance_FailsWithoutPaymentsContract(t *testing.T) {
+	contracts := deployContracts(t)
+
+	balances, err := NewContractBalance(contracts.backend, common.HexToAddress("0x1"))
+	assert.NoError(t, err)
+
+	_, err = balances.Balance(identity.FromAddress("0x000000000000000000000000000000000000cafe"))
+	assert.Error(t, err)
+}
+
+func TestIdentityRegistryContract_UnregisteredIdentity(t *testing.T) {
+	contracts := deployContracts(t)
+
+	registry, err := NewIdentityRegistryContract(contracts.backend, contracts.paymentsAddress)
+	assert.NoError(t, err)
+
+	registered, err := registry.IsRegistered(identity.FromAddress("0x000000000000000000000000000000000000cafe"))
+	assert.NoError(t, err)
+	assert.False(t, registered)
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package registry
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/identity"
+)
+
+// Status is the on-chain state of identity
+type Status struct {
+	Registered bool
+	Balance    Balance
+	UpdatedAt  time.Time
+}
+
+// unusedStatusTimeout is how long status is kept refreshed without anyone asking for it
+const unusedStatusTimeout = 10 * time.Minute
+
+// StatusCache keeps on-chain statuses of identities and periodically refreshes them.
+// Identities are refreshed only after they were asked for once,
+// and are forgotten when nobody asked for them during unusedStatusTimeout.
+type StatusCache struct {
+	balances      BalanceProvider
+	registry      IdentityRegistry
+	interval      time.Duration
+	unusedTimeout time.Duration
+	now           func() time.Time
+
+	statuses map[string]cachedStatus
+	lock     sync.Mutex
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewStatusCache creates status cache which refreshes statuses every given interval
+func NewStatusCache(balances BalanceProvider, registry IdentityRegistry, interval time.Duration) *StatusCache {
+	return &StatusCache{
+		balances:      balances,
+		registry:      registry,
+		interval:      interval,
+		unusedTimeout: unusedStatusTimeout,
+		now:           time.Now,
+		statuses:      make(map[string]cachedStatus),
+		stop:          make(chan struct{}),
+	}
+}
+
+type cachedStatus struct {
+	status Status
+	usedAt time.Time
+}
+
+// Get returns cached status of identity, it is fetched from blockchain when identity is not known yet
+func (sc *StatusCache) Get(id identity.Identity) (Status, error) {
+	sc.lock.Lock()
+	cached, found := sc.statuses[id.Address]
+	if found {
+		cached.usedAt = sc.now()
+		sc.statuses[id.Address] = cached
+	}
+	sc.lock.Unlock()
+	if found {
+		return cached.status, nil
+	}
+
+	return sc.refresh(id)
+}
+
+// Start starts periodic refresh of known statuses
+func (sc *StatusCache) Start() {
+	go func() {
+		for {
+			select {
+			case <-sc.stop:
+				return
+			case <-time.After(sc.interval):
+				sc.refreshAll()
+			}
+		}
+	}()
+}
+
+// Stop stops periodic refresh
+func (sc *StatusCache) Stop() {
+	sc.stopOnce.Do(func() {
+		close(sc.stop)
+	})
+}
+
+func (sc *StatusCache) refreshAll() {
+	sc.lock.Lock()
+	ids := make([]identity.Identity, 0, len(sc.statuses))
+	for address, cached := range sc.statuses {
+		if sc.now().Sub(cached.usedAt) > sc.unusedTimeout {
+			delete(sc.statuses, address)
+			continue
+		}
+		ids = append(ids, identity.FromAddress(address))
+	}
+	sc.lock.Unlock()
+
+	for _, id := range ids {
+		if _, err := sc.refresh(id); err != nil {
+			log.Warn(logPrefix, "failed to refresh status of identity ", id.Address, ": ", err)
+		}
+	}
+}
+
+func (sc *StatusCache) refresh(id identity.Identity) (Status, error) {
+	registered, err := sc.registry.IsRegistered(id)
+	if err != nil {
+		return Status{}, err
+	}
+	balance, err := sc.balances.Balance(id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	status := Status{
+		Registered: registered,
+		Balance:    balance,
+		UpdatedAt:  sc.now().UTC(),
+	}
+	sc.lock.Lock()
+	cached, found := sc.statuses[id.Address]
+	if !found {
+		cached.usedAt = sc.now()
+	}
+	cached.status = status
+	sc.statuses[id.Address] = cached
+	sc.lock.Unlock()
+	return status, nil
+}
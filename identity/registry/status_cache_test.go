@@ -0,0 +1,171 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package registry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeBalanceProvider struct {
+	balance Balance
+	err     error
+	calls   int
+}
+
+func (fbp *fakeBalanceProvider) Balance(id identity.Identity) (Balance, error) {
+	fbp.calls++
+	return fbp.balance, fbp.err
+}
+
+var (
+	cacheTestIdentity = identity.FromAddress("0x000000000000000000000000000000000000beef")
+	cacheTestTime     = time.Date(2018, 11, 1, 10, 0, 0, 0, time.UTC)
+)
+
+func myst(units uint64) money.Money {
+	return money.NewUnits(units, money.CURRENCY_MYST)
+}
+
+func newTestStatusCache(balances BalanceProvider, registry IdentityRegistry) *StatusCache {
+	cache := NewStatusCache(balances, registry, time.Hour)
+	cache.now = func() time.Time {
+		return cacheTestTime
+	}
+	return cache
+}
+
+func TestStatusCache_GetFetchesUnknownIdentity(t *testing.T) {
+	balances := &fakeBalanceProvider{balance: Balance{Token: myst(10), Payments: myst(5)}}
+	cache := newTestStatusCache(balances, &FakeRegistry{Registered: true})
+
+	status, err := cache.Get(cacheTestIdentity)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		Status{Registered: true, Balance: Balance{Token: myst(10), Payments: myst(5)}, UpdatedAt: cacheTestTime},
+		status,
+	)
+	assert.Equal(t, 1, balances.calls)
+}
+
+func TestStatusCache_GetReturnsCachedStatus(t *testing.T) {
+	balances := &fakeBalanceProvider{balance: Balance{Token: myst(10)}}
+	cache := newTestStatusCache(balances, &FakeRegistry{})
+
+	_, err := cache.Get(cacheTestIdentity)
+	assert.NoError(t, err)
+
+	balances.balance = Balance{Token: myst(20)}
+	status, err := cache.Get(cacheTestIdentity)
+	assert.NoError(t, err)
+	assert.Equal(t, Balance{Token: myst(10)}, status.Balance)
+	assert.Equal(t, 1, balances.calls)
+}
+
+func TestStatusCache_GetReturnsFetchError(t *testing.T) {
+	balances := &fakeBalanceProvider{err: errors.New("boom")}
+	cache := newTestStatusCache(balances, &FakeRegistry{})
+
+	_, err := cache.Get(cacheTestIdentity)
+	assert.EqualError(t, err, "boom")
+}
+
+func TestStatusCache_RefreshUpdatesKnownIdentities(t *testing.T) {
+	balances := &fakeBalanceProvider{balance: Balance{Token: myst(10)}}
+	cache := newTestStatusCache(balances, &FakeRegistry{})
+
+	_, err := cache.Get(cacheTestIdentity)
+	assert.NoError(t, err)
+
+	balances.balance = Balance{Token: myst(20)}
+	cache.refreshAll()
+
+	status, err := cache.Get(cacheTestIdentity)
+	assert.NoError(t, err)
+	assert.Equal(t, Balance{Token: myst(20)}, status.Balance)
+}
+
+func TestStatusCache_RefreshKeepsOldStatusOnError(t *testing.T) {
+	balances := &fakeBalanceProvider{balance: Balance{Token: myst(10)}}
+	cache := newTestStatusCache(balances, &FakeRegistry{})
+
+	_, err := cache.Get(cacheTestIdentity)
+	assert.NoError(t, err)
+
+	balances.err = errors.New("boom")
+	cache.refreshAll()
+
+	status, err := cache.Get(cacheTestIdentity)
+	assert.NoError(t, err)
+	assert.Equal(t, Balance{Token: myst(10)}, status.Balance)
+}
+
+func TestStatusCache_RefreshForgetsUnusedIdentities(t *testing.T) {
+	balances := &fakeBalanceProvider{balance: Balance{Token: myst(10)}}
+	cache := newTestStatusCache(balances, &FakeRegistry{})
+	now := cacheTestTime
+	cache.now = func() time.Time {
+		return now
+	}
+
+	_, err := cache.Get(cacheTestIdentity)
+	assert.NoError(t, err)
+
+	now = now.Add(unusedStatusTimeout)
+	cache.refreshAll()
+	assert.Equal(t, 2, balances.calls)
+
+	now = now.Add(time.Second)
+	cache.refreshAll()
+	assert.Equal(t, 2, balances.calls)
+	assert.Len(t, cache.statuses, 0)
+}
+
+func TestStatusCache_GetKeepsIdentityRefreshed(t *testing.T) {
+	balances := &fakeBalanceProvider{balance: Balance{Token: myst(10)}}
+	cache := newTestStatusCache(balances, &FakeRegistry{})
+	now := cacheTestTime
+	cache.now = func() time.Time {
+		return now
+	}
+
+	_, err := cache.Get(cacheTestIdentity)
+	assert.NoError(t, err)
+
+	now = now.Add(unusedStatusTimeout)
+	_, err = cache.Get(cacheTestIdentity)
+	assert.NoError(t, err)
+
+	now = now.Add(time.Second)
+	cache.refreshAll()
+	assert.Equal(t, 2, balances.calls)
+	assert.Len(t, cache.statuses, 1)
+}
+
+func TestStatusCache_StopIsIdempotent(t *testing.T) {
+	cache := newTestStatusCache(&fakeBalanceProvider{}, &FakeRegistry{})
+	cache.Start()
+	cache.Stop()
+	cache.Stop()
+}
@@ -0,0 +1,172 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package registry
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+	"github.com/pkg/errors"
+)
+
+// BalanceDTO represents on-chain MYST balance of identity
+//
+// swagger:model BalanceDTO
+type BalanceDTO struct {
+	// MYST tokens owned by identity, amount is in the smallest token units
+	// example: {"amount": 1000, "currency": "MYST"}
+	Token money.Money `json:"token"`
+	// MYST deposited to the payments contract, amount is in the smallest token units
+	// example: {"amount": 500, "currency": "MYST"}
+	Payments money.Money `json:"payments"`
+	// Time when the balance was read from blockchain
+	// example: 2018-11-01T10:00:00Z
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// StatusDTO represents registration status of identity in payments contract
+//
+// swagger:model IdentityStatusDTO
+type StatusDTO struct {
+	// Returns true if identity is registered in payments smart contract
+	Registered bool `json:"registered"`
+	// Time when the status was read from blockchain
+	// example: 2018-11-01T10:00:00Z
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// StatusProvider provides cached on-chain status of identity
+type StatusProvider interface {
+	Get(id identity.Identity) (Status, error)
+}
+
+type statusEndpoint struct {
+	statuses StatusProvider
+}
+
+func newStatusEndpoint(statuses StatusProvider) *statusEndpoint {
+	return &statusEndpoint{
+		statuses: statuses,
+	}
+}
+
+// swagger:operation GET /identities/{id}/balance Identity identityBalance
+// ---
+// summary: Provide identity balance
+// description: Provides MYST balance of given identity in token and payments contracts
+// parameters:
+//   - in: path
+//     name: id
+//     description: hex address of identity
+//     type: string
+//     required: true
+// responses:
+//   200:
+//     description: Identity balance
+//     schema:
+//       "$ref": "#/definitions/BalanceDTO"
+//   400:
+//     description: Bad Request
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *statusEndpoint) Balance(resp http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	id, err := identityFromParams(params)
+	if err != nil {
+		utils.SendError(resp, err, http.StatusBadRequest)
+		return
+	}
+	status, err := endpoint.statuses.Get(id)
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+
+	balanceDTO := &BalanceDTO{
+		Token:     status.Balance.Token,
+		Payments:  status.Balance.Payments,
+		UpdatedAt: status.UpdatedAt.Format(time.RFC3339),
+	}
+	utils.WriteAsJSON(balanceDTO, resp)
+}
+
+// swagger:operation GET /identities/{id}/status Identity identityStatus
+// ---
+// summary: Provide identity status
+// description: Provides registration status of given identity in payments contract
+// parameters:
+//   - in: path
+//     name: id
+//     description: hex address of identity
+//     type: string
+//     required: true
+// responses:
+//   200:
+//     description: Identity status
+//     schema:
+//       "$ref": "#/definitions/IdentityStatusDTO"
+//   400:
+//     description: Bad Request
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *statusEndpoint) Status(resp http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	id, err := identityFromParams(params)
+	if err != nil {
+		utils.SendError(resp, err, http.StatusBadRequest)
+		return
+	}
+	status, err := endpoint.statuses.Get(id)
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+
+	statusDTO := &StatusDTO{
+		Registered: status.Registered,
+		UpdatedAt:  status.UpdatedAt.Format(time.RFC3339),
+	}
+	utils.WriteAsJSON(statusDTO, resp)
+}
+
+func identityFromParams(params httprouter.Params) (identity.Identity, error) {
+	address := params.ByName("id")
+	if !common.IsHexAddress(address) {
+		return identity.Identity{}, errors.Errorf("invalid identity: %q", address)
+	}
+	return identity.FromAddress(address), nil
+}
+
+// AddIdentityStatusEndpoints adds identity balance and status endpoints to given http router
+func AddIdentityStatusEndpoints(router *httprouter.Router, statuses StatusProvider) {
+	statusEndpoint := newStatusEndpoint(statuses)
+
+	router.GET("/identities/:id/balance", statusEndpoint.Balance)
+	router.GET("/identities/:id/status", statusEndpoint.Status)
+}
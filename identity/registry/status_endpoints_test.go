@@ -0,0 +1,165 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package registry
+
+import (
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStatusProvider struct {
+	status     Status
+	err        error
+	recordedID identity.Identity
+}
+
+func (fsp *fakeStatusProvider) Get(id identity.Identity) (Status, error) {
+	fsp.recordedID = id
+	return fsp.status, fsp.err
+}
+
+var statusEndpointParams = httprouter.Params{
+	httprouter.Param{
+		Key:   "id",
+		Value: "0x000000000000000000000000000000000000BEEF",
+	},
+}
+
+func TestStatusEndpoint_Balance(t *testing.T) {
+	statuses := &fakeStatusProvider{
+		status: Status{
+			Registered: true,
+			Balance:    Balance{Token: myst(1000), Payments: myst(500)},
+			UpdatedAt:  cacheTestTime,
+		},
+	}
+	req, err := http.NewRequest(http.MethodGet, "/notimportant", nil)
+	assert.NoError(t, err)
+	resp := httptest.NewRecorder()
+
+	newStatusEndpoint(statuses).Balance(resp, req, statusEndpointParams)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, identity.FromAddress("0x000000000000000000000000000000000000beef"), statuses.recordedID)
+	assert.JSONEq(
+		t,
+		`{
+			"token": {"amount": 1000, "currency": "MYST"},
+			"payments": {"amount": 500, "currency": "MYST"},
+			"updatedAt": "2018-11-01T10:00:00Z"
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func TestStatusEndpoint_Status(t *testing.T) {
+	statuses := &fakeStatusProvider{
+		status: Status{
+			Registered: true,
+			Balance:    Balance{Token: myst(1000), Payments: myst(500)},
+			UpdatedAt:  cacheTestTime,
+		},
+	}
+	req, err := http.NewRequest(http.MethodGet, "/notimportant", nil)
+	assert.NoError(t, err)
+	resp := httptest.NewRecorder()
+
+	newStatusEndpoint(statuses).Status(resp, req, statusEndpointParams)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"registered": true,
+			"updatedAt": "2018-11-01T10:00:00Z"
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func TestStatusEndpoint_ReturnsErrorWhenStatusIsUnavailable(t *testing.T) {
+	statuses := &fakeStatusProvider{err: errors.New("node is down")}
+	req, err := http.NewRequest(http.MethodGet, "/notimportant", nil)
+	assert.NoError(t, err)
+	resp := httptest.NewRecorder()
+
+	newStatusEndpoint(statuses).Status(resp, req, statusEndpointParams)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.JSONEq(t, `{"message": "node is down"}`, resp.Body.String())
+}
+
+func TestStatusEndpoint_BalanceAboveUint64(t *testing.T) {
+	token := new(big.Int).Lsh(big.NewInt(1), 70)
+	statuses := &fakeStatusProvider{
+		status: Status{
+			Balance: Balance{
+				Token:    money.NewBigUnits(token, money.CURRENCY_MYST),
+				Payments: money.NewUnits(0, money.CURRENCY_MYST),
+			},
+			UpdatedAt: cacheTestTime,
+		},
+	}
+	req, err := http.NewRequest(http.MethodGet, "/notimportant", nil)
+	assert.NoError(t, err)
+	resp := httptest.NewRecorder()
+
+	newStatusEndpoint(statuses).Balance(resp, req, statusEndpointParams)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"token": {"amount": 1180591620717411303424, "currency": "MYST"},
+			"payments": {"currency": "MYST"},
+			"updatedAt": "2018-11-01T10:00:00Z"
+		}`,
+		resp.Body.String(),
+	)
+	assert.Contains(t, resp.Body.String(), `"amount":1180591620717411303424`)
+}
+
+func TestStatusEndpoint_RejectsInvalidIdentity(t *testing.T) {
+	statuses := &fakeStatusProvider{}
+	params := httprouter.Params{
+		httprouter.Param{
+			Key:   "id",
+			Value: "0x1231323131",
+		},
+	}
+
+	for _, handle := range []httprouter.Handle{newStatusEndpoint(statuses).Balance, newStatusEndpoint(statuses).Status} {
+		req, err := http.NewRequest(http.MethodGet, "/notimportant", nil)
+		assert.NoError(t, err)
+		resp := httptest.NewRecorder()
+
+		handle(resp, req, params)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.JSONEq(t, `{"message": "invalid identity: \"0x1231323131\""}`, resp.Body.String())
+	}
+	assert.Equal(t, identity.Identity{}, statuses.recordedID)
+}
@@ -0,0 +1,36 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package market
+
+import (
+	"time"
+
+	"github.com/mysteriumnetwork/node/datasize"
+)
+
+// FreeCredit describes the free allowance which provider grants to every consumer identity per period.
+// The allowance is measured in units of proposal payment method: time for proposals priced per time,
+// transferred data for proposals priced per bytes.
+type FreeCredit struct {
+	// Free service duration, for proposals priced per time
+	Duration time.Duration `json:"duration,omitempty"`
+	// Free data transferred, for proposals priced per bytes
+	Bytes datasize.BitSize `json:"bytes,omitempty"`
+	// Period after which the allowance of consumer is renewed, zero means it is never renewed
+	Period time.Duration `json:"period"`
+}
@@ -17,10 +17,28 @@
 
 package market
 
+// NAT types of service provider
+const (
+	// NATTypeNone indicates that provider has public IP assigned to its outbound interface
+	NATTypeNone = "none"
+	// NATTypeNAT indicates that provider is reachable only through NAT
+	NATTypeNAT = "nat"
+)
+
 // Location struct represents geographic location of service provider
 type Location struct {
 	Country string `json:"country,omitempty"`
 	City    string `json:"city,omitempty"`
 	// Autonomous System Number http://www.whatismyip.cx/
 	ASN string `json:"asn,omitempty"`
+	// Connectivity class of service provider, see NATType* constants
+	NATType string `json:"nat_type,omitempty"`
+}
+
+// NATTypeFor returns NAT type of provider with given public and outbound IPs
+func NATTypeFor(publicIP, outboundIP string) string {
+	if publicIP == outboundIP {
+		return NATTypeNone
+	}
+	return NATTypeNAT
 }
@@ -31,7 +31,7 @@ func TestLocationSerialize(t *testing.T) {
 		expectedJSON string
 	}{
 		{
-			Location{"XX", "YY", "AS123"},
+			Location{"XX", "YY", "AS123", ""},
 			`{
 				"country": "XX",
 				"city": "YY",
@@ -70,7 +70,7 @@ func TestLocationUnserialize(t *testing.T) {
 				"city": "YY",
 				"asn": "AS123"
 			}`,
-			Location{"XX", "YY", "AS123"},
+			Location{"XX", "YY", "AS123", ""},
 			nil,
 		},
 		{
@@ -107,3 +107,8 @@ func TestLocationUnserializeError(t *testing.T) {
 	assert.IsType(t, &json.UnmarshalTypeError{}, err)
 	assert.Regexp(t, regexp.MustCompile("^json: "), err.Error())
 }
+
+func TestNATTypeFor(t *testing.T) {
+	assert.Equal(t, NATTypeNone, NATTypeFor("1.2.3.4", "1.2.3.4"))
+	assert.Equal(t, NATTypeNAT, NATTypeFor("1.2.3.4", "192.168.1.2"))
+}
@@ -18,6 +18,7 @@
 package mysterium
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -34,6 +35,9 @@ const (
 	mysteriumAPILogPrefix = "[Mysterium.api] "
 )
 
+// ErrProposalsNotModified indicates that proposals did not change since the given ETag
+var ErrProposalsNotModified = errors.New("proposals not modified")
+
 // HTTPTransport interface with single method do is extracted from net/transport.Client structure
 type HTTPTransport interface {
 	Do(*http.Request) (*http.Response, error)
@@ -159,6 +163,45 @@ func (mApi *MysteriumAPI) FindProposals(providerID string, serviceType string) (
 	return supported, nil
 }
 
+// QueryProposals fetches all currently active service proposals from discovery.
+// When etag is given and proposals did not change since, ErrProposalsNotModified is returned.
+func (mApi *MysteriumAPI) QueryProposals(etag string) ([]market.ServiceProposal, string, error) {
+	req, err := requests.NewGetRequest(mApi.discoveryAPIAddress, "proposals", url.Values{})
+	if err != nil {
+		return nil, etag, err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := mApi.http.Do(req)
+	if err != nil {
+		log.Error(mysteriumAPILogPrefix, err)
+		return nil, etag, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, etag, ErrProposalsNotModified
+	}
+
+	err = ParseResponseError(resp)
+	if err != nil {
+		log.Error(mysteriumAPILogPrefix, err)
+		return nil, etag, err
+	}
+
+	var proposalsResponse ProposalsResponse
+	err = ParseResponseJSON(resp, &proposalsResponse)
+	if err != nil {
+		return nil, etag, err
+	}
+	total := len(proposalsResponse.Proposals)
+	supported := supportedProposalsOnly(proposalsResponse.Proposals)
+	log.Info(mysteriumAPILogPrefix, "Total proposals: ", total, " supported: ", len(supported))
+	return supported, resp.Header.Get("ETag"), nil
+}
+
 // SendSessionStats sends session statistics
 func (mApi *MysteriumAPI) SendSessionStats(sessionID session.ID, sessionStats SessionStats, signer identity.Signer) error {
 	path := fmt.Sprintf("sessions/%s/stats", sessionID)
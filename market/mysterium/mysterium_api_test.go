@@ -63,3 +63,26 @@ func createHTTPServer(handlerFunc http.HandlerFunc) (address string, err error)
 	go http.Serve(listener, handlerFunc)
 	return listener.Addr().String(), nil
 }
+
+func TestQueryProposalsUsesETag(t *testing.T) {
+	address, err := createHTTPServer(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("If-None-Match") == `"v1"` {
+			writer.WriteHeader(http.StatusNotModified)
+			return
+		}
+		writer.Header().Set("ETag", `"v1"`)
+		writer.Write([]byte(`{"proposals": []}`))
+	})
+	assert.NoError(t, err)
+
+	api := NewClient("http://" + address)
+
+	proposals, etag, err := api.QueryProposals("")
+	assert.NoError(t, err)
+	assert.Empty(t, proposals)
+	assert.Equal(t, `"v1"`, etag)
+
+	_, etag, err = api.QueryProposals(etag)
+	assert.Equal(t, ErrProposalsNotModified, err)
+	assert.Equal(t, `"v1"`, etag)
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package market
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/mysteriumnetwork/node/identity"
+)
+
+var (
+	// ErrProposalNotSigned represents error when proposal of signed format carries no signature
+	ErrProposalNotSigned = errors.New("proposal is not signed")
+	// ErrProposalSignatureInvalid represents error when proposal signature does not match its content and provider
+	ErrProposalSignatureInvalid = errors.New("proposal signature is invalid")
+	// ErrProposalSignatureStripped represents error when proposal of provider known to sign its proposals carries no signature
+	ErrProposalSignatureStripped = errors.New("proposal signature was stripped off")
+)
+
+// Sign switches proposal to the signed format and signs its content by provider
+func (proposal *ServiceProposal) Sign(signer identity.Signer) error {
+	signed := *proposal
+	signed.Format = proposalFormatSigned
+	signed.Signature = ""
+	signed.signedContent = nil
+
+	data, err := json.Marshal(signed)
+	if err != nil {
+		return err
+	}
+	message, err := signedMessage(data)
+	if err != nil {
+		return err
+	}
+	signature, err := signer.Sign(message)
+	if err != nil {
+		return err
+	}
+
+	signed.Signature = signature.Base64()
+	*proposal = signed
+	return nil
+}
+
+// IsSigned returns true if proposal is in the format which must carry provider signature
+func (proposal ServiceProposal) IsSigned() bool {
+	return proposal.Format == proposalFormatSigned
+}
+
+// VerifySignature checks that signed proposal content was issued by its provider.
+// Received proposals are verified against the JSON they were decoded from.
+// Proposals of older formats carry no signature and are not verified.
+func (proposal ServiceProposal) VerifySignature(verifier identity.Verifier) error {
+	if !proposal.IsSigned() {
+		return nil
+	}
+	if proposal.Signature == "" {
+		return ErrProposalNotSigned
+	}
+
+	message := proposal.signedContent
+	if message == nil {
+		data, err := json.Marshal(proposal)
+		if err != nil {
+			return err
+		}
+		if message, err = signedMessage(data); err != nil {
+			return err
+		}
+	}
+	if !verifier.Verify(message, identity.SignatureBase64(proposal.Signature)) {
+		return ErrProposalSignatureInvalid
+	}
+	return nil
+}
+
+// VerifyProviderSignature checks proposal signature against identity of its provider
+func (proposal ServiceProposal) VerifyProviderSignature() error {
+	return proposal.VerifySignature(identity.NewVerifierIdentity(identity.FromAddress(proposal.ProviderID)))
+}
+
+// signedMessage strips signature field from proposal JSON, leaving the other fields as they are.
+// Top level fields are ordered by key, so that provider and consumer sign and verify the same bytes.
+func signedMessage(data []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "signature")
+	return json.Marshal(fields)
+}
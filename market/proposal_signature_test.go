@@ -0,0 +1,106 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package market
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func signedProposal(t *testing.T) ServiceProposal {
+	proposal := ServiceProposal{
+		ID:                1,
+		Format:            proposalFormat,
+		ServiceType:       "mock_service",
+		ServiceDefinition: serviceDefinition,
+		PaymentMethodType: "mock_payment",
+		PaymentMethod:     paymentMethod,
+		ProviderID:        "0x1",
+		ProviderContacts:  ContactList{{Type: "mock_contact", Definition: mockContact{}}},
+	}
+	assert.NoError(t, proposal.Sign(&identity.SignerFake{}))
+	return proposal
+}
+
+func Test_ServiceProposal_SignSwitchesToSignedFormat(t *testing.T) {
+	proposal := signedProposal(t)
+
+	assert.Equal(t, proposalFormatSigned, proposal.Format)
+	assert.True(t, proposal.IsSigned())
+	assert.NotEmpty(t, proposal.Signature)
+	assert.NoError(t, proposal.VerifySignature(&identity.VerifierFake{}))
+}
+
+func Test_ServiceProposal_SignatureSurvivesSerialization(t *testing.T) {
+	proposal := signedProposal(t)
+
+	data, err := json.Marshal(proposal)
+	assert.NoError(t, err)
+	var received ServiceProposal
+	assert.NoError(t, json.Unmarshal(data, &received))
+
+	assert.Equal(t, proposal.Signature, received.Signature)
+	assert.NoError(t, received.VerifySignature(&identity.VerifierFake{}))
+}
+
+func Test_ServiceProposal_VerifySignatureDetectsTampering(t *testing.T) {
+	proposal := signedProposal(t)
+	proposal.ServiceType = "other_service"
+	assert.Equal(t, ErrProposalSignatureInvalid, proposal.VerifySignature(&identity.VerifierFake{}))
+
+	proposal = signedProposal(t)
+	proposal.ProviderContacts = ContactList{}
+	assert.Equal(t, ErrProposalSignatureInvalid, proposal.VerifySignature(&identity.VerifierFake{}))
+
+	proposal = signedProposal(t)
+	proposal.Signature = ""
+	assert.Equal(t, ErrProposalNotSigned, proposal.VerifySignature(&identity.VerifierFake{}))
+}
+
+func Test_ServiceProposal_UnsignedFormatIsNotVerified(t *testing.T) {
+	proposal := ServiceProposal{Format: proposalFormat, ProviderID: "0x1"}
+	assert.False(t, proposal.IsSigned())
+	assert.NoError(t, proposal.VerifySignature(&identity.VerifierFake{}))
+}
+
+func Test_ServiceProposal_VerifiesReceivedJSON(t *testing.T) {
+	// proposal of newer provider carries a field this node does not know about
+	message := []byte(`{"extension":{"b":1,"a":2},"format":"service-proposal/v2","id":1,"provider_id":"0x1","service_type":"new_service"}`)
+	signature, err := (&identity.SignerFake{}).Sign(message)
+	assert.NoError(t, err)
+	received := `{
+		"id": 1,
+		"format": "service-proposal/v2",
+		"service_type": "new_service",
+		"provider_id": "0x1",
+		"extension": {"b": 1, "a": 2},
+		"signature": "` + signature.Base64() + `"
+	}`
+
+	var proposal ServiceProposal
+	assert.NoError(t, json.Unmarshal([]byte(received), &proposal))
+	assert.NoError(t, proposal.VerifySignature(&identity.VerifierFake{}))
+
+	var tampered ServiceProposal
+	assert.NoError(t, json.Unmarshal([]byte(strings.Replace(received, `"b": 1`, `"b": 3`, 1)), &tampered))
+	assert.Equal(t, ErrProposalSignatureInvalid, tampered.VerifySignature(&identity.VerifierFake{}))
+}
@@ -0,0 +1,269 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package broker
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/communication/nats"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/market/mysterium"
+	nats_lib "github.com/nats-io/go-nats"
+	"github.com/pkg/errors"
+)
+
+const (
+	logPrefix = "[broker-discovery] "
+
+	// SubjectProposals is a well-known subject where providers broadcast their proposals
+	SubjectProposals = "proposals.broadcast"
+	// SubjectQuery is a well-known subject where consumers ask providers to broadcast their proposals immediately
+	SubjectQuery = "proposals.query"
+
+	actionRegister   = "register"
+	actionUnregister = "unregister"
+)
+
+// VerifierFactory creates verifier of messages signed by given identity
+type VerifierFactory func(id identity.Identity) identity.Verifier
+
+// envelope is a signed broadcast message
+type envelope struct {
+	Payload   json.RawMessage `json:"payload"`
+	Signature string          `json:"signature"`
+}
+
+// announcement is a payload of broadcast message
+type announcement struct {
+	Action   string                 `json:"action"`
+	Proposal market.ServiceProposal `json:"proposal"`
+	SentAt   time.Time              `json:"sent_at"`
+}
+
+type registeredProposal struct {
+	proposal market.ServiceProposal
+	signer   identity.Signer
+}
+
+type knownProposal struct {
+	proposal  market.ServiceProposal
+	expiresAt time.Time
+}
+
+// Backend is a decentralised proposal discovery over the message broker.
+// Providers periodically broadcast signed proposals and consumers keep a local proposal table with expiry.
+type Backend struct {
+	connection      nats.Connection
+	verifierFactory VerifierFactory
+	ttl             time.Duration
+	now             func() time.Time
+
+	registered     map[string]registeredProposal
+	registeredLock sync.Mutex
+
+	known     map[string]knownProposal
+	version   uint64
+	knownLock sync.Mutex
+}
+
+// NewBackend creates broker discovery backend over given connection.
+// Proposals which are not announced again within ttl are forgotten.
+func NewBackend(connection nats.Connection, verifierFactory VerifierFactory, ttl time.Duration) *Backend {
+	return &Backend{
+		connection:      connection,
+		verifierFactory: verifierFactory,
+		ttl:             ttl,
+		now:             time.Now,
+		registered:      make(map[string]registeredProposal),
+		known:           make(map[string]knownProposal),
+	}
+}
+
+// Start subscribes to proposal broadcasts and asks providers to announce themselves
+func (b *Backend) Start() error {
+	if _, err := b.connection.Subscribe(SubjectProposals, b.consumeAnnouncement); err != nil {
+		return errors.Wrap(err, "failed to subscribe to proposal broadcasts")
+	}
+	if _, err := b.connection.Subscribe(SubjectQuery, b.consumeQuery); err != nil {
+		return errors.Wrap(err, "failed to subscribe to proposal queries")
+	}
+	return b.connection.Publish(SubjectQuery, []byte{})
+}
+
+// Stop closes broker connection
+func (b *Backend) Stop() {
+	b.connection.Close()
+}
+
+// RegisterProposal starts announcing given proposal
+func (b *Backend) RegisterProposal(proposal market.ServiceProposal, signer identity.Signer) error {
+	b.registeredLock.Lock()
+	b.registered[proposalKey(proposal)] = registeredProposal{proposal: proposal, signer: signer}
+	b.registeredLock.Unlock()
+
+	return b.announce(actionRegister, proposal, signer)
+}
+
+// PingProposal announces given proposal again to keep it alive in consumer tables
+func (b *Backend) PingProposal(proposal market.ServiceProposal, signer identity.Signer) error {
+	return b.announce(actionRegister, proposal, signer)
+}
+
+// UnregisterProposal stops announcing given proposal and asks consumers to forget it
+func (b *Backend) UnregisterProposal(proposal market.ServiceProposal, signer identity.Signer) error {
+	b.registeredLock.Lock()
+	delete(b.registered, proposalKey(proposal))
+	b.registeredLock.Unlock()
+
+	return b.announce(actionUnregister, proposal, signer)
+}
+
+// QueryProposals returns all proposals known from broadcasts.
+// When etag is given and proposals did not change since, mysterium.ErrProposalsNotModified is returned.
+func (b *Backend) QueryProposals(etag string) ([]market.ServiceProposal, string, error) {
+	b.knownLock.Lock()
+	defer b.knownLock.Unlock()
+
+	now := b.now()
+	for key, known := range b.known {
+		if now.After(known.expiresAt) {
+			delete(b.known, key)
+			b.version++
+		}
+	}
+
+	currentETag := strconv.FormatUint(b.version, 10)
+	if etag == currentETag {
+		return nil, etag, mysterium.ErrProposalsNotModified
+	}
+
+	proposals := make([]market.ServiceProposal, 0, len(b.known))
+	for _, known := range b.known {
+		proposals = append(proposals, known.proposal)
+	}
+	return proposals, currentETag, nil
+}
+
+func (b *Backend) announce(action string, proposal market.ServiceProposal, signer identity.Signer) error {
+	payload, err := json.Marshal(announcement{
+		Action:   action,
+		Proposal: proposal,
+		SentAt:   b.now().UTC(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize proposal announcement")
+	}
+
+	signature, err := signer.Sign(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to sign proposal announcement")
+	}
+
+	message, err := json.Marshal(envelope{Payload: payload, Signature: signature.Base64()})
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize proposal announcement")
+	}
+	return b.connection.Publish(SubjectProposals, message)
+}
+
+func (b *Backend) consumeQuery(_ *nats_lib.Msg) {
+	b.registeredLock.Lock()
+	registered := make([]registeredProposal, 0, len(b.registered))
+	for _, proposal := range b.registered {
+		registered = append(registered, proposal)
+	}
+	b.registeredLock.Unlock()
+
+	for _, proposal := range registered {
+		if err := b.announce(actionRegister, proposal.proposal, proposal.signer); err != nil {
+			log.Warn(logPrefix, "failed to answer proposal query: ", err)
+		}
+	}
+}
+
+func (b *Backend) consumeAnnouncement(message *nats_lib.Msg) {
+	received, err := b.verify(message.Data)
+	if err != nil {
+		log.Debug(logPrefix, "dropped proposal announcement: ", err)
+		return
+	}
+
+	key := proposalKey(received.Proposal)
+
+	b.knownLock.Lock()
+	defer b.knownLock.Unlock()
+
+	switch received.Action {
+	case actionRegister:
+		if !received.Proposal.IsSupported() {
+			return
+		}
+		previous, found := b.known[key]
+		if !found || !sameProposal(previous.proposal, received.Proposal) {
+			b.version++
+		}
+		b.known[key] = knownProposal{
+			proposal:  received.Proposal,
+			expiresAt: b.now().Add(b.ttl),
+		}
+	case actionUnregister:
+		if _, found := b.known[key]; found {
+			delete(b.known, key)
+			b.version++
+		}
+	}
+}
+
+// verify checks that announcement is fresh and signed by the provider of announced proposal
+func (b *Backend) verify(data []byte) (announcement, error) {
+	var received envelope
+	if err := json.Unmarshal(data, &received); err != nil {
+		return announcement{}, errors.Wrap(err, "malformed message")
+	}
+
+	var payload announcement
+	if err := json.Unmarshal(received.Payload, &payload); err != nil {
+		return announcement{}, errors.Wrap(err, "malformed payload")
+	}
+
+	providerID := identity.FromAddress(payload.Proposal.ProviderID)
+	if !b.verifierFactory(providerID).Verify(received.Payload, identity.SignatureBase64(received.Signature)) {
+		return announcement{}, errors.Errorf("invalid signature of provider %s", providerID.Address)
+	}
+
+	age := b.now().Sub(payload.SentAt)
+	if age > b.ttl || age < -b.ttl {
+		return announcement{}, errors.Errorf("outdated announcement of provider %s sent at %s", providerID.Address, payload.SentAt)
+	}
+	return payload, nil
+}
+
+func sameProposal(a, b market.ServiceProposal) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aJSON) == string(bJSON)
+}
+
+func proposalKey(proposal market.ServiceProposal) string {
+	return proposal.ProviderID + "." + proposal.ServiceType
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package broker
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/communication/nats"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/market/mysterium"
+	"github.com/mysteriumnetwork/node/money"
+	nats_lib "github.com/nats-io/go-nats"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	proposalProvider1 = market.ServiceProposal{
+		ProviderID:        "0x1",
+		ServiceType:       "mock_service",
+		ServiceDefinition: mockServiceDefinition{},
+		PaymentMethodType: "mock_payment",
+		PaymentMethod:     mockPaymentMethod{},
+		ProviderContacts:  market.ContactList{{Type: "mock_contact"}},
+	}
+	proposalUnsupported = market.ServiceProposal{
+		ProviderID:  "0x2",
+		ServiceType: "unknown_service",
+	}
+	signer = &identity.SignerFake{}
+)
+
+type mockServiceDefinition struct{}
+
+func (service mockServiceDefinition) GetLocation() market.Location {
+	return market.Location{}
+}
+
+type mockPaymentMethod struct{}
+
+func (method mockPaymentMethod) GetPrice() money.Money {
+	return money.Money{}
+}
+
+type mockContact struct{}
+
+func init() {
+	market.RegisterServiceDefinitionUnserializer("mock_service", func(*json.RawMessage) (market.ServiceDefinition, error) {
+		return mockServiceDefinition{}, nil
+	})
+	market.RegisterPaymentMethodUnserializer("mock_payment", func(*json.RawMessage) (market.PaymentMethod, error) {
+		return mockPaymentMethod{}, nil
+	})
+	market.RegisterContactUnserializer("mock_contact", func(*json.RawMessage) (market.ContactDefinition, error) {
+		return mockContact{}, nil
+	})
+}
+
+func verifierFake(_ identity.Identity) identity.Verifier {
+	return &identity.VerifierFake{}
+}
+
+// waitProposals polls backend until it knows given count of proposals
+func waitProposals(backend *Backend, count int) []market.ServiceProposal {
+	var proposals []market.ServiceProposal
+	for i := 0; i < 100; i++ {
+		proposals, _, _ = backend.QueryProposals("")
+		if len(proposals) == count {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return proposals
+}
+
+func Test_Backend_DiscoversProposalsAnnouncedOverBroker(t *testing.T) {
+	connection := nats.StartConnectionFake()
+	defer connection.Close()
+
+	provider := NewBackend(connection, verifierFake, time.Minute)
+	consumer := NewBackend(connection, verifierFake, time.Minute)
+	assert.NoError(t, consumer.Start())
+
+	assert.NoError(t, provider.RegisterProposal(proposalProvider1, signer))
+	proposals := waitProposals(consumer, 1)
+	assert.Len(t, proposals, 1)
+	assert.Equal(t, "0x1", proposals[0].ProviderID)
+
+	assert.NoError(t, provider.UnregisterProposal(proposalProvider1, signer))
+	assert.Len(t, waitProposals(consumer, 0), 0)
+}
+
+func Test_Backend_ProvidersAnswerQueryOfNewConsumer(t *testing.T) {
+	connection := nats.StartConnectionFake()
+	defer connection.Close()
+
+	provider := NewBackend(connection, verifierFake, time.Minute)
+	assert.NoError(t, provider.Start())
+	assert.NoError(t, provider.RegisterProposal(proposalProvider1, signer))
+
+	consumer := NewBackend(connection, verifierFake, time.Minute)
+	assert.NoError(t, consumer.Start())
+	assert.Len(t, waitProposals(consumer, 1), 1)
+}
+
+func Test_Backend_QueryProposalsReturnsNotModifiedForSameETag(t *testing.T) {
+	backend := NewBackend(nil, verifierFake, time.Minute)
+	backend.consumeAnnouncement(announcementMessage(t, backend, actionRegister, proposalProvider1))
+
+	proposals, etag, err := backend.QueryProposals("")
+	assert.NoError(t, err)
+	assert.Len(t, proposals, 1)
+
+	_, _, err = backend.QueryProposals(etag)
+	assert.Equal(t, mysterium.ErrProposalsNotModified, err)
+
+	// repeated announcement of the same proposal does not change the table
+	backend.consumeAnnouncement(announcementMessage(t, backend, actionRegister, proposalProvider1))
+	_, _, err = backend.QueryProposals(etag)
+	assert.Equal(t, mysterium.ErrProposalsNotModified, err)
+}
+
+func Test_Backend_ForgetsExpiredProposals(t *testing.T) {
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	backend := NewBackend(nil, verifierFake, time.Minute)
+	backend.now = func() time.Time { return now }
+	backend.consumeAnnouncement(announcementMessage(t, backend, actionRegister, proposalProvider1))
+
+	proposals, _, _ := backend.QueryProposals("")
+	assert.Len(t, proposals, 1)
+
+	now = now.Add(2 * time.Minute)
+	proposals, _, _ = backend.QueryProposals("")
+	assert.Len(t, proposals, 0)
+}
+
+func Test_Backend_DropsInvalidAnnouncements(t *testing.T) {
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	backend := NewBackend(nil, verifierFake, time.Minute)
+	backend.now = func() time.Time { return now }
+
+	backend.consumeAnnouncement(&nats_lib.Msg{Data: []byte("{malformed")})
+
+	forged := announcementMessage(t, backend, actionRegister, proposalProvider1)
+	var message envelope
+	assert.NoError(t, json.Unmarshal(forged.Data, &message))
+	forgedSignature := identity.SignatureBytes([]byte("forged"))
+	message.Signature = forgedSignature.Base64()
+	forged.Data, _ = json.Marshal(message)
+	backend.consumeAnnouncement(forged)
+
+	backend.consumeAnnouncement(announcementMessage(t, backend, actionRegister, proposalUnsupported))
+
+	outdated := announcementMessage(t, backend, actionRegister, proposalProvider1)
+	now = now.Add(2 * time.Minute)
+	backend.consumeAnnouncement(outdated)
+
+	proposals, _, _ := backend.QueryProposals("")
+	assert.Len(t, proposals, 0)
+}
+
+func announcementMessage(t *testing.T, backend *Backend, action string, proposal market.ServiceProposal) *nats_lib.Msg {
+	payload, err := json.Marshal(announcement{Action: action, Proposal: proposal, SentAt: backend.now()})
+	assert.NoError(t, err)
+	signature, err := signer.Sign(payload)
+	assert.NoError(t, err)
+	data, err := json.Marshal(envelope{Payload: payload, Signature: signature.Base64()})
+	assert.NoError(t, err)
+	return &nats_lib.Msg{Subject: SubjectProposals, Data: data}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/mysteriumnetwork/node/identity"
 	identity_registry "github.com/mysteriumnetwork/node/identity/registry"
 	"github.com/mysteriumnetwork/node/market"
+	"github.com/pkg/errors"
 )
 
 // Status describes stage of proposal registration
@@ -44,13 +45,18 @@ const (
 
 const logPrefix = "[discovery] "
 
-// Start launches discovery service
-func (d *Discovery) Start(ownIdentity identity.Identity, proposal market.ServiceProposal) {
+// Start launches discovery service, proposal is announced signed by own identity
+func (d *Discovery) Start(ownIdentity identity.Identity, proposal market.ServiceProposal) error {
 	d.RLock()
 	defer d.RUnlock()
 
+	signer := d.signerCreate(ownIdentity)
+	if err := proposal.Sign(signer); err != nil {
+		return errors.Wrap(err, "failed to sign proposal")
+	}
+
 	d.ownIdentity = ownIdentity
-	d.signer = d.signerCreate(ownIdentity)
+	d.signer = signer
 	d.proposal = proposal
 
 	stopLoop := make(chan bool)
@@ -64,6 +70,7 @@ func (d *Discovery) Start(ownIdentity identity.Identity, proposal market.Service
 	go d.checkRegistration()
 
 	go d.mainDiscoveryLoop(stopLoop)
+	return nil
 }
 
 // Wait wait for proposal announcements to stop / unregister
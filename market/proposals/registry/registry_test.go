@@ -18,6 +18,7 @@
 package registry
 
 import (
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -51,7 +52,7 @@ func TestStartRegistersProposal(t *testing.T) {
 	d := discoveryWithMockedDependencies()
 	d.identityRegistry = &identity_registry.FakeRegistry{RegistrationEventExists: false, Registered: true}
 
-	d.Start(providerID, proposal)
+	assert.NoError(t, d.Start(providerID, proposal))
 
 	actualStatus := observeStatus(d, PingProposal)
 	assert.Equal(t, PingProposal, actualStatus)
@@ -61,7 +62,7 @@ func TestStartRegistersIdentitySuccessfully(t *testing.T) {
 	d := discoveryWithMockedDependencies()
 	d.identityRegistry = &identity_registry.FakeRegistry{RegistrationEventExists: true, Registered: false}
 
-	d.Start(providerID, proposal)
+	assert.NoError(t, d.Start(providerID, proposal))
 
 	actualStatus := observeStatus(d, PingProposal)
 	assert.Equal(t, PingProposal, actualStatus)
@@ -71,7 +72,7 @@ func TestStartRegisterIdentityCancelled(t *testing.T) {
 	d := discoveryWithMockedDependencies()
 	d.identityRegistry = &identity_registry.FakeRegistry{RegistrationEventExists: false, Registered: false}
 
-	d.Start(providerID, proposal)
+	assert.NoError(t, d.Start(providerID, proposal))
 
 	actualStatus := observeStatus(d, WaitingForRegistration)
 	assert.Equal(t, WaitingForRegistration, actualStatus)
@@ -86,7 +87,7 @@ func TestStartStopUnregisterProposal(t *testing.T) {
 	d := discoveryWithMockedDependencies()
 	d.identityRegistry = &identity_registry.FakeRegistry{RegistrationEventExists: false, Registered: true}
 
-	d.Start(providerID, proposal)
+	assert.NoError(t, d.Start(providerID, proposal))
 
 	actualStatus := observeStatus(d, PingProposal)
 	assert.Equal(t, PingProposal, actualStatus)
@@ -97,6 +98,19 @@ func TestStartStopUnregisterProposal(t *testing.T) {
 	assert.Equal(t, ProposalUnregistered, actualStatus)
 }
 
+func TestStartFailsWhenProposalCannotBeSigned(t *testing.T) {
+	d := discoveryWithMockedDependencies()
+	d.identityRegistry = &identity_registry.FakeRegistry{RegistrationEventExists: false, Registered: true}
+	d.signerCreate = func(id identity.Identity) identity.Signer {
+		return &identity.SignerFake{ErrorMock: errors.New("keystore locked")}
+	}
+
+	err := d.Start(providerID, proposal)
+
+	assert.EqualError(t, err, "failed to sign proposal: keystore locked")
+	assert.Equal(t, market.ServiceProposal{}, d.proposal)
+}
+
 func observeStatus(d *Discovery, status Status) Status {
 	for {
 		d.RLock()
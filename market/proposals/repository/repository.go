@@ -0,0 +1,306 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package repository
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/market/mysterium"
+)
+
+const (
+	logPrefix = "[proposal-repository] "
+
+	proposalsBucket = "proposals"
+	snapshotID      = 1
+
+	// missRefreshTimeout limits how long a lookup of unknown provider waits for discovery
+	missRefreshTimeout = 2 * time.Second
+)
+
+// Fetcher fetches all active proposals from discovery
+type Fetcher interface {
+	QueryProposals(etag string) ([]market.ServiceProposal, string, error)
+}
+
+// Storage keeps the last known proposals between node restarts
+type Storage interface {
+	Store(bucket string, data interface{}) error
+	GetOneByField(bucket string, fieldName string, key interface{}, to interface{}) error
+}
+
+// snapshot is the last known state of discovery persisted in storage
+type snapshot struct {
+	ID        int `storm:"id"`
+	ETag      string
+	UpdatedAt time.Time
+	Proposals []market.ServiceProposal
+	// SigningProviders are providers which were seen signing their proposals
+	SigningProviders []string
+}
+
+// Repository keeps the last known proposals and refreshes them from discovery in the background.
+// Stale proposals are served when discovery is unreachable.
+// Proposals which were not signed by their provider are skipped, as well as unsigned proposals
+// of providers which were seen signing theirs, so that the signature can not be stripped off.
+type Repository struct {
+	fetcher         Fetcher
+	storage         Storage
+	refreshInterval time.Duration
+	missTimeout     time.Duration
+
+	snapshot snapshot
+	loaded   bool
+	stale    bool
+	lock     sync.RWMutex
+
+	fetchLock   sync.Mutex
+	pending     *pendingRefresh
+	pendingLock sync.Mutex
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewRepository creates proposal repository which refreshes proposals with given interval
+func NewRepository(fetcher Fetcher, storage Storage, refreshInterval time.Duration) *Repository {
+	return &Repository{
+		fetcher:         fetcher,
+		storage:         storage,
+		refreshInterval: refreshInterval,
+		missTimeout:     missRefreshTimeout,
+		stop:            make(chan struct{}),
+	}
+}
+
+// Start restores the last known proposals from storage and starts refreshing them in the background
+func (r *Repository) Start() {
+	r.restore()
+	go r.refreshLoop()
+}
+
+// Stop stops background refresh
+func (r *Repository) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+}
+
+// FindProposals returns the last known proposals filtered by provider and service type.
+// When nothing is known yet or the requested provider is unknown, discovery is refreshed in the background
+// and the lookup waits for it no longer than the miss timeout.
+func (r *Repository) FindProposals(providerID string, serviceType string) ([]market.ServiceProposal, error) {
+	r.lock.RLock()
+	loaded := r.loaded
+	proposals := filterProposals(r.snapshot.Proposals, providerID, serviceType)
+	r.lock.RUnlock()
+
+	if loaded && (len(proposals) > 0 || providerID == "") {
+		return proposals, nil
+	}
+
+	refresh := r.refreshInBackground()
+	select {
+	case <-refresh.done:
+		if refresh.err != nil && !loaded {
+			return nil, refresh.err
+		}
+	case <-time.After(r.missTimeout):
+		log.Info(logPrefix, "discovery did not respond in ", r.missTimeout, ", serving known proposals")
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return filterProposals(r.snapshot.Proposals, providerID, serviceType), nil
+}
+
+// Freshness returns the time of the last successful refresh and whether proposals may be outdated
+func (r *Repository) Freshness() (updatedAt time.Time, stale bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.snapshot.UpdatedAt, r.stale
+}
+
+// Refresh fetches proposals from discovery unless they did not change since the last refresh
+func (r *Repository) Refresh() error {
+	r.fetchLock.Lock()
+	defer r.fetchLock.Unlock()
+
+	r.lock.RLock()
+	etag := r.snapshot.ETag
+	signingProviders := r.snapshot.SigningProviders
+	r.lock.RUnlock()
+
+	proposals, etag, err := r.fetcher.QueryProposals(etag)
+	switch err {
+	case nil:
+		verified, signingProviders := verifiedProposals(proposals, signingProviders)
+		r.update(snapshot{
+			ID:               snapshotID,
+			ETag:             etag,
+			UpdatedAt:        time.Now(),
+			Proposals:        verified,
+			SigningProviders: signingProviders,
+		})
+	case mysterium.ErrProposalsNotModified:
+		r.lock.Lock()
+		r.snapshot.UpdatedAt = time.Now()
+		r.stale = false
+		updated := r.snapshot
+		r.lock.Unlock()
+		r.persist(updated)
+	default:
+		r.lock.Lock()
+		r.stale = true
+		r.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+// pendingRefresh is a refresh running in the background, shared by concurrent lookups
+type pendingRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+// refreshInBackground starts refresh unless one is already running
+func (r *Repository) refreshInBackground() *pendingRefresh {
+	r.pendingLock.Lock()
+	defer r.pendingLock.Unlock()
+	if r.pending != nil {
+		return r.pending
+	}
+
+	refresh := &pendingRefresh{done: make(chan struct{})}
+	r.pending = refresh
+	go func() {
+		refresh.err = r.Refresh()
+		if refresh.err != nil {
+			log.Warn(logPrefix, "failed to refresh proposals: ", refresh.err)
+		}
+
+		r.pendingLock.Lock()
+		r.pending = nil
+		r.pendingLock.Unlock()
+		close(refresh.done)
+	}()
+	return refresh
+}
+
+func (r *Repository) update(updated snapshot) {
+	r.lock.Lock()
+	r.snapshot = updated
+	r.loaded = true
+	r.stale = false
+	r.lock.Unlock()
+
+	r.persist(updated)
+}
+
+func (r *Repository) persist(updated snapshot) {
+	if err := r.storage.Store(proposalsBucket, &updated); err != nil {
+		log.Warn(logPrefix, "failed to persist proposals: ", err)
+	}
+}
+
+func (r *Repository) restore() {
+	var stored snapshot
+	if err := r.storage.GetOneByField(proposalsBucket, "ID", snapshotID, &stored); err != nil {
+		log.Info(logPrefix, "no stored proposals: ", err)
+		return
+	}
+	stored.Proposals, stored.SigningProviders = verifiedProposals(stored.Proposals, stored.SigningProviders)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.loaded {
+		return
+	}
+	r.snapshot = stored
+	r.loaded = true
+	// restored proposals are considered stale until discovery confirms them
+	r.stale = true
+	log.Info(logPrefix, "restored proposals: ", len(stored.Proposals), " updated at: ", stored.UpdatedAt)
+}
+
+func (r *Repository) refreshLoop() {
+	for {
+		if err := r.Refresh(); err != nil {
+			log.Warn(logPrefix, "failed to refresh proposals, serving stale ones: ", err)
+		}
+
+		select {
+		case <-r.stop:
+			return
+		case <-time.After(r.refreshInterval):
+		}
+	}
+}
+
+// verifiedProposals skips proposals whose content was not issued by their provider.
+// Unsigned proposals of providers known to sign theirs are skipped too, as their signature was stripped off.
+// Returns the verified proposals together with the providers known to sign their proposals.
+func verifiedProposals(proposals []market.ServiceProposal, known []string) ([]market.ServiceProposal, []string) {
+	signingProviders := make([]string, 0, len(known))
+	signing := make(map[string]bool, len(known))
+	for _, providerID := range known {
+		signing[providerID] = true
+		signingProviders = append(signingProviders, providerID)
+	}
+
+	valid := make([]market.ServiceProposal, 0, len(proposals))
+	for _, proposal := range proposals {
+		if err := proposal.VerifyProviderSignature(); err != nil {
+			log.Warn(logPrefix, "skipping proposal of provider ", proposal.ProviderID, ": ", err)
+			continue
+		}
+		if proposal.IsSigned() && !signing[proposal.ProviderID] {
+			signing[proposal.ProviderID] = true
+			signingProviders = append(signingProviders, proposal.ProviderID)
+		}
+		valid = append(valid, proposal)
+	}
+
+	verified := make([]market.ServiceProposal, 0, len(valid))
+	for _, proposal := range valid {
+		if !proposal.IsSigned() && signing[proposal.ProviderID] {
+			log.Warn(logPrefix, "skipping proposal of provider ", proposal.ProviderID, ": ", market.ErrProposalSignatureStripped)
+			continue
+		}
+		verified = append(verified, proposal)
+	}
+	return verified, signingProviders
+}
+
+func filterProposals(proposals []market.ServiceProposal, providerID, serviceType string) []market.ServiceProposal {
+	filtered := make([]market.ServiceProposal, 0, len(proposals))
+	for _, proposal := range proposals {
+		if providerID != "" && proposal.ProviderID != providerID {
+			continue
+		}
+		if serviceType != "" && proposal.ServiceType != serviceType {
+			continue
+		}
+		filtered = append(filtered, proposal)
+	}
+	return filtered
+}
@@ -0,0 +1,294 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package repository
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/market/mysterium"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	proposalProvider1 = market.ServiceProposal{ProviderID: "0x1", ServiceType: "openvpn"}
+	proposalProvider2 = market.ServiceProposal{ProviderID: "0x2", ServiceType: "wireguard"}
+)
+
+var providerKey, _ = crypto.ToECDSA(common.FromHex("0x0b4eef4e99796ebfffe5046488525cd906ebc87b30f86ca6bd21b19dc2b319db"))
+
+type privateKeySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (signer *privateKeySigner) Sign(message []byte) (identity.Signature, error) {
+	signature, err := crypto.Sign(crypto.Keccak256(message), signer.key)
+	if err != nil {
+		return identity.Signature{}, err
+	}
+	return identity.SignatureBytes(signature), nil
+}
+
+// signedAndStrippedProposals returns a proposal signed by its provider and the same proposal with the signature stripped off
+func signedAndStrippedProposals(t *testing.T) (market.ServiceProposal, market.ServiceProposal) {
+	signed := market.ServiceProposal{
+		ProviderID:  strings.ToLower(crypto.PubkeyToAddress(providerKey.PublicKey).Hex()),
+		ServiceType: "openvpn",
+	}
+	assert.NoError(t, signed.Sign(&privateKeySigner{key: providerKey}))
+	assert.NoError(t, signed.VerifyProviderSignature())
+
+	stripped := signed
+	stripped.Format = proposalProvider1.Format
+	stripped.Signature = ""
+	stripped.ServiceType = "wireguard"
+	return signed, stripped
+}
+
+type fetcherFake struct {
+	proposals     []market.ServiceProposal
+	etag          string
+	err           error
+	requestedETag string
+	calls         int
+}
+
+func (ff *fetcherFake) QueryProposals(etag string) ([]market.ServiceProposal, string, error) {
+	ff.calls++
+	ff.requestedETag = etag
+	if ff.err != nil {
+		return nil, etag, ff.err
+	}
+	if etag != "" && etag == ff.etag {
+		return nil, etag, mysterium.ErrProposalsNotModified
+	}
+	return ff.proposals, ff.etag, nil
+}
+
+type blockingFetcher struct {
+	fetcherFake
+	release chan struct{}
+}
+
+func (bf *blockingFetcher) QueryProposals(etag string) ([]market.ServiceProposal, string, error) {
+	<-bf.release
+	return bf.fetcherFake.QueryProposals(etag)
+}
+
+type storageFake struct {
+	stored *snapshot
+}
+
+func (sf *storageFake) Store(bucket string, data interface{}) error {
+	stored := *data.(*snapshot)
+	sf.stored = &stored
+	return nil
+}
+
+func (sf *storageFake) GetOneByField(bucket string, fieldName string, key interface{}, to interface{}) error {
+	if sf.stored == nil {
+		return errors.New("not found")
+	}
+	*to.(*snapshot) = *sf.stored
+	return nil
+}
+
+func TestRepository_FindProposalsFetchesWhenEmpty(t *testing.T) {
+	fetcher := &fetcherFake{proposals: []market.ServiceProposal{proposalProvider1, proposalProvider2}, etag: "v1"}
+	storage := &storageFake{}
+	repository := NewRepository(fetcher, storage, time.Minute)
+
+	proposals, err := repository.FindProposals("", "wireguard")
+	assert.NoError(t, err)
+	assert.Equal(t, []market.ServiceProposal{proposalProvider2}, proposals)
+
+	proposals, err = repository.FindProposals("0x1", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []market.ServiceProposal{proposalProvider1}, proposals)
+	assert.Equal(t, 1, fetcher.calls)
+
+	assert.Equal(t, "v1", storage.stored.ETag)
+	assert.Len(t, storage.stored.Proposals, 2)
+}
+
+func TestRepository_FindProposalsFailsWhenNothingIsKnown(t *testing.T) {
+	fetcher := &fetcherFake{err: errors.New("discovery unreachable")}
+	repository := NewRepository(fetcher, &storageFake{}, time.Minute)
+
+	_, err := repository.FindProposals("", "")
+	assert.EqualError(t, err, "discovery unreachable")
+}
+
+func TestRepository_ServesStaleProposalsWhenDiscoveryIsUnreachable(t *testing.T) {
+	updatedAt := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	storage := &storageFake{stored: &snapshot{
+		ID:        snapshotID,
+		ETag:      "v1",
+		UpdatedAt: updatedAt,
+		Proposals: []market.ServiceProposal{proposalProvider1},
+	}}
+	fetcher := &fetcherFake{err: errors.New("discovery unreachable")}
+	repository := NewRepository(fetcher, storage, time.Minute)
+	repository.restore()
+
+	assert.Error(t, repository.Refresh())
+
+	proposals, err := repository.FindProposals("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []market.ServiceProposal{proposalProvider1}, proposals)
+
+	lastUpdate, stale := repository.Freshness()
+	assert.Equal(t, updatedAt, lastUpdate)
+	assert.True(t, stale)
+}
+
+func TestRepository_RefreshUsesETag(t *testing.T) {
+	storage := &storageFake{stored: &snapshot{
+		ID:        snapshotID,
+		ETag:      "v1",
+		Proposals: []market.ServiceProposal{proposalProvider1},
+	}}
+	fetcher := &fetcherFake{etag: "v1"}
+	repository := NewRepository(fetcher, storage, time.Minute)
+	repository.restore()
+
+	assert.NoError(t, repository.Refresh())
+	assert.Equal(t, "v1", fetcher.requestedETag)
+
+	proposals, err := repository.FindProposals("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []market.ServiceProposal{proposalProvider1}, proposals)
+
+	_, stale := repository.Freshness()
+	assert.False(t, stale)
+}
+
+func TestRepository_FindProposalsQueriesDiscoveryForUnknownProvider(t *testing.T) {
+	fetcher := &fetcherFake{proposals: []market.ServiceProposal{proposalProvider1}, etag: "v1"}
+	repository := NewRepository(fetcher, &storageFake{}, time.Minute)
+	assert.NoError(t, repository.Refresh())
+
+	fetcher.proposals = []market.ServiceProposal{proposalProvider1, proposalProvider2}
+	fetcher.etag = "v2"
+
+	proposals, err := repository.FindProposals("0x2", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []market.ServiceProposal{proposalProvider2}, proposals)
+	assert.Equal(t, 2, fetcher.calls)
+}
+
+func TestRepository_SkipsProposalsWithInvalidSignature(t *testing.T) {
+	forged := proposalProvider2
+	assert.NoError(t, forged.Sign(&identity.SignerFake{}))
+
+	fetcher := &fetcherFake{proposals: []market.ServiceProposal{proposalProvider1, forged}, etag: "v1"}
+	repository := NewRepository(fetcher, &storageFake{}, time.Minute)
+
+	proposals, err := repository.FindProposals("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []market.ServiceProposal{proposalProvider1}, proposals)
+}
+
+func TestRepository_FindProposalsDoesNotWaitForSlowDiscovery(t *testing.T) {
+	fetcher := &blockingFetcher{
+		fetcherFake: fetcherFake{proposals: []market.ServiceProposal{proposalProvider1, proposalProvider2}, etag: "v2"},
+		release:     make(chan struct{}),
+	}
+	storage := &storageFake{stored: &snapshot{ID: snapshotID, ETag: "v1", Proposals: []market.ServiceProposal{proposalProvider1}}}
+	repository := NewRepository(fetcher, storage, time.Minute)
+	repository.missTimeout = 10 * time.Millisecond
+	repository.restore()
+
+	proposals, err := repository.FindProposals("0x2", "")
+	assert.NoError(t, err)
+	assert.Len(t, proposals, 0)
+
+	refresh := repository.refreshInBackground()
+	close(fetcher.release)
+	<-refresh.done
+
+	proposals, err = repository.FindProposals("0x2", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []market.ServiceProposal{proposalProvider2}, proposals)
+	assert.Equal(t, 1, fetcher.calls)
+}
+
+func TestRepository_SkipsRestoredProposalsWithInvalidSignature(t *testing.T) {
+	forged := proposalProvider2
+	assert.NoError(t, forged.Sign(&identity.SignerFake{}))
+
+	storage := &storageFake{stored: &snapshot{ID: snapshotID, ETag: "v1", Proposals: []market.ServiceProposal{proposalProvider1, forged}}}
+	repository := NewRepository(&fetcherFake{err: errors.New("discovery unreachable")}, storage, time.Minute)
+	repository.restore()
+
+	proposals, err := repository.FindProposals("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []market.ServiceProposal{proposalProvider1}, proposals)
+}
+
+func TestRepository_SkipsProposalsWithStrippedSignature(t *testing.T) {
+	signed, stripped := signedAndStrippedProposals(t)
+
+	fetcher := &fetcherFake{proposals: []market.ServiceProposal{proposalProvider1, signed}, etag: "v1"}
+	repository := NewRepository(fetcher, &storageFake{}, time.Minute)
+	assert.NoError(t, repository.Refresh())
+
+	// provider was seen signing its proposals, unsigned one can only be a downgrade
+	fetcher.proposals = []market.ServiceProposal{proposalProvider1, stripped}
+	fetcher.etag = "v2"
+	assert.NoError(t, repository.Refresh())
+
+	proposals, err := repository.FindProposals("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []market.ServiceProposal{proposalProvider1}, proposals)
+}
+
+func TestRepository_SkipsProposalsWithStrippedSignatureInTheSameResponse(t *testing.T) {
+	signed, stripped := signedAndStrippedProposals(t)
+
+	fetcher := &fetcherFake{proposals: []market.ServiceProposal{stripped, signed}, etag: "v1"}
+	repository := NewRepository(fetcher, &storageFake{}, time.Minute)
+
+	proposals, err := repository.FindProposals("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []market.ServiceProposal{signed}, proposals)
+}
+
+func TestRepository_RemembersSigningProvidersAcrossRestarts(t *testing.T) {
+	signed, stripped := signedAndStrippedProposals(t)
+
+	storage := &storageFake{}
+	fetcher := &fetcherFake{proposals: []market.ServiceProposal{signed}, etag: "v1"}
+	assert.NoError(t, NewRepository(fetcher, storage, time.Minute).Refresh())
+
+	fetcher = &fetcherFake{proposals: []market.ServiceProposal{proposalProvider1, stripped}, etag: "v2"}
+	repository := NewRepository(fetcher, storage, time.Minute)
+	repository.restore()
+	assert.NoError(t, repository.Refresh())
+
+	proposals, err := repository.FindProposals("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []market.ServiceProposal{proposalProvider1}, proposals)
+}
@@ -24,7 +24,8 @@ import (
 )
 
 const (
-	proposalFormat = "service-proposal/v1"
+	proposalFormat       = "service-proposal/v1"
+	proposalFormatSigned = "service-proposal/v2"
 )
 
 // ServiceProposal is top level structure which is presented to marketplace by service provider, and looked up by service consumer
@@ -53,6 +54,15 @@ type ServiceProposal struct {
 
 	// Communication methods possible
 	ProviderContacts ContactList `json:"provider_contacts"`
+
+	// Free allowance offered to every consumer, nil if there is none
+	FreeCredit *FreeCredit `json:"free_credit,omitempty"`
+
+	// Provider signature of canonical proposal serialization, present since proposal format v2
+	Signature string `json:"signature,omitempty"`
+
+	// Proposal JSON as received from provider without signature, kept for signed proposals only
+	signedContent []byte
 }
 
 // UnmarshalJSON is custom json unmarshaler to dynamically fill in ServiceProposal values
@@ -66,6 +76,8 @@ func (proposal *ServiceProposal) UnmarshalJSON(data []byte) error {
 		ServiceDefinition *json.RawMessage `json:"service_definition"`
 		PaymentMethod     *json.RawMessage `json:"payment_method"`
 		ProviderContacts  *json.RawMessage `json:"provider_contacts"`
+		FreeCredit        *FreeCredit      `json:"free_credit"`
+		Signature         string           `json:"signature"`
 	}
 	if err := json.Unmarshal(data, &jsonData); err != nil {
 		return err
@@ -76,6 +88,8 @@ func (proposal *ServiceProposal) UnmarshalJSON(data []byte) error {
 	proposal.ServiceType = jsonData.ServiceType
 	proposal.ProviderID = jsonData.ProviderID
 	proposal.PaymentMethodType = jsonData.PaymentMethodType
+	proposal.Signature = jsonData.Signature
+	proposal.FreeCredit = jsonData.FreeCredit
 
 	// run the service definition implementation from our registry
 	proposal.ServiceDefinition = unserializeServiceDefinition(
@@ -92,6 +106,15 @@ func (proposal *ServiceProposal) UnmarshalJSON(data []byte) error {
 	// run contact unserializer
 	proposal.ProviderContacts = unserializeContacts(jsonData.ProviderContacts)
 
+	proposal.signedContent = nil
+	if proposal.IsSigned() {
+		signedContent, err := signedMessage(data)
+		if err != nil {
+			return err
+		}
+		proposal.signedContent = signedContent
+	}
+
 	return nil
 }
 
@@ -20,6 +20,7 @@ package market
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/money"
@@ -152,6 +153,26 @@ func Test_ServiceProposal_Unserialize(t *testing.T) {
 	assert.True(t, actual.IsSupported())
 }
 
+func Test_ServiceProposal_SerializeFreeCredit(t *testing.T) {
+	sp := ServiceProposal{
+		ID:                1,
+		ServiceType:       "mock_service",
+		ServiceDefinition: serviceDefinition,
+		PaymentMethodType: "mock_payment",
+		PaymentMethod:     paymentMethod,
+		ProviderContacts:  ContactList{},
+		FreeCredit:        &FreeCredit{Duration: 10 * time.Minute, Period: 24 * time.Hour},
+	}
+
+	jsonBytes, err := json.Marshal(sp)
+	assert.NoError(t, err)
+
+	var actual ServiceProposal
+	err = json.Unmarshal(jsonBytes, &actual)
+	assert.NoError(t, err)
+	assert.Equal(t, &FreeCredit{Duration: 10 * time.Minute, Period: 24 * time.Hour}, actual.FreeCredit)
+}
+
 func Test_ServiceProposal_UnserializeUnknownService(t *testing.T) {
 	jsonData := []byte(`{
 		"service_type": "unknown",
@@ -127,6 +127,10 @@ func (mobNode *MobileNode) OverrideWireguardConnection(wgTunnelSetup WireguardTu
 type deviceFactory func(options connection.ConnectOptions) (*device.DeviceApi, error)
 
 func setupWireguardDevice(devApi *device.DeviceApi, config *wireguard.ServiceConfig) error {
+	if config.Provider.PresharedKey != "" {
+		return errors.New("preshared keys are not supported by mobile wireguard")
+	}
+
 	err := devApi.SetListeningPort(0) //random port
 	if err != nil {
 		return err
@@ -261,6 +265,7 @@ func (wg *wireguardConnection) GetConfig() (connection.ConsumerConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	// preshared key is not supported by mobile wireguard, so provider is not asked for it
 	return wireguard.ConsumerConfig{
 		PublicKey: publicKey,
 	}, nil
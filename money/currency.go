@@ -17,8 +17,39 @@
 
 package money
 
+import "fmt"
+
 type Currency string
 
 const (
 	CURRENCY_MYST = Currency("MYST")
 )
+
+// defaultDecimals is the number of fractional digits of currencies without their own definition
+const defaultDecimals = 8
+
+// decimals holds the number of fractional digits of known currencies
+var decimals = map[Currency]int{
+	CURRENCY_MYST: 8,
+}
+
+// Decimals returns how many fractional digits one unit of currency has,
+// e.g. 1 MYST is 100000000 of its smallest units
+func (currency Currency) Decimals() int {
+	if count, ok := decimals[currency]; ok {
+		return count
+	}
+	return defaultDecimals
+}
+
+// ParseCurrency returns the currency of given name, MYST is assumed when name is empty
+func ParseCurrency(name string) (Currency, error) {
+	switch currency := Currency(name); currency {
+	case "":
+		return CURRENCY_MYST, nil
+	case CURRENCY_MYST:
+		return currency, nil
+	default:
+		return "", fmt.Errorf("unsupported currency: %q", name)
+	}
+}
@@ -18,23 +18,229 @@
 package money
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
 )
 
+var (
+	// ErrCurrencyMismatch is returned when amounts of different currencies are combined
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrNegativeAmount is returned when an amount would drop below zero
+	ErrNegativeAmount = errors.New("negative amount")
+	// ErrOverflow is returned when an amount does not fit into uint64
+	ErrOverflow = errors.New("amount overflows uint64")
+)
+
+// Money is a non-negative amount of currency, expressed in the smallest units of that currency.
+// Amount is nil when the amount is zero, so the zero value of Money is a zero amount.
+// Money is a value type: Amount is never modified in place and callers must not modify it either.
 type Money struct {
-	Amount   uint64   `json:"amount,omitempty"`
+	Amount   *big.Int `json:"amount,omitempty"`
 	Currency Currency `json:"currency,omitempty"`
 }
 
+// NewMoney converts the amount given in whole units of currency, e.g. 0.5 MYST.
+// The amount is rounded to the decimals of currency, negative and non-finite amounts give zero.
 func NewMoney(amount float64, currency Currency) Money {
-	return Money{uint64(amount * 100000000), currency}
+	if amount <= 0 || math.IsInf(amount, 0) || math.IsNaN(amount) {
+		return Money{Currency: currency}
+	}
+	money, err := parseAmount(strconv.FormatFloat(amount, 'f', currency.Decimals(), 64), currency)
+	if err != nil {
+		return Money{Currency: currency}
+	}
+	return money
+}
+
+// NewUnits creates money from the amount given in the smallest units of currency
+func NewUnits(units uint64, currency Currency) Money {
+	return newMoney(new(big.Int).SetUint64(units), currency)
+}
+
+// NewBigUnits creates money from the amount given in the smallest units of currency.
+// Negative units give zero.
+func NewBigUnits(units *big.Int, currency Currency) Money {
+	if units == nil || units.Sign() < 0 {
+		return Money{Currency: currency}
+	}
+	return newMoney(new(big.Int).Set(units), currency)
+}
+
+// Parse parses human readable amounts like "0.5 MYST" or "0.5MYST".
+// MYST is assumed when the currency is omitted.
+func Parse(value string) (Money, error) {
+	value = strings.TrimSpace(value)
+	split := strings.IndexFunc(value, unicode.IsLetter)
+	if split < 0 {
+		split = len(value)
+	}
+
+	currency, err := ParseCurrency(strings.TrimSpace(value[split:]))
+	if err != nil {
+		return Money{}, err
+	}
+	return parseAmount(strings.TrimSpace(value[:split]), currency)
+}
+
+func parseAmount(value string, currency Currency) (Money, error) {
+	whole, fraction, hasPoint := value, "", false
+	if point := strings.IndexByte(value, '.'); point >= 0 {
+		whole, fraction, hasPoint = value[:point], value[point+1:], true
+	}
+	if !isDigits(whole) || (hasPoint && !isDigits(fraction)) {
+		return Money{}, fmt.Errorf("invalid amount: %q", value)
+	}
+	if len(fraction) > currency.Decimals() {
+		return Money{}, fmt.Errorf("amount %q has more than %d decimals of %s", value, currency.Decimals(), currency)
+	}
+
+	fraction += strings.Repeat("0", currency.Decimals()-len(fraction))
+	units, ok := new(big.Int).SetString(whole+fraction, 10)
+	if !ok {
+		return Money{}, fmt.Errorf("invalid amount: %q", value)
+	}
+	return newMoney(units, currency), nil
+}
+
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// newMoney takes the ownership of amount and keeps zero amounts as nil
+func newMoney(amount *big.Int, currency Currency) Money {
+	if amount.Sign() == 0 {
+		return Money{Currency: currency}
+	}
+	return Money{Amount: amount, Currency: currency}
+}
+
+func (value Money) amount() *big.Int {
+	if value.Amount == nil {
+		return new(big.Int)
+	}
+	return value.Amount
+}
+
+// Units returns a copy of the amount in the smallest units of currency, never nil
+func (value Money) Units() *big.Int {
+	return new(big.Int).Set(value.amount())
 }
 
-// String converts struct to string
-func (value *Money) String() string {
-	return fmt.Sprintf(
-		"%d%s",
-		value.Amount,
-		value.Currency,
-	)
+// IsZero tells if there is no money
+func (value Money) IsZero() bool {
+	return value.amount().Sign() == 0
+}
+
+// Uint64 returns the amount in the smallest units of currency, or ErrOverflow when it does not fit
+func (value Money) Uint64() (uint64, error) {
+	amount := value.amount()
+	if !amount.IsUint64() {
+		return 0, ErrOverflow
+	}
+	return amount.Uint64(), nil
+}
+
+// Add returns the sum of both amounts
+func (value Money) Add(other Money) (Money, error) {
+	currency, err := commonCurrency(value, other)
+	if err != nil {
+		return Money{}, err
+	}
+	return newMoney(new(big.Int).Add(value.amount(), other.amount()), currency), nil
+}
+
+// Sub returns the amount left after taking other away, ErrNegativeAmount is returned when other is bigger
+func (value Money) Sub(other Money) (Money, error) {
+	currency, err := commonCurrency(value, other)
+	if err != nil {
+		return Money{}, err
+	}
+	result := new(big.Int).Sub(value.amount(), other.amount())
+	if result.Sign() < 0 {
+		return Money{}, ErrNegativeAmount
+	}
+	return newMoney(result, currency), nil
+}
+
+// Mul returns the amount multiplied by factor
+func (value Money) Mul(factor uint64) Money {
+	return newMoney(new(big.Int).Mul(value.amount(), new(big.Int).SetUint64(factor)), value.Currency)
+}
+
+// Cmp compares both amounts and returns -1, 0 or +1 when value is less, equal or greater than other
+func (value Money) Cmp(other Money) (int, error) {
+	if _, err := commonCurrency(value, other); err != nil {
+		return 0, err
+	}
+	return value.amount().Cmp(other.amount()), nil
+}
+
+// commonCurrency returns the currency both amounts are in. Money without currency
+// is treated as being in the currency of the other amount, so it can be used as an accumulator.
+func commonCurrency(a, b Money) (Currency, error) {
+	switch {
+	case a.Currency == "":
+		return b.Currency, nil
+	case b.Currency == "", a.Currency == b.Currency:
+		return a.Currency, nil
+	default:
+		return "", ErrCurrencyMismatch
+	}
+}
+
+// UnmarshalJSON reads the amount in smallest units of currency, same as it is written
+func (value *Money) UnmarshalJSON(data []byte) error {
+	type jsonMoney Money
+	var decoded jsonMoney
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if decoded.Amount == nil {
+		*value = Money{Currency: decoded.Currency}
+		return nil
+	}
+	if decoded.Amount.Sign() < 0 {
+		return ErrNegativeAmount
+	}
+	*value = newMoney(decoded.Amount, decoded.Currency)
+	return nil
+}
+
+// String formats money for humans, e.g. "0.5 MYST"
+func (value Money) String() string {
+	formatted := formatAmount(value.amount(), value.Currency.Decimals())
+	if value.Currency == "" {
+		return formatted
+	}
+	return formatted + " " + string(value.Currency)
+}
+
+func formatAmount(amount *big.Int, decimals int) string {
+	digits := amount.String()
+	if decimals == 0 {
+		return digits
+	}
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+
+	whole, fraction := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if fraction == "" {
+		return whole
+	}
+	return whole + "." + fraction
 }
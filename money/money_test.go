@@ -18,33 +18,211 @@
 package money
 
 import (
+	"encoding/json"
+	"math"
+	"math/big"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_NewMoney(t *testing.T) {
-	assert.Equal(
-		t,
-		uint64(0.150*100000000),
-		NewMoney(0.150, CURRENCY_MYST).Amount,
-	)
-
-	assert.Equal(
-		t,
-		uint64(0*100000000),
-		NewMoney(0, CURRENCY_MYST).Amount,
-	)
-
-	assert.Equal(
-		t,
-		uint64(10*100000000),
-		NewMoney(10, CURRENCY_MYST).Amount,
-	)
-
-	assert.NotEqual(
-		t,
-		uint64(1),
-		NewMoney(1, CURRENCY_MYST).Amount,
-	)
+	tests := []struct {
+		name   string
+		amount float64
+		want   uint64
+	}{
+		{name: "fraction", amount: 0.150, want: 15000000},
+		{name: "fraction not representable as float", amount: 0.002, want: 200000},
+		{name: "zero", amount: 0, want: 0},
+		{name: "whole", amount: 10, want: 1000000000},
+		{name: "rounded to decimals", amount: 0.000000016, want: 2},
+		{name: "negative", amount: -1, want: 0},
+		{name: "infinite", amount: math.Inf(1), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, err := NewMoney(tt.amount, CURRENCY_MYST).Uint64()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, amount)
+		})
+	}
+}
+
+func Test_NewMoney_ZeroIsZeroValue(t *testing.T) {
+	assert.Equal(t, Money{Currency: CURRENCY_MYST}, NewMoney(0, CURRENCY_MYST))
+	assert.Equal(t, Money{Currency: CURRENCY_MYST}, NewUnits(0, CURRENCY_MYST))
+}
+
+func Test_NewBigUnits(t *testing.T) {
+	units := big.NewInt(5)
+	value := NewBigUnits(units, CURRENCY_MYST)
+	units.SetInt64(7)
+	assert.Equal(t, NewUnits(5, CURRENCY_MYST), value)
+
+	assert.Equal(t, Money{Currency: CURRENCY_MYST}, NewBigUnits(big.NewInt(0), CURRENCY_MYST))
+	assert.Equal(t, Money{Currency: CURRENCY_MYST}, NewBigUnits(big.NewInt(-1), CURRENCY_MYST))
+}
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    Money
+		wantErr string
+	}{
+		{name: "with currency", value: "0.5 MYST", want: NewUnits(50000000, CURRENCY_MYST)},
+		{name: "without space", value: "12.345MYST", want: NewUnits(1234500000, CURRENCY_MYST)},
+		{name: "MYST by default", value: " 7 ", want: NewUnits(700000000, CURRENCY_MYST)},
+		{name: "smallest unit", value: "0.00000001 MYST", want: NewUnits(1, CURRENCY_MYST)},
+		{name: "zero", value: "0 MYST", want: Money{Currency: CURRENCY_MYST}},
+		{
+			name:  "bigger than uint64",
+			value: "1000000000000 MYST",
+			want:  Money{Amount: new(big.Int).Mul(big.NewInt(1000000000000), big.NewInt(100000000)), Currency: CURRENCY_MYST},
+		},
+		{name: "too many decimals", value: "0.000000001 MYST", wantErr: `amount "0.000000001" has more than 8 decimals of MYST`},
+		{name: "negative", value: "-1 MYST", wantErr: `invalid amount: "-1"`},
+		{name: "missing fraction", value: "1. MYST", wantErr: `invalid amount: "1."`},
+		{name: "missing amount", value: "MYST", wantErr: `invalid amount: ""`},
+		{name: "unknown currency", value: "1 BTC", wantErr: `unsupported currency: "BTC"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.value)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_String(t *testing.T) {
+	tests := []struct {
+		value Money
+		want  string
+	}{
+		{value: NewUnits(1000000000, CURRENCY_MYST), want: "10 MYST"},
+		{value: NewUnits(150000, CURRENCY_MYST), want: "0.0015 MYST"},
+		{value: NewUnits(1, CURRENCY_MYST), want: "0.00000001 MYST"},
+		{value: NewUnits(123456789012, CURRENCY_MYST), want: "1234.56789012 MYST"},
+		{value: Money{Currency: CURRENCY_MYST}, want: "0 MYST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.value.String())
+
+			parsed, err := Parse(tt.want)
+			assert.NoError(t, err)
+			cmp, err := parsed.Cmp(tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, cmp)
+		})
+	}
+}
+
+func Test_String_WithoutCurrency(t *testing.T) {
+	assert.Equal(t, "0.00000042", NewUnits(42, "").String())
+}
+
+func Test_Arithmetic(t *testing.T) {
+	one, half := NewUnits(100000000, CURRENCY_MYST), NewUnits(50000000, CURRENCY_MYST)
+
+	sum, err := one.Add(half)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.5 MYST", sum.String())
+
+	left, err := one.Sub(half)
+	assert.NoError(t, err)
+	assert.Equal(t, half, left)
+
+	left, err = half.Sub(half)
+	assert.NoError(t, err)
+	assert.True(t, left.IsZero())
+
+	_, err = half.Sub(one)
+	assert.Equal(t, ErrNegativeAmount, err)
+
+	assert.Equal(t, "3 MYST", one.Mul(3).String())
+	assert.True(t, one.Mul(0).IsZero())
+
+	cmp, err := half.Cmp(one)
+	assert.NoError(t, err)
+	assert.Equal(t, -1, cmp)
+	cmp, err = one.Cmp(half)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, cmp)
+
+	// amounts are never modified in place
+	assert.Equal(t, "1 MYST", one.String())
+	assert.Equal(t, "0.5 MYST", half.String())
+}
+
+func Test_ArithmeticChecksCurrency(t *testing.T) {
+	myst, other := NewUnits(1, CURRENCY_MYST), NewUnits(1, Currency("TEST"))
+
+	_, err := myst.Add(other)
+	assert.Equal(t, ErrCurrencyMismatch, err)
+	_, err = myst.Sub(other)
+	assert.Equal(t, ErrCurrencyMismatch, err)
+	_, err = myst.Cmp(other)
+	assert.Equal(t, ErrCurrencyMismatch, err)
+
+	total, err := Money{}.Add(myst)
+	assert.NoError(t, err)
+	assert.Equal(t, myst, total)
+}
+
+func Test_Uint64(t *testing.T) {
+	amount, err := NewUnits(math.MaxUint64, CURRENCY_MYST).Uint64()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(math.MaxUint64), amount)
+
+	tooBig := NewUnits(math.MaxUint64, CURRENCY_MYST).Mul(2)
+	_, err = tooBig.Uint64()
+	assert.Equal(t, ErrOverflow, err)
+}
+
+func Test_JSON(t *testing.T) {
+	value := NewUnits(50000000, CURRENCY_MYST)
+	data, err := json.Marshal(value)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount":50000000,"currency":"MYST"}`, string(data))
+
+	var decoded Money
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, value, decoded)
+
+	data, err = json.Marshal(Money{Currency: CURRENCY_MYST})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"currency":"MYST"}`, string(data))
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount":0,"currency":"MYST"}`), &decoded))
+	assert.Equal(t, Money{Currency: CURRENCY_MYST}, decoded)
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount":36893488147419103230,"currency":"MYST"}`), &decoded))
+	assert.Equal(t, NewUnits(math.MaxUint64, CURRENCY_MYST).Mul(2), decoded)
+
+	assert.Equal(t, ErrNegativeAmount, json.Unmarshal([]byte(`{"amount":-1,"currency":"MYST"}`), &decoded))
+}
+
+func Test_ParseCurrency(t *testing.T) {
+	currency, err := ParseCurrency("MYST")
+	assert.NoError(t, err)
+	assert.Equal(t, CURRENCY_MYST, currency)
+
+	currency, err = ParseCurrency("")
+	assert.NoError(t, err)
+	assert.Equal(t, CURRENCY_MYST, currency)
+
+	_, err = ParseCurrency("BTC")
+	assert.EqualError(t, err, `unsupported currency: "BTC"`)
+}
+
+func Test_CurrencyDecimals(t *testing.T) {
+	assert.Equal(t, 8, CURRENCY_MYST.Decimals())
+	assert.Equal(t, 8, Currency("TEST").Decimals())
 }
@@ -45,7 +45,7 @@ type Manager struct {
 }
 
 // ProvideConfig provides the session configuration
-func (manager *Manager) ProvideConfig(cfg json.RawMessage) (session.ServiceConfiguration, session.DestroyCallback, error) {
+func (manager *Manager) ProvideConfig(sessionID session.ID, cfg json.RawMessage) (session.ServiceConfiguration, session.DestroyCallback, error) {
 	return nil, nil, nil
 }
 
@@ -47,7 +47,6 @@ func Test_GetProposal(t *testing.T) {
 			PaymentMethodType: "NOOP",
 			PaymentMethod: PaymentNoop{
 				Price: money.Money{
-					Amount:   0,
 					Currency: money.Currency("MYST"),
 				},
 			},
@@ -58,7 +57,7 @@ func Test_GetProposal(t *testing.T) {
 
 func Test_Manager_ProvideConfig(t *testing.T) {
 	manager := NewManager()
-	sessionConfig, cb, err := manager.ProvideConfig(nil)
+	sessionConfig, cb, err := manager.ProvideConfig("", nil)
 	assert.NoError(t, err)
 	assert.Nil(t, sessionConfig)
 	assert.Nil(t, cb)
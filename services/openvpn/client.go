@@ -19,26 +19,53 @@ package openvpn
 
 import (
 	"errors"
+	"time"
 
+	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/go-openvpn/openvpn"
 	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
 )
 
+const clientLogPrefix = "[openvpn-client] "
+
 // ErrProcessNotStarted represents the error we return when the process is not started yet
 var ErrProcessNotStarted = errors.New("process not started yet")
 
-// processFactory creates a new openvpn process
-type processFactory func(options connection.ConnectOptions) (openvpn.Process, error)
+// processFactory creates a new openvpn process for given client config, process states are reported to stateChannel
+type processFactory func(options connection.ConnectOptions, config *ClientConfig, stateChannel connection.StateChannel) (openvpn.Process, error)
+
+// clientConfigsFactory creates client configs for all transports of the session config in order of preference
+type clientConfigsFactory func(sessionConfig []byte) ([]*ClientConfig, error)
 
 // Client takes in the openvpn process and works with it
 type Client struct {
-	process        openvpn.Process
-	processFactory processFactory
+	process              openvpn.Process
+	processFactory       processFactory
+	clientConfigsFactory clientConfigsFactory
+	stateChannel         connection.StateChannel
+	fallbackTimeout      time.Duration
 }
 
-// Start starts the connection
+// Start starts the connection. Each transport except the last one gets fallbackTimeout to reach connected state,
+// the next transport is tried otherwise
 func (c *Client) Start(options connection.ConnectOptions) error {
-	proc, err := c.processFactory(options)
+	configs, err := c.clientConfigsFactory(options.SessionConfig)
+	if err != nil {
+		return err
+	}
+
+	for _, config := range configs[:len(configs)-1] {
+		connected, err := c.tryConnect(options, config)
+		if err != nil {
+			return err
+		}
+		if connected {
+			return nil
+		}
+	}
+
+	proc, err := c.processFactory(options, configs[len(configs)-1], c.stateChannel)
 	if err != nil {
 		return err
 	}
@@ -46,6 +73,63 @@ func (c *Client) Start(options connection.ConnectOptions) error {
 	return c.process.Start()
 }
 
+// tryConnect starts the process and waits for it to connect. States are forwarded to the client only once connected,
+// so that the connection manager does not see the failed attempt
+func (c *Client) tryConnect(options connection.ConnectOptions, config *ClientConfig) (bool, error) {
+	attemptStates := make(chan connection.State, 10)
+	proc, err := c.processFactory(options, config, attemptStates)
+	if err != nil {
+		return false, err
+	}
+	if err := proc.Start(); err != nil {
+		log.Warn(clientLogPrefix, "failed to start openvpn process, trying next transport: ", err)
+		return false, nil
+	}
+
+	if waitForConnected(attemptStates, c.fallbackTimeout) {
+		c.process = proc
+		c.stateChannel <- connection.Connected
+		go forwardStates(attemptStates, c.stateChannel)
+		return true, nil
+	}
+
+	log.Warn(clientLogPrefix, "openvpn connection not established in ", c.fallbackTimeout, ", trying next transport")
+	go drainStates(attemptStates)
+	proc.Stop()
+	return false, nil
+}
+
+func waitForConnected(states <-chan connection.State, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case state, more := <-states:
+			if !more {
+				return false
+			}
+			if state == connection.Connected {
+				return true
+			}
+		case <-timer.C:
+			return false
+		}
+	}
+}
+
+func forwardStates(from <-chan connection.State, to connection.StateChannel) {
+	for state := range from {
+		to <- state
+	}
+	close(to)
+}
+
+func drainStates(states <-chan connection.State) {
+	for range states {
+	}
+}
+
 // Wait waits for the connection to exit
 func (c *Client) Wait() error {
 	if c.process == nil {
@@ -73,4 +157,10 @@ type VPNConfig struct {
 	RemoteProtocol  string `json:"protocol"`
 	TLSPresharedKey string `json:"TLSPresharedKey"`
 	CACertificate   string `json:"CACertificate"`
+
+	// Transports lists all transports of provider in order of preference, remote port and protocol hold the first one
+	Transports []dto.Transport `json:"transports,omitempty"`
+
+	// CryptoProfile names ciphers and session timing used by provider, empty means default profile
+	CryptoProfile string `json:"crypto_profile,omitempty"`
 }
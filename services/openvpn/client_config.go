@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 
 	"github.com/mysteriumnetwork/go-openvpn/openvpn/config"
+	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
 )
 
 // ClientConfig represents specific "openvpn as client" configuration
@@ -41,6 +42,11 @@ func (c *ClientConfig) SetClientMode(serverIP string, serverPort int) {
 	c.SetFlag("management-query-passwords")
 }
 
+// SetCryptoProfile sets ciphers and session timing matching the ones of server
+func (c *ClientConfig) SetCryptoProfile(profile CryptoProfile) {
+	setCryptoProfile(c.GenericConfig, profile)
+}
+
 // SetProtocol specifies openvpn connection protocol type (tcp or udp)
 func (c *ClientConfig) SetProtocol(protocol string) {
 	if protocol == "tcp" {
@@ -54,14 +60,10 @@ func defaultClientConfig(runtimeDir string, scriptSearchPath string) *ClientConf
 	clientConfig := ClientConfig{config.NewConfig(runtimeDir, scriptSearchPath)}
 
 	clientConfig.SetDevice("tun")
-	clientConfig.SetParam("cipher", "AES-256-GCM")
 	clientConfig.SetParam("verb", "3")
-	clientConfig.SetParam("tls-cipher", "TLS-ECDHE-ECDSA-WITH-AES-256-GCM-SHA384")
-	clientConfig.SetKeepAlive(10, 60)
 	clientConfig.SetPingTimerRemote()
 	clientConfig.SetPersistKey()
 
-	clientConfig.SetParam("reneg-sec", "60")
 	clientConfig.SetParam("resolv-retry", "infinite")
 	clientConfig.SetParam("redirect-gateway", "def1", "bypass-dhcp")
 	clientConfig.SetParam("dhcp-option", "DNS", "208.67.222.222")
@@ -74,6 +76,38 @@ func defaultClientConfig(runtimeDir string, scriptSearchPath string) *ClientConf
 // configuration filename to store other args
 // TODO this will become the part of openvpn service consumer separate package
 func NewClientConfigFromSession(sessionConfig []byte, configDir string, runtimeDir string) (*ClientConfig, error) {
+	vpnConfig, err := parseVPNConfig(sessionConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return newClientConfigForTransport(vpnConfig, vpnConfig.RemoteProtocol, vpnConfig.RemotePort, configDir, runtimeDir)
+}
+
+// NewClientConfigsFromSession creates client configuration for each transport of given VPNConfig in order of preference
+func NewClientConfigsFromSession(sessionConfig []byte, configDir string, runtimeDir string) ([]*ClientConfig, error) {
+	vpnConfig, err := parseVPNConfig(sessionConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	transports := vpnConfig.Transports
+	if len(transports) == 0 {
+		transports = []dto.Transport{{Protocol: vpnConfig.RemoteProtocol, Port: vpnConfig.RemotePort}}
+	}
+
+	clientConfigs := make([]*ClientConfig, 0, len(transports))
+	for _, transport := range transports {
+		clientConfig, err := newClientConfigForTransport(vpnConfig, transport.Protocol, transport.Port, configDir, runtimeDir)
+		if err != nil {
+			return nil, err
+		}
+		clientConfigs = append(clientConfigs, clientConfig)
+	}
+	return clientConfigs, nil
+}
+
+func parseVPNConfig(sessionConfig []byte) (*VPNConfig, error) {
 	vpnConfig := &VPNConfig{}
 	err := json.Unmarshal(sessionConfig, vpnConfig)
 	if err != nil {
@@ -84,11 +118,20 @@ func NewClientConfigFromSession(sessionConfig []byte, configDir string, runtimeD
 	if err != nil {
 		return nil, err
 	}
+	return vpnConfig, nil
+}
+
+func newClientConfigForTransport(vpnConfig *VPNConfig, protocol string, port int, configDir string, runtimeDir string) (*ClientConfig, error) {
+	cryptoProfile, err := FindCryptoProfile(vpnConfig.CryptoProfile)
+	if err != nil {
+		return nil, err
+	}
 
 	clientFileConfig := newClientConfig(runtimeDir, configDir)
+	clientFileConfig.SetCryptoProfile(cryptoProfile)
 	clientFileConfig.SetReconnectRetry(2)
-	clientFileConfig.SetClientMode(vpnConfig.RemoteIP, vpnConfig.RemotePort)
-	clientFileConfig.SetProtocol(vpnConfig.RemoteProtocol)
+	clientFileConfig.SetClientMode(vpnConfig.RemoteIP, port)
+	clientFileConfig.SetProtocol(protocol)
 	clientFileConfig.SetTLSCACertificate(vpnConfig.CACertificate)
 	clientFileConfig.SetTLSCrypt(vpnConfig.TLSPresharedKey)
 
@@ -0,0 +1,103 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package openvpn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/go-openvpn/openvpn"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeProcess struct {
+	stateChannel connection.StateChannel
+	connects     bool
+	started      bool
+	stopped      bool
+}
+
+func (fp *fakeProcess) Start() error {
+	fp.started = true
+	if fp.connects {
+		fp.stateChannel <- connection.Connected
+	}
+	return nil
+}
+
+func (fp *fakeProcess) Wait() error {
+	return nil
+}
+
+func (fp *fakeProcess) Stop() {
+	fp.stopped = true
+	close(fp.stateChannel)
+}
+
+type fakeProcessFactory struct {
+	connectingConfigs map[*ClientConfig]bool
+	processes         []*fakeProcess
+}
+
+func (fpf *fakeProcessFactory) create(_ connection.ConnectOptions, config *ClientConfig, stateChannel connection.StateChannel) (openvpn.Process, error) {
+	process := &fakeProcess{stateChannel: stateChannel, connects: fpf.connectingConfigs[config]}
+	fpf.processes = append(fpf.processes, process)
+	return process, nil
+}
+
+func newFallbackClient(configs []*ClientConfig, factory *fakeProcessFactory, stateChannel connection.StateChannel) *Client {
+	return &Client{
+		processFactory: factory.create,
+		clientConfigsFactory: func([]byte) ([]*ClientConfig, error) {
+			return configs, nil
+		},
+		stateChannel:    stateChannel,
+		fallbackTimeout: 10 * time.Millisecond,
+	}
+}
+
+func TestClient_StartUsesPreferredTransportWhenItConnects(t *testing.T) {
+	udpConfig, tcpConfig := &ClientConfig{}, &ClientConfig{}
+	factory := &fakeProcessFactory{connectingConfigs: map[*ClientConfig]bool{udpConfig: true, tcpConfig: true}}
+	stateChannel := make(chan connection.State, 10)
+	client := newFallbackClient([]*ClientConfig{udpConfig, tcpConfig}, factory, stateChannel)
+
+	assert.NoError(t, client.Start(connection.ConnectOptions{}))
+
+	assert.Len(t, factory.processes, 1)
+	assert.Equal(t, connection.Connected, <-stateChannel)
+
+	client.Stop()
+	_, more := <-stateChannel
+	assert.False(t, more)
+}
+
+func TestClient_StartFallsBackToNextTransport(t *testing.T) {
+	udpConfig, tcpConfig := &ClientConfig{}, &ClientConfig{}
+	factory := &fakeProcessFactory{connectingConfigs: map[*ClientConfig]bool{tcpConfig: true}}
+	stateChannel := make(chan connection.State, 10)
+	client := newFallbackClient([]*ClientConfig{udpConfig, tcpConfig}, factory, stateChannel)
+
+	assert.NoError(t, client.Start(connection.ConnectOptions{}))
+
+	assert.Len(t, factory.processes, 2)
+	assert.True(t, factory.processes[0].stopped)
+	assert.True(t, factory.processes[1].started)
+	assert.Equal(t, connection.Connected, <-stateChannel)
+}
@@ -42,6 +42,8 @@ func NewDefaultValidator() *ConfigValidator {
 		validators: []ValidateConfig{
 			validProtocol,
 			validPort,
+			validTransports,
+			validCryptoProfile,
 			validIPFormat,
 			validTLSPresharedKey,
 			validCACertificate,
@@ -60,17 +62,42 @@ func (v *ConfigValidator) IsValid(config *VPNConfig) error {
 }
 
 func validProtocol(config *VPNConfig) error {
-	switch config.RemoteProtocol {
+	return checkProtocol(config.RemoteProtocol)
+}
+
+func validPort(config *VPNConfig) error {
+	return checkPort(config.RemotePort)
+}
+
+func validTransports(config *VPNConfig) error {
+	for _, transport := range config.Transports {
+		if err := checkProtocol(transport.Protocol); err != nil {
+			return err
+		}
+		if err := checkPort(transport.Port); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validCryptoProfile(config *VPNConfig) error {
+	_, err := FindCryptoProfile(config.CryptoProfile)
+	return err
+}
+
+func checkProtocol(protocol string) error {
+	switch protocol {
 	case
 		"udp",
 		"tcp":
 		return nil
 	}
-	return errors.New("invalid protocol: " + config.RemoteProtocol)
+	return errors.New("invalid protocol: " + protocol)
 }
 
-func validPort(config *VPNConfig) error {
-	if config.RemotePort > 65535 || config.RemotePort < 1024 {
+func checkPort(port int) error {
+	if port > 65535 || port < 1024 {
 		return errors.New("invalid port range, should fall within 1024 .. 65535 range")
 	}
 	return nil
@@ -20,6 +20,7 @@ package openvpn
 import (
 	"testing"
 
+	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -67,10 +68,32 @@ func TestValidatorReturnsNilErrorOnValidVPNConfig(t *testing.T) {
 		"tcp",
 		tlsTestKey,
 		caCertificate,
+		nil,
+		"",
 	}
 	assert.NoError(t, NewDefaultValidator().IsValid(vpnConfig))
 }
 
+func TestUnsupportedCryptoProfileIsNotAllowed(t *testing.T) {
+	vpnConfig := VPNConfig{CryptoProfile: "chacha20"}
+	assert.NoError(t, validCryptoProfile(&vpnConfig))
+
+	vpnConfig = VPNConfig{CryptoProfile: "rot13"}
+	assert.EqualError(
+		t,
+		validCryptoProfile(&vpnConfig),
+		`unsupported crypto profile: "rot13", supported profiles: chacha20, default, long-reneg`,
+	)
+}
+
+func TestInvalidTransportIsNotAllowed(t *testing.T) {
+	vpnConfig := VPNConfig{Transports: []dto.Transport{{Protocol: "udp", Port: 10999}, {Protocol: "sctp", Port: 10999}}}
+	assert.EqualError(t, validTransports(&vpnConfig), "invalid protocol: sctp")
+
+	vpnConfig = VPNConfig{Transports: []dto.Transport{{Protocol: "tcp", Port: 80}}}
+	assert.Error(t, validTransports(&vpnConfig))
+}
+
 func TestIPv6AreNotAllowed(t *testing.T) {
 	vpnConfig := VPNConfig{RemoteIP: "2001:db8:85a3::8a2e:370:7334"}
 	assert.Error(t, validIPFormat(&vpnConfig))
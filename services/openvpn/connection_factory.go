@@ -33,6 +33,9 @@ import (
 	"github.com/mysteriumnetwork/node/session"
 )
 
+// transportFallbackTimeout defines how long connection over preferred transport is awaited before trying the next one
+const transportFallbackTimeout = 15 * time.Second
+
 // ProcessBasedConnectionFactory represents a factory for creating process-based openvpn connections
 type ProcessBasedConnectionFactory struct {
 	openvpnBinary         string
@@ -74,12 +77,7 @@ func (op *ProcessBasedConnectionFactory) newStateMiddleware(session session.ID,
 
 // Create creates a new openvpnn connection
 func (op *ProcessBasedConnectionFactory) Create(stateChannel connection.StateChannel, statisticsChannel connection.StatisticsChannel) (connection.Connection, error) {
-	procFactory := func(options connection.ConnectOptions) (openvpn.Process, error) {
-		vpnClientConfig, err := NewClientConfigFromSession(options.SessionConfig, op.configDirectory, op.runtimeDirectory)
-		if err != nil {
-			return nil, err
-		}
-
+	procFactory := func(options connection.ConnectOptions, vpnClientConfig *ClientConfig, stateChannel connection.StateChannel) (openvpn.Process, error) {
 		signer := op.signerFactory(options.ConsumerID)
 
 		stateMiddleware := op.newStateMiddleware(options.SessionID, signer, options, stateChannel)
@@ -89,7 +87,14 @@ func (op *ProcessBasedConnectionFactory) Create(stateChannel connection.StateCha
 		return proc, nil
 	}
 
+	clientConfigsFactory := func(sessionConfig []byte) ([]*ClientConfig, error) {
+		return NewClientConfigsFromSession(sessionConfig, op.configDirectory, op.runtimeDirectory)
+	}
+
 	return &Client{
-		processFactory: procFactory,
+		processFactory:       procFactory,
+		clientConfigsFactory: clientConfigsFactory,
+		stateChannel:         stateChannel,
+		fallbackTimeout:      transportFallbackTimeout,
 	}, nil
 }
@@ -0,0 +1,100 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package openvpn
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/config"
+)
+
+// DefaultCryptoProfile is used when provider does not advertise any crypto profile
+const DefaultCryptoProfile = "default"
+
+// CryptoProfile defines data channel cipher, control channel cipher and session timing used by both server and client
+type CryptoProfile struct {
+	Name             string
+	Cipher           string
+	TLSCipher        string
+	RenegotiationSec int
+	KeepAlivePing    int
+	KeepAliveTimeout int
+}
+
+// cryptoProfiles lists profiles supported by this node, consumer must support the profile advertised by provider
+var cryptoProfiles = map[string]CryptoProfile{
+	DefaultCryptoProfile: {
+		Name:             DefaultCryptoProfile,
+		Cipher:           "AES-256-GCM",
+		TLSCipher:        "TLS-ECDHE-ECDSA-WITH-AES-256-GCM-SHA384",
+		RenegotiationSec: 60,
+		KeepAlivePing:    10,
+		KeepAliveTimeout: 60,
+	},
+	// for devices without AES hardware acceleration, e.g. ARM routers
+	"chacha20": {
+		Name:             "chacha20",
+		Cipher:           "CHACHA20-POLY1305",
+		TLSCipher:        "TLS-ECDHE-ECDSA-WITH-CHACHA20-POLY1305-SHA256",
+		RenegotiationSec: 3600,
+		KeepAlivePing:    10,
+		KeepAliveTimeout: 60,
+	},
+	// for low-power devices where frequent key renegotiation is too expensive
+	"long-reneg": {
+		Name:             "long-reneg",
+		Cipher:           "AES-256-GCM",
+		TLSCipher:        "TLS-ECDHE-ECDSA-WITH-AES-256-GCM-SHA384",
+		RenegotiationSec: 3600,
+		KeepAlivePing:    20,
+		KeepAliveTimeout: 120,
+	},
+}
+
+// FindCryptoProfile returns crypto profile by given name, empty name means default profile
+func FindCryptoProfile(name string) (CryptoProfile, error) {
+	if name == "" {
+		name = DefaultCryptoProfile
+	}
+
+	profile, ok := cryptoProfiles[name]
+	if !ok {
+		return CryptoProfile{}, fmt.Errorf("unsupported crypto profile: %q, supported profiles: %s", name, strings.Join(CryptoProfileNames(), ", "))
+	}
+	return profile, nil
+}
+
+// CryptoProfileNames returns names of all supported crypto profiles
+func CryptoProfileNames() []string {
+	names := make([]string, 0, len(cryptoProfiles))
+	for name := range cryptoProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func setCryptoProfile(c *config.GenericConfig, profile CryptoProfile) {
+	c.SetParam("cipher", profile.Cipher)
+	c.SetParam("tls-cipher", profile.TLSCipher)
+	c.SetParam("reneg-sec", strconv.Itoa(profile.RenegotiationSec))
+	c.SetKeepAlive(profile.KeepAlivePing, profile.KeepAliveTimeout)
+}
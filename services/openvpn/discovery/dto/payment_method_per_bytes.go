@@ -31,6 +31,12 @@ type PaymentPerBytes struct {
 
 	// Service bytes provided for paid price
 	Bytes datasize.BitSize `json:"bytes,omitempty"`
+
+	// Rounding of the price charged for a fraction of bytes: "down", "up" or "nearest", rounded down if empty
+	Rounding string `json:"rounding,omitempty"`
+
+	// Least amount charged for the session, in smallest units of price currency
+	MinimumCharge uint64 `json:"minimumCharge,omitempty"`
 }
 
 // GetPrice returns payment price
@@ -32,6 +32,12 @@ type PaymentPerTime struct {
 
 	// Service duration provided for paid price
 	Duration time.Duration `json:"duration"`
+
+	// Rounding of the price charged for a fraction of duration: "down", "up" or "nearest", rounded down if empty
+	Rounding string `json:"rounding,omitempty"`
+
+	// Least amount charged for the session, in smallest units of price currency
+	MinimumCharge uint64 `json:"minimumCharge,omitempty"`
 }
 
 // GetPrice returns price of payment per time
@@ -33,6 +33,12 @@ type ServiceDefinition struct {
 
 	// Transport protocol used by service
 	Protocol string `json:"protocol,omitempty"`
+
+	// All transports served, in order of preference. Protocol holds the first one for older consumers
+	Transports []Transport `json:"transports,omitempty"`
+
+	// Ciphers and renegotiation profile of the service, consumer has to support it to connect
+	CryptoProfile string `json:"crypto_profile,omitempty"`
 }
 
 // GetLocation returns geographic location of service definition provider
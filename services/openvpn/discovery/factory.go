@@ -18,11 +18,8 @@
 package discovery
 
 import (
-	"time"
-
 	"github.com/mysteriumnetwork/node/datasize"
 	"github.com/mysteriumnetwork/node/market"
-	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/services/openvpn"
 	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
 )
@@ -30,7 +27,9 @@ import (
 // NewServiceProposalWithLocation creates service proposal description for openvpn service
 func NewServiceProposalWithLocation(
 	serviceLocation market.Location,
-	protocol string,
+	transports []dto.Transport,
+	cryptoProfile string,
+	payment dto.PaymentPerTime,
 ) market.ServiceProposal {
 	return market.ServiceProposal{
 		ServiceType: openvpn.ServiceType,
@@ -38,13 +37,11 @@ func NewServiceProposalWithLocation(
 			Location:          serviceLocation,
 			LocationOriginate: serviceLocation,
 			SessionBandwidth:  dto.Bandwidth(10 * datasize.MB),
-			Protocol:          protocol,
+			Protocol:          transports[0].Protocol,
+			Transports:        transports,
+			CryptoProfile:     cryptoProfile,
 		},
 		PaymentMethodType: dto.PaymentMethodPerTime,
-		PaymentMethod: dto.PaymentPerTime{
-			// 15 MYST/month = 0,5 MYST/day = 0,125 MYST/hour
-			Price:    money.NewMoney(0.125, money.CURRENCY_MYST),
-			Duration: 1 * time.Hour,
-		},
+		PaymentMethod:     payment,
 	}
 }
@@ -28,12 +28,16 @@ import (
 )
 
 var (
-	locationLTTelia = market.Location{"LT", "Vilnius", "AS8764"}
-	protocol        = "tcp"
+	locationLTTelia = market.Location{"LT", "Vilnius", "AS8764", ""}
+	transports      = []dto.Transport{{Protocol: "udp", Port: 1194}, {Protocol: "tcp", Port: 1194}}
 )
 
 func Test_NewServiceProposalWithLocation(t *testing.T) {
-	proposal := NewServiceProposalWithLocation(locationLTTelia, protocol)
+	payment := dto.PaymentPerTime{
+		Price:    money.NewUnits(200000, money.Currency("MYST")),
+		Duration: time.Minute,
+	}
+	proposal := NewServiceProposalWithLocation(locationLTTelia, transports, "chacha20", payment)
 
 	assert.Exactly(
 		t,
@@ -43,13 +47,18 @@ func Test_NewServiceProposalWithLocation(t *testing.T) {
 				Location:          locationLTTelia,
 				LocationOriginate: locationLTTelia,
 				SessionBandwidth:  83886080,
-				Protocol:          "tcp",
+				Protocol:          "udp",
+				Transports: []dto.Transport{
+					{Protocol: "udp", Port: 1194},
+					{Protocol: "tcp", Port: 1194},
+				},
+				CryptoProfile: "chacha20",
 			},
 
 			PaymentMethodType: "PER_TIME",
 			PaymentMethod: dto.PaymentPerTime{
-				Price:    money.Money{12500000, money.Currency("MYST")},
-				Duration: 60 * time.Minute,
+				Price:    money.NewUnits(200000, money.Currency("MYST")),
+				Duration: time.Minute,
 			},
 		},
 		proposal,
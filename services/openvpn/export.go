@@ -0,0 +1,118 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package openvpn
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
+	openvpn_session "github.com/mysteriumnetwork/node/services/openvpn/session"
+)
+
+// ExportFormat is the format of configuration rendered for external tunnel clients
+const ExportFormat = "ovpn"
+
+type configExporter struct {
+	signerFactory identity.SignerFactory
+}
+
+// CreateExporter creates config exporter rendering self-contained .ovpn configuration
+func (op *ProcessBasedConnectionFactory) CreateExporter() (connection.ConfigExporter, error) {
+	return &configExporter{signerFactory: op.signerFactory}, nil
+}
+
+// GetConfig returns the consumer configuration for session creation. In openvpn case - it doesn't return anything
+func (ce *configExporter) GetConfig() (connection.ConsumerConfig, error) {
+	return nil, nil
+}
+
+// Export renders .ovpn configuration of the created session with session credentials inlined
+func (ce *configExporter) Export(options connection.ConnectOptions) (connection.ExportedConfig, error) {
+	vpnConfig, err := parseVPNConfig(options.SessionConfig)
+	if err != nil {
+		return connection.ExportedConfig{}, err
+	}
+
+	cryptoProfile, err := FindCryptoProfile(vpnConfig.CryptoProfile)
+	if err != nil {
+		return connection.ExportedConfig{}, err
+	}
+
+	credentialsProvider := openvpn_session.SignatureCredentialsProvider(options.SessionID, ce.signerFactory(options.ConsumerID))
+	username, password, err := credentialsProvider()
+	if err != nil {
+		return connection.ExportedConfig{}, err
+	}
+
+	return connection.ExportedConfig{
+		Format:  ExportFormat,
+		Content: renderOvpnConfig(vpnConfig, cryptoProfile, username, password),
+	}, nil
+}
+
+// renderOvpnConfig mirrors options of ClientConfig, transports are rendered as connection profiles tried in order
+func renderOvpnConfig(vpnConfig *VPNConfig, cryptoProfile CryptoProfile, username, password string) string {
+	transports := vpnConfig.Transports
+	if len(transports) == 0 {
+		transports = []dto.Transport{{Protocol: vpnConfig.RemoteProtocol, Port: vpnConfig.RemotePort}}
+	}
+
+	var content strings.Builder
+	fmt.Fprintln(&content, "client")
+	fmt.Fprintln(&content, "dev tun")
+	fmt.Fprintln(&content, "nobind")
+	fmt.Fprintln(&content, "remote-cert-tls server")
+	fmt.Fprintln(&content, "auth-nocache")
+	fmt.Fprintln(&content, "resolv-retry infinite")
+	fmt.Fprintln(&content, "persist-key")
+	fmt.Fprintln(&content, "ping-timer-rem")
+	fmt.Fprintln(&content, "verb 3")
+	fmt.Fprintln(&content, "cipher", cryptoProfile.Cipher)
+	fmt.Fprintln(&content, "tls-cipher", cryptoProfile.TLSCipher)
+	fmt.Fprintln(&content, "reneg-sec", cryptoProfile.RenegotiationSec)
+	fmt.Fprintln(&content, "keepalive", cryptoProfile.KeepAlivePing, cryptoProfile.KeepAliveTimeout)
+	fmt.Fprintln(&content, "redirect-gateway def1 bypass-dhcp")
+	fmt.Fprintln(&content, "dhcp-option DNS 208.67.222.222")
+	fmt.Fprintln(&content, "dhcp-option DNS 208.67.220.220")
+	if len(transports) > 1 {
+		fmt.Fprintln(&content, "server-poll-timeout", int(transportFallbackTimeout.Seconds()))
+	}
+
+	for _, transport := range transports {
+		fmt.Fprintln(&content, "<connection>")
+		if transport.Protocol == "tcp" {
+			fmt.Fprintln(&content, "remote", vpnConfig.RemoteIP, transport.Port, "tcp-client")
+		} else {
+			fmt.Fprintln(&content, "remote", vpnConfig.RemoteIP, transport.Port, "udp")
+			fmt.Fprintln(&content, "explicit-exit-notify")
+		}
+		fmt.Fprintln(&content, "</connection>")
+	}
+
+	renderInline(&content, "ca", vpnConfig.CACertificate)
+	renderInline(&content, "tls-crypt", vpnConfig.TLSPresharedKey)
+	renderInline(&content, "auth-user-pass", username+"\n"+password)
+	return content.String()
+}
+
+func renderInline(content *strings.Builder, tag, value string) {
+	fmt.Fprintf(content, "<%s>\n%s\n</%s>\n", tag, strings.Trim(value, "\n"), tag)
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package openvpn
+
+import (
+	"testing"
+
+	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_renderOvpnConfig(t *testing.T) {
+	vpnConfig := &VPNConfig{
+		RemoteIP:        "1.2.3.4",
+		RemotePort:      1194,
+		RemoteProtocol:  "udp",
+		TLSPresharedKey: tlsTestKeyPreformatted,
+		CACertificate:   caCertificate,
+		Transports:      []dto.Transport{{Protocol: "udp", Port: 1194}, {Protocol: "tcp", Port: 1194}},
+	}
+	profile, err := FindCryptoProfile("chacha20")
+	assert.NoError(t, err)
+
+	content := renderOvpnConfig(vpnConfig, profile, "session-1", "signature")
+
+	assert.Contains(t, content, "cipher CHACHA20-POLY1305\n")
+	assert.Contains(t, content, "reneg-sec 3600\n")
+	assert.Contains(t, content, "server-poll-timeout 15\n")
+	assert.Contains(t, content, "<connection>\nremote 1.2.3.4 1194 udp\nexplicit-exit-notify\n</connection>\n")
+	assert.Contains(t, content, "<connection>\nremote 1.2.3.4 1194 tcp-client\n</connection>\n")
+	assert.Contains(t, content, "<tls-crypt>\n-----BEGIN OpenVPN Static key V1-----\n")
+	assert.Contains(t, content, "<auth-user-pass>\nsession-1\nsignature\n</auth-user-pass>\n")
+}
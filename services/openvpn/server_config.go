@@ -49,6 +49,11 @@ func (c *ServerConfig) SetProtocol(protocol string) {
 	}
 }
 
+// SetCryptoProfile sets ciphers and session timing, consumers are told to use the same profile
+func (c *ServerConfig) SetCryptoProfile(profile CryptoProfile) {
+	setCryptoProfile(c.GenericConfig, profile)
+}
+
 // NewServerConfig creates server configuration structure from given basic parameters
 func NewServerConfig(
 	runtimeDir string,
@@ -57,6 +62,7 @@ func NewServerConfig(
 	secPrimitives *tls.Primitives,
 	port int,
 	protocol string,
+	cryptoProfile CryptoProfile,
 ) *ServerConfig {
 	serverConfig := ServerConfig{config.NewConfig(runtimeDir, configDir)}
 	serverConfig.SetServerMode(port, network, netmask)
@@ -69,14 +75,11 @@ func NewServerConfig(
 	)
 	serverConfig.SetTLSCrypt(secPrimitives.PresharedKey.ToPEMFormat())
 
-	serverConfig.SetParam("cipher", "AES-256-GCM")
+	serverConfig.SetCryptoProfile(cryptoProfile)
 	serverConfig.SetParam("verb", "3")
 	serverConfig.SetParam("tls-version-min", "1.2")
 	serverConfig.SetFlag("management-client-auth")
 	serverConfig.SetParam("verify-client-cert", "none")
-	serverConfig.SetParam("tls-cipher", "TLS-ECDHE-ECDSA-WITH-AES-256-GCM-SHA384")
-	serverConfig.SetParam("reneg-sec", "60")
-	serverConfig.SetKeepAlive(10, 60)
 	serverConfig.SetPingTimerRemote()
 	serverConfig.SetPersistKey()
 
@@ -19,8 +19,12 @@ package service
 
 import (
 	"crypto/x509/pkix"
+	"encoding/binary"
 	"encoding/json"
+	"net"
+	"time"
 
+	"github.com/mysteriumnetwork/node/core/ippool"
 	"github.com/mysteriumnetwork/node/core/location"
 
 	log "github.com/cihub/seelog"
@@ -32,10 +36,18 @@ import (
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/nat"
 	openvpn_service "github.com/mysteriumnetwork/node/services/openvpn"
+	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
 	openvpn_session "github.com/mysteriumnetwork/node/services/openvpn/session"
 	"github.com/mysteriumnetwork/node/session"
+	"github.com/pkg/errors"
 )
 
+// defaultSubnets are tried in order when the server network is not configured explicitly
+var defaultSubnets = []string{"10.8.0.0/24", "10.9.0.0/24", "172.27.8.0/24", "100.80.8.0/24"}
+
+// bytecountInterval defines how often traffic statistics of connected clients are published
+const bytecountInterval = 30 * time.Second
+
 // NewManager creates new instance of Openvpn service
 func NewManager(
 	nodeOptions node.Options,
@@ -43,9 +55,24 @@ func NewManager(
 	location location.ServiceLocationInfo,
 	sessionMap openvpn_session.SessionMap,
 	natService nat.NATService,
-	mapPort func() (releasePortMapping func()),
-) *Manager {
-	sessionValidator := openvpn_session.NewValidator(sessionMap, identity.NewExtractor())
+	mapPort func(protocol string) (releasePortMapping func()),
+	eventPublisher openvpn_session.Publisher,
+) (*Manager, error) {
+	if err := serviceOptions.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid openvpn options")
+	}
+
+	vpnNetwork, err := ippool.SelectNetwork(serviceOptions.Subnet, defaultSubnets)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select openvpn subnet")
+	}
+	log.Info(logPrefix, "Using subnet for openvpn server: ", vpnNetwork.String())
+
+	// every server process needs its own tunnel network, NAT rule of the whole subnet covers them all
+	serverNetworks, err := splitNetwork(vpnNetwork, len(serviceOptions.OpenvpnProtocols))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to split openvpn subnet between servers")
+	}
 
 	return &Manager{
 		publicIP:                       location.PubIP,
@@ -54,35 +81,48 @@ func NewManager(
 		natService:                     natService,
 		sessionConfigNegotiatorFactory: newSessionConfigNegotiatorFactory(nodeOptions.OptionsNetwork, serviceOptions),
 		vpnServerConfigFactory:         newServerConfigFactory(nodeOptions, serviceOptions),
-		vpnServerFactory:               newServerFactory(nodeOptions, sessionValidator),
+		vpnServerFactory:               newServerFactory(nodeOptions, sessionMap, eventPublisher),
 		serviceOptions:                 serviceOptions,
+		vpnNetwork:                     vpnNetwork,
+		serverNetworks:                 serverNetworks,
 		mapPort:                        mapPort,
-	}
+	}, nil
 }
 
 // newServerConfigFactory returns function generating server config and generates required security primitives
 func newServerConfigFactory(nodeOptions node.Options, serviceOptions Options) ServerConfigFactory {
-	return func(secPrimitives *tls.Primitives) *openvpn_service.ServerConfig {
-		// TODO: check nodeOptions for --openvpn-transport option
+	// options are validated when manager is created
+	cryptoProfile, _ := openvpn_service.FindCryptoProfile(serviceOptions.CryptoProfile)
+	return func(secPrimitives *tls.Primitives, vpnNetwork net.IPNet, transport dto.Transport) *openvpn_service.ServerConfig {
 		return openvpn_service.NewServerConfig(
 			nodeOptions.Directories.Runtime,
 			nodeOptions.Directories.Config,
-			"10.8.0.0", "255.255.255.0",
+			vpnNetwork.IP.String(), net.IP(vpnNetwork.Mask).String(),
 			secPrimitives,
-			serviceOptions.OpenvpnPort,
-			serviceOptions.OpenvpnProtocol,
+			transport.Port,
+			transport.Protocol,
+			cryptoProfile,
 		)
 	}
 }
 
-func newServerFactory(nodeOptions node.Options, sessionValidator *openvpn_session.Validator) ServerFactory {
-	return func(config *openvpn_service.ServerConfig) openvpn.Process {
-		return openvpn.CreateNewProcess(
+// newServerFactory returns function creating server processes, client ids are only unique within a process,
+// so each of them gets own validator working with the shared sessions
+func newServerFactory(nodeOptions node.Options, sessionMap openvpn_session.SessionMap, eventPublisher openvpn_session.Publisher) ServerFactory {
+	return func(config *openvpn_service.ServerConfig) (openvpn.Process, SessionKiller) {
+		sessionValidator := openvpn_session.NewValidator(sessionMap, identity.NewExtractor())
+		clientKiller := openvpn_session.NewClientKiller(sessionValidator)
+		bytecount := openvpn_session.NewBytecountMiddleware(sessionValidator, eventPublisher, bytecountInterval)
+
+		process := openvpn.CreateNewProcess(
 			nodeOptions.Openvpn.BinaryPath(),
 			config.GenericConfig,
 			auth.NewMiddleware(sessionValidator.Validate, sessionValidator.Cleanup),
 			state.NewMiddleware(vpnStateCallback),
+			clientKiller,
+			bytecount,
 		)
+		return process, clientKiller
 	}
 }
 
@@ -90,13 +130,16 @@ func newServerFactory(nodeOptions node.Options, sessionValidator *openvpn_sessio
 func newSessionConfigNegotiatorFactory(networkOptions node.OptionsNetwork, serviceOptions Options) SessionConfigNegotiatorFactory {
 	return func(secPrimitives *tls.Primitives, outboundIP, publicIP string) session.ConfigNegotiator {
 		serverIP := vpnServerIP(serviceOptions, outboundIP, publicIP, networkOptions.Localnet)
+		transports := serviceOptions.Transports()
 		return &OpenvpnConfigNegotiator{
 			vpnConfig: openvpn_service.VPNConfig{
 				RemoteIP:        serverIP,
-				RemotePort:      serviceOptions.OpenvpnPort,
-				RemoteProtocol:  serviceOptions.OpenvpnProtocol,
+				RemotePort:      transports[0].Port,
+				RemoteProtocol:  transports[0].Protocol,
 				TLSPresharedKey: secPrimitives.PresharedKey.ToPEMFormat(),
 				CACertificate:   secPrimitives.CertificateAuthority.ToPEMFormat(),
+				Transports:      transports,
+				CryptoProfile:   serviceOptions.CryptoProfile,
 			},
 		}
 	}
@@ -108,7 +151,7 @@ type OpenvpnConfigNegotiator struct {
 }
 
 // ProvideConfig returns the config for user
-func (ocn *OpenvpnConfigNegotiator) ProvideConfig(json.RawMessage) (session.ServiceConfiguration, session.DestroyCallback, error) {
+func (ocn *OpenvpnConfigNegotiator) ProvideConfig(session.ID, json.RawMessage) (session.ServiceConfiguration, session.DestroyCallback, error) {
 	return &ocn.vpnConfig, nil, nil
 }
 
@@ -158,3 +201,31 @@ func primitiveFactory(currentCountry, providerID string) (*tls.Primitives, error
 
 	return tls.NewTLSPrimitives(caSubject, serverCertSubject)
 }
+
+// splitNetwork divides network into equal subnets, one for each of the given number of servers
+func splitNetwork(network net.IPNet, parts int) ([]net.IPNet, error) {
+	ones, bits := network.Mask.Size()
+	extraBits := 0
+	for 1<<uint(extraBits) < parts {
+		extraBits++
+	}
+	// openvpn server network needs at least 4 addresses
+	if bits-ones-extraBits < 2 {
+		return nil, errors.Errorf("network %s is too small for %d servers", network.String(), parts)
+	}
+
+	base := network.IP.Mask(network.Mask).To4()
+	if base == nil {
+		return nil, errors.Errorf("network %s is not IPv4", network.String())
+	}
+	baseValue := binary.BigEndian.Uint32(base)
+	subnetSize := uint32(1) << uint(bits-ones-extraBits)
+
+	subnets := make([]net.IPNet, 0, parts)
+	for i := 0; i < parts; i++ {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, baseValue+uint32(i)*subnetSize)
+		subnets = append(subnets, net.IPNet{IP: ip, Mask: net.CIDRMask(ones+extraBits, bits)})
+	}
+	return subnets, nil
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_splitNetwork(t *testing.T) {
+	_, network, _ := net.ParseCIDR("10.8.0.0/24")
+
+	subnets, err := splitNetwork(*network, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.8.0.0/24"}, networkStrings(subnets))
+
+	subnets, err = splitNetwork(*network, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.8.0.0/25", "10.8.0.128/25"}, networkStrings(subnets))
+}
+
+func Test_splitNetworkFailsForSmallNetwork(t *testing.T) {
+	_, network, _ := net.ParseCIDR("10.8.0.0/30")
+
+	_, err := splitNetwork(*network, 2)
+	assert.EqualError(t, err, "network 10.8.0.0/30 is too small for 2 servers")
+}
+
+func Test_OptionsTransports(t *testing.T) {
+	options := Options{OpenvpnProtocols: parseProtocols("udp, tcp"), OpenvpnPort: 1194}
+
+	assert.NoError(t, options.Validate())
+	assert.Equal(
+		t,
+		[]dto.Transport{{Protocol: "udp", Port: 1194}, {Protocol: "tcp", Port: 1194}},
+		options.Transports(),
+	)
+}
+
+func Test_OptionsValidate(t *testing.T) {
+	assert.EqualError(t, Options{OpenvpnProtocols: parseProtocols("")}.Validate(), "no openvpn protocol given")
+	assert.EqualError(t, Options{OpenvpnProtocols: parseProtocols("udp,sctp")}.Validate(), `unsupported openvpn protocol: "sctp"`)
+	assert.EqualError(t, Options{OpenvpnProtocols: parseProtocols("tcp,tcp")}.Validate(), `openvpn protocol given more than once: "tcp"`)
+	assert.EqualError(
+		t,
+		Options{OpenvpnProtocols: parseProtocols("udp"), CryptoProfile: "rot13"}.Validate(),
+		`unsupported crypto profile: "rot13", supported profiles: chacha20, default, long-reneg`,
+	)
+	assert.EqualError(t, Options{OpenvpnProtocols: parseProtocols("udp"), PricePerMinute: -1}.Validate(), "openvpn price can not be negative: -1")
+	assert.EqualError(t, Options{OpenvpnProtocols: parseProtocols("udp"), PriceCurrency: "BTC"}.Validate(), `unsupported currency: "BTC"`)
+	assert.EqualError(t, Options{OpenvpnProtocols: parseProtocols("udp"), PriceRounding: "half"}.Validate(), `unsupported rounding: "half"`)
+	assert.EqualError(t, Options{OpenvpnProtocols: parseProtocols("udp"), MinimumCharge: -1}.Validate(), "openvpn minimum charge can not be negative: -1")
+}
+
+func Test_OptionsPaymentChargesConfiguredMinimum(t *testing.T) {
+	options := Options{PricePerMinute: 0.5, PriceCurrency: "MYST", PriceRounding: "up", MinimumCharge: 0.1}
+	assert.NoError(t, options.Validate())
+
+	payment := options.Payment()
+	assert.Equal(t, "up", payment.Rounding)
+	assert.Equal(t, uint64(10000000), payment.MinimumCharge)
+
+	calc, err := session.NewAmountCalc(payment)
+	assert.NoError(t, err)
+	assert.Equal(t, money.NewMoney(0.1, money.CURRENCY_MYST), calc.TotalAmount(time.Second))
+	assert.Equal(t, money.NewMoney(0.5, money.CURRENCY_MYST), calc.TotalAmount(time.Minute))
+}
+
+func Test_OptionsPayment(t *testing.T) {
+	options := Options{PricePerMinute: 0.5, PriceCurrency: "MYST"}
+
+	assert.Equal(
+		t,
+		dto.PaymentPerTime{Price: money.NewMoney(0.5, money.CURRENCY_MYST), Duration: time.Minute},
+		options.Payment(),
+	)
+}
+
+func networkStrings(networks []net.IPNet) []string {
+	result := make([]string, 0, len(networks))
+	for _, network := range networks {
+		result = append(result, network.String())
+	}
+	return result
+}
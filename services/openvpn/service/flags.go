@@ -18,20 +18,113 @@
 package service
 
 import (
+	"strings"
+	"time"
+
 	"github.com/mysteriumnetwork/node/core/service"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/money"
+	openvpn_service "github.com/mysteriumnetwork/node/services/openvpn"
+	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/pkg/errors"
 	"github.com/urfave/cli"
 )
 
 // Options describes options which are required to start Openvpn service
 type Options struct {
-	OpenvpnProtocol string
-	OpenvpnPort     int
+	OpenvpnProtocols []string
+	OpenvpnPort      int
+	Subnet           string
+	CryptoProfile    string
+	PricePerMinute   float64
+	PriceCurrency    string
+	PriceRounding    string
+	MinimumCharge    float64
+	FreeMinutes      int
+	FreePeriod       time.Duration
+}
+
+// Payment returns the price of the service, which is both advertised and charged
+func (options Options) Payment() dto.PaymentPerTime {
+	currency, _ := money.ParseCurrency(options.PriceCurrency)
+	minimumCharge, _ := money.NewMoney(options.MinimumCharge, currency).Uint64()
+	return dto.PaymentPerTime{
+		Price:         money.NewMoney(options.PricePerMinute, currency),
+		Duration:      time.Minute,
+		Rounding:      options.PriceRounding,
+		MinimumCharge: minimumCharge,
+	}
+}
+
+// FreeCredit returns the free minutes offered to every consumer, nil if there are none
+func (options Options) FreeCredit() *market.FreeCredit {
+	if options.FreeMinutes == 0 {
+		return nil
+	}
+	return &market.FreeCredit{
+		Duration: time.Duration(options.FreeMinutes) * time.Minute,
+		Period:   options.FreePeriod,
+	}
+}
+
+// Transports returns transports served by the service in order of preference
+func (options Options) Transports() []dto.Transport {
+	transports := make([]dto.Transport, 0, len(options.OpenvpnProtocols))
+	for _, protocol := range options.OpenvpnProtocols {
+		transports = append(transports, dto.Transport{Protocol: protocol, Port: options.OpenvpnPort})
+	}
+	return transports
+}
+
+// Validate checks that each of the supported protocols is requested at most once
+func (options Options) Validate() error {
+	if len(options.OpenvpnProtocols) == 0 {
+		return errors.New("no openvpn protocol given")
+	}
+
+	requested := make(map[string]bool)
+	for _, protocol := range options.OpenvpnProtocols {
+		if protocol != "udp" && protocol != "tcp" {
+			return errors.Errorf("unsupported openvpn protocol: %q", protocol)
+		}
+		if requested[protocol] {
+			return errors.Errorf("openvpn protocol given more than once: %q", protocol)
+		}
+		requested[protocol] = true
+	}
+
+	if options.PricePerMinute < 0 {
+		return errors.Errorf("openvpn price can not be negative: %v", options.PricePerMinute)
+	}
+	currency, err := money.ParseCurrency(options.PriceCurrency)
+	if err != nil {
+		return err
+	}
+	if _, err := session.ParseRounding(options.PriceRounding); err != nil {
+		return err
+	}
+	if options.MinimumCharge < 0 {
+		return errors.Errorf("openvpn minimum charge can not be negative: %v", options.MinimumCharge)
+	}
+	if _, err := money.NewMoney(options.MinimumCharge, currency).Uint64(); err != nil {
+		return errors.Wrapf(err, "openvpn minimum charge is too large: %v", options.MinimumCharge)
+	}
+	if options.FreeMinutes < 0 {
+		return errors.Errorf("openvpn free minutes can not be negative: %v", options.FreeMinutes)
+	}
+	if options.FreePeriod < 0 {
+		return errors.Errorf("openvpn free period can not be negative: %v", options.FreePeriod)
+	}
+
+	_, err = openvpn_service.FindCryptoProfile(options.CryptoProfile)
+	return err
 }
 
 var (
 	protocolFlag = cli.StringFlag{
 		Name:  "openvpn.proto",
-		Usage: "Openvpn protocol to use. Options: { udp, tcp }",
+		Usage: "Openvpn protocols to serve, comma separated in order of preference. Options: { udp, tcp, \"udp,tcp\" }",
 		Value: "udp",
 	}
 	portFlag = cli.IntFlag{
@@ -39,17 +132,72 @@ var (
 		Usage: "Openvpn port to use. Default 1194",
 		Value: 1194,
 	}
+	subnetFlag = cli.StringFlag{
+		Name:  "openvpn.subnet",
+		Usage: "Openvpn server network (e.g. 10.8.0.0/24). Free subnet is selected automatically if not set",
+	}
+	cryptoProfileFlag = cli.StringFlag{
+		Name:  "openvpn.crypto.profile",
+		Usage: "Openvpn ciphers and renegotiation profile. Options: { " + strings.Join(openvpn_service.CryptoProfileNames(), ", ") + " }",
+		Value: openvpn_service.DefaultCryptoProfile,
+	}
+	pricePerMinuteFlag = cli.Float64Flag{
+		Name:  "openvpn.price-per-minute",
+		Usage: "Price charged for every minute of Openvpn session",
+		Value: 0.002,
+	}
+	priceCurrencyFlag = cli.StringFlag{
+		Name:  "openvpn.price-currency",
+		Usage: "Currency of Openvpn service price",
+		Value: string(money.CURRENCY_MYST),
+	}
+	priceRoundingFlag = cli.StringFlag{
+		Name:  "openvpn.price-rounding",
+		Usage: "Rounding of the price charged for a started minute of Openvpn session. Options: { down, up, nearest }",
+		Value: "down",
+	}
+	minimumChargeFlag = cli.Float64Flag{
+		Name:  "openvpn.minimum-charge",
+		Usage: "Least amount charged for Openvpn session, in currency of the price",
+	}
+	freeMinutesFlag = cli.IntFlag{
+		Name:  "openvpn.free-minutes",
+		Usage: "Minutes of Openvpn service given for free to every consumer identity per free period",
+	}
+	freePeriodFlag = cli.DurationFlag{
+		Name:  "openvpn.free-period",
+		Usage: "Period after which free minutes of consumer are renewed (e.g. 24h). Free minutes are given only once if set to 0",
+		Value: 24 * time.Hour,
+	}
 )
 
 // RegisterFlags function register Openvpn flags to flag list
 func RegisterFlags(flags *[]cli.Flag) {
-	*flags = append(*flags, protocolFlag, portFlag)
+	*flags = append(*flags, protocolFlag, portFlag, subnetFlag, cryptoProfileFlag, pricePerMinuteFlag, priceCurrencyFlag, priceRoundingFlag, minimumChargeFlag, freeMinutesFlag, freePeriodFlag)
 }
 
 // ParseFlags function fills in Openvpn options from CLI context
 func ParseFlags(ctx *cli.Context) service.Options {
 	return Options{
-		OpenvpnProtocol: ctx.String(protocolFlag.Name),
-		OpenvpnPort:     ctx.Int(portFlag.Name),
+		OpenvpnProtocols: parseProtocols(ctx.String(protocolFlag.Name)),
+		OpenvpnPort:      ctx.Int(portFlag.Name),
+		Subnet:           ctx.String(subnetFlag.Name),
+		CryptoProfile:    ctx.String(cryptoProfileFlag.Name),
+		PricePerMinute:   ctx.Float64(pricePerMinuteFlag.Name),
+		PriceCurrency:    ctx.String(priceCurrencyFlag.Name),
+		PriceRounding:    ctx.String(priceRoundingFlag.Name),
+		MinimumCharge:    ctx.Float64(minimumChargeFlag.Name),
+		FreeMinutes:      ctx.Int(freeMinutesFlag.Name),
+		FreePeriod:       ctx.Duration(freePeriodFlag.Name),
+	}
+}
+
+func parseProtocols(value string) []string {
+	protocols := make([]string, 0)
+	for _, protocol := range strings.Split(value, ",") {
+		if protocol = strings.TrimSpace(protocol); protocol != "" {
+			protocols = append(protocols, protocol)
+		}
 	}
+	return protocols
 }
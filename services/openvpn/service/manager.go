@@ -19,6 +19,8 @@ package service
 
 import (
 	"encoding/json"
+	"net"
+	"sync"
 
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/go-openvpn/openvpn"
@@ -27,6 +29,7 @@ import (
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/nat"
 	openvpn_service "github.com/mysteriumnetwork/node/services/openvpn"
+	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
 	"github.com/mysteriumnetwork/node/session"
 	"github.com/pkg/errors"
 )
@@ -34,10 +37,10 @@ import (
 const logPrefix = "[service-openvpn] "
 
 // ServerConfigFactory callback generates session config for remote client
-type ServerConfigFactory func(*tls.Primitives) *openvpn_service.ServerConfig
+type ServerConfigFactory func(secPrimitives *tls.Primitives, vpnNetwork net.IPNet, transport dto.Transport) *openvpn_service.ServerConfig
 
-// ServerFactory initiates Openvpn server instance during runtime
-type ServerFactory func(*openvpn_service.ServerConfig) openvpn.Process
+// ServerFactory initiates Openvpn server instance during runtime, returned SessionKiller disconnects clients of that server
+type ServerFactory func(*openvpn_service.ServerConfig) (openvpn.Process, SessionKiller)
 
 // ProposalFactory prepares service proposal during runtime
 type ProposalFactory func(currentLocation market.Location) market.ServiceProposal
@@ -45,10 +48,28 @@ type ProposalFactory func(currentLocation market.Location) market.ServiceProposa
 // SessionConfigNegotiatorFactory initiates ConfigProvider instance during runtime
 type SessionConfigNegotiatorFactory func(secPrimitives *tls.Primitives, outboundIP, publicIP string) session.ConfigNegotiator
 
+// SessionKiller disconnects consumer of the given session from the running server
+type SessionKiller interface {
+	KillSession(id session.ID) error
+}
+
+// sessionKillers disconnects consumer of the session from whichever server it is connected to
+type sessionKillers []SessionKiller
+
+// KillSession disconnects consumer of the given session from all servers
+func (killers sessionKillers) KillSession(id session.ID) error {
+	for _, killer := range killers {
+		if err := killer.KillSession(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Manager represents entrypoint for Openvpn service with top level components
 type Manager struct {
 	natService   nat.NATService
-	mapPort      func() (releasePortMapping func())
+	mapPort      func(protocol string) (releasePortMapping func())
 	releasePorts func()
 
 	sessionConfigNegotiatorFactory SessionConfigNegotiatorFactory
@@ -56,62 +77,123 @@ type Manager struct {
 	vpnServerConfigFactory   ServerConfigFactory
 	vpnServiceConfigProvider session.ConfigNegotiator
 	vpnServerFactory         ServerFactory
-	vpnServer                openvpn.Process
+	vpnServers               []openvpn.Process
+	stopServers              sync.Once
+	clientKiller             SessionKiller
 
 	publicIP        string
 	outboundIP      string
 	currentLocation string
 	serviceOptions  Options
+	vpnNetwork      net.IPNet
+	serverNetworks  []net.IPNet
 }
 
 // Serve starts service - does block
 func (m *Manager) Serve(providerID identity.Identity) (err error) {
 	err = m.natService.Add(nat.RuleForwarding{
-		SourceAddress: "10.8.0.0/24",
+		SourceAddress: m.vpnNetwork.String(),
 		TargetIP:      m.outboundIP,
 	})
 	if err != nil {
 		return errors.Wrap(err, "failed to add NAT forwarding rule")
 	}
 
-	m.releasePorts = m.mapPort()
+	m.releasePorts = m.mapPorts()
 
 	primitives, err := primitiveFactory(m.currentLocation, providerID.Address)
 	if err != nil {
 		return
 	}
 
+	// servers share TLS primitives, so consumer can use the same session config with any of them
+	killers := make(sessionKillers, 0, len(m.serverNetworks))
+	for i, transport := range m.serviceOptions.Transports() {
+		vpnServerConfig := m.vpnServerConfigFactory(primitives, m.serverNetworks[i], transport)
+		vpnServer, killer := m.vpnServerFactory(vpnServerConfig)
+		m.vpnServers = append(m.vpnServers, vpnServer)
+		killers = append(killers, killer)
+	}
+	m.clientKiller = killers
+
 	m.vpnServiceConfigProvider = m.sessionConfigNegotiatorFactory(primitives, m.outboundIP, m.publicIP)
 
-	vpnServerConfig := m.vpnServerConfigFactory(primitives)
-	m.vpnServer = m.vpnServerFactory(vpnServerConfig)
+	for _, vpnServer := range m.vpnServers {
+		if err = vpnServer.Start(); err != nil {
+			return
+		}
+	}
 
-	if err = m.vpnServer.Start(); err != nil {
-		return
+	return m.waitServers()
+}
+
+// waitServers blocks until any of the servers exits, the rest are stopped then
+func (m *Manager) waitServers() error {
+	exits := make(chan error, len(m.vpnServers))
+	for _, vpnServer := range m.vpnServers {
+		go func(vpnServer openvpn.Process) {
+			exits <- vpnServer.Wait()
+		}(vpnServer)
+	}
+
+	err := <-exits
+	m.stopServers.Do(m.stopAllServers)
+	return err
+}
+
+func (m *Manager) mapPorts() (releasePortMapping func()) {
+	releases := make([]func(), 0)
+	for _, transport := range m.serviceOptions.Transports() {
+		releases = append(releases, m.mapPort(transport.Protocol))
+	}
+
+	return func() {
+		for _, release := range releases {
+			release()
+		}
 	}
+}
 
-	return m.vpnServer.Wait()
+func (m *Manager) stopAllServers() {
+	for _, vpnServer := range m.vpnServers {
+		vpnServer.Stop()
+	}
 }
 
 // Stop stops service
 func (m *Manager) Stop() (err error) {
 	m.releasePorts()
-
-	if m.vpnServer != nil {
-		m.vpnServer.Stop()
-	}
+	m.stopServers.Do(m.stopAllServers)
 
 	return nil
 }
 
 // ProvideConfig provides the configuration to end consumer
-func (m *Manager) ProvideConfig(publicKey json.RawMessage) (session.ServiceConfiguration, session.DestroyCallback, error) {
+func (m *Manager) ProvideConfig(sessionID session.ID, publicKey json.RawMessage) (session.ServiceConfiguration, session.DestroyCallback, error) {
 	if m.vpnServiceConfigProvider == nil {
 		log.Info(logPrefix, "Config provider not initialized")
 		return nil, nil, errors.New("Config provider not initialized")
 	}
 
-	return m.vpnServiceConfigProvider.ProvideConfig(publicKey)
+	config, destroyCallback, err := m.vpnServiceConfigProvider.ProvideConfig(sessionID, publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return config, m.newDestroyCallback(sessionID, destroyCallback), nil
+}
+
+// newDestroyCallback disconnects the consumer once the session is destroyed, otherwise the tunnel would outlive the session
+func (m *Manager) newDestroyCallback(sessionID session.ID, next session.DestroyCallback) session.DestroyCallback {
+	return func() {
+		if err := m.clientKiller.KillSession(sessionID); err != nil {
+			log.Warn(logPrefix, "failed to disconnect client of session ", sessionID, ": ", err)
+		}
+
+		if next != nil {
+			next()
+		}
+	}
 }
 
 func vpnStateCallback(state openvpn.State) {
@@ -0,0 +1,103 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/pkg/errors"
+)
+
+const bytecountLogPrefix = "[openvpn-server-bytecount] "
+
+// >BYTECOUNT_CLI:{CID},{BYTES_IN},{BYTES_OUT}
+var bytecountClientRegex = regexp.MustCompile(`^>BYTECOUNT_CLI:(\d+),(\d+),(\d+)$`)
+
+// Publisher is responsible for publishing given events
+type Publisher interface {
+	Publish(topic string, args ...interface{})
+}
+
+// BytecountMiddleware is a management interface middleware which publishes traffic statistics of connected clients
+type BytecountMiddleware struct {
+	clientMap *clientMap
+	publisher Publisher
+	interval  time.Duration
+}
+
+// NewBytecountMiddleware returns BytecountMiddleware for clients authenticated by given validator
+func NewBytecountMiddleware(validator *Validator, publisher Publisher, interval time.Duration) *BytecountMiddleware {
+	return &BytecountMiddleware{
+		clientMap: validator.clientMap,
+		publisher: publisher,
+		interval:  interval,
+	}
+}
+
+// Start enables periodic per client bytecount notifications
+func (bm *BytecountMiddleware) Start(commandWriter management.CommandWriter) error {
+	_, err := commandWriter.SingleLineCommand("bytecount %d", int(bm.interval.Seconds()))
+	return err
+}
+
+// Stop disables bytecount notifications
+func (bm *BytecountMiddleware) Stop(commandWriter management.CommandWriter) error {
+	_, err := commandWriter.SingleLineCommand("bytecount %d", 0)
+	return err
+}
+
+// ConsumeLine publishes statistics of the session client is connected with
+func (bm *BytecountMiddleware) ConsumeLine(line string) (bool, error) {
+	match := bytecountClientRegex.FindStringSubmatch(line)
+	if len(match) == 0 {
+		return false, nil
+	}
+
+	clientID, err := strconv.Atoi(match[1])
+	if err != nil {
+		return true, errors.Wrap(err, "invalid client id")
+	}
+	bytesIn, err := strconv.ParseUint(match[2], 10, 64)
+	if err != nil {
+		return true, errors.Wrap(err, "invalid received bytes count")
+	}
+	bytesOut, err := strconv.ParseUint(match[3], 10, 64)
+	if err != nil {
+		return true, errors.Wrap(err, "invalid sent bytes count")
+	}
+
+	sessionID, found := bm.clientMap.GetSessionID(clientID)
+	if !found {
+		log.Debug(bytecountLogPrefix, "no session found for client: ", clientID)
+		return true, nil
+	}
+
+	bm.publisher.Publish(session.StatisticsEventTopic, session.StatisticsEvent{
+		SessionID: sessionID,
+		Statistics: session.DataTransfer{
+			BytesSent:     bytesOut,
+			BytesReceived: bytesIn,
+		},
+	})
+	return true, nil
+}
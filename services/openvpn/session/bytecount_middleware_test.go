@@ -0,0 +1,86 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePublisher struct {
+	topic string
+	event interface{}
+}
+
+func (fp *fakePublisher) Publish(topic string, args ...interface{}) {
+	fp.topic = topic
+	fp.event = args[0]
+}
+
+func TestBytecountMiddleware_StartAndStop(t *testing.T) {
+	connection := &management.MockConnection{CommandResult: "SUCCESS"}
+	middleware := NewBytecountMiddleware(mockValidator(identityToExtract), &fakePublisher{}, 30*time.Second)
+
+	assert.NoError(t, middleware.Start(connection))
+	assert.Equal(t, "bytecount 30", connection.LastLine)
+
+	assert.NoError(t, middleware.Stop(connection))
+	assert.Equal(t, "bytecount 0", connection.LastLine)
+}
+
+func TestBytecountMiddleware_PublishesSessionStatistics(t *testing.T) {
+	sessionValidator := mockValidatorWithSession(identityToExtract, session.Session{ID: "session1", ConsumerID: identityToExtract})
+	sessionValidator.clientMap.UpdateClientSession(3, "session1")
+	publisher := &fakePublisher{}
+	middleware := NewBytecountMiddleware(sessionValidator, publisher, time.Second)
+
+	consumed, err := middleware.ConsumeLine(">BYTECOUNT_CLI:3,100,2000")
+	assert.NoError(t, err)
+	assert.True(t, consumed)
+	assert.Equal(t, session.StatisticsEventTopic, publisher.topic)
+	assert.Equal(
+		t,
+		session.StatisticsEvent{
+			SessionID:  "session1",
+			Statistics: session.DataTransfer{BytesSent: 2000, BytesReceived: 100},
+		},
+		publisher.event,
+	)
+}
+
+func TestBytecountMiddleware_IgnoresUnknownClient(t *testing.T) {
+	publisher := &fakePublisher{}
+	middleware := NewBytecountMiddleware(mockValidator(identityToExtract), publisher, time.Second)
+
+	consumed, err := middleware.ConsumeLine(">BYTECOUNT_CLI:3,100,2000")
+	assert.NoError(t, err)
+	assert.True(t, consumed)
+	assert.Nil(t, publisher.event)
+}
+
+func TestBytecountMiddleware_DoesNotConsumeOtherLines(t *testing.T) {
+	middleware := NewBytecountMiddleware(mockValidator(identityToExtract), &fakePublisher{}, time.Second)
+
+	consumed, err := middleware.ConsumeLine(">BYTECOUNT:100,2000")
+	assert.NoError(t, err)
+	assert.False(t, consumed)
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
+	"github.com/mysteriumnetwork/node/session"
+)
+
+// ClientKiller is a management interface middleware which disconnects OpenVPN clients of finished sessions
+type ClientKiller struct {
+	clientMap *clientMap
+
+	commandWriter management.CommandWriter
+	lock          sync.Mutex
+}
+
+// NewClientKiller returns ClientKiller for clients authenticated by given validator
+func NewClientKiller(validator *Validator) *ClientKiller {
+	return &ClientKiller{
+		clientMap: validator.clientMap,
+	}
+}
+
+// Start remembers management interface connection for later client-kill commands
+func (ck *ClientKiller) Start(commandWriter management.CommandWriter) error {
+	ck.lock.Lock()
+	defer ck.lock.Unlock()
+
+	ck.commandWriter = commandWriter
+	return nil
+}
+
+// Stop forgets management interface connection
+func (ck *ClientKiller) Stop(commandWriter management.CommandWriter) error {
+	ck.lock.Lock()
+	defer ck.lock.Unlock()
+
+	ck.commandWriter = nil
+	return nil
+}
+
+// ConsumeLine does not consume any management interface output
+func (ck *ClientKiller) ConsumeLine(line string) (bool, error) {
+	return false, nil
+}
+
+// KillSession disconnects OpenVPN client connected with given session, does nothing if no client is connected
+func (ck *ClientKiller) KillSession(id session.ID) error {
+	clientID, found := ck.clientMap.GetClientID(id)
+	if !found {
+		return nil
+	}
+
+	ck.lock.Lock()
+	defer ck.lock.Unlock()
+
+	if ck.commandWriter == nil {
+		return errors.New("management interface is not connected")
+	}
+
+	_, err := ck.commandWriter.SingleLineCommand("client-kill %d", clientID)
+	return err
+}
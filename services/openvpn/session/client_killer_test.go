@@ -0,0 +1,72 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"testing"
+
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientKiller_KillSession(t *testing.T) {
+	sessionValidator := mockValidatorWithSession(identityToExtract, session.Session{ID: "session1", ConsumerID: identityToExtract})
+	sessionValidator.clientMap.UpdateClientSession(7, "session1")
+
+	connection := &management.MockConnection{CommandResult: "SUCCESS"}
+	killer := NewClientKiller(sessionValidator)
+	assert.NoError(t, killer.Start(connection))
+
+	assert.NoError(t, killer.KillSession("session1"))
+	assert.Equal(t, "client-kill 7", connection.LastLine)
+}
+
+func TestClientKiller_KillSessionWithoutClient(t *testing.T) {
+	sessionValidator := mockValidator(identity.FromAddress("deadbeef"))
+
+	connection := &management.MockConnection{CommandResult: "SUCCESS"}
+	killer := NewClientKiller(sessionValidator)
+	assert.NoError(t, killer.Start(connection))
+
+	assert.NoError(t, killer.KillSession("session1"))
+	assert.Empty(t, connection.LastLine)
+}
+
+func TestClientKiller_KillSessionWhenManagementNotConnected(t *testing.T) {
+	sessionValidator := mockValidator(identity.FromAddress("deadbeef"))
+	sessionValidator.clientMap.UpdateClientSession(7, "session1")
+
+	killer := NewClientKiller(sessionValidator)
+
+	assert.EqualError(t, killer.KillSession("session1"), "management interface is not connected")
+}
+
+func TestClientKiller_RemovedSessionIsNotKilled(t *testing.T) {
+	sessionValidator := mockValidatorWithSession(identityToExtract, session.Session{ID: "session1", ConsumerID: identityToExtract})
+	sessionValidator.clientMap.UpdateClientSession(7, "session1")
+	assert.NoError(t, sessionValidator.Cleanup("session1"))
+
+	connection := &management.MockConnection{CommandResult: "SUCCESS"}
+	killer := NewClientKiller(sessionValidator)
+	assert.NoError(t, killer.Start(connection))
+
+	assert.NoError(t, killer.KillSession("session1"))
+	assert.Empty(t, connection.LastLine)
+}
@@ -33,8 +33,7 @@ type SessionMap interface {
 
 // clientMap extends current sessions with client id metadata from Openvpn
 type clientMap struct {
-	sessions SessionMap
-	// TODO: use clientID to kill OpenVPN session (client-kill {clientID}) when promise processor instructs so
+	sessions         SessionMap
 	sessionClientIDs map[session.ID]int
 	sessionMapLock   sync.Mutex
 }
@@ -64,17 +63,41 @@ func (cm *clientMap) UpdateClientSession(clientID int, id session.ID) bool {
 	return cm.sessionClientIDs[id] == clientID
 }
 
+// GetClientID returns OpenVPN client id of given session
+func (cm *clientMap) GetClientID(id session.ID) (int, bool) {
+	cm.sessionMapLock.Lock()
+	defer cm.sessionMapLock.Unlock()
+
+	clientID, clientIDExist := cm.sessionClientIDs[id]
+	return clientID, clientIDExist
+}
+
+// GetSessionID returns session of given OpenVPN client id
+func (cm *clientMap) GetSessionID(clientID int) (session.ID, bool) {
+	cm.sessionMapLock.Lock()
+	defer cm.sessionMapLock.Unlock()
+
+	for id, sessionClientID := range cm.sessionClientIDs {
+		if sessionClientID == clientID {
+			return id, true
+		}
+	}
+	return "", false
+}
+
 // RemoveSession removes given session from underlying session managers
 func (cm *clientMap) RemoveSession(id session.ID) error {
 	cm.sessionMapLock.Lock()
 	defer cm.sessionMapLock.Unlock()
 
-	_, clientIDExist := cm.sessions.Find(id)
-	if !clientIDExist {
+	// client is already gone, so it must not be killed once the session is removed
+	delete(cm.sessionClientIDs, id)
+
+	_, sessionExist := cm.sessions.Find(id)
+	if !sessionExist {
 		return errors.New("no underlying session exists: " + string(id))
 	}
 
 	cm.sessions.Remove(id)
-	delete(cm.sessionClientIDs, id)
 	return nil
 }
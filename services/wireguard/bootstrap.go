@@ -35,7 +35,7 @@ func Bootstrap() {
 		},
 	)
 
-	// TODO per time or per bytes payment methods should be defined here
+	// Wireguard proposals are priced per bytes, this payment method is kept for proposals of older providers
 	market.RegisterPaymentMethodUnserializer(
 		PaymentMethod,
 		func(rawDefinition *json.RawMessage) (market.PaymentMethod, error) {
@@ -19,6 +19,7 @@ package connection
 
 import (
 	"encoding/json"
+	"fmt"
 	"net"
 	"sync"
 	"time"
@@ -26,6 +27,7 @@ import (
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/consumer"
 	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/core/ippool"
 	"github.com/mysteriumnetwork/node/core/location"
 	wg "github.com/mysteriumnetwork/node/services/wireguard"
 	endpoint "github.com/mysteriumnetwork/node/services/wireguard/endpoint"
@@ -36,6 +38,9 @@ import (
 
 const logPrefix = "[connection-wireguard] "
 
+// keyRotationHandshakeTimeout limits the wait for the handshake with the new key, the old key is restored after it
+const keyRotationHandshakeTimeout = 10 * time.Second
+
 // Connection which does wireguard tunneling.
 type Connection struct {
 	connection  sync.WaitGroup
@@ -45,7 +50,11 @@ type Connection struct {
 	statisticsChannel connection.StatisticsChannel
 
 	config             wg.ServiceConfig
+	configLock         sync.Mutex
 	connectionEndpoint wg.ConnectionEndpoint
+	updateSession      func(config connection.ConsumerConfig) error
+	modeSelector       *endpoint.ModeSelector
+	handshakeTimeout   time.Duration
 }
 
 // Start establish wireguard connection to the service provider.
@@ -54,17 +63,29 @@ func (c *Connection) Start(options connection.ConnectOptions) (err error) {
 	if err := json.Unmarshal(options.SessionConfig, &config); err != nil {
 		return errors.Wrap(err, "failed to unmarshal connection config")
 	}
+	c.configLock.Lock()
 	c.config.Provider = config.Provider
 	c.config.Consumer.IPAddress = config.Consumer.IPAddress
+	c.configLock.Unlock()
+	c.updateSession = options.UpdateSession
+
+	if err := checkSubnetConflicts(config.Consumer.IPAddress); err != nil {
+		return err
+	}
+
+	mode, err := c.modeSelector.Mode()
+	if err != nil {
+		return errors.Wrap(err, "failed to select wireguard mode")
+	}
 
-	resourceAllocator := resources.NewAllocator()
+	resourceAllocator := resources.NewAllocator(nil)
 
 	// We do not need port mapping for consumer, since it initiates the session
 	fakePortMapper := func(port int) (releasePortMapping func()) {
 		return func() {}
 	}
 
-	c.connectionEndpoint, err = endpoint.NewConnectionEndpoint(location.ServiceLocationInfo{}, &resourceAllocator, fakePortMapper, 0)
+	c.connectionEndpoint, err = endpoint.NewConnectionEndpoint(location.ServiceLocationInfo{}, &resourceAllocator, fakePortMapper, 0, false, mode)
 	if err != nil {
 		return errors.Wrap(err, "failed to create new connection endpoint")
 	}
@@ -103,6 +124,9 @@ func (c *Connection) Start(options connection.ConnectOptions) (err error) {
 	}
 
 	go c.runPeriodically(time.Second)
+	if config.Consumer.KeyRotationPeriod > 0 && c.updateSession != nil {
+		go c.rotateKeysPeriodically(time.Duration(config.Consumer.KeyRotationPeriod) * time.Second)
+	}
 
 	c.stateChannel <- connection.Connected
 	return nil
@@ -116,12 +140,17 @@ func (c *Connection) Wait() error {
 
 // GetConfig returns the consumer configuration for session creation
 func (c *Connection) GetConfig() (connection.ConsumerConfig, error) {
-	publicKey, err := key.PrivateKeyToPublicKey(c.config.Consumer.PrivateKey)
+	publicKey, err := key.PrivateKeyToPublicKey(c.privateKey())
 	if err != nil {
 		return nil, err
 	}
+	mode, err := c.modeSelector.Mode()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select wireguard mode")
+	}
 	return wg.ConsumerConfig{
-		PublicKey: publicKey,
+		PublicKey:             publicKey,
+		PresharedKeySupported: mode.SupportsPresharedKey(),
 	}, nil
 }
 
@@ -153,6 +182,89 @@ func (c *Connection) runPeriodically(duration time.Duration) {
 	}
 }
 
+func (c *Connection) rotateKeysPeriodically(period time.Duration) {
+	for {
+		select {
+		case <-time.After(period):
+			if err := c.rotateKey(); err != nil {
+				log.Error(logPrefix, "failed to rotate key: ", err)
+			}
+
+		case <-c.stopChannel:
+			return
+		}
+	}
+}
+
+// rotateKey announces the new public key to the provider, which keeps the old one next to it, and switches the local key afterwards.
+// Once the handshake with the new key succeeds, the new key is announced again for provider to retire the old one.
+// Otherwise the old key is restored and announced again for provider to drop the new one.
+func (c *Connection) rotateKey() error {
+	privateKey, err := key.GeneratePrivateKey()
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := key.PrivateKeyToPublicKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	oldPrivateKey := c.privateKey()
+	oldPublicKey, err := key.PrivateKeyToPublicKey(oldPrivateKey)
+	if err != nil {
+		return err
+	}
+
+	if err := c.updateSession(wg.ConsumerConfig{PublicKey: publicKey}); err != nil {
+		return errors.Wrap(err, "provider rejected the new key")
+	}
+
+	stats, err := c.connectionEndpoint.PeerStats()
+	if err != nil {
+		return c.cancelKeyRotation(oldPublicKey, errors.Wrap(err, "failed to receive peer stats"))
+	}
+	if err := c.connectionEndpoint.SetPrivateKey(privateKey); err != nil {
+		return c.cancelKeyRotation(oldPublicKey, errors.Wrap(err, "failed to set the new private key"))
+	}
+	if err := c.waitHandshakeAfter(stats.LastHandshake, time.After(c.handshakeTimeout)); err != nil {
+		if restoreErr := c.connectionEndpoint.SetPrivateKey(oldPrivateKey); restoreErr != nil {
+			log.Error(logPrefix, "failed to restore the old private key: ", restoreErr)
+		}
+		return c.cancelKeyRotation(oldPublicKey, errors.Wrap(err, "failed to handshake with the new key"))
+	}
+	c.setPrivateKey(privateKey)
+
+	if err := c.updateSession(wg.ConsumerConfig{PublicKey: publicKey}); err != nil {
+		return errors.Wrap(err, "provider did not retire the old key")
+	}
+
+	log.Info(logPrefix, "key rotated")
+	return nil
+}
+
+// cancelKeyRotation announces the old public key again, so the provider drops the new one
+func (c *Connection) cancelKeyRotation(oldPublicKey string, cause error) error {
+	if err := c.updateSession(wg.ConsumerConfig{PublicKey: oldPublicKey}); err != nil {
+		log.Error(logPrefix, "failed to drop the new key: ", err)
+	}
+	return cause
+}
+
+func (c *Connection) privateKey() string {
+	c.configLock.Lock()
+	defer c.configLock.Unlock()
+
+	return c.config.Consumer.PrivateKey
+}
+
+func (c *Connection) setPrivateKey(privateKey string) {
+	c.configLock.Lock()
+	defer c.configLock.Unlock()
+
+	c.config.Consumer.PrivateKey = privateKey
+}
+
 func (c *Connection) sendStats() {
 	stats, err := c.connectionEndpoint.PeerStats()
 	if err != nil {
@@ -166,6 +278,11 @@ func (c *Connection) sendStats() {
 }
 
 func (c *Connection) waitHandshake() error {
+	return c.waitHandshakeAfter(time.Time{}, nil)
+}
+
+// waitHandshakeAfter waits for the handshake newer than the given one, until the timeout fires
+func (c *Connection) waitHandshakeAfter(previous time.Time, timeout <-chan time.Time) error {
 	// We need to send any packet to initialize handshake process
 	_, _ = net.DialTimeout("tcp", "8.8.8.8:53", 100*time.Millisecond)
 	for {
@@ -175,12 +292,28 @@ func (c *Connection) waitHandshake() error {
 			if err != nil {
 				return err
 			}
-			if !stats.LastHandshake.IsZero() {
+			if !stats.LastHandshake.IsZero() && stats.LastHandshake.After(previous) {
 				return nil
 			}
 
+		case <-timeout:
+			return errors.New("handshake timed out")
+
 		case <-c.stopChannel:
 			return errors.New("stop received")
 		}
 	}
 }
+
+// checkSubnetConflicts ensures that the subnet assigned by provider does not shadow any of local networks
+func checkSubnetConflicts(subnet net.IPNet) error {
+	hostNetworks, err := ippool.HostNetworks()
+	if err != nil {
+		return errors.Wrap(err, "failed to list host networks")
+	}
+
+	if conflict, ok := ippool.FindConflict(subnet, hostNetworks); ok {
+		return fmt.Errorf("subnet %s assigned by provider conflicts with local network %s", subnet.String(), conflict.String())
+	}
+	return nil
+}
@@ -20,11 +20,14 @@ package connection
 import (
 	"github.com/mysteriumnetwork/node/core/connection"
 	wg "github.com/mysteriumnetwork/node/services/wireguard"
+	"github.com/mysteriumnetwork/node/services/wireguard/endpoint"
 	"github.com/mysteriumnetwork/node/services/wireguard/key"
 )
 
 // Factory is the wireguard connection factory
-type Factory struct{}
+type Factory struct {
+	modeSelector *endpoint.ModeSelector
+}
 
 // Create creates a new wireguard connection
 func (f *Factory) Create(stateChannel connection.StateChannel, statisticsChannel connection.StatisticsChannel) (connection.Connection, error) {
@@ -40,10 +43,12 @@ func (f *Factory) Create(stateChannel connection.StateChannel, statisticsChannel
 		stateChannel:      stateChannel,
 		statisticsChannel: statisticsChannel,
 		config:            config,
+		modeSelector:      f.modeSelector,
+		handshakeTimeout:  keyRotationHandshakeTimeout,
 	}, nil
 }
 
 // NewConnectionCreator creates wireguard connections
-func NewConnectionCreator() connection.Factory {
-	return &Factory{}
+func NewConnectionCreator(modeSelector *endpoint.ModeSelector) *Factory {
+	return &Factory{modeSelector: modeSelector}
 }
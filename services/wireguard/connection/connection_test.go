@@ -0,0 +1,119 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/core/connection"
+	wg "github.com/mysteriumnetwork/node/services/wireguard"
+	"github.com/mysteriumnetwork/node/services/wireguard/key"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRotatingConnection(t *testing.T, endpoint *keyRecordingEndpoint, announcedKeys *[]string) (*Connection, string) {
+	oldPrivateKey, err := key.GeneratePrivateKey()
+	assert.NoError(t, err)
+
+	conn := &Connection{connectionEndpoint: endpoint, handshakeTimeout: time.Second}
+	conn.config.Consumer.PrivateKey = oldPrivateKey
+	conn.updateSession = func(config connection.ConsumerConfig) error {
+		*announcedKeys = append(*announcedKeys, config.(wg.ConsumerConfig).PublicKey)
+		return nil
+	}
+	return conn, oldPrivateKey
+}
+
+func Test_Connection_RotateKeyAnnouncesNewKeyBeforeSwitchingAndConfirmsItAfterHandshake(t *testing.T) {
+	var announcedKeys []string
+	endpoint := &keyRecordingEndpoint{handshakeOnKeyChange: true}
+	conn, _ := newRotatingConnection(t, endpoint, &announcedKeys)
+	conn.updateSession = func(config connection.ConsumerConfig) error {
+		if len(announcedKeys) == 0 {
+			assert.Len(t, endpoint.privateKeys, 0, "provider has to know the new key before it is used")
+		}
+		announcedKeys = append(announcedKeys, config.(wg.ConsumerConfig).PublicKey)
+		return nil
+	}
+
+	assert.NoError(t, conn.rotateKey())
+
+	assert.Len(t, endpoint.privateKeys, 1)
+	assert.Equal(t, endpoint.privateKeys[0], conn.privateKey())
+	publicKey, err := key.PrivateKeyToPublicKey(conn.privateKey())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{publicKey, publicKey}, announcedKeys)
+}
+
+func Test_Connection_RotateKeyKeepsOldKeyWhenProviderRejectsNewOne(t *testing.T) {
+	var announcedKeys []string
+	endpoint := &keyRecordingEndpoint{handshakeOnKeyChange: true}
+	conn, oldPrivateKey := newRotatingConnection(t, endpoint, &announcedKeys)
+	conn.updateSession = func(config connection.ConsumerConfig) error {
+		return errors.New("unknown session")
+	}
+
+	assert.EqualError(t, conn.rotateKey(), "provider rejected the new key: unknown session")
+
+	assert.Len(t, endpoint.privateKeys, 0)
+	assert.Equal(t, oldPrivateKey, conn.privateKey())
+}
+
+func Test_Connection_RotateKeyRestoresOldKeyWhenHandshakeFails(t *testing.T) {
+	var announcedKeys []string
+	endpoint := &keyRecordingEndpoint{}
+	conn, oldPrivateKey := newRotatingConnection(t, endpoint, &announcedKeys)
+	conn.handshakeTimeout = time.Millisecond
+
+	assert.EqualError(t, conn.rotateKey(), "failed to handshake with the new key: handshake timed out")
+
+	assert.Len(t, endpoint.privateKeys, 2)
+	assert.Equal(t, oldPrivateKey, endpoint.privateKeys[1])
+	assert.Equal(t, oldPrivateKey, conn.privateKey())
+	oldPublicKey, err := key.PrivateKeyToPublicKey(oldPrivateKey)
+	assert.NoError(t, err)
+	assert.Len(t, announcedKeys, 2)
+	assert.Equal(t, oldPublicKey, announcedKeys[1], "provider has to drop the new key")
+}
+
+// keyRecordingEndpoint records the private keys set and handshakes with every new key if asked to
+type keyRecordingEndpoint struct {
+	privateKeys          []string
+	handshakeOnKeyChange bool
+	lastHandshake        time.Time
+}
+
+func (e *keyRecordingEndpoint) Start(config *wg.ServiceConfig) error                  { return nil }
+func (e *keyRecordingEndpoint) AddPeer(publicKey string, endpoint *net.UDPAddr) error { return nil }
+func (e *keyRecordingEndpoint) ReplacePeer(publicKey string) error                    { return nil }
+func (e *keyRecordingEndpoint) SetPrivateKey(privateKey string) error {
+	e.privateKeys = append(e.privateKeys, privateKey)
+	if e.handshakeOnKeyChange {
+		e.lastHandshake = time.Now()
+	}
+	return nil
+}
+func (e *keyRecordingEndpoint) PeerStats() (wg.Stats, error) {
+	return wg.Stats{LastHandshake: e.lastHandshake}, nil
+}
+func (e *keyRecordingEndpoint) ConfigureRoutes(ip net.IP) error   { return nil }
+func (e *keyRecordingEndpoint) Config() (wg.ServiceConfig, error) { return wg.ServiceConfig{}, nil }
+func (e *keyRecordingEndpoint) Stop() error                       { return nil }
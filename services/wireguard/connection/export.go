@@ -0,0 +1,88 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mysteriumnetwork/node/core/connection"
+	wg "github.com/mysteriumnetwork/node/services/wireguard"
+	"github.com/mysteriumnetwork/node/services/wireguard/key"
+	"github.com/pkg/errors"
+)
+
+// ExportFormat is the format of configuration rendered for external tunnel clients
+const ExportFormat = "wg-quick"
+
+// persistentKeepalive keeps handshakes going even without traffic, so that provider does not consider the peer dead
+const persistentKeepalive = 25
+
+type configExporter struct {
+	privateKey string
+}
+
+// CreateExporter creates config exporter rendering wg-quick configuration
+func (f *Factory) CreateExporter() (connection.ConfigExporter, error) {
+	privateKey, err := key.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	return &configExporter{privateKey: privateKey}, nil
+}
+
+// GetConfig returns the consumer configuration for session creation
+func (ce *configExporter) GetConfig() (connection.ConsumerConfig, error) {
+	publicKey, err := key.PrivateKeyToPublicKey(ce.privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return wg.ConsumerConfig{PublicKey: publicKey}, nil
+}
+
+// Export renders wg-quick configuration of the created session
+func (ce *configExporter) Export(options connection.ConnectOptions) (connection.ExportedConfig, error) {
+	var config wg.ServiceConfig
+	if err := json.Unmarshal(options.SessionConfig, &config); err != nil {
+		return connection.ExportedConfig{}, errors.Wrap(err, "failed to unmarshal connection config")
+	}
+	config.Consumer.PrivateKey = ce.privateKey
+
+	return connection.ExportedConfig{
+		Format:  ExportFormat,
+		Content: renderWgQuickConfig(config),
+	}, nil
+}
+
+func renderWgQuickConfig(config wg.ServiceConfig) string {
+	var content strings.Builder
+	fmt.Fprintln(&content, "[Interface]")
+	fmt.Fprintln(&content, "PrivateKey =", config.Consumer.PrivateKey)
+	fmt.Fprintln(&content, "Address =", config.Consumer.IPAddress.String())
+	fmt.Fprintln(&content)
+	fmt.Fprintln(&content, "[Peer]")
+	fmt.Fprintln(&content, "PublicKey =", config.Provider.PublicKey)
+	if config.Provider.PresharedKey != "" {
+		fmt.Fprintln(&content, "PresharedKey =", config.Provider.PresharedKey)
+	}
+	fmt.Fprintln(&content, "Endpoint =", config.Provider.Endpoint.String())
+	fmt.Fprintln(&content, "AllowedIPs = 0.0.0.0/0")
+	fmt.Fprintln(&content, "PersistentKeepalive =", persistentKeepalive)
+	return content.String()
+}
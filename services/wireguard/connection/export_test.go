@@ -0,0 +1,48 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"net"
+	"testing"
+
+	wg "github.com/mysteriumnetwork/node/services/wireguard"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_renderWgQuickConfig(t *testing.T) {
+	config := wg.ServiceConfig{}
+	config.Provider.PublicKey = "wAx/KOR3TLNyJC4cHhn8ERXa8fdmD4Rn/ZWOGGDVwS4="
+	config.Provider.PresharedKey = "cPLPExOQwBkHalXBzBkK1AzR3VzC/W4w9DPxkcjFjXo="
+	config.Provider.Endpoint = net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 52820}
+	config.Consumer.PrivateKey = "gOUhXfFUGVrmF9i3LKFkGTdeyzz+CX2qQi+5bDM3oXw="
+	config.Consumer.IPAddress = net.IPNet{IP: net.IPv4(10, 182, 0, 2), Mask: net.CIDRMask(24, 32)}
+
+	expected := `[Interface]
+PrivateKey = gOUhXfFUGVrmF9i3LKFkGTdeyzz+CX2qQi+5bDM3oXw=
+Address = 10.182.0.2/24
+
+[Peer]
+PublicKey = wAx/KOR3TLNyJC4cHhn8ERXa8fdmD4Rn/ZWOGGDVwS4=
+PresharedKey = cPLPExOQwBkHalXBzBkK1AzR3VzC/W4w9DPxkcjFjXo=
+Endpoint = 1.2.3.4:52820
+AllowedIPs = 0.0.0.0/0
+PersistentKeepalive = 25
+`
+	assert.Equal(t, expected, renderWgQuickConfig(config))
+}
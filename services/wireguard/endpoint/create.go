@@ -31,7 +31,9 @@ func NewConnectionEndpoint(
 	location location.ServiceLocationInfo,
 	resourceAllocator *resources.Allocator,
 	portMap func(port int) (releasePortMapping func()),
-	connectDelay int) (wg.ConnectionEndpoint, error) {
+	connectDelay int,
+	usePresharedKey bool,
+	mode Mode) (wg.ConnectionEndpoint, error) {
 
 	client, err := userspace.NewWireguardClient()
 	return &connectionEndpoint{
@@ -41,5 +43,11 @@ func NewConnectionEndpoint(
 		mapPort:            portMap,
 		releasePortMapping: func() {},
 		connectDelay:       connectDelay,
+		usePresharedKey:    usePresharedKey,
 	}, err
 }
+
+// isKernelSpaceSupported reports that only user space implementation is available on this platform
+func isKernelSpaceSupported() bool {
+	return false
+}
@@ -34,9 +34,11 @@ func NewConnectionEndpoint(
 	location location.ServiceLocationInfo,
 	resourceAllocator *resources.Allocator,
 	mapPort func(port int) (releasePortMapping func()),
-	connectDelay int) (wg.ConnectionEndpoint, error) {
+	connectDelay int,
+	usePresharedKey bool,
+	mode Mode) (wg.ConnectionEndpoint, error) {
 
-	wgClient, err := getWGClient()
+	wgClient, err := getWGClient(mode)
 	if err != nil {
 		return nil, err
 	}
@@ -48,24 +50,34 @@ func NewConnectionEndpoint(
 		releasePortMapping: func() {},
 		mapPort:            mapPort,
 		connectDelay:       connectDelay,
+		usePresharedKey:    usePresharedKey,
 	}, nil
 }
 
-func getWGClient() (wgClient wgClient, err error) {
-	if isKernelSpaceSupported() {
+func getWGClient(mode Mode) (wgClient wgClient, err error) {
+	if mode == ModeKernelspace {
 		return kernelspace.NewWireguardClient()
 	}
-
-	log.Info("Wireguard kernel space is not supported. Switching to user space implementation.")
 	return userspace.NewWireguardClient()
 }
 
+// isKernelSpaceSupported checks that the wireguard kernel module is loaded
+// and its generic netlink family is reachable for device configuration
 func isKernelSpaceSupported() bool {
-	err := utils.SudoExec("ip", "link", "add", "iswgsupported", "type", "wireguard")
+	const testIface = "iswgsupported"
+
+	err := utils.SudoExec("ip", "link", "add", testIface, "type", "wireguard")
 	if err != nil {
 		log.Debug(logPrefix, "failed to create wireguard network interface: ", err)
+		return false
 	}
+	defer func() {
+		_ = utils.SudoExec("ip", "link", "del", testIface)
+	}()
 
-	_ = utils.SudoExec("ip", "link", "del", "iswgsupported")
-	return err == nil
+	if err := kernelspace.CheckDevice(testIface); err != nil {
+		log.Debug(logPrefix, "failed to reach wireguard netlink family: ", err)
+		return false
+	}
+	return true
 }
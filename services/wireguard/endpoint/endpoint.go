@@ -18,13 +18,16 @@
 package endpoint
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"sync"
 
 	"github.com/mysteriumnetwork/node/core/location"
 
 	log "github.com/cihub/seelog"
 	wg "github.com/mysteriumnetwork/node/services/wireguard"
+	"github.com/mysteriumnetwork/node/services/wireguard/endpoint/userspace"
 	"github.com/mysteriumnetwork/node/services/wireguard/key"
 	"github.com/mysteriumnetwork/node/services/wireguard/resources"
 )
@@ -36,13 +39,18 @@ type wgClient interface {
 	ConfigureRoutes(iface string, ip net.IP) error
 	DestroyDevice(name string) error
 	AddPeer(name string, peer wg.PeerInfo) error
-	PeerStats() (wg.Stats, error)
+	RemovePeer(name string, publicKey string) error
+	SetPrivateKey(name string, privateKey string) error
+	PeerStats(publicKey string) (wg.Stats, error)
 	Close() error
 }
 
 type connectionEndpoint struct {
 	iface              string
 	privateKey         string
+	presharedKey       string
+	peerPublicKey      string
+	pendingPublicKey   string // new key of the peer, which is kept next to the current one until the peer proves it
 	location           location.ServiceLocationInfo
 	ipAddr             net.IPNet
 	endpoint           net.UDPAddr
@@ -51,6 +59,11 @@ type connectionEndpoint struct {
 	releasePortMapping func()
 	mapPort            func(port int) (releasePortMapping func())
 	connectDelay       int // connect delay in milliseconds
+	usePresharedKey    bool
+
+	// statsOffset holds the traffic of removed peers, as counters of the new peer start from zero
+	statsOffset wg.Stats
+	statsLock   sync.Mutex
 }
 
 // Start starts and configure wireguard network interface for providing service.
@@ -88,9 +101,15 @@ func (ce *connectionEndpoint) Start(config *wg.ServiceConfig) error {
 		ce.ipAddr = ipAddr
 		ce.ipAddr.IP = providerIP(ce.ipAddr)
 		ce.privateKey = privateKey
+		if ce.usePresharedKey {
+			if ce.presharedKey, err = key.GeneratePresharedKey(); err != nil {
+				return err
+			}
+		}
 	} else {
 		ce.ipAddr = config.Consumer.IPAddress
 		ce.privateKey = config.Consumer.PrivateKey
+		ce.presharedKey = config.Provider.PresharedKey
 	}
 
 	var deviceConfig deviceConfig
@@ -101,11 +120,112 @@ func (ce *connectionEndpoint) Start(config *wg.ServiceConfig) error {
 
 // AddPeer adds new wireguard peer to the wireguard network interface.
 func (ce *connectionEndpoint) AddPeer(publicKey string, endpoint *net.UDPAddr) error {
-	return ce.wgClient.AddPeer(ce.iface, peerInfo{endpoint, publicKey})
+	if err := ce.wgClient.AddPeer(ce.iface, peerInfo{endpoint, publicKey, ce.presharedKey}); err != nil {
+		return err
+	}
+	ce.peerPublicKey = publicKey
+	return nil
+}
+
+// ReplacePeer replaces the public key of the peer in two steps, so the old key keeps working until the peer handshakes with the new one.
+// A new key is added next to the current one and takes over the traffic. The same key given again retires the old one,
+// while the current key given again drops the new one, i.e. the peer failed to handshake with it.
+// Traffic of the removed peers is carried over to the statistics of the remaining one.
+func (ce *connectionEndpoint) ReplacePeer(publicKey string) error {
+	if publicKey == "" {
+		return errors.New("public key of the peer is empty")
+	}
+
+	ce.statsLock.Lock()
+	defer ce.statsLock.Unlock()
+
+	switch publicKey {
+	case ce.peerPublicKey:
+		return ce.dropPendingPeer()
+	case ce.pendingPublicKey:
+		return ce.retireCurrentPeer()
+	}
+
+	if err := ce.dropPendingPeer(); err != nil {
+		return err
+	}
+	if err := ce.wgClient.AddPeer(ce.iface, peerInfo{nil, publicKey, ce.presharedKey}); err != nil {
+		return err
+	}
+	ce.pendingPublicKey = publicKey
+	return nil
+}
+
+func (ce *connectionEndpoint) retireCurrentPeer() error {
+	if err := ce.removePeer(ce.peerPublicKey); err != nil {
+		return err
+	}
+	ce.peerPublicKey = ce.pendingPublicKey
+	ce.pendingPublicKey = ""
+	return nil
+}
+
+// dropPendingPeer removes the new peer and adds the current one again, as the new peer has taken over its allowed IPs
+func (ce *connectionEndpoint) dropPendingPeer() error {
+	if ce.pendingPublicKey == "" {
+		return nil
+	}
+
+	if err := ce.removePeer(ce.pendingPublicKey); err != nil {
+		return err
+	}
+	ce.pendingPublicKey = ""
+	if err := ce.removePeer(ce.peerPublicKey); err != nil {
+		return err
+	}
+	return ce.wgClient.AddPeer(ce.iface, peerInfo{nil, ce.peerPublicKey, ce.presharedKey})
+}
+
+func (ce *connectionEndpoint) removePeer(publicKey string) error {
+	stats, err := ce.wgClient.PeerStats(publicKey)
+	if err != nil {
+		return err
+	}
+	if err := ce.wgClient.RemovePeer(ce.iface, publicKey); err != nil {
+		return err
+	}
+	ce.statsOffset.BytesSent += stats.BytesSent
+	ce.statsOffset.BytesReceived += stats.BytesReceived
+	return nil
+}
+
+// SetPrivateKey changes the private key of the wireguard network interface.
+func (ce *connectionEndpoint) SetPrivateKey(privateKey string) error {
+	if err := ce.wgClient.SetPrivateKey(ce.iface, privateKey); err != nil {
+		return err
+	}
+	ce.privateKey = privateKey
+	return nil
 }
 
+// PeerStats returns the statistics of the peer, including the traffic of its new key and of the keys it replaced
 func (ce *connectionEndpoint) PeerStats() (wg.Stats, error) {
-	return ce.wgClient.PeerStats()
+	ce.statsLock.Lock()
+	defer ce.statsLock.Unlock()
+
+	stats, err := ce.wgClient.PeerStats(ce.peerPublicKey)
+	if err != nil {
+		return wg.Stats{}, err
+	}
+	if ce.pendingPublicKey != "" {
+		pending, err := ce.wgClient.PeerStats(ce.pendingPublicKey)
+		if err != nil {
+			return wg.Stats{}, err
+		}
+		stats.BytesSent += pending.BytesSent
+		stats.BytesReceived += pending.BytesReceived
+		if pending.LastHandshake.After(stats.LastHandshake) {
+			stats.LastHandshake = pending.LastHandshake
+		}
+	}
+	stats.BytesSent += ce.statsOffset.BytesSent
+	stats.BytesReceived += ce.statsOffset.BytesReceived
+	return stats, nil
 }
 
 // Config provides wireguard service configuration for the current connection endpoint.
@@ -117,6 +237,7 @@ func (ce *connectionEndpoint) Config() (wg.ServiceConfig, error) {
 
 	var config wg.ServiceConfig
 	config.Provider.PublicKey = publicKey
+	config.Provider.PresharedKey = ce.presharedKey
 	config.Provider.Endpoint = ce.endpoint
 	config.Consumer.IPAddress = ce.ipAddr
 	config.Consumer.IPAddress.IP = consumerIP(ce.ipAddr)
@@ -150,19 +271,63 @@ func (ce *connectionEndpoint) Stop() error {
 }
 
 func (ce *connectionEndpoint) cleanAbandonedInterfaces() error {
-	ifaces, err := ce.resourceAllocator.AbandonedInterfaces()
+	_, err := cleanAbandonedInterfaces(ce.resourceAllocator, ce.wgClient.DestroyDevice)
+	return err
+}
+
+// RemovedInterface describes abandoned wireguard network interface removed from the system.
+type RemovedInterface struct {
+	Name    string
+	Subnets []net.IPNet
+}
+
+// CleanAbandonedInterfaces destroys wireguard network interfaces which exist in the system,
+// but were not allocated by the given allocator. Only the interfaces named by the allocator
+// are considered, so provider and consumer do not destroy each other's interfaces. Device removal does not depend on the wireguard
+// implementation, so user space client is used to destroy both kernel and user space devices.
+func CleanAbandonedInterfaces(resourceAllocator *resources.Allocator) ([]RemovedInterface, error) {
+	client, err := userspace.NewWireguardClient()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return cleanAbandonedInterfaces(resourceAllocator, client.DestroyDevice)
+}
 
+func cleanAbandonedInterfaces(resourceAllocator *resources.Allocator, destroyDevice func(name string) error) ([]RemovedInterface, error) {
+	ifaces, err := resourceAllocator.AbandonedInterfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	removed := make([]RemovedInterface, 0)
 	for _, iface := range ifaces {
-		if err := ce.wgClient.DestroyDevice(iface.Name); err != nil {
+		// addresses have to be collected before the interface is gone
+		subnets := interfaceSubnets(iface)
+		if err := destroyDevice(iface.Name); err != nil {
 			log.Warn(logPrefix, fmt.Sprintf("failed to destroy abandoned interface: %s, error: %v", iface.Name, err))
+			continue
 		}
 		log.Info(logPrefix, "abandoned interface destroyed: ", iface.Name)
+		removed = append(removed, RemovedInterface{Name: iface.Name, Subnets: subnets})
 	}
 
-	return nil
+	return removed, nil
+}
+
+func interfaceSubnets(iface net.Interface) []net.IPNet {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		log.Warn(logPrefix, fmt.Sprintf("failed to get addresses of interface: %s, error: %v", iface.Name, err))
+		return nil
+	}
+
+	subnets := make([]net.IPNet, 0)
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			subnets = append(subnets, net.IPNet{IP: ipNet.IP.Mask(ipNet.Mask), Mask: ipNet.Mask})
+		}
+	}
+	return subnets
 }
 
 type deviceConfig struct {
@@ -179,8 +344,9 @@ func (d deviceConfig) ListenPort() int {
 }
 
 type peerInfo struct {
-	endpoint  *net.UDPAddr
-	publicKey string
+	endpoint     *net.UDPAddr
+	publicKey    string
+	presharedKey string
 }
 
 func (p peerInfo) Endpoint() *net.UDPAddr {
@@ -189,6 +355,9 @@ func (p peerInfo) Endpoint() *net.UDPAddr {
 func (p peerInfo) PublicKey() string {
 	return p.publicKey
 }
+func (p peerInfo) PresharedKey() string {
+	return p.presharedKey
+}
 
 func providerIP(subnet net.IPNet) net.IP {
 	subnet.IP[len(subnet.IP)-1] = byte(1)
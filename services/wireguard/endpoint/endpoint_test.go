@@ -0,0 +1,152 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoint
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	wg "github.com/mysteriumnetwork/node/services/wireguard"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ConnectionEndpoint_ReplacePeerKeepsOldKeyUntilNewOneIsConfirmed(t *testing.T) {
+	client := newFakeWgClient("old-key")
+	ce := &connectionEndpoint{iface: "myst0", wgClient: client, peerPublicKey: "old-key"}
+
+	assert.NoError(t, ce.ReplacePeer("new-key"))
+	assert.Equal(t, []string{"old-key", "new-key"}, client.peerKeys())
+	assert.Equal(t, "old-key", ce.peerPublicKey)
+
+	assert.NoError(t, ce.ReplacePeer("new-key"))
+	assert.Equal(t, []string{"new-key"}, client.peerKeys())
+	assert.Equal(t, "new-key", ce.peerPublicKey)
+	assert.Equal(t, "", ce.pendingPublicKey)
+}
+
+func Test_ConnectionEndpoint_ReplacePeerDropsNewKeyWhenOldOneIsGivenAgain(t *testing.T) {
+	client := newFakeWgClient("old-key")
+	ce := &connectionEndpoint{iface: "myst0", wgClient: client, peerPublicKey: "old-key"}
+
+	assert.NoError(t, ce.ReplacePeer("new-key"))
+	assert.NoError(t, ce.ReplacePeer("old-key"))
+	assert.Equal(t, []string{"old-key"}, client.peerKeys())
+	assert.Equal(t, "old-key", ce.peerPublicKey)
+	assert.Equal(t, "", ce.pendingPublicKey)
+}
+
+func Test_ConnectionEndpoint_ReplacePeerRejectsEmptyKey(t *testing.T) {
+	client := newFakeWgClient("old-key")
+	ce := &connectionEndpoint{iface: "myst0", wgClient: client, peerPublicKey: "old-key"}
+
+	assert.Error(t, ce.ReplacePeer(""))
+	assert.Equal(t, []string{"old-key"}, client.peerKeys())
+}
+
+func Test_ConnectionEndpoint_ReplacePeerKeepsTrafficStatistics(t *testing.T) {
+	client := newFakeWgClient("old-key")
+	client.stats["old-key"] = wg.Stats{BytesSent: 100, BytesReceived: 200}
+	ce := &connectionEndpoint{iface: "myst0", wgClient: client, peerPublicKey: "old-key"}
+
+	assert.NoError(t, ce.ReplacePeer("new-key"))
+	handshake := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	client.stats["new-key"] = wg.Stats{BytesSent: 10, BytesReceived: 20, LastHandshake: handshake}
+	stats, err := ce.PeerStats()
+	assert.NoError(t, err)
+	assert.Equal(t, wg.Stats{BytesSent: 110, BytesReceived: 220, LastHandshake: handshake}, stats)
+
+	assert.NoError(t, ce.ReplacePeer("new-key"))
+	stats, err = ce.PeerStats()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(110), stats.BytesSent)
+	assert.Equal(t, uint64(220), stats.BytesReceived)
+
+	assert.NoError(t, ce.ReplacePeer("newer-key"))
+	client.stats["newer-key"] = wg.Stats{BytesSent: 1, BytesReceived: 2}
+	// dropping the newer key adds the current one again, its counters start from zero
+	assert.NoError(t, ce.ReplacePeer("new-key"))
+	stats, err = ce.PeerStats()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(111), stats.BytesSent)
+	assert.Equal(t, uint64(222), stats.BytesReceived)
+}
+
+func Test_ConnectionEndpoint_FailedReplacePeerKeepsStatistics(t *testing.T) {
+	client := newFakeWgClient("old-key")
+	client.stats["old-key"] = wg.Stats{BytesSent: 100, BytesReceived: 200}
+	client.addErr = errors.New("device busy")
+	ce := &connectionEndpoint{iface: "myst0", wgClient: client, peerPublicKey: "old-key"}
+
+	assert.EqualError(t, ce.ReplacePeer("new-key"), "device busy")
+	assert.Equal(t, "old-key", ce.peerPublicKey)
+	assert.Equal(t, "", ce.pendingPublicKey)
+
+	stats, err := ce.PeerStats()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100), stats.BytesSent)
+	assert.Equal(t, uint64(200), stats.BytesReceived)
+}
+
+type fakeWgClient struct {
+	peers  []string
+	stats  map[string]wg.Stats
+	addErr error
+}
+
+func newFakeWgClient(peers ...string) *fakeWgClient {
+	return &fakeWgClient{peers: peers, stats: make(map[string]wg.Stats)}
+}
+
+func (c *fakeWgClient) peerKeys() []string {
+	return c.peers
+}
+
+func (c *fakeWgClient) ConfigureDevice(name string, config wg.DeviceConfig, subnet net.IPNet) error {
+	return nil
+}
+func (c *fakeWgClient) ConfigureRoutes(iface string, ip net.IP) error { return nil }
+func (c *fakeWgClient) DestroyDevice(name string) error               { return nil }
+func (c *fakeWgClient) AddPeer(name string, peer wg.PeerInfo) error {
+	if c.addErr != nil {
+		return c.addErr
+	}
+	c.peers = append(c.peers, peer.PublicKey())
+	return nil
+}
+func (c *fakeWgClient) RemovePeer(name string, publicKey string) error {
+	for i, key := range c.peers {
+		if key == publicKey {
+			c.peers = append(c.peers[:i], c.peers[i+1:]...)
+			delete(c.stats, publicKey)
+			return nil
+		}
+	}
+	return errors.New("peer not found")
+}
+func (c *fakeWgClient) SetPrivateKey(name string, privateKey string) error { return nil }
+func (c *fakeWgClient) PeerStats(publicKey string) (wg.Stats, error) {
+	for _, key := range c.peers {
+		if key == publicKey {
+			return c.stats[publicKey], nil
+		}
+	}
+	return wg.Stats{}, errors.New("peer not found")
+}
+func (c *fakeWgClient) Close() error { return nil }
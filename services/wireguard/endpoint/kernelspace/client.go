@@ -49,6 +49,18 @@ func NewWireguardClient() (*client, error) {
 	return &client{wgClient: wgClient}, nil
 }
 
+// CheckDevice verifies that the given wireguard device can be queried over netlink
+func CheckDevice(iface string) error {
+	wgClient, err := wireguardctrl.New()
+	if err != nil {
+		return err
+	}
+	defer wgClient.Close()
+
+	_, err = wgClient.Device(iface)
+	return err
+}
+
 func (c *client) ConfigureDevice(iface string, config wg.DeviceConfig, ipAddr net.IPNet) error {
 	var deviceConfig wgtypes.Config
 	if config != nil {
@@ -69,36 +81,79 @@ func (c *client) ConfigureDevice(iface string, config wg.DeviceConfig, ipAddr ne
 }
 
 func (c *client) AddPeer(iface string, peer wg.PeerInfo) error {
-	endpoint := peer.Endpoint()
-	publicKey, err := stringToKey(peer.PublicKey())
+	peerConfig, err := newPeerConfig(peer)
 	if err != nil {
 		return err
 	}
 
 	var deviceConfig wgtypes.Config
-	deviceConfig.Peers = []wgtypes.PeerConfig{{
-		Endpoint:   endpoint,
+	deviceConfig.Peers = []wgtypes.PeerConfig{peerConfig}
+	return c.wgClient.ConfigureDevice(iface, deviceConfig)
+}
+
+func (c *client) RemovePeer(iface string, publicKey string) error {
+	key, err := stringToKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	var deviceConfig wgtypes.Config
+	deviceConfig.Peers = []wgtypes.PeerConfig{{PublicKey: key, Remove: true}}
+	return c.wgClient.ConfigureDevice(iface, deviceConfig)
+}
+
+func (c *client) SetPrivateKey(iface string, privateKey string) error {
+	key, err := stringToKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	return c.wgClient.ConfigureDevice(iface, wgtypes.Config{PrivateKey: &key})
+}
+
+func newPeerConfig(peer wg.PeerInfo) (wgtypes.PeerConfig, error) {
+	publicKey, err := stringToKey(peer.PublicKey())
+	if err != nil {
+		return wgtypes.PeerConfig{}, err
+	}
+
+	peerConfig := wgtypes.PeerConfig{
+		Endpoint:   peer.Endpoint(),
 		PublicKey:  publicKey,
 		AllowedIPs: allowedIPs,
-	}}
-	return c.wgClient.ConfigureDevice(iface, deviceConfig)
+	}
+
+	if peer.PresharedKey() != "" {
+		presharedKey, err := stringToKey(peer.PresharedKey())
+		if err != nil {
+			return wgtypes.PeerConfig{}, err
+		}
+		peerConfig.PresharedKey = &presharedKey
+	}
+	return peerConfig, nil
 }
 
-func (c *client) PeerStats() (wg.Stats, error) {
-	d, err := c.wgClient.Device(c.iface)
+func (c *client) PeerStats(publicKey string) (wg.Stats, error) {
+	key, err := stringToKey(publicKey)
 	if err != nil {
 		return wg.Stats{}, err
 	}
 
-	if len(d.Peers) != 1 {
-		return wg.Stats{}, errors.New("exactly 1 peer expected")
+	d, err := c.wgClient.Device(c.iface)
+	if err != nil {
+		return wg.Stats{}, err
 	}
 
-	return wg.Stats{
-		BytesReceived: uint64(d.Peers[0].ReceiveBytes),
-		BytesSent:     uint64(d.Peers[0].TransmitBytes),
-		LastHandshake: d.Peers[0].LastHandshakeTime,
-	}, nil
+	for _, peer := range d.Peers {
+		if peer.PublicKey == key {
+			return wg.Stats{
+				BytesReceived: uint64(peer.ReceiveBytes),
+				BytesSent:     uint64(peer.TransmitBytes),
+				LastHandshake: peer.LastHandshakeTime,
+			}, nil
+		}
+	}
+	return wg.Stats{}, errors.New("peer not found")
 }
 
 func (c *client) DestroyDevice(name string) error {
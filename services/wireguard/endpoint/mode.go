@@ -0,0 +1,117 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoint
+
+import (
+	"sync"
+
+	log "github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// Mode represents wireguard implementation used by connection endpoints
+type Mode string
+
+const (
+	// ModeAuto selects kernel space implementation if it is supported and falls back to user space otherwise
+	ModeAuto = Mode("auto")
+	// ModeKernelspace uses wireguard kernel module
+	ModeKernelspace = Mode("kernelspace")
+	// ModeUserspace uses wireguard-go with a TUN device
+	ModeUserspace = Mode("userspace")
+
+	// ModeNotResolved is reported until wireguard is used for the first time
+	ModeNotResolved = Mode("not yet resolved")
+	// ModeUnavailable is reported when none of implementations could be selected
+	ModeUnavailable = Mode("unavailable")
+)
+
+// SupportsPresharedKey reports whether the implementation of the mode is able to use preshared keys
+func (m Mode) SupportsPresharedKey() bool {
+	return m == ModeKernelspace
+}
+
+// ModeSelector resolves the requested mode on first use and remembers the result
+type ModeSelector struct {
+	requested Mode
+	resolved  Mode
+	err       error
+	once      sync.Once
+	lock      sync.Mutex
+}
+
+// NewModeSelector creates mode selector for the requested mode
+func NewModeSelector(requested Mode) *ModeSelector {
+	return &ModeSelector{requested: requested}
+}
+
+// Mode returns the resolved wireguard mode, detecting kernel space support on the first call
+func (ms *ModeSelector) Mode() (Mode, error) {
+	ms.once.Do(func() {
+		mode, err := resolveMode(ms.requested, isKernelSpaceSupported)
+		if err != nil {
+			log.Error(logPrefix, "Failed to select wireguard mode: ", err)
+		} else {
+			log.Info(logPrefix, "Using wireguard mode: ", mode)
+		}
+
+		ms.lock.Lock()
+		defer ms.lock.Unlock()
+		ms.resolved, ms.err = mode, err
+	})
+
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	return ms.resolved, ms.err
+}
+
+// Resolved returns the resolved wireguard mode for reporting, ModeNotResolved if wireguard was not used yet
+// and ModeUnavailable if the mode could not be selected
+func (ms *ModeSelector) Resolved() Mode {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	switch {
+	case ms.err != nil:
+		return ModeUnavailable
+	case ms.resolved == "":
+		return ModeNotResolved
+	default:
+		return ms.resolved
+	}
+}
+
+func resolveMode(requested Mode, kernelSpaceSupported func() bool) (Mode, error) {
+	switch requested {
+	case ModeAuto, "":
+		if kernelSpaceSupported() {
+			return ModeKernelspace, nil
+		}
+		log.Info(logPrefix, "Wireguard kernel space is not supported. Switching to user space implementation.")
+		return ModeUserspace, nil
+	case ModeKernelspace:
+		if !kernelSpaceSupported() {
+			return "", errors.New("wireguard kernel space is not supported on this system")
+		}
+		return ModeKernelspace, nil
+	case ModeUserspace:
+		return ModeUserspace, nil
+	default:
+		return "", errors.Errorf("unknown wireguard mode: %s", requested)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func supported() bool   { return true }
+func unsupported() bool { return false }
+
+func Test_ResolveMode(t *testing.T) {
+	var tests = []struct {
+		requested     Mode
+		kernelSpace   func() bool
+		expectedMode  Mode
+		expectedError string
+	}{
+		{ModeAuto, supported, ModeKernelspace, ""},
+		{ModeAuto, unsupported, ModeUserspace, ""},
+		{"", unsupported, ModeUserspace, ""},
+		{ModeKernelspace, supported, ModeKernelspace, ""},
+		{ModeKernelspace, unsupported, "", "wireguard kernel space is not supported on this system"},
+		{ModeUserspace, supported, ModeUserspace, ""},
+		{Mode("magic"), supported, "", "unknown wireguard mode: magic"},
+	}
+
+	for _, test := range tests {
+		mode, err := resolveMode(test.requested, test.kernelSpace)
+		assert.Equal(t, test.expectedMode, mode)
+		if test.expectedError == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, test.expectedError)
+		}
+	}
+}
+
+func Test_ModeSelector_ResolvesOnce(t *testing.T) {
+	selector := NewModeSelector(ModeUserspace)
+	assert.Equal(t, ModeNotResolved, selector.Resolved())
+
+	mode, err := selector.Mode()
+	assert.NoError(t, err)
+	assert.Equal(t, ModeUserspace, mode)
+	assert.Equal(t, ModeUserspace, selector.Resolved())
+}
+
+func Test_ModeSelector_ReportsUnavailableMode(t *testing.T) {
+	selector := NewModeSelector(Mode("magic"))
+
+	_, err := selector.Mode()
+	assert.EqualError(t, err, "unknown wireguard mode: magic")
+	assert.Equal(t, ModeUnavailable, selector.Resolved())
+}
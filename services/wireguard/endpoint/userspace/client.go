@@ -62,6 +62,10 @@ func (c *client) ConfigureDevice(name string, config wg.DeviceConfig, subnet net
 }
 
 func (c *client) AddPeer(name string, peer wg.PeerInfo) error {
+	if peer.PresharedKey() != "" {
+		return errors.New("preshared keys are not supported by userspace wireguard")
+	}
+
 	key, err := base64stringTo32ByteArray(peer.PublicKey())
 	if err != nil {
 		return err
@@ -82,6 +86,24 @@ func (c *client) AddPeer(name string, peer wg.PeerInfo) error {
 	return c.devAPI.AddPeer(extPeer)
 }
 
+func (c *client) RemovePeer(name string, publicKey string) error {
+	key, err := base64stringTo32ByteArray(publicKey)
+	if err != nil {
+		return err
+	}
+
+	return c.devAPI.RemovePeer(device.NoisePublicKey(key))
+}
+
+func (c *client) SetPrivateKey(name string, privateKey string) error {
+	key, err := base64stringTo32ByteArray(privateKey)
+	if err != nil {
+		return err
+	}
+
+	return c.devAPI.SetPrivateKey(device.NoisePrivateKey(key))
+}
+
 func (c *client) Close() error {
 	c.devAPI.Close() // c.devAPI.Close() closes c.tun too
 	return nil
@@ -94,21 +116,27 @@ func (c *client) ConfigureRoutes(iface string, ip net.IP) error {
 	return addDefaultRoute(iface)
 }
 
-func (c *client) PeerStats() (wg.Stats, error) {
+func (c *client) PeerStats(publicKey string) (wg.Stats, error) {
+	key, err := base64stringTo32ByteArray(publicKey)
+	if err != nil {
+		return wg.Stats{}, err
+	}
+
 	peers, err := c.devAPI.Peers()
 	if err != nil {
 		return wg.Stats{}, nil
 	}
 
-	if len(peers) != 1 {
-		return wg.Stats{}, errors.New("exactly 1 peer expected")
+	for _, peer := range peers {
+		if peer.PublicKey == device.NoisePublicKey(key) {
+			return wg.Stats{
+				BytesSent:     peer.Stats.Sent,
+				BytesReceived: peer.Stats.Received,
+				LastHandshake: time.Unix(int64(peer.LastHanshake), 0),
+			}, nil
+		}
 	}
-
-	return wg.Stats{
-		BytesSent:     peers[0].Stats.Sent,
-		BytesReceived: peers[0].Stats.Received,
-		LastHandshake: time.Unix(int64(peers[0].LastHanshake), 0),
-	}, nil
+	return wg.Stats{}, errors.New("peer not found")
 }
 
 func (c *client) DestroyDevice(name string) error {
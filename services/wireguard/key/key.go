@@ -56,3 +56,13 @@ func GeneratePrivateKey() (string, error) {
 
 	return base64.StdEncoding.EncodeToString(randomBytes), nil
 }
+
+// GeneratePresharedKey generates a symmetric key used as an additional layer of peer encryption
+func GeneratePresharedKey() (string, error) {
+	randomBytes := make([]byte, keyLength)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", fmt.Errorf("GeneratePresharedKey failed to read random bytes: %v", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(randomBytes), nil
+}
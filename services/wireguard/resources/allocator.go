@@ -24,43 +24,76 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+
+	"github.com/mysteriumnetwork/node/core/ippool"
 )
 
 const maxResources = 255
 
+// SubnetPrefix is the size of the subnet allocated for every wireguard connection
+const SubnetPrefix = 24
+
+var errIPPoolNotConfigured = errors.New("IP address pool is not configured")
+
 // Allocator is mock wireguard resource handler.
 // It will manage lists of network interfaces names, IP addresses and port for endpoints.
 type Allocator struct {
-	Ifaces      map[int]struct{}
-	IPAddresses map[int]struct{}
-	Ports       map[int]struct{}
-	mu          sync.Mutex
+	Ifaces map[int]struct{}
+	Ports  map[int]struct{}
+	ipPool *ippool.Pool
+	mu     sync.Mutex
+
+	ifacePrefix string
+	// ownsPrefix tells whether every interface with ifacePrefix belongs to the owner of the allocator
+	ownsPrefix bool
 }
 
 // NewAllocator creates new resource pool for wireguard connection.
-func NewAllocator() Allocator {
+// IP addresses are allocated from the given pool, consumers which never allocate addresses may pass nil.
+func NewAllocator(ipPool *ippool.Pool) Allocator {
 	return Allocator{
-		Ifaces:      make(map[int]struct{}),
-		IPAddresses: make(map[int]struct{}),
-		Ports:       make(map[int]struct{}),
+		Ifaces: make(map[int]struct{}),
+		Ports:  make(map[int]struct{}),
+		ipPool: ipPool,
+
+		ifacePrefix: interfacePrefix,
+		ownsPrefix:  true,
 	}
 }
 
+// NewProviderAllocator creates new resource pool for the provider side of wireguard connections.
+// Provider interfaces are named apart from the consumer ones where the system allows it,
+// otherwise no interfaces are reported as abandoned.
+func NewProviderAllocator(ipPool *ippool.Pool) Allocator {
+	allocator := NewAllocator(ipPool)
+	allocator.ifacePrefix = providerInterfacePrefix
+	allocator.ownsPrefix = providerOwnsPrefix
+	return allocator
+}
+
 // AbandonedInterfaces returns a list of abandoned interfaces that exist in the system,
 // but was not allocated by the Allocator.
 func (a *Allocator) AbandonedInterfaces() ([]net.Interface, error) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-
 	ifaces, err := net.Interfaces()
 	if err != nil {
 		return nil, err
 	}
 
+	return a.abandonedInterfaces(ifaces), nil
+}
+
+func (a *Allocator) abandonedInterfaces(ifaces []net.Interface) []net.Interface {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
 	list := make([]net.Interface, 0)
+	if !a.ownsPrefix {
+		return list
+	}
+
 	for _, iface := range ifaces {
-		if strings.HasPrefix(iface.Name, interfacePrefix) {
-			ifaceID, err := strconv.Atoi(strings.TrimPrefix(iface.Name, interfacePrefix))
+		if strings.HasPrefix(iface.Name, a.ifacePrefix) {
+			ifaceID, err := strconv.Atoi(strings.TrimPrefix(iface.Name, a.ifacePrefix))
 			if err == nil {
 				if _, ok := a.Ifaces[ifaceID]; !ok {
 					list = append(list, iface)
@@ -69,7 +102,7 @@ func (a *Allocator) AbandonedInterfaces() ([]net.Interface, error) {
 		}
 	}
 
-	return list, nil
+	return list
 }
 
 // AllocateInterface provides available name for the wireguard network interface.
@@ -85,11 +118,11 @@ func (a *Allocator) AllocateInterface() (string, error) {
 	for i := 0; i < maxResources; i++ {
 		if _, ok := a.Ifaces[i]; !ok {
 			a.Ifaces[i] = struct{}{}
-			if interfaceExists(ifaces, fmt.Sprintf("%s%d", interfacePrefix, i)) {
+			if interfaceExists(ifaces, fmt.Sprintf("%s%d", a.ifacePrefix, i)) {
 				continue
 			}
 
-			return fmt.Sprintf("%s%d", interfacePrefix, i), nil
+			return fmt.Sprintf("%s%d", a.ifacePrefix, i), nil
 		}
 	}
 
@@ -98,20 +131,11 @@ func (a *Allocator) AllocateInterface() (string, error) {
 
 // AllocateIPNet provides available IP address for the wireguard connection.
 func (a *Allocator) AllocateIPNet() (net.IPNet, error) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-
-	var s string
-	for i := 0; i < maxResources; i++ {
-		if _, ok := a.IPAddresses[i]; !ok {
-			a.IPAddresses[i] = struct{}{}
-			s = fmt.Sprintf("10.182.%d.0/24", i)
-			break
-		}
+	if a.ipPool == nil {
+		return net.IPNet{}, errIPPoolNotConfigured
 	}
 
-	_, subnet, err := net.ParseCIDR(s)
-	return *subnet, err
+	return a.ipPool.Allocate()
 }
 
 // AllocatePort provides available UDP port for the wireguard endpoint.
@@ -134,7 +158,7 @@ func (a *Allocator) ReleaseInterface(iface string) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	i, err := strconv.Atoi(strings.TrimPrefix(iface, interfacePrefix))
+	i, err := strconv.Atoi(strings.TrimPrefix(iface, a.ifacePrefix))
 	if err != nil {
 		return err
 	}
@@ -149,21 +173,11 @@ func (a *Allocator) ReleaseInterface(iface string) error {
 
 // ReleaseIPNet releases IP address.
 func (a *Allocator) ReleaseIPNet(ipnet net.IPNet) error {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-
-	ip4 := ipnet.IP.To4()
-	if len(ip4) != net.IPv4len {
-		return errors.New("allocated subnet not found")
+	if a.ipPool == nil {
+		return errIPPoolNotConfigured
 	}
 
-	i := int(ip4[2])
-	if _, ok := a.IPAddresses[i]; !ok {
-		return errors.New("allocated subnet not found")
-	}
-
-	delete(a.IPAddresses, i)
-	return nil
+	return a.ipPool.Release(ipnet)
 }
 
 // ReleasePort releases UDP port.
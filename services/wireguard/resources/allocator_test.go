@@ -0,0 +1,62 @@
+// +build !darwin
+
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package resources
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var systemInterfaces = []net.Interface{
+	{Name: "eth0"},
+	{Name: "myst0"},
+	{Name: "myst1"},
+	{Name: "mystp0"},
+	{Name: "mystp1"},
+}
+
+func Test_Allocator_AbandonedInterfacesSkipsProviderInterfaces(t *testing.T) {
+	allocator := NewAllocator(nil)
+	iface, err := allocator.AllocateInterface()
+	assert.NoError(t, err)
+	assert.Equal(t, "myst0", iface)
+
+	assert.Equal(t, []net.Interface{{Name: "myst1"}}, allocator.abandonedInterfaces(systemInterfaces))
+}
+
+func Test_ProviderAllocator_AbandonedInterfacesSkipsConsumerInterfaces(t *testing.T) {
+	allocator := NewProviderAllocator(nil)
+	iface, err := allocator.AllocateInterface()
+	assert.NoError(t, err)
+	assert.Equal(t, "mystp0", iface)
+
+	assert.Equal(t, []net.Interface{{Name: "mystp1"}}, allocator.abandonedInterfaces(systemInterfaces))
+}
+
+func Test_ProviderAllocator_ReleasesInterface(t *testing.T) {
+	allocator := NewProviderAllocator(nil)
+	iface, err := allocator.AllocateInterface()
+	assert.NoError(t, err)
+
+	assert.NoError(t, allocator.ReleaseInterface(iface))
+	assert.Empty(t, allocator.Ifaces)
+}
@@ -20,3 +20,9 @@
 package resources
 
 const interfacePrefix = "myst"
+
+// providerInterfacePrefix keeps the provider interfaces apart from the consumer ones,
+// so that each side destroys only its own abandoned interfaces
+const providerInterfacePrefix = "mystp"
+
+const providerOwnsPrefix = true
@@ -18,3 +18,9 @@
 package resources
 
 const interfacePrefix = "utun"
+
+// system accepts utun prefix only, so the provider interfaces can not be told apart
+// from the consumer and other applications interfaces
+const providerInterfacePrefix = interfacePrefix
+
+const providerOwnsPrefix = false
@@ -0,0 +1,60 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/nat"
+	"github.com/pkg/errors"
+)
+
+// CleanupReport describes abandoned resources removed by the wireguard service
+type CleanupReport struct {
+	Interfaces []string
+	NATRules   []nat.RuleForwarding
+}
+
+// Cleanup removes wireguard interfaces left behind by previous runs together with their NAT forwarding rules.
+// Addresses and routes of the interfaces are removed by the system along with the interfaces.
+func (manager *Manager) Cleanup() (CleanupReport, error) {
+	report := CleanupReport{
+		Interfaces: make([]string, 0),
+		NATRules:   make([]nat.RuleForwarding, 0),
+	}
+
+	removed, err := manager.cleanAbandonedInterfaces()
+	if err != nil {
+		return report, errors.Wrap(err, "failed to clean abandoned interfaces")
+	}
+
+	for _, iface := range removed {
+		report.Interfaces = append(report.Interfaces, iface.Name)
+
+		for _, subnet := range iface.Subnets {
+			rule := nat.RuleForwarding{SourceAddress: subnet.String(), TargetIP: manager.outboundIP}
+			if err := manager.natService.Del(rule); err != nil {
+				log.Debug(logPrefix, "no NAT forwarding rule removed for abandoned subnet ", subnet.String(), ": ", err)
+				continue
+			}
+			report.NATRules = append(report.NATRules, rule)
+		}
+	}
+
+	log.Info(logPrefix, "cleanup removed ", len(report.Interfaces), " abandoned interfaces and ", len(report.NATRules), " NAT forwarding rules")
+	return report, nil
+}
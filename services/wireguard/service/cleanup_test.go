@@ -0,0 +1,90 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/nat"
+	"github.com/mysteriumnetwork/node/services/wireguard/endpoint"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Manager_Cleanup_RemovesInterfacesAndNATRules(t *testing.T) {
+	natService := &natServiceRecorder{failFor: "10.182.2.0/24"}
+	manager := newManagerStub(pubIP, outIP, country)
+	manager.natService = natService
+	manager.cleanAbandonedInterfaces = func() ([]endpoint.RemovedInterface, error) {
+		return []endpoint.RemovedInterface{
+			{Name: "myst0", Subnets: []net.IPNet{{IP: net.IPv4(10, 182, 1, 0).To4(), Mask: net.CIDRMask(24, 32)}}},
+			{Name: "myst1", Subnets: []net.IPNet{{IP: net.IPv4(10, 182, 2, 0).To4(), Mask: net.CIDRMask(24, 32)}}},
+		}, nil
+	}
+
+	report, err := manager.Cleanup()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"myst0", "myst1"}, report.Interfaces)
+	assert.Equal(t, []nat.RuleForwarding{{SourceAddress: "10.182.1.0/24", TargetIP: outIP}}, report.NATRules)
+	assert.Len(t, natService.deleted, 2)
+}
+
+func Test_Manager_Cleanup_ReturnsError(t *testing.T) {
+	manager := newManagerStub(pubIP, outIP, country)
+	manager.cleanAbandonedInterfaces = func() ([]endpoint.RemovedInterface, error) {
+		return nil, errors.New("no interfaces")
+	}
+
+	report, err := manager.Cleanup()
+	assert.EqualError(t, err, "failed to clean abandoned interfaces: no interfaces")
+	assert.Empty(t, report.Interfaces)
+}
+
+func Test_Manager_SelectSubnet_CleansUpAbandonedInterfacesFirst(t *testing.T) {
+	var calls []string
+	manager := newManagerStub(pubIP, outIP, country)
+	manager.cleanAbandonedInterfaces = func() ([]endpoint.RemovedInterface, error) {
+		calls = append(calls, "cleanup")
+		return nil, nil
+	}
+	defer func(original func(string, []string) (net.IPNet, error)) { selectNetwork = original }(selectNetwork)
+	selectNetwork = func(configured string, candidates []string) (net.IPNet, error) {
+		calls = append(calls, "select")
+		return net.IPNet{IP: net.IPv4(10, 182, 0, 0).To4(), Mask: net.CIDRMask(16, 32)}, nil
+	}
+
+	subnet, err := manager.selectSubnet("")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.182.0.0/16", subnet.String())
+	assert.Equal(t, []string{"cleanup", "select"}, calls)
+}
+
+type natServiceRecorder struct {
+	serviceFake
+	failFor string
+	deleted []nat.RuleForwarding
+}
+
+func (service *natServiceRecorder) Del(rule nat.RuleForwarding) error {
+	service.deleted = append(service.deleted, rule)
+	if rule.SourceAddress == service.failFor {
+		return errors.New("rule not found")
+	}
+	return nil
+}
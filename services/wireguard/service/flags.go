@@ -18,13 +18,82 @@
 package service
 
 import (
+	"time"
+
 	"github.com/mysteriumnetwork/node/core/service"
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/money"
+	dto_openvpn "github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/pkg/errors"
 	"github.com/urfave/cli"
 )
 
 // Options describes options which are required to start Wireguard service
 type Options struct {
 	ConnectDelay int
+	Subnet       string
+	PeerTimeout  time.Duration
+	KeyRotation  time.Duration
+	PresharedKey bool
+
+	PricePerGB    float64
+	PriceCurrency string
+	PriceRounding string
+	MinimumCharge float64
+	FreeMB        int
+	FreePeriod    time.Duration
+}
+
+// Payment returns the price of the service, which is both advertised and charged
+func (options Options) Payment() dto_openvpn.PaymentPerBytes {
+	currency, _ := money.ParseCurrency(options.PriceCurrency)
+	minimumCharge, _ := money.NewMoney(options.MinimumCharge, currency).Uint64()
+	return dto_openvpn.PaymentPerBytes{
+		Price:         money.NewMoney(options.PricePerGB, currency),
+		Bytes:         datasize.GB,
+		Rounding:      options.PriceRounding,
+		MinimumCharge: minimumCharge,
+	}
+}
+
+// FreeCredit returns the free data offered to every consumer, nil if there is none
+func (options Options) FreeCredit() *market.FreeCredit {
+	if options.FreeMB == 0 {
+		return nil
+	}
+	return &market.FreeCredit{
+		Bytes:  datasize.BitSize(options.FreeMB) * datasize.MB,
+		Period: options.FreePeriod,
+	}
+}
+
+// Validate checks that the service price and free data are valid
+func (options Options) Validate() error {
+	if options.PricePerGB < 0 {
+		return errors.Errorf("wireguard price can not be negative: %v", options.PricePerGB)
+	}
+	if options.FreeMB < 0 {
+		return errors.Errorf("wireguard free data can not be negative: %v", options.FreeMB)
+	}
+	if options.FreePeriod < 0 {
+		return errors.Errorf("wireguard free period can not be negative: %v", options.FreePeriod)
+	}
+	if _, err := session.ParseRounding(options.PriceRounding); err != nil {
+		return err
+	}
+	if options.MinimumCharge < 0 {
+		return errors.Errorf("wireguard minimum charge can not be negative: %v", options.MinimumCharge)
+	}
+	currency, err := money.ParseCurrency(options.PriceCurrency)
+	if err != nil {
+		return err
+	}
+	if _, err := money.NewMoney(options.MinimumCharge, currency).Uint64(); err != nil {
+		return errors.Wrapf(err, "wireguard minimum charge is too large: %v", options.MinimumCharge)
+	}
+	return nil
 }
 
 var (
@@ -33,16 +102,71 @@ var (
 		Usage: "Consumer is delayed by specified time (2000 millisec default) if provider is behind NAT",
 		Value: 2000,
 	}
+	subnetFlag = cli.StringFlag{
+		Name:  "wireguard.subnet",
+		Usage: "Subnet from which wireguard consumer addresses are allocated (e.g. 10.182.0.0/16). Free subnet is selected automatically if not set",
+	}
+	peerTimeoutFlag = cli.DurationFlag{
+		Name:  "wireguard.peer.timeout",
+		Usage: "Session is destroyed when consumer peer does not handshake for the specified time",
+		Value: 5 * time.Minute,
+	}
+	keyRotationFlag = cli.DurationFlag{
+		Name:  "wireguard.key.rotation",
+		Usage: "Consumers are asked to rotate their keys periodically with the specified period (e.g. 1h). Rotation is disabled if not set",
+	}
+	presharedKeyFlag = cli.BoolFlag{
+		Name:  "wireguard.psk",
+		Usage: "Generate a preshared key for every session as an additional layer of symmetric encryption",
+	}
+	pricePerGBFlag = cli.Float64Flag{
+		Name:  "wireguard.price-per-gb",
+		Usage: "Price charged for every gigabyte transferred through Wireguard session",
+	}
+	priceCurrencyFlag = cli.StringFlag{
+		Name:  "wireguard.price-currency",
+		Usage: "Currency of Wireguard service price",
+		Value: string(money.CURRENCY_MYST),
+	}
+	priceRoundingFlag = cli.StringFlag{
+		Name:  "wireguard.price-rounding",
+		Usage: "Rounding of the price charged for a started gigabyte of Wireguard session. Options: { down, up, nearest }",
+		Value: "down",
+	}
+	minimumChargeFlag = cli.Float64Flag{
+		Name:  "wireguard.minimum-charge",
+		Usage: "Least amount charged for Wireguard session, in currency of the price",
+	}
+	freeMBFlag = cli.IntFlag{
+		Name:  "wireguard.free-mb",
+		Usage: "Megabytes transferred through Wireguard session given for free to every consumer identity per free period",
+	}
+	freePeriodFlag = cli.DurationFlag{
+		Name:  "wireguard.free-period",
+		Usage: "Period after which free data of consumer is renewed (e.g. 24h). Free data is given only once if set to 0",
+		Value: 24 * time.Hour,
+	}
 )
 
 // RegisterFlags function register Wireguard flags to flag list
 func RegisterFlags(flags *[]cli.Flag) {
-	*flags = append(*flags, delayFlag)
+	*flags = append(*flags, delayFlag, subnetFlag, peerTimeoutFlag, keyRotationFlag, presharedKeyFlag, pricePerGBFlag, priceCurrencyFlag, priceRoundingFlag, minimumChargeFlag, freeMBFlag, freePeriodFlag)
 }
 
 // ParseFlags function fills in Wireguard options from CLI context
 func ParseFlags(ctx *cli.Context) service.Options {
 	return Options{
 		ConnectDelay: ctx.Int(delayFlag.Name),
+		Subnet:       ctx.String(subnetFlag.Name),
+		PeerTimeout:  ctx.Duration(peerTimeoutFlag.Name),
+		KeyRotation:  ctx.Duration(keyRotationFlag.Name),
+		PresharedKey: ctx.Bool(presharedKeyFlag.Name),
+
+		PricePerGB:    ctx.Float64(pricePerGBFlag.Name),
+		PriceCurrency: ctx.String(priceCurrencyFlag.Name),
+		PriceRounding: ctx.String(priceRoundingFlag.Name),
+		MinimumCharge: ctx.Float64(minimumChargeFlag.Name),
+		FreeMB:        ctx.Int(freeMBFlag.Name),
+		FreePeriod:    ctx.Duration(freePeriodFlag.Name),
 	}
 }
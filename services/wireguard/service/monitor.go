@@ -0,0 +1,168 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	wg "github.com/mysteriumnetwork/node/services/wireguard"
+	"github.com/mysteriumnetwork/node/session"
+)
+
+const defaultStatsInterval = 30 * time.Second
+
+// Publisher is responsible for publishing given events
+type Publisher interface {
+	Publish(topic string, args ...interface{})
+}
+
+// SessionStorage allows the service to end sessions whose peers went away
+type SessionStorage interface {
+	Remove(id session.ID)
+}
+
+type peerStatsProvider interface {
+	PeerStats() (wg.Stats, error)
+}
+
+type monitoredPeer struct {
+	endpoint peerStatsProvider
+	added    time.Time
+	replaced time.Time
+}
+
+// peerMonitor polls the statistics of every session peer, publishes them
+// and destroys the sessions whose peer did not handshake for longer than peerTimeout
+type peerMonitor struct {
+	publisher   Publisher
+	sessions    SessionStorage
+	peerTimeout time.Duration
+	interval    time.Duration
+	now         func() time.Time
+
+	peers map[session.ID]monitoredPeer
+	lock  sync.Mutex
+	stop  chan struct{}
+	once  sync.Once
+}
+
+func newPeerMonitor(publisher Publisher, sessions SessionStorage, peerTimeout time.Duration) *peerMonitor {
+	return &peerMonitor{
+		publisher:   publisher,
+		sessions:    sessions,
+		peerTimeout: peerTimeout,
+		interval:    defaultStatsInterval,
+		now:         time.Now,
+		peers:       make(map[session.ID]monitoredPeer),
+		stop:        make(chan struct{}),
+	}
+}
+
+// Add starts monitoring of the given session peer
+func (pm *peerMonitor) Add(sessionID session.ID, endpoint peerStatsProvider) {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+
+	pm.peers[sessionID] = monitoredPeer{endpoint: endpoint, added: pm.now()}
+}
+
+// PeerReplaced restarts the handshake wait of the given session peer, as the replaced peer starts without a handshake
+func (pm *peerMonitor) PeerReplaced(sessionID session.ID) {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+
+	if peer, ok := pm.peers[sessionID]; ok {
+		peer.replaced = pm.now()
+		pm.peers[sessionID] = peer
+	}
+}
+
+// Remove stops monitoring of the given session peer
+func (pm *peerMonitor) Remove(sessionID session.ID) {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+
+	delete(pm.peers, sessionID)
+}
+
+// Start polls the peers until the monitor is stopped - does block
+func (pm *peerMonitor) Start() {
+	ticker := time.NewTicker(pm.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-pm.stop:
+			return
+		case <-ticker.C:
+			pm.checkPeers()
+		}
+	}
+}
+
+// Stop stops polling of the peers
+func (pm *peerMonitor) Stop() {
+	pm.once.Do(func() {
+		close(pm.stop)
+	})
+}
+
+func (pm *peerMonitor) checkPeers() {
+	pm.lock.Lock()
+	peers := make(map[session.ID]monitoredPeer, len(pm.peers))
+	for sessionID, peer := range pm.peers {
+		peers[sessionID] = peer
+	}
+	pm.lock.Unlock()
+
+	for sessionID, peer := range peers {
+		stats, err := peer.endpoint.PeerStats()
+		if err != nil {
+			log.Warn(logPrefix, "failed to get peer stats for session ", sessionID, ": ", err)
+			continue
+		}
+
+		pm.publisher.Publish(session.StatisticsEventTopic, session.StatisticsEvent{
+			SessionID: sessionID,
+			Statistics: session.DataTransfer{
+				BytesSent:     stats.BytesSent,
+				BytesReceived: stats.BytesReceived,
+			},
+		})
+
+		if pm.isDead(peer, stats) {
+			log.Info(logPrefix, "peer did not handshake for ", pm.peerTimeout, ", destroying session ", sessionID)
+			pm.Remove(sessionID)
+			pm.sessions.Remove(sessionID)
+		}
+	}
+}
+
+func (pm *peerMonitor) isDead(peer monitoredPeer, stats wg.Stats) bool {
+	lastSeen := stats.LastHandshake
+	if lastSeen.Unix() <= 0 {
+		lastSeen = peer.added
+	}
+	// handshakes of the peer before its key was replaced do not count for the new key
+	if lastSeen.Before(peer.replaced) {
+		lastSeen = peer.replaced
+	}
+	return pm.now().Sub(lastSeen) > pm.peerTimeout
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	wg "github.com/mysteriumnetwork/node/services/wireguard"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/stretchr/testify/assert"
+)
+
+var monitorNow = time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func Test_PeerMonitor_PublishesStatistics(t *testing.T) {
+	publisher := &publisherFake{}
+	sessions := &sessionStorageFake{}
+	monitor := newMonitorStub(publisher, sessions)
+
+	monitor.Add("session-1", &statsProviderFake{stats: wg.Stats{BytesSent: 1, BytesReceived: 2, LastHandshake: monitorNow}})
+	monitor.checkPeers()
+
+	assert.Equal(t, []session.StatisticsEvent{
+		{SessionID: "session-1", Statistics: session.DataTransfer{BytesSent: 1, BytesReceived: 2}},
+	}, publisher.events)
+	assert.Empty(t, sessions.removed)
+}
+
+func Test_PeerMonitor_DestroysDeadPeerSession(t *testing.T) {
+	publisher := &publisherFake{}
+	sessions := &sessionStorageFake{}
+	monitor := newMonitorStub(publisher, sessions)
+
+	monitor.Add("alive", &statsProviderFake{stats: wg.Stats{LastHandshake: monitorNow.Add(-time.Minute)}})
+	monitor.Add("dead", &statsProviderFake{stats: wg.Stats{LastHandshake: monitorNow.Add(-3 * time.Minute)}})
+	monitor.checkPeers()
+
+	assert.Equal(t, []session.ID{"dead"}, sessions.removed)
+	assert.Len(t, monitor.peers, 1)
+	assert.Contains(t, monitor.peers, session.ID("alive"))
+}
+
+func Test_PeerMonitor_UsesAddTimeBeforeFirstHandshake(t *testing.T) {
+	sessions := &sessionStorageFake{}
+	monitor := newMonitorStub(&publisherFake{}, sessions)
+
+	monitor.Add("new", &statsProviderFake{stats: wg.Stats{LastHandshake: time.Unix(0, 0)}})
+	monitor.checkPeers()
+	assert.Empty(t, sessions.removed)
+
+	monitor.now = func() time.Time { return monitorNow.Add(3 * time.Minute) }
+	monitor.checkPeers()
+	assert.Equal(t, []session.ID{"new"}, sessions.removed)
+}
+
+func Test_PeerMonitor_WaitsForHandshakeOfReplacedPeer(t *testing.T) {
+	sessions := &sessionStorageFake{}
+	monitor := newMonitorStub(&publisherFake{}, sessions)
+
+	peer := &statsProviderFake{stats: wg.Stats{LastHandshake: monitorNow}}
+	monitor.Add("rotated", peer)
+
+	// the key is replaced long after the session start, new peer has not handshaked yet
+	monitor.now = func() time.Time { return monitorNow.Add(time.Hour) }
+	peer.stats = wg.Stats{}
+	monitor.PeerReplaced("rotated")
+	monitor.checkPeers()
+	assert.Empty(t, sessions.removed)
+
+	monitor.now = func() time.Time { return monitorNow.Add(time.Hour + 3*time.Minute) }
+	monitor.checkPeers()
+	assert.Equal(t, []session.ID{"rotated"}, sessions.removed)
+}
+
+func Test_PeerMonitor_IgnoresHandshakeOfPeerBeforeReplacement(t *testing.T) {
+	sessions := &sessionStorageFake{}
+	monitor := newMonitorStub(&publisherFake{}, sessions)
+
+	peer := &statsProviderFake{stats: wg.Stats{LastHandshake: monitorNow}}
+	monitor.Add("rotated", peer)
+	monitor.now = func() time.Time { return monitorNow.Add(time.Hour) }
+	monitor.PeerReplaced("rotated")
+
+	monitor.now = func() time.Time { return monitorNow.Add(time.Hour + 3*time.Minute) }
+	monitor.checkPeers()
+	assert.Equal(t, []session.ID{"rotated"}, sessions.removed)
+}
+
+func Test_PeerMonitor_PeerReplacedIgnoresUnknownSession(t *testing.T) {
+	monitor := newMonitorStub(&publisherFake{}, &sessionStorageFake{})
+
+	monitor.PeerReplaced("unknown")
+	assert.Empty(t, monitor.peers)
+}
+
+func Test_PeerMonitor_SkipsPeerOnStatsError(t *testing.T) {
+	publisher := &publisherFake{}
+	sessions := &sessionStorageFake{}
+	monitor := newMonitorStub(publisher, sessions)
+	monitor.now = func() time.Time { return monitorNow.Add(time.Hour) }
+
+	monitor.Add("broken", &statsProviderFake{err: errors.New("no device")})
+	monitor.checkPeers()
+
+	assert.Empty(t, publisher.events)
+	assert.Empty(t, sessions.removed)
+}
+
+func Test_PeerMonitor_StopsPolling(t *testing.T) {
+	monitor := newMonitorStub(&publisherFake{}, &sessionStorageFake{})
+
+	done := make(chan struct{})
+	go func() {
+		monitor.Start()
+		close(done)
+	}()
+
+	monitor.Stop()
+	monitor.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("monitor did not stop")
+	}
+}
+
+func newMonitorStub(publisher Publisher, sessions SessionStorage) *peerMonitor {
+	monitor := newPeerMonitor(publisher, sessions, 2*time.Minute)
+	monitor.now = func() time.Time { return monitorNow }
+	return monitor
+}
+
+type statsProviderFake struct {
+	stats wg.Stats
+	err   error
+}
+
+func (spf *statsProviderFake) PeerStats() (wg.Stats, error) {
+	return spf.stats, spf.err
+}
+
+type publisherFake struct {
+	events []session.StatisticsEvent
+}
+
+func (pf *publisherFake) Publish(topic string, args ...interface{}) {
+	pf.events = append(pf.events, args[0].(session.StatisticsEvent))
+}
+
+type sessionStorageFake struct {
+	removed []session.ID
+}
+
+func (ssf *sessionStorageFake) Remove(id session.ID) {
+	ssf.removed = append(ssf.removed, id)
+}
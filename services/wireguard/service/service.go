@@ -19,15 +19,17 @@ package service
 
 import (
 	"encoding/json"
+	"net"
 	"sync"
 
+	"github.com/mysteriumnetwork/node/core/ippool"
 	"github.com/mysteriumnetwork/node/core/location"
 
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
-	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/nat"
+	dto_openvpn "github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
 	wg "github.com/mysteriumnetwork/node/services/wireguard"
 	"github.com/mysteriumnetwork/node/services/wireguard/endpoint"
 	"github.com/mysteriumnetwork/node/services/wireguard/resources"
@@ -37,33 +39,81 @@ import (
 
 const logPrefix = "[service-wireguard] "
 
+// defaultSubnets are tried in order when the subnet is not configured explicitly
+var defaultSubnets = []string{"10.182.0.0/16", "10.183.0.0/16", "172.27.0.0/16", "100.80.0.0/16"}
+
 // NewManager creates new instance of Wireguard service
 func NewManager(
 	location location.ServiceLocationInfo,
 	natService nat.NATService,
 	portMap func(port int) (releasePortMapping func()),
-	options Options) *Manager {
+	options Options,
+	eventPublisher Publisher,
+	sessions SessionStorage,
+	modeSelector *endpoint.ModeSelector) (*Manager, error) {
 
-	resourceAllocator := resources.NewAllocator()
-	return &Manager{
-		natService: natService,
+	mode, err := modeSelector.Mode()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select wireguard mode")
+	}
+
+	manager := &Manager{
+		natService:  natService,
+		peerMonitor: newPeerMonitor(eventPublisher, sessions, options.PeerTimeout),
+		endpoints:   make(map[session.ID]wg.ConnectionEndpoint),
 
 		publicIP:        location.PubIP,
 		outboundIP:      location.OutIP,
 		currentLocation: location.OutIP,
 
-		connectionEndpointFactory: func() (wg.ConnectionEndpoint, error) {
-			return endpoint.NewConnectionEndpoint(location, &resourceAllocator, portMap, options.ConnectDelay)
-		},
+		keyRotationPeriod: int(options.KeyRotation.Seconds()),
+	}
+
+	startupAllocator := resources.NewProviderAllocator(nil)
+	manager.cleanAbandonedInterfaces = func() ([]endpoint.RemovedInterface, error) {
+		return endpoint.CleanAbandonedInterfaces(&startupAllocator)
+	}
+	subnet, err := manager.selectSubnet(options.Subnet)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select wireguard subnet")
 	}
+	log.Info(logPrefix, "Using subnet for wireguard connections: ", subnet.String())
+
+	resourceAllocator := resources.NewProviderAllocator(ippool.NewPool(subnet, resources.SubnetPrefix))
+	manager.connectionEndpointFactory = func(presharedKeySupported bool) (wg.ConnectionEndpoint, error) {
+		// preshared key is only given to consumers able to use it, others would fail to connect
+		usePresharedKey := options.PresharedKey && presharedKeySupported
+		return endpoint.NewConnectionEndpoint(location, &resourceAllocator, portMap, options.ConnectDelay, usePresharedKey, mode)
+	}
+	manager.cleanAbandonedInterfaces = func() ([]endpoint.RemovedInterface, error) {
+		return endpoint.CleanAbandonedInterfaces(&resourceAllocator)
+	}
+	return manager, nil
+}
+
+// selectNetwork picks the subnet not used by the host, replaceable in tests
+var selectNetwork = ippool.SelectNetwork
+
+// selectSubnet cleans up the interfaces left by previous runs first, as they would still hold their subnets
+func (manager *Manager) selectSubnet(configured string) (net.IPNet, error) {
+	if _, err := manager.Cleanup(); err != nil {
+		log.Warn(logPrefix, "startup cleanup failed: ", err)
+	}
+	return selectNetwork(configured, defaultSubnets)
 }
 
 // Manager represents an instance of Wireguard service
 type Manager struct {
-	wg         sync.WaitGroup
-	natService nat.NATService
+	wg          sync.WaitGroup
+	natService  nat.NATService
+	peerMonitor *peerMonitor
 
-	connectionEndpointFactory func() (wg.ConnectionEndpoint, error)
+	connectionEndpointFactory func(presharedKeySupported bool) (wg.ConnectionEndpoint, error)
+	keyRotationPeriod         int
+	cleanAbandonedInterfaces  func() ([]endpoint.RemovedInterface, error)
+
+	endpoints     map[session.ID]wg.ConnectionEndpoint
+	endpointsLock sync.Mutex
 
 	publicIP        string
 	outboundIP      string
@@ -71,14 +121,14 @@ type Manager struct {
 }
 
 // ProvideConfig provides the config for consumer
-func (manager *Manager) ProvideConfig(publicKey json.RawMessage) (session.ServiceConfiguration, session.DestroyCallback, error) {
+func (manager *Manager) ProvideConfig(sessionID session.ID, publicKey json.RawMessage) (session.ServiceConfiguration, session.DestroyCallback, error) {
 	key := &wg.ConsumerConfig{}
 	err := json.Unmarshal(publicKey, key)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	connectionEndpoint, err := manager.connectionEndpointFactory()
+	connectionEndpoint, err := manager.connectionEndpointFactory(key.PresharedKeySupported)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -95,13 +145,19 @@ func (manager *Manager) ProvideConfig(publicKey json.RawMessage) (session.Servic
 	if err != nil {
 		return nil, nil, err
 	}
+	config.Consumer.KeyRotationPeriod = manager.keyRotationPeriod
 
 	natRule := nat.RuleForwarding{SourceAddress: config.Consumer.IPAddress.String(), TargetIP: manager.outboundIP}
 	if err := manager.natService.Add(natRule); err != nil {
 		return nil, nil, errors.Wrap(err, "failed to add NAT forwarding rule")
 	}
 
+	manager.peerMonitor.Add(sessionID, connectionEndpoint)
+	manager.addEndpoint(sessionID, connectionEndpoint)
+
 	destroy := func() {
+		manager.peerMonitor.Remove(sessionID)
+		manager.removeEndpoint(sessionID)
 		if err := manager.natService.Del(natRule); err != nil {
 			log.Error(logPrefix, "failed to delete NAT forwarding rule: ", err)
 		}
@@ -113,9 +169,48 @@ func (manager *Manager) ProvideConfig(publicKey json.RawMessage) (session.Servic
 	return config, destroy, nil
 }
 
+// UpdateConfig replaces the consumer key of the running session
+func (manager *Manager) UpdateConfig(sessionID session.ID, publicKey json.RawMessage) error {
+	key := &wg.ConsumerConfig{}
+	if err := json.Unmarshal(publicKey, key); err != nil {
+		return err
+	}
+
+	manager.endpointsLock.Lock()
+	defer manager.endpointsLock.Unlock()
+
+	connectionEndpoint, ok := manager.endpoints[sessionID]
+	if !ok {
+		return errors.Errorf("no connection endpoint for session %s", sessionID)
+	}
+
+	if err := connectionEndpoint.ReplacePeer(key.PublicKey); err != nil {
+		return errors.Wrap(err, "failed to replace consumer key")
+	}
+	manager.peerMonitor.PeerReplaced(sessionID)
+
+	log.Info(logPrefix, "consumer key updated for session ", sessionID)
+	return nil
+}
+
+func (manager *Manager) addEndpoint(sessionID session.ID, connectionEndpoint wg.ConnectionEndpoint) {
+	manager.endpointsLock.Lock()
+	defer manager.endpointsLock.Unlock()
+
+	manager.endpoints[sessionID] = connectionEndpoint
+}
+
+func (manager *Manager) removeEndpoint(sessionID session.ID) {
+	manager.endpointsLock.Lock()
+	defer manager.endpointsLock.Unlock()
+
+	delete(manager.endpoints, sessionID)
+}
+
 // Serve starts service - does block
 func (manager *Manager) Serve(providerID identity.Identity) error {
 	manager.wg.Add(1)
+	go manager.peerMonitor.Start()
 	log.Info(logPrefix, "Wireguard service started successfully")
 
 	manager.wg.Wait()
@@ -123,22 +218,21 @@ func (manager *Manager) Serve(providerID identity.Identity) error {
 }
 
 // GetProposal returns the proposal for wireguard service
-func GetProposal(country string) market.ServiceProposal {
+func GetProposal(location market.Location, payment dto_openvpn.PaymentPerBytes) market.ServiceProposal {
 	return market.ServiceProposal{
 		ServiceType: wg.ServiceType,
 		ServiceDefinition: wg.ServiceDefinition{
-			Location:          market.Location{Country: country},
-			LocationOriginate: market.Location{Country: country},
-		},
-		PaymentMethodType: wg.PaymentMethod,
-		PaymentMethod: wg.Payment{
-			Price: money.NewMoney(0, money.CURRENCY_MYST),
+			Location:          location,
+			LocationOriginate: location,
 		},
+		PaymentMethodType: dto_openvpn.PaymentMethodPerBytes,
+		PaymentMethod:     payment,
 	}
 }
 
 // Stop stops service.
 func (manager *Manager) Stop() error {
+	manager.peerMonitor.Stop()
 	manager.wg.Done()
 
 	log.Info(logPrefix, "Wireguard service stopped")
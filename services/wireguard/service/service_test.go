@@ -23,11 +23,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mysteriumnetwork/node/datasize"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/nat"
+	dto_openvpn "github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
 	wg "github.com/mysteriumnetwork/node/services/wireguard"
+	"github.com/mysteriumnetwork/node/services/wireguard/endpoint"
+	"github.com/mysteriumnetwork/node/session"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -49,18 +53,38 @@ func Test_GetProposal(t *testing.T) {
 				Location:          market.Location{Country: country},
 				LocationOriginate: market.Location{Country: country},
 			},
-			PaymentMethodType: "WG",
-			PaymentMethod: wg.Payment{
-				Price: money.Money{
-					Amount:   0,
-					Currency: money.Currency("MYST"),
-				},
+			PaymentMethodType: "PER_BYTES",
+			PaymentMethod: dto_openvpn.PaymentPerBytes{
+				Price: money.NewUnits(50000000, money.Currency("MYST")),
+				Bytes: datasize.GB,
 			},
 		},
-		GetProposal(country),
+		GetProposal(market.Location{Country: country}, Options{PricePerGB: 0.5, PriceCurrency: "MYST"}.Payment()),
 	)
 }
 
+func Test_OptionsValidate(t *testing.T) {
+	assert.NoError(t, Options{PricePerGB: 0.5, PriceCurrency: "MYST"}.Validate())
+	assert.EqualError(t, Options{PricePerGB: -1}.Validate(), "wireguard price can not be negative: -1")
+	assert.EqualError(t, Options{PriceCurrency: "BTC"}.Validate(), `unsupported currency: "BTC"`)
+	assert.EqualError(t, Options{PriceRounding: "half"}.Validate(), `unsupported rounding: "half"`)
+	assert.EqualError(t, Options{MinimumCharge: -1}.Validate(), "wireguard minimum charge can not be negative: -1")
+}
+
+func Test_OptionsPaymentChargesConfiguredMinimum(t *testing.T) {
+	options := Options{PricePerGB: 0.5, PriceCurrency: "MYST", PriceRounding: "nearest", MinimumCharge: 0.01}
+	assert.NoError(t, options.Validate())
+
+	payment := options.Payment()
+	assert.Equal(t, "nearest", payment.Rounding)
+	assert.Equal(t, uint64(1000000), payment.MinimumCharge)
+
+	calc, err := session.NewTrafficAmountCalc(payment)
+	assert.NoError(t, err)
+	assert.Equal(t, money.NewMoney(0.01, money.CURRENCY_MYST), calc.TotalAmount(datasize.MB))
+	assert.Equal(t, money.NewMoney(0.5, money.CURRENCY_MYST), calc.TotalAmount(datasize.GB))
+}
+
 func Test_Manager_Serve(t *testing.T) {
 	manager := newManagerStub(pubIP, outIP, country)
 
@@ -69,11 +93,44 @@ func Test_Manager_Serve(t *testing.T) {
 		assert.NoError(t, err)
 	}()
 
-	sessionConfig, _, err := manager.ProvideConfig(json.RawMessage(`{"PublicKey": "gZfkZArbw9lqfl4Yzr1Kv3nqGlhe/ynH9KKRbzPFMGk="}`))
+	sessionConfig, _, err := manager.ProvideConfig("session-id", json.RawMessage(`{"PublicKey": "gZfkZArbw9lqfl4Yzr1Kv3nqGlhe/ynH9KKRbzPFMGk="}`))
 	assert.NoError(t, err)
 	assert.NotNil(t, sessionConfig)
 }
 
+func Test_Manager_UpdateConfig(t *testing.T) {
+	manager := newManagerStub(pubIP, outIP, country)
+
+	_, destroy, err := manager.ProvideConfig("session-id", json.RawMessage(`{"PublicKey": "gZfkZArbw9lqfl4Yzr1Kv3nqGlhe/ynH9KKRbzPFMGk="}`))
+	assert.NoError(t, err)
+
+	err = manager.UpdateConfig("session-id", json.RawMessage(`{"PublicKey": "rotated-key"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "rotated-key", connectionEndpointStub.peerPublicKey)
+
+	destroy()
+	err = manager.UpdateConfig("session-id", json.RawMessage(`{"PublicKey": "rotated-key"}`))
+	assert.EqualError(t, err, "no connection endpoint for session session-id")
+}
+
+func Test_Manager_ProvideConfigAsksForPresharedKeyOnlyWhenConsumerSupportsIt(t *testing.T) {
+	manager := newManagerStub(pubIP, outIP, country)
+	var presharedKeyRequests []bool
+	manager.connectionEndpointFactory = func(presharedKeySupported bool) (wg.ConnectionEndpoint, error) {
+		presharedKeyRequests = append(presharedKeyRequests, presharedKeySupported)
+		return connectionEndpointStub, nil
+	}
+
+	_, destroy, err := manager.ProvideConfig("session-id", json.RawMessage(`{"PublicKey": "gZfkZArbw9lqfl4Yzr1Kv3nqGlhe/ynH9KKRbzPFMGk="}`))
+	assert.NoError(t, err)
+	destroy()
+	_, destroy, err = manager.ProvideConfig("session-id", json.RawMessage(`{"PublicKey": "gZfkZArbw9lqfl4Yzr1Kv3nqGlhe/ynH9KKRbzPFMGk=", "PresharedKeySupported": true}`))
+	assert.NoError(t, err)
+	destroy()
+
+	assert.Equal(t, []bool{false, true}, presharedKeyRequests)
+}
+
 func Test_Manager_Stop(t *testing.T) {
 	manager := newManagerStub(pubIP, outIP, country)
 
@@ -92,13 +149,20 @@ func waitABit() {
 	time.Sleep(10 * time.Millisecond)
 }
 
-type fakeConnectionEndpoint struct{}
+type fakeConnectionEndpoint struct {
+	peerPublicKey string
+}
 
 func (fce *fakeConnectionEndpoint) Stop() error                            { return nil }
 func (fce *fakeConnectionEndpoint) Start(_ *wg.ServiceConfig) error        { return nil }
 func (fce *fakeConnectionEndpoint) Config() (wg.ServiceConfig, error)      { return wg.ServiceConfig{}, nil }
 func (fce *fakeConnectionEndpoint) AddPeer(_ string, _ *net.UDPAddr) error { return nil }
 func (fce *fakeConnectionEndpoint) ConfigureRoutes(_ net.IP) error         { return nil }
+func (fce *fakeConnectionEndpoint) SetPrivateKey(_ string) error           { return nil }
+func (fce *fakeConnectionEndpoint) ReplacePeer(publicKey string) error {
+	fce.peerPublicKey = publicKey
+	return nil
+}
 func (fce *fakeConnectionEndpoint) PeerStats() (wg.Stats, error) {
 	return wg.Stats{LastHandshake: time.Now()}, nil
 }
@@ -109,7 +173,12 @@ func newManagerStub(pub, out, country string) *Manager {
 		publicIP:        pub,
 		outboundIP:      out,
 		natService:      &serviceFake{},
-		connectionEndpointFactory: func() (wg.ConnectionEndpoint, error) {
+		peerMonitor:     newPeerMonitor(&publisherFake{}, &sessionStorageFake{}, time.Minute),
+		endpoints:       make(map[session.ID]wg.ConnectionEndpoint),
+		cleanAbandonedInterfaces: func() ([]endpoint.RemovedInterface, error) {
+			return nil, nil
+		},
+		connectionEndpointFactory: func(presharedKeySupported bool) (wg.ConnectionEndpoint, error) {
 			return connectionEndpointStub, nil
 		},
 	}
@@ -62,6 +62,8 @@ func (method Payment) GetPrice() money.Money {
 type ConnectionEndpoint interface {
 	Start(config *ServiceConfig) error
 	AddPeer(publicKey string, endpoint *net.UDPAddr) error
+	ReplacePeer(publicKey string) error
+	SetPrivateKey(privateKey string) error
 	PeerStats() (Stats, error)
 	ConfigureRoutes(ip net.IP) error
 	Config() (ServiceConfig, error)
@@ -78,6 +80,7 @@ type DeviceConfig interface {
 type PeerInfo interface {
 	Endpoint() *net.UDPAddr
 	PublicKey() string
+	PresharedKey() string
 }
 
 // Stats represents wireguard peer statistics information.
@@ -90,6 +93,8 @@ type Stats struct {
 // ConsumerConfig is used for sending the public key from consumer to provider
 type ConsumerConfig struct {
 	PublicKey string
+	// PresharedKeySupported tells provider that consumer is able to use the preshared key, it is not sent otherwise
+	PresharedKeySupported bool `json:",omitempty"`
 }
 
 // ConsumerPrivateKey represents the private part of the consumer key
@@ -100,26 +105,30 @@ type ConsumerPrivateKey struct {
 // ServiceConfig represent a Wireguard service provider configuration that will be passed to the consumer for establishing a connection.
 type ServiceConfig struct {
 	Provider struct {
-		PublicKey string
-		Endpoint  net.UDPAddr
+		PublicKey    string
+		PresharedKey string
+		Endpoint     net.UDPAddr
 	}
 	Consumer struct {
-		PrivateKey   string `json:"-"`
-		IPAddress    net.IPNet
-		ConnectDelay int
+		PrivateKey        string `json:"-"`
+		IPAddress         net.IPNet
+		ConnectDelay      int
+		KeyRotationPeriod int // key rotation period in seconds, rotation is disabled if 0
 	}
 }
 
 // MarshalJSON implements json.Marshaler interface to provide human readable configuration.
 func (s ServiceConfig) MarshalJSON() ([]byte, error) {
 	type provider struct {
-		PublicKey string `json:"public_key"`
-		Endpoint  string `json:"endpoint"`
+		PublicKey    string `json:"public_key"`
+		PresharedKey string `json:"preshared_key,omitempty"`
+		Endpoint     string `json:"endpoint"`
 	}
 	type consumer struct {
-		PrivateKey   string `json:"private_key"`
-		IPAddress    string `json:"ip_address"`
-		ConnectDelay int    `json:"connect_delay"`
+		PrivateKey        string `json:"private_key"`
+		IPAddress         string `json:"ip_address"`
+		ConnectDelay      int    `json:"connect_delay"`
+		KeyRotationPeriod int    `json:"key_rotation_period,omitempty"`
 	}
 
 	return json.Marshal(&struct {
@@ -128,11 +137,13 @@ func (s ServiceConfig) MarshalJSON() ([]byte, error) {
 	}{
 		provider{
 			s.Provider.PublicKey,
+			s.Provider.PresharedKey,
 			s.Provider.Endpoint.String(),
 		},
 		consumer{
-			IPAddress:    s.Consumer.IPAddress.String(),
-			ConnectDelay: s.Consumer.ConnectDelay,
+			IPAddress:         s.Consumer.IPAddress.String(),
+			ConnectDelay:      s.Consumer.ConnectDelay,
+			KeyRotationPeriod: s.Consumer.KeyRotationPeriod,
 		},
 	})
 }
@@ -140,13 +151,15 @@ func (s ServiceConfig) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON implements json.Unmarshaler interface to receive human readable configuration.
 func (s *ServiceConfig) UnmarshalJSON(data []byte) error {
 	type provider struct {
-		PublicKey string `json:"public_key"`
-		Endpoint  string `json:"endpoint"`
+		PublicKey    string `json:"public_key"`
+		PresharedKey string `json:"preshared_key,omitempty"`
+		Endpoint     string `json:"endpoint"`
 	}
 	type consumer struct {
-		PrivateKey   string `json:"private_key"`
-		IPAddress    string `json:"ip_address"`
-		ConnectDelay int    `json:"connect_delay"`
+		PrivateKey        string `json:"private_key"`
+		IPAddress         string `json:"ip_address"`
+		ConnectDelay      int    `json:"connect_delay"`
+		KeyRotationPeriod int    `json:"key_rotation_period,omitempty"`
 	}
 	var config struct {
 		Provider provider `json:"provider"`
@@ -168,9 +181,11 @@ func (s *ServiceConfig) UnmarshalJSON(data []byte) error {
 
 	s.Provider.Endpoint = *endpoint
 	s.Provider.PublicKey = config.Provider.PublicKey
+	s.Provider.PresharedKey = config.Provider.PresharedKey
 	s.Consumer.IPAddress = *ipnet
 	s.Consumer.IPAddress.IP = ip
 	s.Consumer.ConnectDelay = config.Consumer.ConnectDelay
+	s.Consumer.KeyRotationPeriod = config.Consumer.KeyRotationPeriod
 
 	return nil
 }
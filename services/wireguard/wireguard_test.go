@@ -19,6 +19,7 @@ package wireguard
 
 import (
 	"encoding/json"
+	"net"
 	"testing"
 
 	"github.com/mysteriumnetwork/node/money"
@@ -101,3 +102,51 @@ func Test_PaymentMethod_Unserialize(t *testing.T) {
 		assert.Equal(t, test.expectedError, err)
 	}
 }
+
+func Test_ServiceConfig_Serialize(t *testing.T) {
+	var config ServiceConfig
+	config.Provider.PublicKey = "provider-public-key"
+	config.Provider.PresharedKey = "preshared-key"
+	config.Provider.Endpoint = net.UDPAddr{IP: net.ParseIP("1.2.3.4"), Port: 52820}
+	config.Consumer.PrivateKey = "consumer-private-key"
+	config.Consumer.IPAddress = net.IPNet{IP: net.ParseIP("10.182.0.2").To4(), Mask: net.CIDRMask(24, 32)}
+	config.Consumer.ConnectDelay = 2000
+	config.Consumer.KeyRotationPeriod = 600
+
+	jsonBytes, err := json.Marshal(config)
+	assert.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`{
+			"provider": {
+				"public_key": "provider-public-key",
+				"preshared_key": "preshared-key",
+				"endpoint": "1.2.3.4:52820"
+			},
+			"consumer": {
+				"private_key": "",
+				"ip_address": "10.182.0.2/24",
+				"connect_delay": 2000,
+				"key_rotation_period": 600
+			}
+		}`,
+		string(jsonBytes),
+	)
+
+	var unmarshalled ServiceConfig
+	assert.NoError(t, json.Unmarshal(jsonBytes, &unmarshalled))
+	assert.Equal(t, "preshared-key", unmarshalled.Provider.PresharedKey)
+	assert.Equal(t, "1.2.3.4:52820", unmarshalled.Provider.Endpoint.String())
+	assert.Equal(t, "10.182.0.2/24", unmarshalled.Consumer.IPAddress.String())
+	assert.Equal(t, 600, unmarshalled.Consumer.KeyRotationPeriod)
+}
+
+func Test_ConsumerConfig_SerializesPresharedKeySupportOnlyWhenSupported(t *testing.T) {
+	jsonBytes, err := json.Marshal(ConsumerConfig{PublicKey: "public-key"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"PublicKey": "public-key"}`, string(jsonBytes))
+
+	jsonBytes, err = json.Marshal(ConsumerConfig{PublicKey: "public-key", PresharedKeySupported: true})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"PublicKey": "public-key", "PresharedKeySupported": true}`, string(jsonBytes))
+}
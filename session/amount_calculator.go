@@ -18,27 +18,126 @@
 package session
 
 import (
+	"fmt"
+	"math"
+	"math/big"
 	"time"
 
+	"github.com/mysteriumnetwork/node/datasize"
 	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
 )
 
-// AmountCalc calculates the pay required given the amount
+// Rounding defines how the fractional part of prorated amount is rounded to the smallest money units
+type Rounding int
+
+const (
+	// RoundDown drops the fractional part, consumer is never charged more than the exact price
+	RoundDown Rounding = iota
+	// RoundUp charges any started smallest money unit
+	RoundUp
+	// RoundNearest rounds half units up and smaller fractions down
+	RoundNearest
+)
+
+// ParseRounding returns rounding of given name, rounding down is assumed when name is empty
+func ParseRounding(name string) (Rounding, error) {
+	switch name {
+	case "", "down":
+		return RoundDown, nil
+	case "up":
+		return RoundUp, nil
+	case "nearest":
+		return RoundNearest, nil
+	default:
+		return RoundDown, fmt.Errorf("unsupported rounding: %q", name)
+	}
+}
+
+// AmountCalc calculates the pay required given the amount.
+// Fractions of the priced duration are charged proportionally.
 type AmountCalc struct {
 	PaymentDef dto.PaymentPerTime
+	// Rounding of the prorated amount, rounded down by default
+	Rounding Rounding
+	// MinimumCharge is the least amount charged once any time has elapsed, in smallest money units
+	MinimumCharge uint64
+}
+
+// NewAmountCalc creates calculator which charges the rounding and minimum charge of given payment method
+func NewAmountCalc(method dto.PaymentPerTime) (AmountCalc, error) {
+	rounding, err := ParseRounding(method.Rounding)
+	if err != nil {
+		return AmountCalc{}, err
+	}
+	return AmountCalc{PaymentDef: method, Rounding: rounding, MinimumCharge: method.MinimumCharge}, nil
 }
 
 // TotalAmount gets the total amount of money to pay given the duration
 func (ac AmountCalc) TotalAmount(duration time.Duration) money.Money {
-	// time.Duration holds info in nanoseconds internally anyway (with max duration of 290 years) so we are probably safe here
-	// however - careful testing of corner cases is needed
-	// another question - in case of amount of 15 seconds, and price 10 myst per minute, total amount will be rounded to zero
-	// add 1 in case it's bad
-	amountInUnits := uint64(duration / ac.PaymentDef.Duration)
+	if duration <= 0 || ac.PaymentDef.Duration <= 0 {
+		return money.Money{Currency: ac.PaymentDef.Price.Currency}
+	}
+	return prorate(uint64(duration), uint64(ac.PaymentDef.Duration), ac.PaymentDef.Price, ac.Rounding, ac.MinimumCharge)
+}
+
+// TrafficAmountCalc calculates the pay required given the data transferred.
+// Fractions of the priced data size are charged proportionally.
+type TrafficAmountCalc struct {
+	PaymentDef dto.PaymentPerBytes
+	// Rounding of the prorated amount, rounded down by default
+	Rounding Rounding
+	// MinimumCharge is the least amount charged once any data was transferred, in smallest money units
+	MinimumCharge uint64
+}
+
+// NewTrafficAmountCalc creates calculator which charges the rounding and minimum charge of given payment method
+func NewTrafficAmountCalc(method dto.PaymentPerBytes) (TrafficAmountCalc, error) {
+	rounding, err := ParseRounding(method.Rounding)
+	if err != nil {
+		return TrafficAmountCalc{}, err
+	}
+	return TrafficAmountCalc{PaymentDef: method, Rounding: rounding, MinimumCharge: method.MinimumCharge}, nil
+}
+
+// TotalAmount gets the total amount of money to pay given the data transferred
+func (ac TrafficAmountCalc) TotalAmount(transferred datasize.BitSize) money.Money {
+	if transferred <= 0 || ac.PaymentDef.Bytes <= 0 {
+		return money.Money{Currency: ac.PaymentDef.Price.Currency}
+	}
+	return prorate(bitsOf(transferred), bitsOf(ac.PaymentDef.Bytes), ac.PaymentDef.Price, ac.Rounding, ac.MinimumCharge)
+}
+
+// prorate returns the price of consumed amount of units, i.e. price * consumed / unit.
+// Calculation is done with arbitrary precision, so the result is exact however large it gets.
+func prorate(consumed, unit uint64, price money.Money, rounding Rounding, minimumCharge uint64) money.Money {
+	product := new(big.Int).Mul(new(big.Int).SetUint64(consumed), price.Units())
+	divisor := new(big.Int).SetUint64(unit)
+	quotient, remainder := new(big.Int).QuoRem(product, divisor, new(big.Int))
+
+	switch rounding {
+	case RoundUp:
+		if remainder.Sign() > 0 {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	case RoundNearest:
+		if remainder.Lsh(remainder, 1).Cmp(divisor) >= 0 {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	amount := money.NewBigUnits(quotient, price.Currency)
+	minimum := money.NewUnits(minimumCharge, price.Currency)
+	if cmp, _ := amount.Cmp(minimum); cmp < 0 {
+		return minimum
+	}
+	return amount
+}
 
-	return money.Money{
-		Amount:   amountInUnits * ac.PaymentDef.Price.Amount,
-		Currency: ac.PaymentDef.Price.Currency,
+// bitsOf converts data size to whole bits, sizes too large to be represented are capped
+func bitsOf(size datasize.BitSize) uint64 {
+	if size >= math.MaxUint64 {
+		return math.MaxUint64
 	}
+	return size.Bits()
 }
@@ -18,28 +18,317 @@
 package session
 
 import (
+	"math"
 	"testing"
 	"time"
 
+	"github.com/mysteriumnetwork/node/datasize"
 	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
 	"github.com/stretchr/testify/assert"
 )
 
-func Test_CorrectMoneyValueIsReturnedForTotalAmount(t *testing.T) {
-	aCalc := AmountCalc{
-		PaymentDef: dto.PaymentPerTime{
-			Duration: time.Minute,
-			Price: money.Money{
-				Amount:   100,
-				Currency: money.CURRENCY_MYST,
-			},
+func perTime(price uint64, duration time.Duration) dto.PaymentPerTime {
+	return dto.PaymentPerTime{
+		Duration: duration,
+		Price:    money.NewUnits(price, money.CURRENCY_MYST),
+	}
+}
+
+func perBytes(price uint64, bytes datasize.BitSize) dto.PaymentPerBytes {
+	return dto.PaymentPerBytes{
+		Bytes: bytes,
+		Price: money.NewUnits(price, money.CURRENCY_MYST),
+	}
+}
+
+func Test_AmountCalc_TotalAmount(t *testing.T) {
+	tests := []struct {
+		name          string
+		payment       dto.PaymentPerTime
+		rounding      Rounding
+		minimumCharge uint64
+		elapsed       time.Duration
+		want          uint64
+	}{
+		{
+			name:    "charges whole periods",
+			payment: perTime(100, time.Minute),
+			elapsed: 3 * time.Minute,
+			want:    300,
+		},
+		{
+			name:    "charges fractional period proportionally",
+			payment: perTime(100, time.Minute),
+			elapsed: 59 * time.Second,
+			want:    98,
+		},
+		{
+			name:    "rounds fraction of money unit down by default",
+			payment: perTime(100, time.Minute),
+			elapsed: 3*time.Minute + 25*time.Second,
+			want:    341,
+		},
+		{
+			name:     "rounds fraction of money unit up",
+			payment:  perTime(100, time.Minute),
+			rounding: RoundUp,
+			elapsed:  3*time.Minute + 25*time.Second,
+			want:     342,
+		},
+		{
+			name:     "rounds fraction of money unit to nearest",
+			payment:  perTime(100, time.Minute),
+			rounding: RoundNearest,
+			elapsed:  3*time.Minute + 25*time.Second,
+			want:     342,
+		},
+		{
+			name:     "rounds less than half of money unit down to nearest",
+			payment:  perTime(100, time.Minute),
+			rounding: RoundNearest,
+			elapsed:  59 * time.Second,
+			want:     98,
+		},
+		{
+			name:     "rounds exactly half of money unit up to nearest",
+			payment:  perTime(1, time.Minute),
+			rounding: RoundNearest,
+			elapsed:  30 * time.Second,
+			want:     1,
+		},
+		{
+			name:     "does not round up exact amount",
+			payment:  perTime(100, time.Minute),
+			rounding: RoundUp,
+			elapsed:  2 * time.Minute,
+			want:     200,
+		},
+		{
+			name:    "tiny price rounds down to nothing",
+			payment: perTime(1, time.Hour),
+			elapsed: time.Second,
+			want:    0,
+		},
+		{
+			name:     "tiny price rounds up to the smallest unit",
+			payment:  perTime(1, time.Hour),
+			rounding: RoundUp,
+			elapsed:  time.Nanosecond,
+			want:     1,
+		},
+		{
+			name:          "tiny price is raised to minimum charge",
+			payment:       perTime(1, time.Hour),
+			minimumCharge: 5,
+			elapsed:       time.Second,
+			want:          5,
+		},
+		{
+			name:          "minimum charge does not limit larger amounts",
+			payment:       perTime(60, time.Minute),
+			minimumCharge: 5,
+			elapsed:       time.Minute,
+			want:          60,
+		},
+		{
+			name:          "nothing is charged before session starts",
+			payment:       perTime(100, time.Minute),
+			minimumCharge: 5,
+			elapsed:       0,
+			want:          0,
+		},
+		{
+			name:    "nothing is charged for negative duration",
+			payment: perTime(100, time.Minute),
+			elapsed: -time.Minute,
+			want:    0,
+		},
+		{
+			name:    "nothing is charged for undefined period",
+			payment: perTime(100, 0),
+			elapsed: time.Minute,
+			want:    0,
+		},
+		{
+			name:    "nothing is charged for free service",
+			payment: perTime(0, time.Minute),
+			elapsed: time.Hour,
+			want:    0,
+		},
+		{
+			name:    "very long session does not overflow intermediate result",
+			payment: perTime(1000000000, time.Minute),
+			elapsed: 200 * 365 * 24 * time.Hour,
+			want:    105120000 * 1000000000,
+		},
+		{
+			name:    "price of period longer than session is prorated",
+			payment: perTime(math.MaxUint64, 2*time.Hour),
+			elapsed: time.Hour,
+			want:    math.MaxUint64 / 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calc := AmountCalc{PaymentDef: tt.payment, Rounding: tt.rounding, MinimumCharge: tt.minimumCharge}
+			amount := calc.TotalAmount(tt.elapsed)
+			units, err := amount.Uint64()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, units)
+			assert.Equal(t, money.CURRENCY_MYST, amount.Currency)
+		})
+	}
+}
+
+func Test_ParseRounding(t *testing.T) {
+	for name, want := range map[string]Rounding{"": RoundDown, "down": RoundDown, "up": RoundUp, "nearest": RoundNearest} {
+		rounding, err := ParseRounding(name)
+		assert.NoError(t, err)
+		assert.Equal(t, want, rounding, name)
+	}
+
+	_, err := ParseRounding("half")
+	assert.EqualError(t, err, `unsupported rounding: "half"`)
+}
+
+func Test_NewAmountCalc_ChargesMinimumOfPaymentMethod(t *testing.T) {
+	method := perTime(100, time.Minute)
+	method.Rounding = "up"
+	method.MinimumCharge = 50
+
+	calc, err := NewAmountCalc(method)
+	assert.NoError(t, err)
+	assert.Equal(t, AmountCalc{PaymentDef: method, Rounding: RoundUp, MinimumCharge: 50}, calc)
+	assert.Equal(t, money.NewUnits(50, money.CURRENCY_MYST), calc.TotalAmount(time.Second))
+	assert.Equal(t, money.NewUnits(52, money.CURRENCY_MYST), calc.TotalAmount(31*time.Second))
+
+	method.Rounding = "half"
+	_, err = NewAmountCalc(method)
+	assert.EqualError(t, err, `unsupported rounding: "half"`)
+}
+
+func Test_AmountCalc_TotalAmount_BeyondUint64(t *testing.T) {
+	calc := AmountCalc{PaymentDef: perTime(math.MaxUint64, time.Minute)}
+
+	amount := calc.TotalAmount(2 * time.Minute)
+	assert.Equal(t, money.NewUnits(math.MaxUint64, money.CURRENCY_MYST).Mul(2), amount)
+}
+
+func Test_TrafficAmountCalc_TotalAmount(t *testing.T) {
+	tests := []struct {
+		name          string
+		payment       dto.PaymentPerBytes
+		rounding      Rounding
+		minimumCharge uint64
+		transferred   datasize.BitSize
+		want          uint64
+	}{
+		{
+			name:        "charges whole units",
+			payment:     perBytes(100, datasize.GB),
+			transferred: 2 * datasize.GB,
+			want:        200,
+		},
+		{
+			name:        "charges fractional unit proportionally",
+			payment:     perBytes(100, datasize.GB),
+			transferred: 2*datasize.GB + 300*datasize.MB,
+			want:        229,
+		},
+		{
+			name:        "rounds fraction of money unit up",
+			payment:     perBytes(100, datasize.GB),
+			rounding:    RoundUp,
+			transferred: 2*datasize.GB + 300*datasize.MB,
+			want:        230,
 		},
+		{
+			name:        "rounds fraction of money unit to nearest",
+			payment:     perBytes(100, datasize.GB),
+			rounding:    RoundNearest,
+			transferred: 2*datasize.GB + 300*datasize.MB,
+			want:        229,
+		},
+		{
+			name:        "counts single bits",
+			payment:     perBytes(8, datasize.Byte),
+			transferred: 3 * datasize.Bit,
+			want:        3,
+		},
+		{
+			name:        "tiny price rounds down to nothing",
+			payment:     perBytes(100, datasize.GB),
+			transferred: datasize.Byte,
+			want:        0,
+		},
+		{
+			name:        "tiny price rounds up to the smallest unit",
+			payment:     perBytes(100, datasize.GB),
+			rounding:    RoundUp,
+			transferred: datasize.Byte,
+			want:        1,
+		},
+		{
+			name:          "tiny price is raised to minimum charge",
+			payment:       perBytes(100, datasize.GB),
+			minimumCharge: 10,
+			transferred:   datasize.MB,
+			want:          10,
+		},
+		{
+			name:          "nothing is charged before any data is transferred",
+			payment:       perBytes(100, datasize.GB),
+			minimumCharge: 10,
+			transferred:   0,
+			want:          0,
+		},
+		{
+			name:        "nothing is charged for undefined unit",
+			payment:     perBytes(100, 0),
+			transferred: datasize.GB,
+			want:        0,
+		},
+		{
+			name:        "large transfer does not overflow intermediate result",
+			payment:     perBytes(1000000000000, datasize.MB),
+			transferred: datasize.TB,
+			want:        1024 * 1024 * 1000000000000,
+		},
+		{
+			name:        "transfer beyond representable bits is capped",
+			payment:     perBytes(1, 1<<63),
+			transferred: 1e30,
+			want:        1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calc := TrafficAmountCalc{PaymentDef: tt.payment, Rounding: tt.rounding, MinimumCharge: tt.minimumCharge}
+			amount := calc.TotalAmount(tt.transferred)
+			units, err := amount.Uint64()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, units)
+			assert.Equal(t, money.CURRENCY_MYST, amount.Currency)
+		})
 	}
+}
+
+func Test_NewTrafficAmountCalc_ChargesMinimumOfPaymentMethod(t *testing.T) {
+	method := perBytes(100, datasize.MB)
+	method.Rounding = "nearest"
+	method.MinimumCharge = 10
 
-	elapsed := 3*time.Minute + 25*time.Second
+	calc, err := NewTrafficAmountCalc(method)
+	assert.NoError(t, err)
+	assert.Equal(t, TrafficAmountCalc{PaymentDef: method, Rounding: RoundNearest, MinimumCharge: 10}, calc)
+	assert.Equal(t, money.NewUnits(10, money.CURRENCY_MYST), calc.TotalAmount(datasize.KB))
+	assert.Equal(t, money.NewUnits(100, money.CURRENCY_MYST), calc.TotalAmount(datasize.MB))
+}
 
-	totalAmount := aCalc.TotalAmount(elapsed)
+func Test_TrafficAmountCalc_TotalAmount_BeyondUint64(t *testing.T) {
+	calc := TrafficAmountCalc{PaymentDef: perBytes(math.MaxUint64, datasize.MB)}
 
-	assert.Equal(t, uint64(300), totalAmount.Amount)
+	amount := calc.TotalAmount(3 * datasize.MB)
+	assert.Equal(t, money.NewUnits(math.MaxUint64, money.CURRENCY_MYST).Mul(3), amount)
 }
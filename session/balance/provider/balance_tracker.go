@@ -21,6 +21,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/mysteriumnetwork/node/datasize"
 	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/session/balance"
 )
@@ -41,47 +42,118 @@ type AmountCalculator interface {
 	TotalAmount(duration time.Duration) money.Money
 }
 
+// TrafficKeeper keeps track of data transferred through the session for payments
+type TrafficKeeper interface {
+	StartTracking()
+	Transferred() datasize.BitSize
+}
+
+// TrafficAmountCalculator is able to deduce the amount required for payment from a given data transferred
+type TrafficAmountCalculator interface {
+	TotalAmount(transferred datasize.BitSize) money.Money
+}
+
 // BalanceTracker is responsible for tracking the balance on the provider side
 type BalanceTracker struct {
-	timeKeeper       TimeKeeper
-	amountCalculator AmountCalculator
+	startTracking func()
+	totalCost     func() money.Money
 
-	totalPromised uint64
-	balance       uint64
+	totalPromised  money.Money
+	freeCredit     money.Money
+	freeCreditUsed money.Money
 
 	sync.Mutex
 }
 
 // NewBalanceTracker returns a new instance of the providerBalanceTracker
-func NewBalanceTracker(timeKeeper TimeKeeper, amountCalculator AmountCalculator, initialBalance uint64) *BalanceTracker {
+func NewBalanceTracker(timeKeeper TimeKeeper, amountCalculator AmountCalculator, initialBalance money.Money) *BalanceTracker {
+	return &BalanceTracker{
+		startTracking: timeKeeper.StartTracking,
+		totalCost: func() money.Money {
+			return amountCalculator.TotalAmount(timeKeeper.Elapsed())
+		},
+		totalPromised: initialBalance,
+	}
+}
+
+// NewTrafficBalanceTracker returns a new instance of the providerBalanceTracker which charges for transferred data
+func NewTrafficBalanceTracker(trafficKeeper TrafficKeeper, amountCalculator TrafficAmountCalculator, initialBalance money.Money) *BalanceTracker {
 	return &BalanceTracker{
-		timeKeeper:       timeKeeper,
-		amountCalculator: amountCalculator,
-		totalPromised:    initialBalance,
+		startTracking: trafficKeeper.StartTracking,
+		totalCost: func() money.Money {
+			return amountCalculator.TotalAmount(trafficKeeper.Transferred())
+		},
+		totalPromised: initialBalance,
 	}
 }
 
-func (bt *BalanceTracker) calculateBalance() {
+// GetBalance returns the current balance, including the free credit left.
+// Balance is never overdrawn, it is zero once the cost exceeds everything available.
+func (bt *BalanceTracker) GetBalance() (money.Money, error) {
 	bt.Lock()
 	defer bt.Unlock()
-	cost := bt.amountCalculator.TotalAmount(bt.timeKeeper.Elapsed())
-	bt.balance = bt.totalPromised - cost.Amount
+
+	cost := bt.totalCost()
+	// free credit is consumed before anything what was promised
+	freeCreditUsed := bt.freeCredit
+	cmp, err := cost.Cmp(bt.freeCredit)
+	if err != nil {
+		return money.Money{}, err
+	}
+	if cmp < 0 {
+		freeCreditUsed = cost
+	}
+
+	available, err := bt.totalPromised.Add(bt.freeCredit)
+	if err != nil {
+		return money.Money{}, err
+	}
+	left, err := available.Sub(cost)
+	if err == money.ErrNegativeAmount {
+		left, err = money.Money{Currency: cost.Currency}, nil
+	}
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	bt.freeCreditUsed = freeCreditUsed
+	return left, nil
 }
 
-// GetBalance returns the current balance
-func (bt *BalanceTracker) GetBalance() uint64 {
-	bt.calculateBalance()
-	return bt.balance
+// FreeCreditUsed returns the free credit consumed as of the last balance calculation
+func (bt *BalanceTracker) FreeCreditUsed() money.Money {
+	bt.Lock()
+	defer bt.Unlock()
+	return bt.freeCreditUsed
 }
 
-// Start starts keeping track of time for balance
+// Start starts keeping track of time or traffic for balance
 func (bt *BalanceTracker) Start() {
-	bt.timeKeeper.StartTracking()
+	bt.startTracking()
 }
 
 // Add increases the current balance by the given amount
-func (bt *BalanceTracker) Add(amount uint64) {
+func (bt *BalanceTracker) Add(amount money.Money) error {
 	bt.Lock()
 	defer bt.Unlock()
-	bt.totalPromised += amount
+
+	total, err := bt.totalPromised.Add(amount)
+	if err != nil {
+		return err
+	}
+	bt.totalPromised = total
+	return nil
+}
+
+// AddFreeCredit increases the free credit which is consumed before the promised amount
+func (bt *BalanceTracker) AddFreeCredit(amount money.Money) error {
+	bt.Lock()
+	defer bt.Unlock()
+
+	total, err := bt.freeCredit.Add(amount)
+	if err != nil {
+		return err
+	}
+	bt.freeCredit = total
+	return nil
 }
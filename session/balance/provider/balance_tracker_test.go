@@ -18,29 +18,29 @@
 package provider
 
 import (
+	"math"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/mysteriumnetwork/node/datasize"
 	"github.com/mysteriumnetwork/node/money"
 )
 
+func myst(units uint64) money.Money {
+	return money.NewUnits(units, money.CURRENCY_MYST)
+}
+
 func Test_BalanceTracker(t *testing.T) {
-	var initialBalance uint64 = 100
 	mockTime := time.Second
-	mockMoney := money.Money{
-		Amount:   1,
-		Currency: money.CURRENCY_MYST,
-	}
 	mtk := &mockTimeKeeper{elapsed: mockTime}
-	mac := &mockAmountCalculator{toReturn: mockMoney}
-	tracker := NewBalanceTracker(mtk, mac, initialBalance)
-	balance := tracker.GetBalance()
+	mac := &mockAmountCalculator{toReturn: myst(1)}
+	tracker := NewBalanceTracker(mtk, mac, myst(100))
 
-	balanceAfter := initialBalance - mockMoney.Amount
-	assert.Equal(t, balanceAfter, tracker.balance)
-	assert.Equal(t, balanceAfter, balance)
+	balance, err := tracker.GetBalance()
+	assert.NoError(t, err)
+	assert.Equal(t, myst(99), balance)
 	assert.Equal(t, mac.calledWith, mockTime)
 
 	assert.False(t, mtk.startCalled)
@@ -48,9 +48,82 @@ func Test_BalanceTracker(t *testing.T) {
 	tracker.Start()
 	assert.True(t, mtk.startCalled)
 
-	var promisedAmount uint64 = 1
-	tracker.Add(promisedAmount)
-	assert.Equal(t, tracker.totalPromised, promisedAmount+initialBalance)
+	assert.NoError(t, tracker.Add(myst(1)))
+	assert.Equal(t, myst(101), tracker.totalPromised)
+}
+
+func Test_TrafficBalanceTracker(t *testing.T) {
+	mtk := &mockTrafficKeeper{transferred: 2 * datasize.GB}
+	mac := &mockTrafficAmountCalculator{toReturn: myst(10)}
+	tracker := NewTrafficBalanceTracker(mtk, mac, myst(100))
+
+	balance, err := tracker.GetBalance()
+	assert.NoError(t, err)
+	assert.Equal(t, myst(90), balance)
+	assert.Equal(t, 2*datasize.GB, mac.calledWith)
+
+	assert.False(t, mtk.startCalled)
+	tracker.Start()
+	assert.True(t, mtk.startCalled)
+}
+
+func Test_BalanceTracker_IsNotOverdrawn(t *testing.T) {
+	mtk := &mockTimeKeeper{elapsed: time.Minute}
+	mac := &mockAmountCalculator{toReturn: myst(200)}
+	tracker := NewBalanceTracker(mtk, mac, myst(100))
+
+	balance, err := tracker.GetBalance()
+	assert.NoError(t, err)
+	assert.True(t, balance.IsZero())
+}
+
+func Test_BalanceTracker_ConsumesFreeCreditFirst(t *testing.T) {
+	mtk := &mockTimeKeeper{elapsed: time.Minute}
+	mac := &mockAmountCalculator{toReturn: myst(30)}
+	tracker := NewBalanceTracker(mtk, mac, myst(100))
+	assert.NoError(t, tracker.AddFreeCredit(myst(50)))
+
+	balance, err := tracker.GetBalance()
+	assert.NoError(t, err)
+	assert.Equal(t, myst(120), balance)
+	assert.Equal(t, myst(30), tracker.FreeCreditUsed())
+
+	mac.toReturn = myst(80)
+	balance, err = tracker.GetBalance()
+	assert.NoError(t, err)
+	assert.Equal(t, myst(70), balance)
+	assert.Equal(t, myst(50), tracker.FreeCreditUsed())
+
+	mac.toReturn = myst(200)
+	balance, err = tracker.GetBalance()
+	assert.NoError(t, err)
+	assert.True(t, balance.IsZero())
+	assert.Equal(t, myst(50), tracker.FreeCreditUsed())
+}
+
+func Test_BalanceTracker_KeepsBalanceBeyondUint64(t *testing.T) {
+	mtk := &mockTimeKeeper{elapsed: time.Minute}
+	mac := &mockAmountCalculator{toReturn: myst(1)}
+	tracker := NewBalanceTracker(mtk, mac, myst(math.MaxUint64))
+	assert.NoError(t, tracker.Add(myst(math.MaxUint64)))
+
+	balance, err := tracker.GetBalance()
+	assert.NoError(t, err)
+	want, err := myst(math.MaxUint64).Mul(2).Sub(myst(1))
+	assert.NoError(t, err)
+	assert.Equal(t, want, balance)
+}
+
+func Test_BalanceTracker_RejectsOtherCurrency(t *testing.T) {
+	mtk := &mockTimeKeeper{elapsed: time.Minute}
+	mac := &mockAmountCalculator{toReturn: myst(1)}
+	tracker := NewBalanceTracker(mtk, mac, myst(100))
+
+	assert.Equal(t, money.ErrCurrencyMismatch, tracker.Add(money.NewUnits(1, money.Currency("BTC"))))
+	assert.NoError(t, tracker.AddFreeCredit(money.NewUnits(1, money.Currency("BTC"))))
+
+	_, err := tracker.GetBalance()
+	assert.Equal(t, money.ErrCurrencyMismatch, err)
 }
 
 type mockTimeKeeper struct {
@@ -75,3 +148,26 @@ func (mac *mockAmountCalculator) TotalAmount(duration time.Duration) money.Money
 	mac.calledWith = duration
 	return mac.toReturn
 }
+
+type mockTrafficKeeper struct {
+	transferred datasize.BitSize
+	startCalled bool
+}
+
+func (mtk *mockTrafficKeeper) StartTracking() {
+	mtk.startCalled = true
+}
+
+func (mtk *mockTrafficKeeper) Transferred() datasize.BitSize {
+	return mtk.transferred
+}
+
+type mockTrafficAmountCalculator struct {
+	calledWith datasize.BitSize
+	toReturn   money.Money
+}
+
+func (mac *mockTrafficAmountCalculator) TotalAmount(transferred datasize.BitSize) money.Money {
+	mac.calledWith = transferred
+	return mac.toReturn
+}
@@ -20,20 +20,28 @@ package session
 import (
 	"encoding/json"
 
+	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/identity"
 )
 
+const createConsumerLogPrefix = "[session-create-consumer] "
+
 // createConsumer processes session create requests from communication channel.
 type createConsumer struct {
 	sessionCreator Creator
 	peerID         identity.Identity
 	configProvider ConfigProvider
+	paymentInfo    PaymentInfoProvider
 }
 
+// PaymentInfoProvider returns the payment information provider has about the consumer, nil if there is none
+type PaymentInfoProvider func(issuerID identity.Identity) *PaymentInfo
+
 // Creator defines method for session creation
 type Creator interface {
 	Create(consumerID, issuerID identity.Identity, proposalID int) (Session, error)
+	Destroy(consumerID identity.Identity, sessionID string) error
 }
 
 // GetMessageEndpoint returns endpoint there to receive messages
@@ -51,31 +59,38 @@ func (consumer *createConsumer) NewRequest() (requestPtr interface{}) {
 func (consumer *createConsumer) Consume(requestPtr interface{}) (response interface{}, err error) {
 	request := requestPtr.(*CreateRequest)
 
-	config, destroyCallback, err := consumer.configProvider(request.Config)
-	if err != nil {
-		return responseInternalError, err
-	}
-
 	issuerID := consumer.peerID
 	if request.ConsumerInfo != nil {
 		issuerID = request.ConsumerInfo.IssuerID
 	}
 
 	sessionInstance, err := consumer.sessionCreator.Create(consumer.peerID, issuerID, request.ProposalID)
-	switch err {
-	case nil:
-		if destroyCallback != nil {
-			go func() {
-				<-sessionInstance.Done
-				destroyCallback()
-			}()
-		}
-		return responseWithSession(sessionInstance, config, nil), nil
-	case ErrorInvalidProposal:
+	if err == ErrorInvalidProposal {
 		return responseInvalidProposal, nil
-	default:
+	}
+	if err != nil {
 		return responseInternalError, nil
 	}
+
+	config, destroyCallback, err := consumer.configProvider(sessionInstance.ID, request.Config)
+	if err != nil {
+		if destroyErr := consumer.sessionCreator.Destroy(consumer.peerID, string(sessionInstance.ID)); destroyErr != nil {
+			log.Error(createConsumerLogPrefix, "failed to destroy session after config negotiation failure: ", destroyErr)
+		}
+		return responseInternalError, err
+	}
+
+	if destroyCallback != nil {
+		go func() {
+			<-sessionInstance.Done
+			destroyCallback()
+		}()
+	}
+	var paymentInfo *PaymentInfo
+	if consumer.paymentInfo != nil {
+		paymentInfo = consumer.paymentInfo(issuerID)
+	}
+	return responseWithSession(sessionInstance, config, paymentInfo), nil
 }
 
 func responseWithSession(sessionInstance Session, config ServiceConfiguration, pi *PaymentInfo) CreateResponse {
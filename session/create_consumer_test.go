@@ -28,7 +28,7 @@ import (
 
 var (
 	config       = json.RawMessage(`{"Param1":"string-param","Param2":123}`)
-	mockConsumer = func(json.RawMessage) (ServiceConfiguration, DestroyCallback, error) {
+	mockConsumer = func(ID, json.RawMessage) (ServiceConfiguration, DestroyCallback, error) {
 		return config, nil, nil
 	}
 )
@@ -123,6 +123,64 @@ func TestConsumer_UsesIssuerID(t *testing.T) {
 	assert.Equal(t, issuerID, mockManager.lastIssuerID)
 }
 
+func TestConsumer_RespondsWithPaymentInfoOfIssuer(t *testing.T) {
+	mockManager := &managerFake{
+		returnSession: Session{
+			ID:         "new-id",
+			ConsumerID: identity.FromAddress("123"),
+		},
+	}
+	issuerID := identity.FromAddress("some-peer-id")
+	consumer := createConsumer{
+		sessionCreator: mockManager,
+		peerID:         identity.FromAddress("peer-id"),
+		configProvider: mockConsumer,
+		paymentInfo: func(id identity.Identity) *PaymentInfo {
+			assert.Equal(t, issuerID, id)
+			return &PaymentInfo{LastPromise: LastPromise{SequenceID: 3, Amount: 200}}
+		},
+	}
+
+	request := consumer.NewRequest().(*CreateRequest)
+	request.ProposalID = 101
+	request.ConsumerInfo = &ConsumerInfo{
+		IssuerID: issuerID,
+	}
+
+	sessionResponse, err := consumer.Consume(request)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		&PaymentInfo{LastPromise: LastPromise{SequenceID: 3, Amount: 200}},
+		sessionResponse.(CreateResponse).PaymentInfo,
+	)
+}
+
+func TestConsumer_DestroysSessionOnConfigError(t *testing.T) {
+	mockManager := &managerFake{
+		returnSession: Session{
+			ID:         "new-id",
+			ConsumerID: identity.FromAddress("123"),
+		},
+	}
+	consumer := createConsumer{
+		sessionCreator: mockManager,
+		peerID:         identity.FromAddress("peer-id"),
+		configProvider: func(sessionID ID, _ json.RawMessage) (ServiceConfiguration, DestroyCallback, error) {
+			assert.Equal(t, ID("new-id"), sessionID)
+			return nil, nil, errors.New("config failure")
+		},
+	}
+
+	request := consumer.NewRequest().(*CreateRequest)
+	request.ProposalID = 101
+	sessionResponse, err := consumer.Consume(request)
+
+	assert.EqualError(t, err, "config failure")
+	assert.Exactly(t, responseInternalError, sessionResponse)
+	assert.Equal(t, "new-id", mockManager.lastDestroyedID)
+}
+
 // managerFake represents fake Manager usually useful in tests
 type managerFake struct {
 	lastConsumerID identity.Identity
@@ -130,6 +188,8 @@ type managerFake struct {
 	lastProposalID int
 	returnSession  Session
 	returnError    error
+
+	lastDestroyedID string
 }
 
 // Create function creates and returns fake session
@@ -142,5 +202,6 @@ func (manager *managerFake) Create(consumerID, issuerID identity.Identity, propo
 
 // Destroy fake destroy function
 func (manager *managerFake) Destroy(consumerID identity.Identity, sessionID string) error {
+	manager.lastDestroyedID = sessionID
 	return nil
 }
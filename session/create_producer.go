@@ -46,8 +46,8 @@ func (producer *createProducer) Produce() (requestPtr interface{}) {
 	}
 }
 
-// RequestSessionCreate requests session creation and returns session DTO
-func RequestSessionCreate(sender communication.Sender, proposalID int, config interface{}, ci ConsumerInfo) (sessionID ID, sessionConfig json.RawMessage, err error) {
+// RequestSessionCreate requests session creation and returns session DTO along with the payment info provider has about the consumer, if any
+func RequestSessionCreate(sender communication.Sender, proposalID int, config interface{}, ci ConsumerInfo) (sessionID ID, sessionConfig json.RawMessage, paymentInfo *PaymentInfo, err error) {
 	sessionCreateConfigJSON, err := json.Marshal(config)
 	if err != nil {
 		return
@@ -70,5 +70,6 @@ func RequestSessionCreate(sender communication.Sender, proposalID int, config in
 
 	sessionID = response.Session.ID
 	sessionConfig = response.Session.Config
+	paymentInfo = response.PaymentInfo
 	return
 }
@@ -45,14 +45,25 @@ type fakeSessionConfig struct {
 
 func TestProducer_RequestSessionCreate(t *testing.T) {
 	sender := &fakeSender{}
-	sid, config, err := RequestSessionCreate(sender, 123, []byte{}, ConsumerInfo{})
+	sid, config, paymentInfo, err := RequestSessionCreate(sender, 123, []byte{}, ConsumerInfo{})
 	assert.NoError(t, err)
 	assert.Exactly(t, succesfullSessionID, sid)
 	assert.Exactly(t, succesfullSessionConfig, config)
+	assert.Nil(t, paymentInfo)
+}
+
+func TestProducer_RequestSessionCreateReturnsPaymentInfo(t *testing.T) {
+	lastPromise := LastPromise{SequenceID: 2, Amount: 100}
+	sender := &fakeSender{paymentInfo: &PaymentInfo{LastPromise: lastPromise}}
+
+	_, _, paymentInfo, err := RequestSessionCreate(sender, 123, []byte{}, ConsumerInfo{})
+	assert.NoError(t, err)
+	assert.Equal(t, &PaymentInfo{LastPromise: lastPromise}, paymentInfo)
 }
 
 type fakeSender struct {
 	lastRequest communication.RequestProducer
+	paymentInfo *PaymentInfo
 }
 
 func (sender *fakeSender) Send(producer communication.MessageProducer) error {
@@ -68,5 +79,6 @@ func (sender *fakeSender) Request(producer communication.RequestProducer) (respo
 			ID:     "session-id",
 			Config: []byte(`{"Param1":"string-param","Param2":123}`),
 		},
+		PaymentInfo: sender.paymentInfo,
 	}, nil
 }
@@ -32,7 +32,7 @@ var (
 
 func TestProducer_RequestSessionDestroy(t *testing.T) {
 	sender := &fakeSender{}
-	sid, _, err := RequestSessionCreate(sender, 123, []byte{}, ConsumerInfo{})
+	sid, _, _, err := RequestSessionCreate(sender, 123, []byte{}, ConsumerInfo{})
 	assert.NoError(t, err)
 
 	destroySender := &fakeDestroySender{}
@@ -26,16 +26,22 @@ import (
 type ManagerFactory func(dialog communication.Dialog) *Manager
 
 // NewDialogHandler constructs handler which gets all incoming dialogs and starts handling them
-func NewDialogHandler(sessionManagerFactory ManagerFactory, configProvider ConfigProvider) *handler {
+// configUpdater is optional, session update requests are rejected when it is nil
+// paymentInfo is optional, no payment info is sent to consumer when it is nil
+func NewDialogHandler(sessionManagerFactory ManagerFactory, configProvider ConfigProvider, configUpdater ConfigUpdater, paymentInfo PaymentInfoProvider) *handler {
 	return &handler{
 		sessionManagerFactory: sessionManagerFactory,
 		configProvider:        configProvider,
+		configUpdater:         configUpdater,
+		paymentInfo:           paymentInfo,
 	}
 }
 
 type handler struct {
 	sessionManagerFactory ManagerFactory
 	configProvider        ConfigProvider
+	configUpdater         ConfigUpdater
+	paymentInfo           PaymentInfoProvider
 }
 
 // Handle starts serving services in given Dialog instance
@@ -49,6 +55,7 @@ func (handler *handler) subscribeSessionRequests(dialog communication.Dialog) er
 			sessionCreator: handler.sessionManagerFactory(dialog),
 			peerID:         dialog.PeerID(),
 			configProvider: handler.configProvider,
+			paymentInfo:    handler.paymentInfo,
 		},
 	)
 
@@ -56,6 +63,17 @@ func (handler *handler) subscribeSessionRequests(dialog communication.Dialog) er
 		return err
 	}
 
+	err = dialog.Respond(
+		&updateConsumer{
+			sessionFinder: handler.sessionManagerFactory(dialog),
+			peerID:        dialog.PeerID(),
+			configUpdater: handler.configUpdater,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
 	return dialog.Respond(
 		&destroyConsumer{
 			SessionDestroyer: &sessionDestroyer{
@@ -30,9 +30,10 @@ type BalanceTracker interface {
 
 // Session structure holds all required information about current session between service consumer and provider
 type Session struct {
-	ID         ID
-	ConsumerID identity.Identity
-	Done       chan struct{}
+	ID           ID
+	ConsumerID   identity.Identity
+	Done         chan struct{}
+	DataTransfer DataTransfer
 }
 
 // ServiceConfiguration defines service configuration from underlying transport mechanism to be passed to remote party
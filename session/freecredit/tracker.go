@@ -0,0 +1,146 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package freecredit keeps track of the free allowance which provider grants to consumers.
+package freecredit
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
+	"github.com/mysteriumnetwork/node/session"
+)
+
+const bucketPrefix = "free-credit-"
+
+// errBoltNotFound represents the bolts not found error
+var errBoltNotFound = errors.New("not found")
+
+// Storer allows to store and get free credit usages
+type Storer interface {
+	Store(bucket string, object interface{}) error
+	GetOneByField(bucket string, fieldName string, key interface{}, to interface{}) error
+}
+
+// Usage is a representation of free credit used by consumer during the current period in storage
+type Usage struct {
+	Consumer    string `storm:"id"`
+	PeriodStart time.Time
+	Used        money.Money
+}
+
+// Tracker keeps track of free credit which consumers used of the allowance offered in service proposal
+type Tracker struct {
+	storage Storer
+	bucket  string
+	amount  money.Money
+	period  time.Duration
+	now     func() time.Time
+	lock    sync.Mutex
+}
+
+// NewTracker returns tracker of free credit offered in the given proposal.
+// Usages are kept per service type, as services are priced differently.
+func NewTracker(storage Storer, proposal market.ServiceProposal) *Tracker {
+	var period time.Duration
+	if proposal.FreeCredit != nil {
+		period = proposal.FreeCredit.Period
+	}
+	return &Tracker{
+		storage: storage,
+		bucket:  bucketPrefix + proposal.ServiceType,
+		amount:  Amount(proposal),
+		period:  period,
+		now:     time.Now,
+	}
+}
+
+// Amount returns the free credit offered in the proposal, converted to amount of money by the proposal price
+func Amount(proposal market.ServiceProposal) money.Money {
+	if proposal.FreeCredit == nil {
+		return money.Money{}
+	}
+
+	switch method := proposal.PaymentMethod.(type) {
+	case dto.PaymentPerTime:
+		return session.AmountCalc{PaymentDef: method}.TotalAmount(proposal.FreeCredit.Duration)
+	case dto.PaymentPerBytes:
+		return session.TrafficAmountCalc{PaymentDef: method}.TotalAmount(proposal.FreeCredit.Bytes)
+	default:
+		return money.Money{}
+	}
+}
+
+// Remaining returns the free credit which consumer has left in the current period
+func (t *Tracker) Remaining(consumer identity.Identity) (money.Money, error) {
+	if t.amount.IsZero() {
+		return t.amount, nil
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	usage, err := t.currentUsage(consumer)
+	if err != nil {
+		return money.Money{}, err
+	}
+	remaining, err := t.amount.Sub(usage.Used)
+	if err == money.ErrNegativeAmount {
+		return money.Money{Currency: t.amount.Currency}, nil
+	}
+	return remaining, err
+}
+
+// Use records the amount of free credit used by consumer
+func (t *Tracker) Use(consumer identity.Identity, amount money.Money) error {
+	if amount.IsZero() {
+		return nil
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	usage, err := t.currentUsage(consumer)
+	if err != nil {
+		return err
+	}
+	usage.Used, err = usage.Used.Add(amount)
+	if err != nil {
+		return err
+	}
+	return t.storage.Store(t.bucket, &usage)
+}
+
+// currentUsage returns usage of consumer in the current period, the new period starts once the previous one is over
+func (t *Tracker) currentUsage(consumer identity.Identity) (Usage, error) {
+	now := t.now().UTC()
+
+	var usage Usage
+	err := t.storage.GetOneByField(t.bucket, "Consumer", consumer.Address, &usage)
+	if err != nil && err.Error() != errBoltNotFound.Error() {
+		return Usage{}, err
+	}
+	if err != nil || (t.period > 0 && !now.Before(usage.PeriodStart.Add(t.period))) {
+		return Usage{Consumer: consumer.Address, PeriodStart: now}, nil
+	}
+	return usage, nil
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package freecredit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/core/storage/boltdb"
+	"github.com/mysteriumnetwork/node/core/storage/boltdb/boltdbtest"
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	consumer      = identity.FromAddress("0x000000000000000000000000000000000000000a")
+	otherConsumer = identity.FromAddress("0x000000000000000000000000000000000000000b")
+	perTime       = dto.PaymentPerTime{Price: money.NewUnits(10, money.CURRENCY_MYST), Duration: time.Minute}
+	perBytes      = dto.PaymentPerBytes{Price: money.NewUnits(50, money.CURRENCY_MYST), Bytes: datasize.MB}
+)
+
+func proposalWithFreeCredit(freeCredit *market.FreeCredit) market.ServiceProposal {
+	return market.ServiceProposal{
+		ServiceType:   "openvpn",
+		PaymentMethod: perTime,
+		FreeCredit:    freeCredit,
+	}
+}
+
+func newTestTracker(t *testing.T, proposal market.ServiceProposal) (*Tracker, func()) {
+	dir := boltdbtest.CreateTempDir(t)
+	bolt, err := boltdb.NewStorage(dir)
+	assert.NoError(t, err)
+
+	return NewTracker(bolt, proposal), func() {
+		bolt.Close()
+		boltdbtest.RemoveTempDir(t, dir)
+	}
+}
+
+func myst(units uint64) money.Money {
+	return money.NewUnits(units, money.CURRENCY_MYST)
+}
+
+func TestAmount(t *testing.T) {
+	assert.True(t, Amount(proposalWithFreeCredit(nil)).IsZero())
+	assert.Equal(t, myst(100), Amount(proposalWithFreeCredit(&market.FreeCredit{Duration: 10 * time.Minute})))
+	assert.Equal(
+		t,
+		myst(250),
+		Amount(market.ServiceProposal{PaymentMethod: perBytes, FreeCredit: &market.FreeCredit{Bytes: 5 * datasize.MB}}),
+	)
+}
+
+func TestTracker_WithoutOfferGivesNothing(t *testing.T) {
+	tracker, cleanup := newTestTracker(t, proposalWithFreeCredit(nil))
+	defer cleanup()
+
+	remaining, err := tracker.Remaining(consumer)
+	assert.NoError(t, err)
+	assert.True(t, remaining.IsZero())
+}
+
+func TestTracker_TracksUsagePerConsumer(t *testing.T) {
+	tracker, cleanup := newTestTracker(t, proposalWithFreeCredit(&market.FreeCredit{Duration: 10 * time.Minute, Period: time.Hour}))
+	defer cleanup()
+
+	remaining, err := tracker.Remaining(consumer)
+	assert.NoError(t, err)
+	assert.Equal(t, myst(100), remaining)
+
+	assert.NoError(t, tracker.Use(consumer, myst(30)))
+	assert.NoError(t, tracker.Use(consumer, myst(20)))
+
+	remaining, err = tracker.Remaining(consumer)
+	assert.NoError(t, err)
+	assert.Equal(t, myst(50), remaining)
+
+	remaining, err = tracker.Remaining(otherConsumer)
+	assert.NoError(t, err)
+	assert.Equal(t, myst(100), remaining)
+
+	assert.NoError(t, tracker.Use(consumer, myst(70)))
+	remaining, err = tracker.Remaining(consumer)
+	assert.NoError(t, err)
+	assert.True(t, remaining.IsZero())
+}
+
+func TestTracker_RenewsCreditAfterPeriod(t *testing.T) {
+	tracker, cleanup := newTestTracker(t, proposalWithFreeCredit(&market.FreeCredit{Duration: 10 * time.Minute, Period: time.Hour}))
+	defer cleanup()
+	now := time.Date(2018, 11, 1, 10, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	assert.NoError(t, tracker.Use(consumer, myst(100)))
+
+	now = now.Add(59 * time.Minute)
+	remaining, err := tracker.Remaining(consumer)
+	assert.NoError(t, err)
+	assert.True(t, remaining.IsZero())
+
+	now = now.Add(time.Minute)
+	remaining, err = tracker.Remaining(consumer)
+	assert.NoError(t, err)
+	assert.Equal(t, myst(100), remaining)
+}
+
+func TestTracker_RejectsUsageInOtherCurrency(t *testing.T) {
+	tracker, cleanup := newTestTracker(t, proposalWithFreeCredit(&market.FreeCredit{Duration: 10 * time.Minute}))
+	defer cleanup()
+
+	assert.NoError(t, tracker.Use(consumer, myst(30)))
+	assert.Equal(t, money.ErrCurrencyMismatch, tracker.Use(consumer, money.NewUnits(30, money.Currency("BTC"))))
+
+	remaining, err := tracker.Remaining(consumer)
+	assert.NoError(t, err)
+	assert.Equal(t, myst(70), remaining)
+}
+
+func TestTracker_WithoutPeriodGivesCreditOnce(t *testing.T) {
+	tracker, cleanup := newTestTracker(t, proposalWithFreeCredit(&market.FreeCredit{Duration: 10 * time.Minute}))
+	defer cleanup()
+	now := time.Date(2018, 11, 1, 10, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	assert.NoError(t, tracker.Use(consumer, myst(100)))
+
+	now = now.AddDate(1, 0, 0)
+	remaining, err := tracker.Remaining(consumer)
+	assert.NoError(t, err)
+	assert.True(t, remaining.IsZero())
+}
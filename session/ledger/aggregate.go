@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledger
+
+import (
+	"fmt"
+
+	"github.com/mysteriumnetwork/node/money"
+)
+
+// GroupBy tells by what the entries are aggregated
+type GroupBy string
+
+const (
+	// GroupByDay aggregates entries of every calendar day (UTC)
+	GroupByDay = GroupBy("day")
+	// GroupByMonth aggregates entries of every calendar month (UTC)
+	GroupByMonth = GroupBy("month")
+	// GroupByPeer aggregates entries of every peer
+	GroupByPeer = GroupBy("peer")
+	// GroupBySession aggregates entries of every session
+	GroupBySession = GroupBy("session")
+)
+
+// ParseGroupBy returns the grouping of given name
+func ParseGroupBy(name string) (GroupBy, error) {
+	switch groupBy := GroupBy(name); groupBy {
+	case GroupByDay, GroupByMonth, GroupByPeer, GroupBySession:
+		return groupBy, nil
+	default:
+		return "", fmt.Errorf("unsupported grouping: %q", name)
+	}
+}
+
+// Total is the aggregated amount of a single group, direction and currency
+type Total struct {
+	Group     string
+	Direction Direction
+	Amount    money.Money
+	Entries   int
+}
+
+type totalKey struct {
+	group     string
+	direction Direction
+	currency  money.Currency
+}
+
+// Aggregate sums up the amounts of given entries by the given grouping.
+// Totals are ordered by the first entry of each of them.
+func Aggregate(entries []Entry, groupBy GroupBy) ([]Total, error) {
+	totals := make([]Total, 0)
+	index := make(map[totalKey]int)
+	for _, entry := range entries {
+		key := totalKey{
+			group:     group(entry, groupBy),
+			direction: entry.Direction,
+			currency:  entry.Amount.Currency,
+		}
+		i, found := index[key]
+		if !found {
+			i = len(totals)
+			index[key] = i
+			totals = append(totals, Total{
+				Group:     key.group,
+				Direction: key.direction,
+				Amount:    money.Money{Currency: key.currency},
+			})
+		}
+
+		amount, err := totals[i].Amount.Add(entry.Amount)
+		if err != nil {
+			return nil, err
+		}
+		totals[i].Amount = amount
+		totals[i].Entries++
+	}
+	return totals, nil
+}
+
+func group(entry Entry, groupBy GroupBy) string {
+	switch groupBy {
+	case GroupByDay:
+		return entry.Timestamp.UTC().Format("2006-01-02")
+	case GroupByMonth:
+		return entry.Timestamp.UTC().Format("2006-01")
+	case GroupByPeer:
+		return entry.Peer
+	default:
+		return entry.SessionID
+	}
+}
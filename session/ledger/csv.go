@@ -0,0 +1,71 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledger
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// WriteEntriesCSV writes the given entries as CSV, one entry per line
+func WriteEntriesCSV(writer io.Writer, entries []Entry) error {
+	w := csv.NewWriter(writer)
+	if err := w.Write([]string{"timestamp", "direction", "identity", "peer", "session", "amount", "currency"}); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		err := w.Write([]string{
+			entry.Timestamp.UTC().Format(time.RFC3339),
+			string(entry.Direction),
+			entry.Identity,
+			entry.Peer,
+			entry.SessionID,
+			entry.Amount.Units().String(),
+			string(entry.Amount.Currency),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// WriteTotalsCSV writes the given totals as CSV, one total per line
+func WriteTotalsCSV(writer io.Writer, totals []Total) error {
+	w := csv.NewWriter(writer)
+	if err := w.Write([]string{"group", "direction", "amount", "currency", "entries"}); err != nil {
+		return err
+	}
+	for _, total := range totals {
+		err := w.Write([]string{
+			total.Group,
+			string(total.Direction),
+			total.Amount.Units().String(),
+			string(total.Amount.Currency),
+			strconv.Itoa(total.Entries),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
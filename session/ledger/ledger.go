@@ -0,0 +1,191 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package ledger keeps the record of amounts promised between peers, i.e. what provider earned and consumer spent.
+package ledger
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/money"
+)
+
+const logPrefix = "[ledger] "
+const bucketName = "ledger"
+
+// errBoltNotFound represents the bolts not found error
+var errBoltNotFound = errors.New("not found")
+
+// Direction tells whether the amount was earned or spent
+type Direction string
+
+const (
+	// Earned is the amount provider received from consumer
+	Earned = Direction("earned")
+	// Spent is the amount consumer promised to provider
+	Spent = Direction("spent")
+)
+
+// Storer allows to store and get ledger entries
+type Storer interface {
+	Store(bucket string, object interface{}) error
+	GetAllFrom(bucket string, array interface{}) error
+}
+
+// Entry is a single promise extension as seen by either side of the session
+type Entry struct {
+	ID        int `storm:"id,increment"`
+	Direction Direction
+	Identity  string
+	Peer      string
+	SessionID string
+	Amount    money.Money
+	Timestamp time.Time
+}
+
+// Ledger records the promised amounts and provides the views of them
+type Ledger struct {
+	storage Storer
+	now     func() time.Time
+
+	sessions map[string]string
+	lock     sync.Mutex
+}
+
+// NewLedger returns a new ledger which keeps entries in the given storage
+func NewLedger(storage Storer) *Ledger {
+	return &Ledger{
+		storage:  storage,
+		now:      time.Now,
+		sessions: make(map[string]string),
+	}
+}
+
+// Earnings returns recorder of amounts provider receives from consumer in the given session
+func (l *Ledger) Earnings(provider, consumer identity.Identity, sessionID string, currency money.Currency) *Recorder {
+	return &Recorder{
+		ledger:   l,
+		currency: currency,
+		entry: Entry{
+			Direction: Earned,
+			Identity:  provider.Address,
+			Peer:      consumer.Address,
+			SessionID: sessionID,
+		},
+	}
+}
+
+// Spending returns recorder of amounts consumer promises to provider.
+// Consumer promises before the session is known, so it is resolved from session events when recording.
+func (l *Ledger) Spending(consumer, provider identity.Identity, currency money.Currency) *Recorder {
+	return &Recorder{
+		ledger:   l,
+		currency: currency,
+		entry: Entry{
+			Direction: Spent,
+			Identity:  consumer.Address,
+			Peer:      provider.Address,
+		},
+	}
+}
+
+// ConsumeSessionEvent keeps track of consumer sessions, so that spending is recorded per session
+func (l *Ledger) ConsumeSessionEvent(sessionEvent connection.SessionEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	info := sessionEvent.SessionInfo
+	key := sessionKey(info.ConsumerID.Address, info.Proposal.ProviderID)
+	switch sessionEvent.Status {
+	case connection.SessionCreatedStatus:
+		l.sessions[key] = string(info.SessionID)
+	case connection.SessionEndedStatus:
+		delete(l.sessions, key)
+	}
+}
+
+// Entries returns the entries recorded within the given period, sorted by time.
+// Zero time means that the period is not limited on that side.
+func (l *Ledger) Entries(from, to time.Time) ([]Entry, error) {
+	var all []Entry
+	err := l.storage.GetAllFrom(bucketName, &all)
+	if err != nil && err.Error() != errBoltNotFound.Error() {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0)
+	for _, entry := range all {
+		if !from.IsZero() && entry.Timestamp.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !entry.Timestamp.Before(to) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	return entries, nil
+}
+
+func (l *Ledger) record(entry Entry) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if entry.Direction == Spent {
+		entry.SessionID = l.sessions[sessionKey(entry.Identity, entry.Peer)]
+	}
+	entry.Timestamp = l.now().UTC()
+	if err := l.storage.Store(bucketName, &entry); err != nil {
+		log.Error(logPrefix, "failed to record ", entry.Direction, " amount of session ", entry.SessionID, ": ", err)
+	}
+}
+
+// CurrencyOf returns the currency the given proposal is paid in
+func CurrencyOf(proposal market.ServiceProposal) money.Currency {
+	if proposal.PaymentMethod == nil || proposal.PaymentMethod.GetPrice().Currency == "" {
+		return money.CURRENCY_MYST
+	}
+	return proposal.PaymentMethod.GetPrice().Currency
+}
+
+func sessionKey(consumer, provider string) string {
+	return consumer + "-" + provider
+}
+
+// Recorder records the amounts promised in a single relation between peers
+type Recorder struct {
+	ledger   *Ledger
+	currency money.Currency
+	entry    Entry
+}
+
+// Record records the given amount of promise units in the currency of the relation.
+// Failures are only logged, as the ledger should never interrupt payments.
+func (r *Recorder) Record(amount uint64) {
+	entry := r.entry
+	entry.Amount = money.NewUnits(amount, r.currency)
+	r.ledger.record(entry)
+}
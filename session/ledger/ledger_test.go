@@ -0,0 +1,211 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledger
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	consumer = identity.FromAddress("0xconsumer")
+	provider = identity.FromAddress("0xprovider")
+	day      = time.Date(2019, 5, 31, 23, 0, 0, 0, time.UTC)
+)
+
+type storerFake struct {
+	entries []Entry
+}
+
+func (sf *storerFake) Store(bucket string, object interface{}) error {
+	entry := object.(*Entry)
+	entry.ID = len(sf.entries) + 1
+	sf.entries = append(sf.entries, *entry)
+	return nil
+}
+
+func (sf *storerFake) GetAllFrom(bucket string, array interface{}) error {
+	*array.(*[]Entry) = append([]Entry{}, sf.entries...)
+	return nil
+}
+
+func newTestLedger(storage Storer, now *time.Time) *Ledger {
+	ledger := NewLedger(storage)
+	ledger.now = func() time.Time { return *now }
+	return ledger
+}
+
+func sessionEvent(status string, sessionID session.ID) connection.SessionEvent {
+	return connection.SessionEvent{
+		Status: status,
+		SessionInfo: connection.SessionInfo{
+			SessionID:  sessionID,
+			ConsumerID: consumer,
+			Proposal:   market.ServiceProposal{ProviderID: provider.Address},
+		},
+	}
+}
+
+func myst(units uint64) money.Money {
+	return money.NewUnits(units, money.CURRENCY_MYST)
+}
+
+func aggregate(t *testing.T, entries []Entry, groupBy GroupBy) []Total {
+	totals, err := Aggregate(entries, groupBy)
+	assert.NoError(t, err)
+	return totals
+}
+
+func TestLedger_RecordsEarnings(t *testing.T) {
+	storage := &storerFake{}
+	now := day
+	ledger := newTestLedger(storage, &now)
+
+	recorder := ledger.Earnings(provider, consumer, "session1", money.CURRENCY_MYST)
+	recorder.Record(100)
+
+	assert.Equal(
+		t,
+		[]Entry{{ID: 1, Direction: Earned, Identity: "0xprovider", Peer: "0xconsumer", SessionID: "session1", Amount: myst(100), Timestamp: day}},
+		storage.entries,
+	)
+}
+
+func TestLedger_RecordsSpendingOfCurrentSession(t *testing.T) {
+	storage := &storerFake{}
+	now := day
+	ledger := newTestLedger(storage, &now)
+	recorder := ledger.Spending(consumer, provider, money.CURRENCY_MYST)
+
+	ledger.ConsumeSessionEvent(sessionEvent(connection.SessionCreatedStatus, "session1"))
+	recorder.Record(100)
+	ledger.ConsumeSessionEvent(sessionEvent(connection.SessionEndedStatus, "session1"))
+	recorder.Record(50)
+
+	assert.Len(t, storage.entries, 2)
+	assert.Equal(t, Spent, storage.entries[0].Direction)
+	assert.Equal(t, "0xconsumer", storage.entries[0].Identity)
+	assert.Equal(t, "0xprovider", storage.entries[0].Peer)
+	assert.Equal(t, "session1", storage.entries[0].SessionID)
+	assert.Equal(t, "", storage.entries[1].SessionID)
+}
+
+func TestLedger_EntriesWithinPeriod(t *testing.T) {
+	now := day
+	ledger := newTestLedger(&storerFake{}, &now)
+	recorder := ledger.Earnings(provider, consumer, "session1", money.CURRENCY_MYST)
+	for i := 0; i < 3; i++ {
+		now = day.Add(time.Duration(i) * time.Hour)
+		recorder.Record(uint64(i + 1))
+	}
+
+	entries, err := ledger.Entries(time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	entries, err = ledger.Entries(day.Add(time.Hour), day.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, myst(2), entries[0].Amount)
+}
+
+func TestAggregate(t *testing.T) {
+	entries := []Entry{
+		{Direction: Earned, Peer: "0xconsumer1", SessionID: "session1", Amount: myst(10), Timestamp: day},
+		{Direction: Spent, Peer: "0xprovider", SessionID: "session2", Amount: myst(5), Timestamp: day},
+		{Direction: Earned, Peer: "0xconsumer1", SessionID: "session1", Amount: myst(20), Timestamp: day.Add(2 * time.Hour)},
+		{Direction: Earned, Peer: "0xconsumer2", SessionID: "session3", Amount: myst(30), Timestamp: day.Add(2 * time.Hour)},
+	}
+
+	assert.Equal(
+		t,
+		[]Total{
+			{Group: "2019-05-31", Direction: Earned, Amount: myst(10), Entries: 1},
+			{Group: "2019-05-31", Direction: Spent, Amount: myst(5), Entries: 1},
+			{Group: "2019-06-01", Direction: Earned, Amount: myst(50), Entries: 2},
+		},
+		aggregate(t, entries, GroupByDay),
+	)
+	assert.Equal(
+		t,
+		[]Total{
+			{Group: "0xconsumer1", Direction: Earned, Amount: myst(30), Entries: 2},
+			{Group: "0xprovider", Direction: Spent, Amount: myst(5), Entries: 1},
+			{Group: "0xconsumer2", Direction: Earned, Amount: myst(30), Entries: 1},
+		},
+		aggregate(t, entries, GroupByPeer),
+	)
+}
+
+func TestAggregate_SumsBeyondUint64(t *testing.T) {
+	entries := []Entry{
+		{Direction: Earned, SessionID: "session1", Amount: myst(math.MaxUint64), Timestamp: day},
+		{Direction: Earned, SessionID: "session1", Amount: myst(1), Timestamp: day},
+		{Direction: Earned, SessionID: "session1", Amount: money.NewUnits(5, money.Currency("BTC")), Timestamp: day},
+	}
+
+	totals := aggregate(t, entries, GroupBySession)
+	assert.Len(t, totals, 2)
+	assert.Equal(t, "18446744073709551616", totals[0].Amount.Units().String())
+	assert.Equal(t, money.CURRENCY_MYST, totals[0].Amount.Currency)
+	assert.Equal(t, 2, totals[0].Entries)
+	assert.Equal(t, money.NewUnits(5, money.Currency("BTC")), totals[1].Amount)
+}
+
+func TestParseGroupBy(t *testing.T) {
+	groupBy, err := ParseGroupBy("month")
+	assert.NoError(t, err)
+	assert.Equal(t, GroupByMonth, groupBy)
+
+	_, err = ParseGroupBy("year")
+	assert.EqualError(t, err, `unsupported grouping: "year"`)
+}
+
+func TestWriteEntriesCSV(t *testing.T) {
+	var buffer bytes.Buffer
+	err := WriteEntriesCSV(&buffer, []Entry{
+		{Direction: Earned, Identity: "0xprovider", Peer: "0xconsumer", SessionID: "session1", Amount: myst(10), Timestamp: day},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		"timestamp,direction,identity,peer,session,amount,currency\n"+
+			"2019-05-31T23:00:00Z,earned,0xprovider,0xconsumer,session1,10,MYST\n",
+		buffer.String(),
+	)
+}
+
+func TestWriteTotalsCSV(t *testing.T) {
+	var buffer bytes.Buffer
+	err := WriteTotalsCSV(&buffer, []Total{
+		{Group: "2019-05", Direction: Spent, Amount: myst(150), Entries: 3},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "group,direction,amount,currency,entries\n2019-05,spent,150,MYST,3\n", buffer.String())
+}
@@ -43,11 +43,19 @@ type IDGenerator func() (ID, error)
 
 // ConfigNegotiator is able to handle config negotiations
 type ConfigNegotiator interface {
-	ProvideConfig(consumerKey json.RawMessage) (ServiceConfiguration, DestroyCallback, error)
+	ProvideConfig(sessionID ID, consumerKey json.RawMessage) (ServiceConfiguration, DestroyCallback, error)
 }
 
 // ConfigProvider provides session config for remote client
-type ConfigProvider func(consumerKey json.RawMessage) (ServiceConfiguration, DestroyCallback, error)
+type ConfigProvider func(sessionID ID, consumerKey json.RawMessage) (ServiceConfiguration, DestroyCallback, error)
+
+// ConfigUpdater applies updated consumer config to the running session
+type ConfigUpdater func(sessionID ID, consumerConfig json.RawMessage) error
+
+// ConfigUpdateNegotiator is implemented by services which are able to update config of the running session
+type ConfigUpdateNegotiator interface {
+	UpdateConfig(sessionID ID, consumerConfig json.RawMessage) error
+}
 
 // DestroyCallback cleanups session
 type DestroyCallback func()
@@ -64,11 +72,12 @@ type PromiseProcessor interface {
 type Storage interface {
 	Add(sessionInstance Session)
 	Find(id ID) (Session, bool)
+	// Remove removes the session and closes its Done channel
 	Remove(id ID)
 }
 
-// BalanceTrackerFactory returns a new instance of balance tracker
-type BalanceTrackerFactory func(consumer, provider, issuer identity.Identity) (BalanceTracker, error)
+// BalanceTrackerFactory returns a new instance of balance tracker for the given session
+type BalanceTrackerFactory func(sessionID ID, consumer, provider, issuer identity.Identity) (BalanceTracker, error)
 
 // NewManager returns new session Manager
 func NewManager(
@@ -114,7 +123,7 @@ func (manager *Manager) Create(consumerID identity.Identity, issuerID identity.I
 	sessionInstance.ConsumerID = consumerID
 	sessionInstance.Done = make(chan struct{})
 
-	balanceTracker, err := manager.balanceTrackerFactory(consumerID, identity.FromAddress(manager.currentProposal.ProviderID), issuerID)
+	balanceTracker, err := manager.balanceTrackerFactory(sessionInstance.ID, consumerID, identity.FromAddress(manager.currentProposal.ProviderID), issuerID)
 	if err != nil {
 		return
 	}
@@ -140,6 +149,20 @@ func (manager *Manager) Create(consumerID identity.Identity, issuerID identity.I
 	return sessionInstance, nil
 }
 
+// Find returns the session of given consumer by sessionID
+func (manager *Manager) Find(consumerID identity.Identity, sessionID ID) (Session, error) {
+	sessionInstance, found := manager.sessionStorage.Find(sessionID)
+	if !found {
+		return Session{}, ErrorSessionNotExists
+	}
+
+	if sessionInstance.ConsumerID != consumerID {
+		return Session{}, ErrorWrongSessionOwner
+	}
+
+	return sessionInstance, nil
+}
+
 // Destroy destroys session by given sessionID
 func (manager *Manager) Destroy(consumerID identity.Identity, sessionID string) error {
 	manager.creationLock.Lock()
@@ -156,7 +179,6 @@ func (manager *Manager) Destroy(consumerID identity.Identity, sessionID string)
 	}
 
 	manager.sessionStorage.Remove(ID(sessionID))
-	close(sessionInstance.Done)
 
 	return nil
 }
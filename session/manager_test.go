@@ -55,7 +55,7 @@ func (m mockBalanceTracker) Stop() {
 
 }
 
-func mockBalanceTrackerFactory(consumer, provider, issuer identity.Identity) (BalanceTracker, error) {
+func mockBalanceTrackerFactory(sessionID ID, consumer, provider, issuer identity.Identity) (BalanceTracker, error) {
 	return &mockBalanceTracker{}, nil
 }
 
@@ -79,3 +79,21 @@ func TestManager_Create_RejectsUnknownProposal(t *testing.T) {
 	assert.Exactly(t, err, ErrorInvalidProposal)
 	assert.Exactly(t, Session{}, sessionInstance)
 }
+
+func TestManager_Find_ChecksOwner(t *testing.T) {
+	sessionStore := NewStorageMemory()
+	manager := NewManager(currentProposal, generateSessionID, sessionStore, mockBalanceTrackerFactory)
+
+	sessionInstance, err := manager.Create(consumerID, consumerID, currentProposalID)
+	assert.NoError(t, err)
+
+	found, err := manager.Find(consumerID, sessionInstance.ID)
+	assert.NoError(t, err)
+	assert.Exactly(t, sessionInstance, found)
+
+	_, err = manager.Find(identity.FromAddress("other"), sessionInstance.ID)
+	assert.Exactly(t, ErrorWrongSessionOwner, err)
+
+	_, err = manager.Find(consumerID, ID("unknown-id"))
+	assert.Exactly(t, ErrorSessionNotExists, err)
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package payment
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/money"
+)
+
+// trafficTolerance allows provider to count more data than consumer does, e.g. because of tunnel overhead
+const trafficTolerance = 0.1
+
+// ErrOvercharged indicates that provider charged more than its advertised price allows
+var ErrOvercharged = errors.New("provider charged more than advertised price")
+
+// ChargeValidator checks that the amount charged by provider matches the advertised price of the consumed service
+type ChargeValidator interface {
+	Validate(charged uint64) error
+}
+
+// NoChargeValidation accepts any charge, it is used when consumer is not able to measure the consumed service
+type NoChargeValidation struct{}
+
+// Validate accepts given charge
+func (NoChargeValidation) Validate(charged uint64) error {
+	return nil
+}
+
+// UsageChargeValidator limits the charge to the price of the service consumed so far
+type UsageChargeValidator struct {
+	maxCharge func() money.Money
+}
+
+// NewTimeChargeValidator limits the charge to the price of the time elapsed since its creation, plus one unit for clock differences
+func NewTimeChargeValidator(now func() time.Time, amountCalculator TimeAmountCalculator, unit time.Duration) *UsageChargeValidator {
+	start := now()
+	return &UsageChargeValidator{
+		maxCharge: func() money.Money {
+			return amountCalculator.TotalAmount(now().Sub(start) + unit)
+		},
+	}
+}
+
+// NewTrafficChargeValidator limits the charge to the price of data counted by consumer, plus tolerance for counting differences
+func NewTrafficChargeValidator(traffic TrafficCounter, amountCalculator TrafficAmountCalculator, unit datasize.BitSize) *UsageChargeValidator {
+	return &UsageChargeValidator{
+		maxCharge: func() money.Money {
+			return amountCalculator.TotalAmount(traffic()*(1+trafficTolerance) + unit)
+		},
+	}
+}
+
+// Validate returns ErrOvercharged if given charge exceeds the price of the consumed service
+func (ucv *UsageChargeValidator) Validate(charged uint64) error {
+	maxCharge := ucv.maxCharge()
+	cmp, err := money.NewUnits(charged, maxCharge.Currency).Cmp(maxCharge)
+	if err != nil {
+		return err
+	}
+	if cmp > 0 {
+		return ErrOvercharged
+	}
+	return nil
+}
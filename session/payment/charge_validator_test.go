@@ -0,0 +1,58 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package payment
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TimeChargeValidator(t *testing.T) {
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	validator := NewTimeChargeValidator(func() time.Time { return now }, perMinuteCalculator{price: 5}, time.Minute)
+
+	assert.NoError(t, validator.Validate(5))
+	assert.Equal(t, ErrOvercharged, validator.Validate(10))
+
+	now = now.Add(2*time.Minute + 10*time.Second)
+	assert.NoError(t, validator.Validate(15))
+	assert.Equal(t, ErrOvercharged, validator.Validate(20))
+}
+
+func Test_TrafficChargeValidator(t *testing.T) {
+	transferred := 10 * datasize.GB
+	validator := NewTrafficChargeValidator(func() datasize.BitSize { return transferred }, perGBCalculator{price: 10}, datasize.GB)
+
+	// 10 GB counted by consumer, 11 GB with tolerance, 12 GB with one unit
+	assert.NoError(t, validator.Validate(120))
+	assert.Equal(t, ErrOvercharged, validator.Validate(130))
+}
+
+func Test_UsageChargeValidator_AcceptsAnyChargeBelowMaxChargeBeyondUint64(t *testing.T) {
+	validator := NewTrafficChargeValidator(func() datasize.BitSize { return 10 * datasize.GB }, perGBCalculator{price: math.MaxUint64}, datasize.GB)
+
+	assert.NoError(t, validator.Validate(math.MaxUint64))
+}
+
+func Test_NoChargeValidation(t *testing.T) {
+	assert.NoError(t, NoChargeValidation{}.Validate(1000))
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package payment
+
+import (
+	"time"
+
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/session/balance"
+)
+
+// ExtensionCalculator decides by which amount the promise is extended after provider reports the balance.
+// Free credit is the amount provider offered to consumer for free during the session.
+type ExtensionCalculator interface {
+	AmountToExtend(balance balance.Message, freeCredit uint64) (uint64, error)
+}
+
+// FixedExtension extends the promise by the fixed amount once provider reports the balance is depleted
+type FixedExtension uint64
+
+// AmountToExtend returns the fixed amount for depleted balance and nothing otherwise.
+// Free credit is already included in the balance reported by provider.
+func (fe FixedExtension) AmountToExtend(balance balance.Message, _ uint64) (uint64, error) {
+	if balance.Balance == 0 {
+		return uint64(fe), nil
+	}
+	return 0, nil
+}
+
+// TrafficCounter returns the data transferred through the session tunnel so far
+type TrafficCounter func() datasize.BitSize
+
+// TrafficAmountCalculator is able to deduce the amount required for payment from a given data transferred
+type TrafficAmountCalculator interface {
+	TotalAmount(transferred datasize.BitSize) money.Money
+}
+
+// TimeAmountCalculator is able to deduce the amount required for payment from a given duration
+type TimeAmountCalculator interface {
+	TotalAmount(duration time.Duration) money.Money
+}
+
+// UsageExtension keeps the promise one unit ahead of the service consumed during the session
+type UsageExtension struct {
+	totalCost func() money.Money
+	unitPrice money.Money
+
+	extended money.Money
+}
+
+// NewTrafficExtension returns extension calculator which pays for the consumed data counted by the consumer itself
+func NewTrafficExtension(traffic TrafficCounter, amountCalculator TrafficAmountCalculator, unitPrice money.Money) *UsageExtension {
+	return &UsageExtension{
+		totalCost: func() money.Money {
+			return amountCalculator.TotalAmount(traffic())
+		},
+		unitPrice: unitPrice,
+	}
+}
+
+// NewTimeExtension returns extension calculator which pays for the time elapsed since its creation
+func NewTimeExtension(now func() time.Time, amountCalculator TimeAmountCalculator, unitPrice money.Money) *UsageExtension {
+	start := now()
+	return &UsageExtension{
+		totalCost: func() money.Money {
+			return amountCalculator.TotalAmount(now().Sub(start))
+		},
+		unitPrice: unitPrice,
+	}
+}
+
+// AmountToExtend returns the price of service consumed since the last extension, the free credit is used up first.
+// Promises are still issued in uint64 units, so an amount beyond it is returned as an error.
+func (ue *UsageExtension) AmountToExtend(_ balance.Message, freeCredit uint64) (uint64, error) {
+	required, err := ue.totalCost().Add(ue.unitPrice)
+	if err != nil {
+		return 0, err
+	}
+	covered, err := ue.extended.Add(money.NewUnits(freeCredit, required.Currency))
+	if err != nil {
+		return 0, err
+	}
+
+	amount, err := required.Sub(covered)
+	if err == money.ErrNegativeAmount {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	units, err := amount.Uint64()
+	if err != nil {
+		return 0, err
+	}
+
+	ue.extended, err = ue.extended.Add(amount)
+	return units, err
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package payment
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/session/balance"
+	"github.com/stretchr/testify/assert"
+)
+
+type perGBCalculator struct {
+	price uint64
+}
+
+func (calc perGBCalculator) TotalAmount(transferred datasize.BitSize) money.Money {
+	return myst(calc.price).Mul(uint64(transferred / datasize.GB))
+}
+
+type perMinuteCalculator struct {
+	price uint64
+}
+
+func (calc perMinuteCalculator) TotalAmount(duration time.Duration) money.Money {
+	return myst(calc.price).Mul(uint64(duration / time.Minute))
+}
+
+func assertExtends(t *testing.T, expected uint64, extension ExtensionCalculator, balance balance.Message, freeCredit uint64) {
+	amount, err := extension.AmountToExtend(balance, freeCredit)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, amount)
+}
+
+func Test_FixedExtension(t *testing.T) {
+	extension := FixedExtension(100)
+
+	assertExtends(t, 100, extension, balance.Message{Balance: 0}, 0)
+	assertExtends(t, 0, extension, balance.Message{Balance: 10}, 0)
+}
+
+func Test_TrafficExtension_PaysForConsumedData(t *testing.T) {
+	var transferred datasize.BitSize
+	extension := NewTrafficExtension(func() datasize.BitSize { return transferred }, perGBCalculator{price: 10}, myst(10))
+
+	// the first unit is paid in advance
+	assertExtends(t, 10, extension, balance.Message{}, 0)
+	assertExtends(t, 0, extension, balance.Message{}, 0)
+
+	transferred = 500 * datasize.MB
+	assertExtends(t, 0, extension, balance.Message{}, 0)
+
+	transferred = 3*datasize.GB + 10*datasize.MB
+	assertExtends(t, 30, extension, balance.Message{Balance: 10}, 0)
+	assertExtends(t, 0, extension, balance.Message{Balance: 10}, 0)
+}
+
+func Test_TimeExtension_PaysForElapsedTime(t *testing.T) {
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	extension := NewTimeExtension(func() time.Time { return now }, perMinuteCalculator{price: 5}, myst(5))
+
+	assertExtends(t, 5, extension, balance.Message{}, 0)
+
+	now = now.Add(30 * time.Second)
+	assertExtends(t, 0, extension, balance.Message{}, 0)
+
+	now = now.Add(2 * time.Minute)
+	assertExtends(t, 10, extension, balance.Message{}, 0)
+}
+
+func Test_FixedExtension_IgnoresFreeCredit(t *testing.T) {
+	extension := FixedExtension(100)
+
+	assertExtends(t, 100, extension, balance.Message{Balance: 0}, 50)
+}
+
+func Test_TimeExtension_UsesFreeCreditFirst(t *testing.T) {
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	extension := NewTimeExtension(func() time.Time { return now }, perMinuteCalculator{price: 5}, myst(5))
+
+	assertExtends(t, 0, extension, balance.Message{}, 20)
+
+	now = now.Add(3 * time.Minute)
+	assertExtends(t, 0, extension, balance.Message{}, 20)
+
+	now = now.Add(2 * time.Minute)
+	assertExtends(t, 10, extension, balance.Message{}, 20)
+
+	now = now.Add(time.Minute)
+	assertExtends(t, 5, extension, balance.Message{}, 20)
+}
+
+func Test_TimeExtension_FailsWhenAmountExceedsPromiseUnits(t *testing.T) {
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	extension := NewTimeExtension(func() time.Time { return now }, perMinuteCalculator{price: math.MaxUint64}, myst(5))
+
+	assertExtends(t, 5, extension, balance.Message{}, 0)
+
+	now = now.Add(2 * time.Minute)
+	_, err := extension.AmountToExtend(balance.Message{}, 0)
+	assert.Equal(t, money.ErrOverflow, err)
+}
@@ -18,10 +18,15 @@
 package factory
 
 import (
+	"time"
+
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/core/connection"
 	"github.com/mysteriumnetwork/node/core/node"
 	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
+	"github.com/mysteriumnetwork/node/session"
 	"github.com/mysteriumnetwork/node/session/balance"
 	"github.com/mysteriumnetwork/node/session/payment"
 	"github.com/mysteriumnetwork/node/session/payment/noop"
@@ -30,43 +35,126 @@ import (
 	"github.com/pkg/errors"
 )
 
-// PaymentIssuerFactoryFunc returns a factory for payment issuer. It will be noop if the experimental payment flag is not set
-func PaymentIssuerFactoryFunc(nodeOptions node.Options, signerFactory identity.SignerFactory) func(
+// TODO: this should probably not be hardcoded.
+const defaultExtension = payment.FixedExtension(100)
+
+// SpendingRecorderFactory creates recorder of amounts consumer promises to provider for the given proposal
+type SpendingRecorderFactory func(consumer, provider identity.Identity, proposal market.ServiceProposal) promise.AmountRecorder
+
+// PaymentIssuerFactoryFunc returns a factory for payment issuer. It will be noop if the experimental payment flag is not set.
+// Proposals priced per bytes are paid for the data counted by the traffic counter of the session, if any.
+// Every issued promise state is persisted by the given keeper, so that it can be resumed on the next session.
+func PaymentIssuerFactoryFunc(
+	nodeOptions node.Options,
+	signerFactory identity.SignerFactory,
+	keeper promise.StateKeeper,
+	newSpendingRecorder SpendingRecorderFactory,
+) func(
 	initialState promise.State,
 	messageChan chan balance.Message,
 	dialog communication.Dialog,
-	consumer, provider identity.Identity) (connection.PaymentIssuer, error) {
+	consumer, provider identity.Identity,
+	proposal market.ServiceProposal,
+	spendingGuard connection.SpendingGuard,
+	traffic connection.TrafficCounter) (connection.PaymentIssuer, error) {
 	if !nodeOptions.ExperimentPayments {
 		return noopPaymentIssuerFactory
 	}
-	return paymentIssuerFactory(signerFactory)
+	return paymentIssuerFactory(signerFactory, keeper, newSpendingRecorder)
 }
 
 func noopPaymentIssuerFactory(initialState promise.State,
 	messageChan chan balance.Message,
 	dialog communication.Dialog,
-	consumer, provider identity.Identity) (connection.PaymentIssuer, error) {
+	consumer, provider identity.Identity,
+	proposal market.ServiceProposal,
+	spendingGuard connection.SpendingGuard,
+	traffic connection.TrafficCounter) (connection.PaymentIssuer, error) {
 	return noop.NewSessionBalance(), nil
 
 }
 
-func paymentIssuerFactory(signerFactory identity.SignerFactory) func(
+func paymentIssuerFactory(signerFactory identity.SignerFactory, keeper promise.StateKeeper, newSpendingRecorder SpendingRecorderFactory) func(
 	initialState promise.State,
 	messageChan chan balance.Message,
 	dialog communication.Dialog,
-	consumer, provider identity.Identity) (connection.PaymentIssuer, error) {
+	consumer, provider identity.Identity,
+	proposal market.ServiceProposal,
+	spendingGuard connection.SpendingGuard,
+	traffic connection.TrafficCounter) (connection.PaymentIssuer, error) {
 	return func(
 		initialState promise.State,
 		messageChan chan balance.Message,
 		dialog communication.Dialog,
-		consumer, provider identity.Identity) (connection.PaymentIssuer, error) {
+		consumer, provider identity.Identity,
+		proposal market.ServiceProposal,
+		spendingGuard connection.SpendingGuard,
+		traffic connection.TrafficCounter) (connection.PaymentIssuer, error) {
 
 		bl := balance.NewListener(messageChan)
 		ps := promise.NewSender(dialog)
 		issuer := issuers.NewLocalIssuer(signerFactory(consumer))
-		tracker := promise.NewConsumerTracker(initialState, consumer, provider, issuer)
-		payments := payment.NewSessionPayments(messageChan, ps, tracker)
-		err := dialog.Receive(bl.GetConsumer())
+		tracker := promise.NewConsumerTracker(initialState, consumer, provider, issuer, keeper, newSpendingRecorder(consumer, provider, proposal))
+		extension, err := newExtension(proposal, payment.TrafficCounter(traffic))
+		if err != nil {
+			return nil, err
+		}
+		chargeValidator, err := newChargeValidator(proposal, payment.TrafficCounter(traffic))
+		if err != nil {
+			return nil, err
+		}
+		payments := payment.NewSessionPayments(messageChan, ps, tracker, extension, chargeValidator, spendingGuard)
+		err = dialog.Receive(bl.GetConsumer())
 		return payments, errors.Wrap(err, "fail to receive from consumer")
 	}
 }
+
+// newExtension pays for the consumed data or time at the price, rounding and minimum charge advertised in the proposal
+func newExtension(proposal market.ServiceProposal, traffic payment.TrafficCounter) (payment.ExtensionCalculator, error) {
+	switch method := proposal.PaymentMethod.(type) {
+	case dto.PaymentPerBytes:
+		if traffic == nil {
+			price, err := method.Price.Uint64()
+			if err != nil {
+				return nil, err
+			}
+			return payment.FixedExtension(price), nil
+		}
+		calc, err := session.NewTrafficAmountCalc(method)
+		if err != nil {
+			return nil, err
+		}
+		return payment.NewTrafficExtension(traffic, calc, method.Price), nil
+	case dto.PaymentPerTime:
+		calc, err := session.NewAmountCalc(method)
+		if err != nil {
+			return nil, err
+		}
+		return payment.NewTimeExtension(time.Now, calc, method.Price), nil
+	default:
+		return defaultExtension, nil
+	}
+}
+
+// newChargeValidator checks that provider charges no more than the price, rounding and minimum charge advertised in the proposal
+func newChargeValidator(proposal market.ServiceProposal, traffic payment.TrafficCounter) (payment.ChargeValidator, error) {
+	switch method := proposal.PaymentMethod.(type) {
+	case dto.PaymentPerBytes:
+		if traffic == nil {
+			return payment.NoChargeValidation{}, nil
+		}
+		calc, err := session.NewTrafficAmountCalc(method)
+		if err != nil {
+			return nil, err
+		}
+		return payment.NewTrafficChargeValidator(traffic, calc, method.Bytes), nil
+	case dto.PaymentPerTime:
+		calc, err := session.NewAmountCalc(method)
+		if err != nil {
+			return nil, err
+		}
+		return payment.NewTimeChargeValidator(time.Now, calc, method.Duration), nil
+	default:
+		return payment.NoChargeValidation{}, nil
+	}
+}
@@ -40,3 +40,7 @@ func (nsb *SessionBalance) Start() error {
 func (nsb *SessionBalance) Stop() {
 	close(nsb.stopChan)
 }
+
+// SetFreeCredit ignores the free credit, as nothing is paid anyway
+func (nsb *SessionBalance) SetFreeCredit(amount uint64) {
+}
@@ -25,12 +25,15 @@ import (
 	"time"
 
 	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/session/balance"
 	"github.com/mysteriumnetwork/node/session/promise"
 )
 
 // PromiseStorage stores the promises and issues new sequenceID's
 type PromiseStorage interface {
+	Acquire(issuerID identity.Identity)
+	Release(issuerID identity.Identity)
 	GetNewSeqIDForIssuer(issuerID identity.Identity) (uint64, error)
 	Update(issuerID identity.Identity, promise promise.StoredPromise) error
 	GetLastPromise(issuerID identity.Identity) (promise.StoredPromise, error)
@@ -38,9 +41,17 @@ type PromiseStorage interface {
 
 // BalanceTracker keeps track of current balance
 type BalanceTracker interface {
-	GetBalance() uint64
+	GetBalance() (money.Money, error)
 	Start()
-	Add(amount uint64)
+	Add(amount money.Money) error
+	AddFreeCredit(amount money.Money) error
+	FreeCreditUsed() money.Money
+}
+
+// FreeCreditKeeper keeps track of the free credit which provider offers to consumers
+type FreeCreditKeeper interface {
+	Remaining(consumer identity.Identity) (money.Money, error)
+	Use(consumer identity.Identity, amount money.Money) error
 }
 
 // PromiseValidator validates given promise
@@ -70,8 +81,13 @@ type SessionBalance struct {
 	promiseValidator   PromiseValidator
 	promiseStorage     PromiseStorage
 	issuer             identity.Identity
+	earnings           promise.AmountRecorder
+	freeCreditKeeper   FreeCreditKeeper
+	currency           money.Currency
 
-	sequenceID uint64
+	sequenceID         uint64
+	freeCredit         money.Money
+	freeCreditRecorded money.Money
 }
 
 // NewSessionBalance creates a new instance of provider payment orchestrator
@@ -83,7 +99,10 @@ func NewSessionBalance(
 	promiseWaitTimeout time.Duration,
 	promiseValidator PromiseValidator,
 	promiseStorage PromiseStorage,
-	issuer identity.Identity) *SessionBalance {
+	issuer identity.Identity,
+	earnings promise.AmountRecorder,
+	freeCreditKeeper FreeCreditKeeper,
+	currency money.Currency) *SessionBalance {
 	return &SessionBalance{
 		stop:               make(chan struct{}),
 		peerBalanceSender:  peerBalanceSender,
@@ -94,17 +113,26 @@ func NewSessionBalance(
 		promiseValidator:   promiseValidator,
 		promiseStorage:     promiseStorage,
 		issuer:             issuer,
+		earnings:           earnings,
+		freeCreditKeeper:   freeCreditKeeper,
+		currency:           currency,
 	}
 }
 
 // Start starts the payment orchestrator. Blocks.
+// Promises of the issuer are not settled while the orchestrator runs.
 func (sb *SessionBalance) Start() error {
+	sb.promiseStorage.Acquire(sb.issuer)
+	defer sb.promiseStorage.Release(sb.issuer)
+
 	lastPromise, err := sb.loadInitialPromiseState()
 	if err != nil {
 		return err
 	}
 
-	sb.startBalanceTracker(lastPromise)
+	if err := sb.startBalanceTracker(lastPromise); err != nil {
+		return err
+	}
 
 	for {
 		select {
@@ -134,28 +162,62 @@ func (sb *SessionBalance) loadInitialPromiseState() (promise.StoredPromise, erro
 	return lastPromise, nil
 }
 
-func (sb *SessionBalance) startBalanceTracker(lastPromise promise.StoredPromise) {
-	amountToAdd := lastPromise.UnconsumedAmount
+func (sb *SessionBalance) startBalanceTracker(lastPromise promise.StoredPromise) error {
+	freeCredit, err := sb.freeCreditKeeper.Remaining(sb.issuer)
+	if err != nil {
+		return err
+	}
+	sb.freeCredit = freeCredit
 
-	sb.balanceTracker.Add(amountToAdd)
+	if err := sb.balanceTracker.Add(lastPromise.UnconsumedAmount); err != nil {
+		return err
+	}
+	if err := sb.balanceTracker.AddFreeCredit(freeCredit); err != nil {
+		return err
+	}
 	sb.balanceTracker.Start()
+	return nil
 }
 
 func (sb *SessionBalance) sendBalance() error {
-	currentBalance := sb.balanceTracker.GetBalance()
+	currentBalance, err := sb.balanceTracker.GetBalance()
+	if err != nil {
+		return err
+	}
+	// the balance message is still sent in uint64 units
+	balanceAmount, err := currentBalance.Uint64()
+	if err != nil {
+		return err
+	}
+
+	freeCreditUsed := sb.balanceTracker.FreeCreditUsed()
+	if err := sb.recordFreeCreditUsage(freeCreditUsed); err != nil {
+		return err
+	}
+
 	p, err := sb.promiseStorage.GetLastPromise(sb.issuer)
 	if err != nil {
 		return err
 	}
 
+	// the free credit left was never promised by consumer, so it is not kept with the promise
+	freeCreditLeft, err := sb.freeCredit.Sub(freeCreditUsed)
+	if err != nil {
+		return fmt.Errorf("free credit used exceeds the given one: %v", err)
+	}
+	promisedBalance, err := currentBalance.Sub(freeCreditLeft)
+	if err != nil {
+		return fmt.Errorf("balance is below the free credit left: %v", err)
+	}
+
 	// if we're ever in a situation where the unconsumed amount is zero, but the balance is not - something is definitely not right
-	if p.UnconsumedAmount == 0 && currentBalance != 0 {
-		return fmt.Errorf("unconsumed amount is 0, while balance is %v", currentBalance)
+	if p.UnconsumedAmount.IsZero() && !promisedBalance.IsZero() {
+		return fmt.Errorf("unconsumed amount is 0, while balance is %v", promisedBalance)
 	}
 
 	err = sb.promiseStorage.Update(sb.issuer, promise.StoredPromise{
 		SequenceID:       p.SequenceID,
-		UnconsumedAmount: currentBalance,
+		UnconsumedAmount: promisedBalance,
 		Message:          p.Message,
 		AddedAt:          p.AddedAt,
 	})
@@ -165,18 +227,37 @@ func (sb *SessionBalance) sendBalance() error {
 
 	// TODO: figure out when to get a new sequenceID
 	return sb.peerBalanceSender.Send(balance.Message{
-		Balance:    currentBalance,
+		Balance:    balanceAmount,
 		SequenceID: sb.sequenceID,
 	})
 }
 
-func (sb *SessionBalance) calculateAmountToAdd(pm promise.Message, p promise.StoredPromise) uint64 {
+// recordFreeCreditUsage stores the free credit consumed since the last record, so that it is not given again in other sessions
+func (sb *SessionBalance) recordFreeCreditUsage(used money.Money) error {
+	unrecorded, err := used.Sub(sb.freeCreditRecorded)
+	if err == money.ErrNegativeAmount || (err == nil && unrecorded.IsZero()) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := sb.freeCreditKeeper.Use(sb.issuer, unrecorded); err != nil {
+		return err
+	}
+	sb.freeCreditRecorded = used
+	return nil
+}
+
+func (sb *SessionBalance) calculateAmountToAdd(pm promise.Message, p promise.StoredPromise) (uint64, error) {
 	var amountToSubtract uint64
 	if p.Message != nil {
 		amountToSubtract = p.Message.Amount
 	}
-	amountToAdd := pm.Amount - amountToSubtract
-	return amountToAdd
+	if pm.Amount < amountToSubtract {
+		return 0, fmt.Errorf("promised amount decreased from %v to %v", amountToSubtract, pm.Amount)
+	}
+	return pm.Amount - amountToSubtract, nil
 }
 
 func (sb *SessionBalance) storePromiseAndUpdateBalance(pm promise.Message) error {
@@ -184,16 +265,31 @@ func (sb *SessionBalance) storePromiseAndUpdateBalance(pm promise.Message) error
 	if err != nil {
 		return err
 	}
-	amount := sb.calculateAmountToAdd(pm, p)
-	sb.balanceTracker.Add(amount)
+	amount, err := sb.calculateAmountToAdd(pm, p)
+	if err != nil {
+		return err
+	}
+	amountToAdd := money.NewUnits(amount, sb.currency)
+	unconsumedAmount, err := p.UnconsumedAmount.Add(amountToAdd)
+	if err != nil {
+		return err
+	}
+	if err := sb.balanceTracker.Add(amountToAdd); err != nil {
+		return err
+	}
 
 	err = sb.promiseStorage.Update(sb.issuer, promise.StoredPromise{
 		SequenceID:       pm.SequenceID,
 		Message:          &pm,
-		UnconsumedAmount: p.UnconsumedAmount + amount,
+		UnconsumedAmount: unconsumedAmount,
 		AddedAt:          p.AddedAt,
 	})
-	return err
+	if err != nil {
+		return err
+	}
+
+	sb.earnings.Record(amount)
+	return nil
 }
 
 func (sb *SessionBalance) receivePromiseOrTimeout() error {
@@ -19,12 +19,15 @@ package payment
 
 import (
 	"errors"
+	"math"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/session/balance"
 	"github.com/mysteriumnetwork/node/session/promise"
 )
@@ -33,12 +36,14 @@ var (
 	promiseChannel      = make(chan promise.Message)
 	issuer              = identity.FromAddress("0x0")
 	BalanceSender       = &MockPeerBalanceSender{balanceMessages: make(chan balance.Message)}
-	MBT                 = &MockBalanceTracker{balanceMessage: balance.Message{Balance: 0, SequenceID: 1}}
+	MBT                 = &MockBalanceTracker{}
 	MPV                 = &MockPromiseValidator{isValid: true}
 	mockPromiseToReturn = promise.StoredPromise{
 		SequenceID: 1,
 	}
 	MPS = &MockPromiseStorage{promiseToReturn: mockPromiseToReturn}
+	MAR = &MockAmountRecorder{}
+	MFK = &MockFreeCreditKeeper{}
 )
 
 func NewMockSessionBalance(mpv *MockPromiseValidator, mps *MockPromiseStorage, mbt *MockBalanceTracker) *SessionBalance {
@@ -51,9 +56,16 @@ func NewMockSessionBalance(mpv *MockPromiseValidator, mps *MockPromiseStorage, m
 		mpv,
 		mps,
 		issuer,
+		MAR,
+		MFK,
+		money.CURRENCY_MYST,
 	)
 }
 
+func myst(units uint64) money.Money {
+	return money.NewUnits(units, money.CURRENCY_MYST)
+}
+
 func Test_SessionBalanceStartStop(t *testing.T) {
 	orch := NewMockSessionBalance(MPV, MPS, MBT)
 	go func() {
@@ -71,6 +83,21 @@ func Test_SessionBalanceSendsBalance(t *testing.T) {
 	assert.Exactly(t, balance.Message{SequenceID: 1, Balance: 0}, <-BalanceSender.balanceMessages)
 }
 
+func Test_SessionBalanceHoldsIssuerPromisesWhileRunning(t *testing.T) {
+	mps := MockPromiseStorage{promiseToReturn: mockPromiseToReturn}
+	orch := NewMockSessionBalance(MPV, &mps, MBT)
+	testDone := make(chan error)
+	go func() {
+		testDone <- orch.Start()
+	}()
+
+	<-BalanceSender.balanceMessages
+	assert.Equal(t, int32(1), atomic.LoadInt32(&mps.acquired))
+
+	assert.Equal(t, ErrPromiseWaitTimeout, <-testDone)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&mps.acquired))
+}
+
 func Test_SessionBalanceSendsBalance_Timeouts(t *testing.T) {
 	orch := NewMockSessionBalance(MPV, MPS, MBT)
 	defer orch.Stop()
@@ -141,20 +168,77 @@ func Test_SessionBalance_LoadInitialPromiseState_BubblesErrors(t *testing.T) {
 }
 
 func Test_SessionBalance_StartBalanceTracker_AddsUnconsumedAmount(t *testing.T) {
-	mbt := *MBT
+	mbt := MockBalanceTracker{}
 	orch := NewMockSessionBalance(MPV, MPS, &mbt)
-	lp := promise.StoredPromise{UnconsumedAmount: 100}
-	orch.startBalanceTracker(lp)
+	lp := promise.StoredPromise{UnconsumedAmount: myst(100)}
+	assert.NoError(t, orch.startBalanceTracker(lp))
 	assert.Equal(t, lp.UnconsumedAmount, mbt.amountAdded)
 }
 
+func Test_SessionBalance_StartBalanceTracker_AddsRemainingFreeCredit(t *testing.T) {
+	mbt := MockBalanceTracker{}
+	orch := NewMockSessionBalance(MPV, MPS, &mbt)
+	orch.freeCreditKeeper = &MockFreeCreditKeeper{remaining: myst(40)}
+
+	assert.NoError(t, orch.startBalanceTracker(promise.StoredPromise{}))
+	assert.Equal(t, myst(40), mbt.freeCreditAdded)
+	assert.True(t, mbt.startCalled)
+}
+
+func Test_SessionBalance_StartBalanceTracker_BubblesFreeCreditErrors(t *testing.T) {
+	mbt := MockBalanceTracker{}
+	orch := NewMockSessionBalance(MPV, MPS, &mbt)
+	keeperErr := errors.New("test")
+	orch.freeCreditKeeper = &MockFreeCreditKeeper{err: keeperErr}
+
+	assert.Equal(t, keeperErr, orch.startBalanceTracker(promise.StoredPromise{}))
+	assert.False(t, mbt.startCalled)
+}
+
+func Test_SessionBalance_SendBalanceRecordsFreeCreditUsage(t *testing.T) {
+	mps := *MPS
+	mbt := MockBalanceTracker{}
+	balanceSender := &MockPeerBalanceSender{balanceMessages: make(chan balance.Message, 2)}
+	keeper := &MockFreeCreditKeeper{remaining: myst(40)}
+	orch := NewMockSessionBalance(MPV, &mps, &mbt)
+	orch.peerBalanceSender = balanceSender
+	orch.freeCreditKeeper = keeper
+	assert.NoError(t, orch.startBalanceTracker(promise.StoredPromise{}))
+
+	mbt.balance = myst(25)
+	mbt.freeCreditUsed = myst(15)
+	assert.NoError(t, orch.sendBalance())
+	assert.Equal(t, uint64(25), (<-balanceSender.balanceMessages).Balance)
+	assert.True(t, mps.updated.UnconsumedAmount.IsZero())
+
+	mbt.balance = myst(0)
+	mbt.freeCreditUsed = myst(40)
+	assert.NoError(t, orch.sendBalance())
+	assert.Equal(t, uint64(0), (<-balanceSender.balanceMessages).Balance)
+	assert.Equal(t, []money.Money{myst(15), myst(25)}, keeper.used)
+}
+
+func Test_SessionBalance_SendBalanceFailsWhenBalanceDoesNotFitMessage(t *testing.T) {
+	mps := *MPS
+	mps.promiseToReturn.UnconsumedAmount = myst(1)
+	mbt := MockBalanceTracker{}
+	balanceSender := &MockPeerBalanceSender{balanceMessages: make(chan balance.Message, 1)}
+	orch := NewMockSessionBalance(MPV, &mps, &mbt)
+	orch.peerBalanceSender = balanceSender
+
+	mbt.balance, _ = myst(math.MaxUint64).Add(myst(1))
+	assert.Equal(t, money.ErrOverflow, orch.sendBalance())
+	assert.Len(t, balanceSender.balanceMessages, 0)
+}
+
 func Test_SessionBalance_CalculateAmountToAdd(t *testing.T) {
 	orch := NewMockSessionBalance(MPV, MPS, MBT)
 	lp := promise.StoredPromise{}
 	msg := promise.Message{
 		Amount: 100,
 	}
-	amount := orch.calculateAmountToAdd(msg, lp)
+	amount, err := orch.calculateAmountToAdd(msg, lp)
+	assert.NoError(t, err)
 	assert.Equal(t, msg.Amount, amount)
 
 	lp = promise.StoredPromise{
@@ -162,7 +246,8 @@ func Test_SessionBalance_CalculateAmountToAdd(t *testing.T) {
 			Amount: 50,
 		},
 	}
-	amount = orch.calculateAmountToAdd(msg, lp)
+	amount, err = orch.calculateAmountToAdd(msg, lp)
+	assert.NoError(t, err)
 	assert.Equal(t, lp.Message.Amount, amount)
 
 	lp = promise.StoredPromise{
@@ -170,9 +255,71 @@ func Test_SessionBalance_CalculateAmountToAdd(t *testing.T) {
 			Amount: 100,
 		},
 	}
-	amount = orch.calculateAmountToAdd(msg, lp)
+	amount, err = orch.calculateAmountToAdd(msg, lp)
+	assert.NoError(t, err)
 	assert.Equal(t, uint64(0), amount)
 
+	lp = promise.StoredPromise{
+		Message: &promise.Message{
+			Amount: 150,
+		},
+	}
+	_, err = orch.calculateAmountToAdd(msg, lp)
+	assert.EqualError(t, err, "promised amount decreased from 150 to 100")
+}
+
+func Test_SessionBalance_StorePromiseRecordsEarnedAmount(t *testing.T) {
+	mps := *MPS
+	mps.promiseToReturn = promise.StoredPromise{
+		SequenceID: 1,
+		Message:    &promise.Message{Amount: 50, SequenceID: 1},
+	}
+	mbt := MockBalanceTracker{}
+	orch := NewMockSessionBalance(MPV, &mps, &mbt)
+	recorder := &MockAmountRecorder{}
+	orch.earnings = recorder
+
+	err := orch.storePromiseAndUpdateBalance(promise.Message{Amount: 80, SequenceID: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, myst(30), mbt.amountAdded)
+	assert.Equal(t, myst(30), mps.updated.UnconsumedAmount)
+	assert.Equal(t, []uint64{30}, recorder.recorded)
+}
+
+func Test_SessionBalance_StorePromiseDoesNotRecordWhenUpdateFails(t *testing.T) {
+	mps := *MPS
+	mps.updateError = errors.New("test")
+	mbt := MockBalanceTracker{}
+	orch := NewMockSessionBalance(MPV, &mps, &mbt)
+	recorder := &MockAmountRecorder{}
+	orch.earnings = recorder
+
+	err := orch.storePromiseAndUpdateBalance(promise.Message{Amount: 80, SequenceID: 1})
+	assert.Equal(t, mps.updateError, err)
+	assert.Len(t, recorder.recorded, 0)
+}
+
+type MockFreeCreditKeeper struct {
+	remaining money.Money
+	err       error
+	used      []money.Money
+}
+
+func (mfk *MockFreeCreditKeeper) Remaining(consumer identity.Identity) (money.Money, error) {
+	return mfk.remaining, mfk.err
+}
+
+func (mfk *MockFreeCreditKeeper) Use(consumer identity.Identity, amount money.Money) error {
+	mfk.used = append(mfk.used, amount)
+	return mfk.err
+}
+
+type MockAmountRecorder struct {
+	recorded []uint64
+}
+
+func (mar *MockAmountRecorder) Record(amount uint64) {
+	mar.recorded = append(mar.recorded, amount)
 }
 
 type MockPromiseStorage struct {
@@ -180,6 +327,16 @@ type MockPromiseStorage struct {
 	newIDerror       error
 	updateError      error
 	lastPromiseError error
+	updated          promise.StoredPromise
+	acquired         int32
+}
+
+func (mps *MockPromiseStorage) Acquire(issuerID identity.Identity) {
+	atomic.AddInt32(&mps.acquired, 1)
+}
+
+func (mps *MockPromiseStorage) Release(issuerID identity.Identity) {
+	atomic.AddInt32(&mps.acquired, -1)
 }
 
 func (mps *MockPromiseStorage) GetNewSeqIDForIssuer(issuerID identity.Identity) (uint64, error) {
@@ -187,6 +344,7 @@ func (mps *MockPromiseStorage) GetNewSeqIDForIssuer(issuerID identity.Identity)
 }
 
 func (mps *MockPromiseStorage) Update(issuerID identity.Identity, p promise.StoredPromise) error {
+	mps.updated = p
 	return mps.updateError
 }
 
@@ -205,17 +363,29 @@ func (mpbs *MockPeerBalanceSender) Send(b balance.Message) error {
 }
 
 type MockBalanceTracker struct {
-	balanceMessage balance.Message
-	amountAdded    uint64
-	startCalled    bool
+	balance         money.Money
+	amountAdded     money.Money
+	freeCreditAdded money.Money
+	freeCreditUsed  money.Money
+	startCalled     bool
 }
 
-func (mbt *MockBalanceTracker) GetBalance() uint64 {
-	return mbt.balanceMessage.Balance
+func (mbt *MockBalanceTracker) GetBalance() (money.Money, error) {
+	return mbt.balance, nil
 }
 
-func (mbt *MockBalanceTracker) Add(amount uint64) {
+func (mbt *MockBalanceTracker) Add(amount money.Money) error {
 	mbt.amountAdded = amount
+	return nil
+}
+
+func (mbt *MockBalanceTracker) AddFreeCredit(amount money.Money) error {
+	mbt.freeCreditAdded = amount
+	return nil
+}
+
+func (mbt *MockBalanceTracker) FreeCreditUsed() money.Money {
+	return mbt.freeCreditUsed
 }
 
 func (mbt *MockBalanceTracker) Start() {
@@ -20,6 +20,7 @@ package payment
 import (
 	"encoding/hex"
 	"fmt"
+	"sync"
 
 	"github.com/mysteriumnetwork/node/session/balance"
 	"github.com/mysteriumnetwork/node/session/promise"
@@ -37,21 +38,57 @@ type PromiseTracker interface {
 	ExtendPromise(amountToAdd uint64) (promises.IssuedPromise, error)
 }
 
+// SpendingGuard approves consumer spending before promises are extended
+type SpendingGuard interface {
+	Spend(amount uint64) error
+	Refund(amount uint64) error
+}
+
+// NoSpendingLimits approves any spending
+type NoSpendingLimits struct{}
+
+// Spend approves spending of any amount
+func (NoSpendingLimits) Spend(amount uint64) error {
+	return nil
+}
+
+// Refund takes back nothing, as nothing was recorded
+func (NoSpendingLimits) Refund(amount uint64) error {
+	return nil
+}
+
 // SessionPayments orchestrates the ping pong of balance received from provider -> promise sent to provider flow
 type SessionPayments struct {
 	stop              chan struct{}
 	balanceChan       chan balance.Message
 	peerPromiseSender PeerPromiseSender
 	promiseTracker    PromiseTracker
+	extension         ExtensionCalculator
+	chargeValidator   ChargeValidator
+	spendingGuard     SpendingGuard
+
+	promised       uint64
+	freeCredit     uint64
+	freeCreditLock sync.Mutex
 }
 
 // NewSessionPayments returns a new instance of consumer payment orchestrator
-func NewSessionPayments(balanceChan chan balance.Message, peerPromiseSender PeerPromiseSender, promiseTracker PromiseTracker) *SessionPayments {
+func NewSessionPayments(
+	balanceChan chan balance.Message,
+	peerPromiseSender PeerPromiseSender,
+	promiseTracker PromiseTracker,
+	extension ExtensionCalculator,
+	chargeValidator ChargeValidator,
+	spendingGuard SpendingGuard,
+) *SessionPayments {
 	return &SessionPayments{
 		stop:              make(chan struct{}),
 		balanceChan:       balanceChan,
 		peerPromiseSender: peerPromiseSender,
 		promiseTracker:    promiseTracker,
+		extension:         extension,
+		chargeValidator:   chargeValidator,
+		spendingGuard:     spendingGuard,
 	}
 }
 
@@ -62,6 +99,10 @@ func (cpo *SessionPayments) Start() error {
 		case <-cpo.stop:
 			return nil
 		case balance := <-cpo.balanceChan:
+			freeCredit := cpo.getFreeCredit()
+			if err := cpo.validateCharge(balance, freeCredit); err != nil {
+				return err
+			}
 			err := cpo.promiseTracker.AlignStateWithProvider(promise.State{
 				Seq:    balance.SequenceID,
 				Amount: balance.Balance,
@@ -69,15 +110,22 @@ func (cpo *SessionPayments) Start() error {
 			if err != nil {
 				return err
 			}
-			var amountToExtend uint64
-			if balance.Balance == 0 {
-				// TODO: this should probably not be hardcoded.
-				amountToExtend = 100
+			amountToExtend, err := cpo.extension.AmountToExtend(balance, freeCredit)
+			if err != nil {
+				return err
+			}
+			if err := cpo.spendingGuard.Spend(amountToExtend); err != nil {
+				return err
 			}
 			issuedPromise, err := cpo.promiseTracker.ExtendPromise(amountToExtend)
 			if err != nil {
+				// nothing was promised, so nothing was spent either
+				if refundErr := cpo.spendingGuard.Refund(amountToExtend); refundErr != nil {
+					return fmt.Errorf("%v, spending was not refunded: %v", err, refundErr)
+				}
 				return err
 			}
+			cpo.promised += amountToExtend
 			err = cpo.peerPromiseSender.Send(promise.Message{
 				Amount:     issuedPromise.Promise.Amount,
 				SequenceID: issuedPromise.Promise.SeqNo,
@@ -90,6 +138,28 @@ func (cpo *SessionPayments) Start() error {
 	}
 }
 
+// SetFreeCredit sets the amount which provider offered for free, it is used up before issuing promises
+func (cpo *SessionPayments) SetFreeCredit(amount uint64) {
+	cpo.freeCreditLock.Lock()
+	defer cpo.freeCreditLock.Unlock()
+	cpo.freeCredit = amount
+}
+
+func (cpo *SessionPayments) getFreeCredit() uint64 {
+	cpo.freeCreditLock.Lock()
+	defer cpo.freeCreditLock.Unlock()
+	return cpo.freeCredit
+}
+
+// validateCharge checks the amount provider charged from everything promised and given for free during the session
+func (cpo *SessionPayments) validateCharge(balance balance.Message, freeCredit uint64) error {
+	available := cpo.promised + freeCredit
+	if balance.Balance >= available {
+		return nil
+	}
+	return cpo.chargeValidator.Validate(available - balance.Balance)
+}
+
 // Stop stops the payment orchestrator
 func (cpo *SessionPayments) Stop() {
 	close(cpo.stop)
@@ -0,0 +1,221 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package payment
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/session/balance"
+	"github.com/mysteriumnetwork/node/session/balance/provider"
+	"github.com/mysteriumnetwork/node/session/promise"
+	"github.com/mysteriumnetwork/payments/promises"
+)
+
+var (
+	paidConsumer = identity.FromAddress("0x1")
+	paidProvider = identity.FromAddress("0x2")
+)
+
+// sessionClock advances by a minute each time provider calculates the balance, so that every round consumes the service
+type sessionClock struct {
+	minutes int64
+	started int64
+}
+
+func (sc *sessionClock) now() time.Time {
+	return time.Unix(atomic.LoadInt64(&sc.minutes)*60, 0)
+}
+
+func (sc *sessionClock) StartTracking() {
+	atomic.StoreInt64(&sc.started, atomic.LoadInt64(&sc.minutes))
+}
+
+func (sc *sessionClock) Elapsed() time.Duration {
+	elapsed := atomic.AddInt64(&sc.minutes, 1) - atomic.LoadInt64(&sc.started)
+	return time.Duration(elapsed) * time.Minute
+}
+
+type unsignedIssuer struct{}
+
+func (unsignedIssuer) Issue(p promises.Promise) (promises.IssuedPromise, error) {
+	return promises.IssuedPromise{Promise: p}, nil
+}
+
+type consumerStateKeeper struct {
+	state promise.State
+}
+
+func (csk *consumerStateKeeper) Save(consumer, provider identity.Identity, state promise.State) error {
+	csk.state = state
+	return nil
+}
+
+// providerPromiseStorage keeps the promises of a single issuer in memory
+type providerPromiseStorage struct {
+	promises []promise.StoredPromise
+	sync.Mutex
+}
+
+func (pps *providerPromiseStorage) Acquire(issuerID identity.Identity) {}
+
+func (pps *providerPromiseStorage) Release(issuerID identity.Identity) {}
+
+func (pps *providerPromiseStorage) GetNewSeqIDForIssuer(issuerID identity.Identity) (uint64, error) {
+	pps.Lock()
+	defer pps.Unlock()
+	seq := uint64(len(pps.promises) + 1)
+	pps.promises = append(pps.promises, promise.StoredPromise{SequenceID: seq})
+	return seq, nil
+}
+
+func (pps *providerPromiseStorage) Update(issuerID identity.Identity, p promise.StoredPromise) error {
+	pps.Lock()
+	defer pps.Unlock()
+	if len(pps.promises) == 0 || pps.promises[len(pps.promises)-1].SequenceID != p.SequenceID {
+		return errors.New("promise not found")
+	}
+	pps.promises[len(pps.promises)-1] = p
+	return nil
+}
+
+func (pps *providerPromiseStorage) GetLastPromise(issuerID identity.Identity) (promise.StoredPromise, error) {
+	pps.Lock()
+	defer pps.Unlock()
+	if len(pps.promises) == 0 {
+		return promise.StoredPromise{}, errors.New("no promises")
+	}
+	return pps.promises[len(pps.promises)-1], nil
+}
+
+// settle starts a new sequence, carrying over the unconsumed amount as the settlement does
+func (pps *providerPromiseStorage) settle() {
+	pps.Lock()
+	defer pps.Unlock()
+	last := pps.promises[len(pps.promises)-1]
+	pps.promises = append(pps.promises, promise.StoredPromise{
+		SequenceID:       last.SequenceID + 1,
+		UnconsumedAmount: last.UnconsumedAmount,
+	})
+}
+
+type balanceForwarder struct {
+	balances chan balance.Message
+}
+
+func (bf *balanceForwarder) Send(b balance.Message) error {
+	select {
+	case bf.balances <- b:
+		return nil
+	case <-time.After(time.Second):
+		return errors.New("consumer did not receive the balance")
+	}
+}
+
+// promiseForwarder stops the provider once the given number of promises is sent, so that no balance is left unanswered
+type promiseForwarder struct {
+	promises  chan promise.Message
+	stopAfter int
+	stop      func()
+
+	sent int
+}
+
+func (pf *promiseForwarder) Send(p promise.Message) error {
+	pf.sent++
+	if pf.sent == pf.stopAfter {
+		pf.stop()
+	}
+	pf.promises <- p
+	return nil
+}
+
+func runPaidSession(t *testing.T, keeper *consumerStateKeeper, storage *providerPromiseStorage, rounds int) {
+	clock := &sessionClock{}
+	calculator := perMinuteCalculator{price: 1}
+	balances := make(chan balance.Message)
+	promisesSent := make(chan promise.Message)
+
+	sessionBalance := NewSessionBalance(
+		&balanceForwarder{balances: balances},
+		provider.NewBalanceTracker(clock, calculator, myst(0)),
+		promisesSent,
+		time.Millisecond,
+		time.Second,
+		&MockPromiseValidator{isValid: true},
+		storage,
+		paidConsumer,
+		&MockAmountRecorder{},
+		&MockFreeCreditKeeper{remaining: myst(0)},
+		money.CURRENCY_MYST,
+	)
+	sessionPayments := NewSessionPayments(
+		balances,
+		&promiseForwarder{promises: promisesSent, stopAfter: rounds, stop: sessionBalance.Stop},
+		promise.NewConsumerTracker(keeper.state, paidConsumer, paidProvider, unsignedIssuer{}, keeper, &MockAmountRecorder{}),
+		NewTimeExtension(clock.now, calculator, myst(5)),
+		NewTimeChargeValidator(clock.now, calculator, time.Minute),
+		NoSpendingLimits{},
+	)
+
+	consumerDone := make(chan error, 1)
+	go func() {
+		consumerDone <- sessionPayments.Start()
+	}()
+
+	assert.NoError(t, sessionBalance.Start())
+	sessionPayments.Stop()
+	assert.NoError(t, <-consumerDone)
+}
+
+func Test_SessionPayments_StayAlignedWithSessionBalanceAcrossSessions(t *testing.T) {
+	keeper := &consumerStateKeeper{}
+	storage := &providerPromiseStorage{}
+
+	runPaidSession(t, keeper, storage, 3)
+	first, err := storage.GetLastPromise(paidConsumer)
+	assert.NoError(t, err)
+	assert.Equal(t, promise.State{Seq: 1, Amount: first.Message.Amount}, keeper.state)
+	assert.False(t, first.UnconsumedAmount.IsZero())
+
+	runPaidSession(t, keeper, storage, 3)
+	second, err := storage.GetLastPromise(paidConsumer)
+	assert.NoError(t, err)
+	assert.Equal(t, promise.State{Seq: 1, Amount: second.Message.Amount}, keeper.state)
+	assert.True(t, second.Message.Amount > first.Message.Amount)
+}
+
+func Test_SessionPayments_StayAlignedWithSessionBalanceAfterSettlement(t *testing.T) {
+	keeper := &consumerStateKeeper{}
+	storage := &providerPromiseStorage{}
+
+	runPaidSession(t, keeper, storage, 3)
+	storage.settle()
+
+	runPaidSession(t, keeper, storage, 3)
+	last, err := storage.GetLastPromise(paidConsumer)
+	assert.NoError(t, err)
+	assert.Equal(t, promise.State{Seq: 2, Amount: last.Message.Amount}, keeper.state)
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/session/balance"
 	"github.com/mysteriumnetwork/node/session/promise"
 	"github.com/mysteriumnetwork/payments/promises"
@@ -72,6 +73,9 @@ func NewTestSessionPayments(bm chan balance.Message, ps PeerPromiseSender, pt Pr
 		bm,
 		ps,
 		pt,
+		FixedExtension(100),
+		NoChargeValidation{},
+		NoSpendingLimits{},
 	)
 }
 
@@ -123,3 +127,127 @@ func Test_SessionPayments_ReportsSendingErrors(t *testing.T) {
 
 	balanceChannel <- balance.Message{Balance: 0, SequenceID: 1}
 }
+
+func Test_SessionPayments_ReportsOvercharging(t *testing.T) {
+	balances := make(chan balance.Message, 1)
+	sender := &MockPeerPromiseSender{chanToWriteTo: make(chan promise.Message, 1)}
+	validator := &UsageChargeValidator{maxCharge: func() money.Money { return myst(50) }}
+	cpo := NewSessionPayments(balances, sender, promiseTracker, FixedExtension(100), validator, NoSpendingLimits{})
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- cpo.Start()
+	}()
+
+	balances <- balance.Message{Balance: 0, SequenceID: 1}
+	<-sender.chanToWriteTo
+
+	balances <- balance.Message{Balance: 20, SequenceID: 1}
+	select {
+	case err := <-errs:
+		assert.Equal(t, ErrOvercharged, err)
+	case <-time.After(time.Second):
+		t.Fatal("overcharging was not reported")
+	}
+}
+
+type spendingGuardFake struct {
+	limit uint64
+	spent uint64
+}
+
+func (sgf *spendingGuardFake) Spend(amount uint64) error {
+	if sgf.spent+amount > sgf.limit {
+		return errSpendingLimit
+	}
+	sgf.spent += amount
+	return nil
+}
+
+func (sgf *spendingGuardFake) Refund(amount uint64) error {
+	sgf.spent -= amount
+	return nil
+}
+
+var errSpendingLimit = errors.New("spending limit reached")
+
+func Test_SessionPayments_StopsIssuingWhenSpendingLimitIsReached(t *testing.T) {
+	balances := make(chan balance.Message, 1)
+	sender := &MockPeerPromiseSender{chanToWriteTo: make(chan promise.Message, 1)}
+	guard := &spendingGuardFake{limit: 150}
+	cpo := NewSessionPayments(balances, sender, promiseTracker, FixedExtension(100), NoChargeValidation{}, guard)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- cpo.Start()
+	}()
+
+	balances <- balance.Message{Balance: 0, SequenceID: 1}
+	<-sender.chanToWriteTo
+
+	// provider reports the extended amount as consumed, so the promise has to be extended again
+	balances <- balance.Message{Balance: 0, SequenceID: 1}
+	select {
+	case err := <-errs:
+		assert.Equal(t, errSpendingLimit, err)
+	case <-time.After(time.Second):
+		t.Fatal("spending limit was not reported")
+	}
+	assert.Equal(t, uint64(100), guard.spent)
+	assert.Len(t, sender.chanToWriteTo, 0)
+}
+
+func Test_SessionPayments_RefundsSpendingWhenPromiseIsNotExtended(t *testing.T) {
+	balances := make(chan balance.Message, 1)
+	sender := &MockPeerPromiseSender{chanToWriteTo: make(chan promise.Message, 1)}
+	failingTracker := *promiseTracker
+	failingTracker.errToReturn = errors.New("issuing failed")
+	guard := &spendingGuardFake{limit: 150}
+	cpo := NewSessionPayments(balances, sender, &failingTracker, FixedExtension(100), NoChargeValidation{}, guard)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- cpo.Start()
+	}()
+
+	balances <- balance.Message{Balance: 0, SequenceID: 1}
+	select {
+	case err := <-errs:
+		assert.Equal(t, failingTracker.errToReturn, err)
+	case <-time.After(time.Second):
+		t.Fatal("issuing error was not reported")
+	}
+	assert.Equal(t, uint64(0), guard.spent)
+}
+
+func Test_SessionPayments_UsesFreeCreditBeforeIssuing(t *testing.T) {
+	balances := make(chan balance.Message, 1)
+	sender := &MockPeerPromiseSender{chanToWriteTo: make(chan promise.Message, 1)}
+	tracker := &amountRecordingTracker{}
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	extension := NewTimeExtension(func() time.Time { return now }, perMinuteCalculator{price: 5}, myst(5))
+	validator := &UsageChargeValidator{maxCharge: func() money.Money { return myst(50) }}
+	cpo := NewSessionPayments(balances, sender, tracker, extension, validator, NoSpendingLimits{})
+	cpo.SetFreeCredit(20)
+	go cpo.Start()
+	defer cpo.Stop()
+
+	balances <- balance.Message{Balance: 20, SequenceID: 1}
+	<-sender.chanToWriteTo
+
+	now = now.Add(5 * time.Minute)
+	balances <- balance.Message{Balance: 0, SequenceID: 1}
+	<-sender.chanToWriteTo
+
+	assert.Equal(t, []uint64{0, 10}, tracker.extended)
+}
+
+type amountRecordingTracker struct {
+	MockPromiseTracker
+	extended []uint64
+}
+
+func (art *amountRecordingTracker) ExtendPromise(amountToAdd uint64) (promises.IssuedPromise, error) {
+	art.extended = append(art.extended, amountToAdd)
+	return art.promiseToReturn, art.errToReturn
+}
@@ -37,45 +37,67 @@ type State struct {
 	Amount uint64
 }
 
+// StateKeeper persists the state of promises issued by consumer to provider
+type StateKeeper interface {
+	Save(consumer, provider identity.Identity, state State) error
+}
+
+// AmountRecorder records the amounts promised between peers
+type AmountRecorder interface {
+	Record(amount uint64)
+}
+
 // ConsumerTracker tracks and issues promises from consumer perspective, also validates states coming from service provider
 type ConsumerTracker struct {
 	current  State
 	consumer identity.Identity
 	receiver identity.Identity
 	issuer   Issuer
+	keeper   StateKeeper
+	recorder AmountRecorder
 }
 
 // NewConsumerTracker returns the consumer side tracker for promises
-func NewConsumerTracker(initial State, consumer, provider identity.Identity, issuer Issuer) *ConsumerTracker {
+func NewConsumerTracker(initial State, consumer, provider identity.Identity, issuer Issuer, keeper StateKeeper, recorder AmountRecorder) *ConsumerTracker {
 	return &ConsumerTracker{
 		current:  initial,
 		consumer: consumer,
 		receiver: provider,
 		issuer:   issuer,
+		keeper:   keeper,
+		recorder: recorder,
 	}
 }
 
-// ErrUnexpectedAmount represents an error that occurs when we get an amount that's not aligned with our current understanding
-var ErrUnexpectedAmount = errors.New("unexpected amount")
+// ErrProviderOverclaimed represents an error that occurs when provider claims promises which were never issued
+var ErrProviderOverclaimed = errors.New("provider claims more than was issued")
 
-// AlignStateWithProvider aligns the consumers world with the providers
-func (t *ConsumerTracker) AlignStateWithProvider(providerState State) error {
-	if providerState.Seq > t.current.Seq {
-		// new promise request
-		t.current.Seq = providerState.Seq
-		// ignore provider state value as new promise amount is always zero,
-		// if provider tries to trick us to send more than expected it will be caught on next align
-		t.current.Amount = 0
-		return nil
+// ValidateProviderClaim checks that the last promise known to provider does not exceed the ones issued by consumer.
+// Provider knowing less than was issued is fine, as the consumer keeps its own state.
+func ValidateProviderClaim(issued, claimed State) error {
+	if claimed.Seq == issued.Seq && claimed.Amount > issued.Amount {
+		return ErrProviderOverclaimed
 	}
-	if providerState.Amount > t.current.Amount {
-		return ErrUnexpectedAmount
+	if claimed.Seq > issued.Seq && claimed.Amount > 0 {
+		return ErrProviderOverclaimed
 	}
-	if providerState.Amount < t.current.Amount {
-		return ErrUnexpectedAmount
+	return nil
+}
+
+// AlignStateWithProvider aligns the consumers world with the providers by the sequence number only.
+// Provider reports its balance, i.e. what was promised minus what was consumed, so it is not comparable with the promised amount.
+// The consumed amount is validated against the price of the service when the balance is received instead.
+func (t *ConsumerTracker) AlignStateWithProvider(providerState State) error {
+	if providerState.Seq <= t.current.Seq {
+		return nil
 	}
 
-	return nil
+	// new promise request
+	t.current.Seq = providerState.Seq
+	// ignore provider state value as new promise amount is always zero,
+	// if provider tries to trick us to send more than expected it will be caught by the charge validation
+	t.current.Amount = 0
+	return t.keeper.Save(t.consumer, t.receiver, t.current)
 }
 
 // ExtendPromise issues a promise with the amount added to the promise
@@ -88,6 +110,11 @@ func (t *ConsumerTracker) ExtendPromise(amountToAdd uint64) (promises.IssuedProm
 		Amount:   t.current.Amount + amountToAdd,
 		SeqNo:    t.current.Seq,
 	}
+	issued, err := t.issuer.Issue(promise)
+	if err != nil {
+		return issued, err
+	}
 	t.current.Amount += amountToAdd
-	return t.issuer.Issue(promise)
+	t.recorder.Record(amountToAdd)
+	return issued, t.keeper.Save(t.consumer, t.receiver, t.current)
 }
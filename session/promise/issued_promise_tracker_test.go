@@ -18,6 +18,7 @@
 package promise
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -35,7 +36,7 @@ var initialState = State{
 }
 
 func TestCurrentStatePromiseWithAddedAmountIsIssued(t *testing.T) {
-	tracker := NewConsumerTracker(initialState, consumer, provider, issuer)
+	tracker := NewConsumerTracker(initialState, consumer, provider, issuer, &fakeKeeper{}, &fakeRecorder{})
 	p, err := tracker.ExtendPromise(200)
 	assert.NoError(t, err)
 	assert.Equal(
@@ -53,7 +54,7 @@ func TestCurrentStatePromiseWithAddedAmountIsIssued(t *testing.T) {
 }
 
 func TestCurrentStateIsAlignedWithConsumer(t *testing.T) {
-	tracker := NewConsumerTracker(initialState, consumer, provider, issuer)
+	tracker := NewConsumerTracker(initialState, consumer, provider, issuer, &fakeKeeper{}, &fakeRecorder{})
 
 	assert.NoError(t, tracker.AlignStateWithProvider(State{Seq: 1, Amount: 100}))
 
@@ -63,20 +64,26 @@ func TestCurrentStateIsAlignedWithConsumer(t *testing.T) {
 	assert.Equal(t, uint64(1), p.Promise.SeqNo)
 }
 
-func TestBiggerConsumerAmountIsRejected(t *testing.T) {
-	tracker := NewConsumerTracker(initialState, consumer, provider, issuer)
+func TestProviderBalanceDoesNotChangePromisedAmount(t *testing.T) {
+	keeper := &fakeKeeper{}
+	tracker := NewConsumerTracker(initialState, consumer, provider, issuer, keeper, &fakeRecorder{})
 
-	assert.Equal(t, ErrUnexpectedAmount, tracker.AlignStateWithProvider(State{Seq: 1, Amount: 200}))
+	// balance is the promised amount minus what was consumed, so it differs once anything is consumed
+	assert.NoError(t, tracker.AlignStateWithProvider(State{Seq: 1, Amount: 0}))
+	assert.NoError(t, tracker.AlignStateWithProvider(State{Seq: 1, Amount: 40}))
+	assert.Equal(t, initialState, tracker.current)
+	assert.Equal(t, State{}, keeper.saved)
 }
 
-func TestSmallerConsumerAmountIsRejected(t *testing.T) {
-	tracker := NewConsumerTracker(initialState, consumer, provider, issuer)
+func TestSmallerSeqNumberIsIgnored(t *testing.T) {
+	tracker := NewConsumerTracker(initialState, consumer, provider, issuer, &fakeKeeper{}, &fakeRecorder{})
 
-	assert.Equal(t, ErrUnexpectedAmount, tracker.AlignStateWithProvider(State{Seq: 1, Amount: 0}))
+	assert.NoError(t, tracker.AlignStateWithProvider(State{Seq: 0, Amount: 500}))
+	assert.Equal(t, initialState, tracker.current)
 }
 
 func TestIncreasedSeqNumberIsAccepted(t *testing.T) {
-	tracker := NewConsumerTracker(initialState, consumer, provider, issuer)
+	tracker := NewConsumerTracker(initialState, consumer, provider, issuer, &fakeKeeper{}, &fakeRecorder{})
 
 	assert.NoError(t, tracker.AlignStateWithProvider(State{Seq: 2, Amount: 0}))
 
@@ -86,6 +93,78 @@ func TestIncreasedSeqNumberIsAccepted(t *testing.T) {
 	assert.Equal(t, uint64(2), p.Promise.SeqNo)
 }
 
+func TestIssuedStateIsSaved(t *testing.T) {
+	keeper := &fakeKeeper{}
+	tracker := NewConsumerTracker(initialState, consumer, provider, issuer, keeper, &fakeRecorder{})
+
+	_, err := tracker.ExtendPromise(50)
+	assert.NoError(t, err)
+	assert.Equal(t, State{Seq: 1, Amount: 150}, keeper.saved)
+
+	assert.NoError(t, tracker.AlignStateWithProvider(State{Seq: 2, Amount: 0}))
+	assert.Equal(t, State{Seq: 2, Amount: 0}, keeper.saved)
+}
+
+func TestStateIsNotChangedWhenIssuingFails(t *testing.T) {
+	keeper := &fakeKeeper{}
+	recorder := &fakeRecorder{}
+	tracker := NewConsumerTracker(initialState, consumer, provider, failingIssuer{}, keeper, recorder)
+
+	_, err := tracker.ExtendPromise(50)
+	assert.Error(t, err)
+	assert.Equal(t, State{}, keeper.saved)
+	assert.Equal(t, initialState, tracker.current)
+	assert.Len(t, recorder.recorded, 0)
+}
+
+func TestIssuedAmountIsRecorded(t *testing.T) {
+	recorder := &fakeRecorder{}
+	tracker := NewConsumerTracker(initialState, consumer, provider, issuer, &fakeKeeper{}, recorder)
+
+	_, err := tracker.ExtendPromise(50)
+	assert.NoError(t, err)
+	_, err = tracker.ExtendPromise(20)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []uint64{50, 20}, recorder.recorded)
+}
+
+func TestValidateProviderClaim(t *testing.T) {
+	issued := State{Seq: 2, Amount: 100}
+
+	assert.NoError(t, ValidateProviderClaim(issued, State{}))
+	assert.NoError(t, ValidateProviderClaim(issued, State{Seq: 1, Amount: 500}))
+	assert.NoError(t, ValidateProviderClaim(issued, State{Seq: 2, Amount: 50}))
+	assert.NoError(t, ValidateProviderClaim(issued, State{Seq: 2, Amount: 100}))
+	assert.NoError(t, ValidateProviderClaim(issued, State{Seq: 3, Amount: 0}))
+	assert.Equal(t, ErrProviderOverclaimed, ValidateProviderClaim(issued, State{Seq: 2, Amount: 101}))
+	assert.Equal(t, ErrProviderOverclaimed, ValidateProviderClaim(issued, State{Seq: 3, Amount: 1}))
+}
+
+type fakeKeeper struct {
+	saved State
+}
+
+func (fk *fakeKeeper) Save(consumer, provider identity.Identity, state State) error {
+	fk.saved = state
+	return nil
+}
+
+type fakeRecorder struct {
+	recorded []uint64
+}
+
+func (fr *fakeRecorder) Record(amount uint64) {
+	fr.recorded = append(fr.recorded, amount)
+}
+
+type failingIssuer struct {
+}
+
+func (issuer failingIssuer) Issue(promise promises.Promise) (promises.IssuedPromise, error) {
+	return promises.IssuedPromise{}, errors.New("failed to sign")
+}
+
 type mockedIssuer struct {
 }
 
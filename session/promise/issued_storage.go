@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package promise
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+)
+
+const issuedBucketPrefix = "issued-promises-"
+
+// IssuedStorer allows to store and get issued promise states
+type IssuedStorer interface {
+	Store(bucket string, object interface{}) error
+	GetOneByField(bucket string, fieldName string, key interface{}, to interface{}) error
+}
+
+// IssuedState is a representation of promises consumer issued to a single provider in storage
+type IssuedState struct {
+	ProviderID string `storm:"id"`
+	Seq        uint64
+	Amount     uint64
+	UpdatedAt  time.Time
+}
+
+// IssuedStorage keeps the state of promises issued by consumer, per every provider
+type IssuedStorage struct {
+	storage IssuedStorer
+	lock    sync.Mutex
+}
+
+// NewIssuedStorage returns a new instance of issued promise storage
+func NewIssuedStorage(storage IssuedStorer) *IssuedStorage {
+	return &IssuedStorage{
+		storage: storage,
+	}
+}
+
+// Load returns the state of promises consumer issued to provider. Empty state is returned if there are none.
+func (s *IssuedStorage) Load(consumer, provider identity.Identity) (State, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var issued IssuedState
+	err := s.storage.GetOneByField(getIssuedBucketName(consumer), "ProviderID", provider.Address, &issued)
+	if err != nil && err.Error() == errBoltNotFound.Error() {
+		return State{}, nil
+	}
+	if err != nil {
+		return State{}, err
+	}
+	return State{Seq: issued.Seq, Amount: issued.Amount}, nil
+}
+
+// Save stores the state of promises consumer issued to provider, replacing the previous one
+func (s *IssuedStorage) Save(consumer, provider identity.Identity, state State) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.storage.Store(getIssuedBucketName(consumer), &IssuedState{
+		ProviderID: provider.Address,
+		Seq:        state.Seq,
+		Amount:     state.Amount,
+		UpdatedAt:  time.Now().UTC(),
+	})
+}
+
+func getIssuedBucketName(consumer identity.Identity) string {
+	return issuedBucketPrefix + consumer.Address
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package promise
+
+import (
+	"testing"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+type issuedStorerFake struct {
+	buckets map[string]map[string]IssuedState
+}
+
+func newIssuedStorerFake() *issuedStorerFake {
+	return &issuedStorerFake{buckets: make(map[string]map[string]IssuedState)}
+}
+
+func (sf *issuedStorerFake) Store(bucket string, object interface{}) error {
+	issued := object.(*IssuedState)
+	if sf.buckets[bucket] == nil {
+		sf.buckets[bucket] = make(map[string]IssuedState)
+	}
+	sf.buckets[bucket][issued.ProviderID] = *issued
+	return nil
+}
+
+func (sf *issuedStorerFake) GetOneByField(bucket string, fieldName string, key interface{}, to interface{}) error {
+	issued, found := sf.buckets[bucket][key.(string)]
+	if !found {
+		return errBoltNotFound
+	}
+	*to.(*IssuedState) = issued
+	return nil
+}
+
+func Test_IssuedStorage_LoadsEmptyStateForUnknownProvider(t *testing.T) {
+	storage := NewIssuedStorage(newIssuedStorerFake())
+
+	state, err := storage.Load(consumer, provider)
+	assert.NoError(t, err)
+	assert.Equal(t, State{}, state)
+}
+
+func Test_IssuedStorage_KeepsStatePerConsumerAndProvider(t *testing.T) {
+	storage := NewIssuedStorage(newIssuedStorerFake())
+	otherProvider := identity.FromAddress("0x3333333333333")
+
+	assert.NoError(t, storage.Save(consumer, provider, State{Seq: 1, Amount: 100}))
+	assert.NoError(t, storage.Save(consumer, provider, State{Seq: 2, Amount: 10}))
+	assert.NoError(t, storage.Save(consumer, otherProvider, State{Seq: 1, Amount: 50}))
+
+	state, err := storage.Load(consumer, provider)
+	assert.NoError(t, err)
+	assert.Equal(t, State{Seq: 2, Amount: 10}, state)
+
+	state, err = storage.Load(consumer, otherProvider)
+	assert.NoError(t, err)
+	assert.Equal(t, State{Seq: 1, Amount: 50}, state)
+
+	state, err = storage.Load(provider, consumer)
+	assert.NoError(t, err)
+	assert.Equal(t, State{}, state)
+}
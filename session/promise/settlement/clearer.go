@@ -0,0 +1,144 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package settlement
+
+import (
+	"context"
+	"math/big"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mysteriumnetwork/payments/contracts/abigen"
+	payments_identity "github.com/mysteriumnetwork/payments/identity"
+	"github.com/mysteriumnetwork/payments/promises"
+)
+
+// TxStatus represents the status of clearing transaction in blockchain
+type TxStatus int
+
+const (
+	// TxPending means that transaction is not mined yet
+	TxPending TxStatus = iota
+	// TxConfirmed means that transaction was mined successfully
+	TxConfirmed
+	// TxFailed means that transaction was mined, but reverted
+	TxFailed
+)
+
+// Clearer submits promises to the payments contract and checks the status of submitted transactions
+type Clearer interface {
+	Clear(promise promises.IssuedPromise) (common.Hash, error)
+	Status(txHash common.Hash) (TxStatus, error)
+}
+
+// Backend is the blockchain backend which clearing transactions are sent to
+type Backend interface {
+	bind.ContractBackend
+	bind.DeployBackend
+}
+
+// ContractClearer clears promises with the payments contract on behalf of the promise receiver
+type ContractClearer struct {
+	contractSession *abigen.IdentityPromisesTransactorSession
+	backend         Backend
+	receiverSigner  payments_identity.Signer
+}
+
+// NewContractClearer creates clearer which sends transactions signed by the given transactor.
+// Promises are signed by the given receiver signer before clearing.
+func NewContractClearer(
+	backend Backend,
+	contractAddress common.Address,
+	transactor *bind.TransactOpts,
+	receiverSigner payments_identity.Signer,
+) (*ContractClearer, error) {
+	contract, err := abigen.NewIdentityPromisesTransactor(contractAddress, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ContractClearer{
+		contractSession: &abigen.IdentityPromisesTransactorSession{
+			Contract:     contract,
+			TransactOpts: *transactor,
+		},
+		backend:        backend,
+		receiverSigner: receiverSigner,
+	}, nil
+}
+
+// Clear signs the given promise by receiver and submits it to the payments contract
+func (cc *ContractClearer) Clear(issued promises.IssuedPromise) (common.Hash, error) {
+	received, err := promises.SignByReceiver(&issued, cc.receiverSigner)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	payerSignature, err := payments_identity.DecomposeSignatureForEth(received.IssuerSignature)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	receiverSignature, err := payments_identity.DecomposeSignatureForEth(received.ReceiverSignature)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	var extraDataHash [32]byte
+	copy(extraDataHash[:], received.Extra.Hash())
+
+	tx, err := cc.contractSession.ClearPromise(
+		extraDataHash,
+		received.Receiver,
+		new(big.Int).SetUint64(received.SeqNo),
+		new(big.Int).SetUint64(received.Amount),
+		payerSignature.V,
+		payerSignature.R,
+		payerSignature.S,
+		receiverSignature.V,
+		receiverSignature.R,
+		receiverSignature.S,
+	)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
+
+// Status returns the status of the given clearing transaction
+func (cc *ContractClearer) Status(txHash common.Hash) (TxStatus, error) {
+	receipt, err := cc.backend.TransactionReceipt(context.Background(), txHash)
+	if err == ethereum.NotFound {
+		return TxPending, nil
+	}
+	if err != nil {
+		return TxPending, err
+	}
+	// some backends do not report missing receipts as an error
+	if receipt == nil {
+		return TxPending, nil
+	}
+
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return TxFailed, nil
+	}
+	return TxConfirmed, nil
+}
+
+var _ Clearer = &ContractClearer{}
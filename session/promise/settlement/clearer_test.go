@@ -0,0 +1,130 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package settlement
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mysteriumnetwork/node/session/promise"
+	"github.com/mysteriumnetwork/payments/contracts/abigen"
+	"github.com/mysteriumnetwork/payments/mysttoken"
+	"github.com/mysteriumnetwork/payments/promises"
+	"github.com/mysteriumnetwork/payments/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	deployerKey, _ = crypto.GenerateKey()
+	issuerKey, _   = crypto.GenerateKey()
+	receiverKey, _ = crypto.GenerateKey()
+)
+
+func deployPaymentsContract(t *testing.T) (*backends.SimulatedBackend, common.Address, *bind.TransactOpts) {
+	transactor := bind.NewKeyedTransactor(deployerKey)
+	backend := backends.NewSimulatedBackend(
+		core.GenesisAlloc{
+			transactor.From: core.GenesisAccount{Balance: big.NewInt(1000000000000000000)},
+		},
+		8000000,
+	)
+
+	tokenAddress, _, _, err := mysttoken.DeployMystToken(transactor, backend)
+	assert.NoError(t, err)
+	backend.Commit()
+
+	paymentsAddress, _, _, err := abigen.DeployIdentityPromises(transactor, backend, tokenAddress, big.NewInt(100))
+	assert.NoError(t, err)
+	backend.Commit()
+
+	return backend, paymentsAddress, transactor
+}
+
+func issuePromise(t *testing.T, seqNo, amount uint64) promises.IssuedPromise {
+	issued, err := promises.SignByPayer(
+		&promises.Promise{
+			Extra: promise.ExtraData{
+				ConsumerAddress: crypto.PubkeyToAddress(issuerKey.PublicKey),
+			},
+			Receiver: crypto.PubkeyToAddress(receiverKey.PublicKey),
+			SeqNo:    seqNo,
+			Amount:   amount,
+		},
+		test_utils.NewPrivateKeySigner(issuerKey),
+	)
+	assert.NoError(t, err)
+	return *issued
+}
+
+func TestContractClearer_ConfirmsMinedTransaction(t *testing.T) {
+	backend, paymentsAddress, transactor := deployPaymentsContract(t)
+	clearer, err := NewContractClearer(backend, paymentsAddress, transactor, test_utils.NewPrivateKeySigner(receiverKey))
+	assert.NoError(t, err)
+
+	_, tx, _, err := mysttoken.DeployMystToken(transactor, backend)
+	assert.NoError(t, err)
+
+	status, err := clearer.Status(tx.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, TxPending, status)
+
+	backend.Commit()
+	status, err = clearer.Status(tx.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, TxConfirmed, status)
+}
+
+func TestContractClearer_UnknownTransactionIsPending(t *testing.T) {
+	backend, paymentsAddress, transactor := deployPaymentsContract(t)
+	clearer, err := NewContractClearer(backend, paymentsAddress, transactor, test_utils.NewPrivateKeySigner(receiverKey))
+	assert.NoError(t, err)
+
+	status, err := clearer.Status(common.HexToHash("0x1"))
+	assert.NoError(t, err)
+	assert.Equal(t, TxPending, status)
+}
+
+func TestContractClearer_PromiseOfUnregisteredIssuerIsNotCleared(t *testing.T) {
+	backend, paymentsAddress, transactor := deployPaymentsContract(t)
+	clearer, err := NewContractClearer(backend, paymentsAddress, transactor, test_utils.NewPrivateKeySigner(receiverKey))
+	assert.NoError(t, err)
+
+	_, err = clearer.Clear(issuePromise(t, 1, 100))
+	assert.Error(t, err)
+}
+
+func TestContractClearer_RevertedClearingIsFailed(t *testing.T) {
+	backend, paymentsAddress, transactor := deployPaymentsContract(t)
+	// fixed gas limit skips estimation, so that the failing transaction is actually mined
+	transactor.GasLimit = 1000000
+	clearer, err := NewContractClearer(backend, paymentsAddress, transactor, test_utils.NewPrivateKeySigner(receiverKey))
+	assert.NoError(t, err)
+
+	txHash, err := clearer.Clear(issuePromise(t, 1, 100))
+	assert.NoError(t, err)
+	backend.Commit()
+
+	status, err := clearer.Status(txHash)
+	assert.NoError(t, err)
+	assert.Equal(t, TxFailed, status)
+}
@@ -0,0 +1,273 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package settlement is responsible for cashing out the promises received by provider.
+// The latest promise of every issuer is cleared with the payments contract and the clearing is tracked until confirmed.
+package settlement
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session/promise"
+	"github.com/mysteriumnetwork/payments/promises"
+	"github.com/pkg/errors"
+)
+
+const logPrefix = "[promise-settlement] "
+
+// maxAttempts is how many times clearing of a single promise is attempted, as every failed transaction costs gas
+const maxAttempts = 5
+
+// miningTimeout is how long the submitted clearing transaction may stay not mined before it is sent again
+const miningTimeout = 2 * time.Hour
+
+// ErrNotStarted indicates that settlement was requested before the receiver is known
+var ErrNotStarted = errors.New("promise settlement is not started")
+
+// PromiseStorage provides the promises received from issuers
+type PromiseStorage interface {
+	GetAllKnownIssuers() []identity.Identity
+	SettleLastPromise(issuerID identity.Identity, settle func(last promise.StoredPromise) (bool, error)) error
+}
+
+// SettlementStorage keeps the settlements of promises
+type SettlementStorage interface {
+	Save(issuerID identity.Identity, settlement Settlement) error
+	GetAllOfIssuer(issuerID identity.Identity) ([]Settlement, error)
+	GetAll() ([]Settlement, error)
+}
+
+// ClearerFactory creates clearer of promises received by the given identity
+type ClearerFactory func(receiver identity.Identity) (Clearer, error)
+
+// Settler periodically clears the latest promises of every issuer which has no active session.
+// Every sequence is cleared only once, clearings which could not be sent are retried on the next run.
+type Settler struct {
+	promises   PromiseStorage
+	storage    SettlementStorage
+	newClearer ClearerFactory
+	interval   time.Duration
+	now        func() time.Time
+
+	receiver identity.Identity
+	clearer  Clearer
+	stop     chan struct{}
+	stopOnce sync.Once
+	lock     sync.Mutex
+}
+
+// NewSettler creates settler which clears promises every given interval
+func NewSettler(promises PromiseStorage, storage SettlementStorage, newClearer ClearerFactory, interval time.Duration) *Settler {
+	return &Settler{
+		promises:   promises,
+		storage:    storage,
+		newClearer: newClearer,
+		interval:   interval,
+		now:        time.Now,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start starts periodic settlement of promises received by the given identity
+func (s *Settler) Start(receiver identity.Identity) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.clearer != nil {
+		return errors.New("promise settlement is already started")
+	}
+
+	clearer, err := s.newClearer(receiver)
+	if err != nil {
+		return errors.Wrap(err, "failed to create promise clearer")
+	}
+	s.receiver = receiver
+	s.clearer = clearer
+
+	go s.settlePeriodically()
+	return nil
+}
+
+// Stop stops periodic settlement
+func (s *Settler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+// Settlements returns settlements of all issuer promises
+func (s *Settler) Settlements() ([]Settlement, error) {
+	return s.storage.GetAll()
+}
+
+// SettleAll clears the latest promises of all known issuers and updates the state of previous clearings
+func (s *Settler) SettleAll() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.clearer == nil {
+		return ErrNotStarted
+	}
+
+	var firstErr error
+	for _, issuer := range s.promises.GetAllKnownIssuers() {
+		if err := s.settle(issuer); err != nil {
+			log.Error(logPrefix, "failed to settle promises of issuer ", issuer.Address, ": ", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Settle clears the latest promise of the given issuer and updates the state of previous clearings
+func (s *Settler) Settle(issuer identity.Identity) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.clearer == nil {
+		return ErrNotStarted
+	}
+	return s.settle(issuer)
+}
+
+func (s *Settler) settlePeriodically() {
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(s.interval):
+			if err := s.SettleAll(); err != nil {
+				log.Warn(logPrefix, "promise settlement failed, will retry: ", err)
+			}
+		}
+	}
+}
+
+func (s *Settler) settle(issuer identity.Identity) error {
+	settlements, err := s.storage.GetAllOfIssuer(issuer)
+	if err != nil {
+		return err
+	}
+
+	settled := make(map[uint64]bool)
+	for _, settlement := range settlements {
+		settled[settlement.SequenceID] = true
+		if err := s.proceed(issuer, settlement); err != nil {
+			return err
+		}
+	}
+
+	err = s.promises.SettleLastPromise(issuer, func(last promise.StoredPromise) (bool, error) {
+		if last.Message == nil || last.Message.Amount == 0 || settled[last.SequenceID] {
+			return false, nil
+		}
+
+		settlement := Settlement{
+			SequenceID: last.SequenceID,
+			Amount:     last.Message.Amount,
+			Signature:  last.Message.Signature,
+			State:      Pending,
+		}
+		if err := s.storage.Save(issuer, settlement); err != nil {
+			return false, err
+		}
+		return true, s.submit(issuer, settlement)
+	})
+	if err == promise.ErrIssuerBusy {
+		log.Debug(logPrefix, "issuer ", issuer.Address, " has an active session, settling later")
+		return nil
+	}
+	return err
+}
+
+func (s *Settler) proceed(issuer identity.Identity, settlement Settlement) error {
+	switch settlement.State {
+	case Confirmed, Failed:
+		return nil
+	case Submitted:
+		status, err := s.clearer.Status(common.HexToHash(settlement.TxHash))
+		if err != nil {
+			return err
+		}
+		switch status {
+		case TxConfirmed:
+			log.Info(logPrefix, "promise ", settlement.SequenceID, " of issuer ", issuer.Address, " is settled")
+			settlement.State = Confirmed
+			return s.storage.Save(issuer, settlement)
+		case TxFailed:
+			// contract rejects the same promise again, so sending it once more only wastes gas
+			log.Error(logPrefix, "clearing of promise ", settlement.SequenceID, " of issuer ", issuer.Address, " reverted")
+			settlement.State = Failed
+			settlement.LastError = "clearing transaction reverted"
+			return s.storage.Save(issuer, settlement)
+		}
+		if s.now().Sub(settlement.SubmittedAt) < miningTimeout {
+			return nil
+		}
+		log.Warn(logPrefix, "clearing transaction of promise ", settlement.SequenceID, " of issuer ", issuer.Address, " is not mined, sending again")
+		settlement.LastError = "clearing transaction was not mined in " + miningTimeout.String()
+	}
+
+	if settlement.Attempts >= maxAttempts {
+		settlement.State = Failed
+		return s.storage.Save(issuer, settlement)
+	}
+	return s.submit(issuer, settlement)
+}
+
+func (s *Settler) submit(issuer identity.Identity, settlement Settlement) error {
+	settlement.Attempts++
+	txHash, err := s.clearer.Clear(s.issuedPromise(issuer, settlement))
+	if err != nil {
+		settlement.State = Pending
+		if settlement.Attempts >= maxAttempts {
+			settlement.State = Failed
+		}
+		settlement.LastError = err.Error()
+		if saveErr := s.storage.Save(issuer, settlement); saveErr != nil {
+			return saveErr
+		}
+		return errors.Wrap(err, "failed to clear promise")
+	}
+
+	log.Info(logPrefix, "promise ", settlement.SequenceID, " of issuer ", issuer.Address, " submitted for clearing: ", txHash.Hex())
+	settlement.State = Submitted
+	settlement.TxHash = txHash.Hex()
+	settlement.SubmittedAt = s.now().UTC()
+	settlement.LastError = ""
+	return s.storage.Save(issuer, settlement)
+}
+
+func (s *Settler) issuedPromise(issuer identity.Identity, settlement Settlement) promises.IssuedPromise {
+	return promises.IssuedPromise{
+		Promise: promises.Promise{
+			Extra: promise.ExtraData{
+				ConsumerAddress: common.HexToAddress(issuer.Address),
+			},
+			Receiver: common.HexToAddress(s.receiver.Address),
+			SeqNo:    settlement.SequenceID,
+			Amount:   settlement.Amount,
+		},
+		IssuerSignature: common.FromHex(settlement.Signature),
+	}
+}
@@ -0,0 +1,276 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package settlement
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/session/promise"
+	"github.com/mysteriumnetwork/payments/promises"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	issuerID   = identity.FromAddress("0x000000000000000000000000000000000000000a")
+	receiverID = identity.FromAddress("0x000000000000000000000000000000000000000b")
+	now        = time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+)
+
+type promiseStorageFake struct {
+	promises map[identity.Identity][]promise.StoredPromise
+	busy     map[identity.Identity]bool
+}
+
+func (psf *promiseStorageFake) GetAllKnownIssuers() []identity.Identity {
+	res := make([]identity.Identity, 0)
+	for issuer := range psf.promises {
+		res = append(res, issuer)
+	}
+	return res
+}
+
+func (psf *promiseStorageFake) GetLastPromise(issuerID identity.Identity) (promise.StoredPromise, error) {
+	stored := psf.promises[issuerID]
+	if len(stored) == 0 {
+		return promise.StoredPromise{}, errors.New("not found")
+	}
+	return stored[len(stored)-1], nil
+}
+
+func (psf *promiseStorageFake) SettleLastPromise(issuerID identity.Identity, settle func(last promise.StoredPromise) (bool, error)) error {
+	if psf.busy[issuerID] {
+		return promise.ErrIssuerBusy
+	}
+	last, err := psf.GetLastPromise(issuerID)
+	if err != nil {
+		return err
+	}
+	taken, err := settle(last)
+	if taken {
+		psf.promises[issuerID] = append(psf.promises[issuerID], promise.StoredPromise{
+			SequenceID:       last.SequenceID + 1,
+			UnconsumedAmount: last.UnconsumedAmount,
+		})
+	}
+	return err
+}
+
+type settlementStorageFake struct {
+	settlements map[uint64]Settlement
+}
+
+func (ssf *settlementStorageFake) Save(issuerID identity.Identity, settlement Settlement) error {
+	settlement.Issuer = issuerID.Address
+	ssf.settlements[settlement.SequenceID] = settlement
+	return nil
+}
+
+func (ssf *settlementStorageFake) GetAllOfIssuer(issuerID identity.Identity) ([]Settlement, error) {
+	return ssf.GetAll()
+}
+
+func (ssf *settlementStorageFake) GetAll() ([]Settlement, error) {
+	res := make([]Settlement, 0)
+	for _, settlement := range ssf.settlements {
+		res = append(res, settlement)
+	}
+	return res, nil
+}
+
+type clearerFake struct {
+	cleared  []promises.IssuedPromise
+	clearErr error
+	status   TxStatus
+}
+
+func (cf *clearerFake) Clear(promise promises.IssuedPromise) (common.Hash, error) {
+	if cf.clearErr != nil {
+		return common.Hash{}, cf.clearErr
+	}
+	cf.cleared = append(cf.cleared, promise)
+	return common.BigToHash(common.Big1), nil
+}
+
+func (cf *clearerFake) Status(txHash common.Hash) (TxStatus, error) {
+	return cf.status, nil
+}
+
+func newTestSettler(promises *promiseStorageFake, storage *settlementStorageFake, clearer *clearerFake) *Settler {
+	settler := NewSettler(promises, storage, func(receiver identity.Identity) (Clearer, error) {
+		return clearer, nil
+	}, time.Hour)
+	settler.now = func() time.Time { return now }
+	return settler
+}
+
+func idlePromises() *promiseStorageFake {
+	return &promiseStorageFake{promises: map[identity.Identity][]promise.StoredPromise{
+		issuerID: {
+			{
+				SequenceID:       3,
+				Message:          &promise.Message{SequenceID: 3, Amount: 150, Signature: "0x01"},
+				UpdatedAt:        now.Add(-time.Hour),
+				UnconsumedAmount: money.NewUnits(20, money.CURRENCY_MYST),
+			},
+		},
+	}}
+}
+
+func TestSettler_SettleBeforeStartFails(t *testing.T) {
+	settler := newTestSettler(idlePromises(), &settlementStorageFake{settlements: map[uint64]Settlement{}}, &clearerFake{})
+
+	assert.Equal(t, ErrNotStarted, settler.SettleAll())
+	assert.Equal(t, ErrNotStarted, settler.Settle(issuerID))
+}
+
+func TestSettler_ClearsLatestPromiseAndStartsNewSequence(t *testing.T) {
+	promiseStorage := idlePromises()
+	storage := &settlementStorageFake{settlements: map[uint64]Settlement{}}
+	clearer := &clearerFake{}
+	settler := newTestSettler(promiseStorage, storage, clearer)
+	assert.NoError(t, settler.Start(receiverID))
+	defer settler.Stop()
+
+	assert.NoError(t, settler.SettleAll())
+
+	assert.Len(t, clearer.cleared, 1)
+	assert.Equal(t, uint64(3), clearer.cleared[0].SeqNo)
+	assert.Equal(t, uint64(150), clearer.cleared[0].Amount)
+	assert.Equal(t, common.HexToAddress(receiverID.Address), clearer.cleared[0].Receiver)
+	assert.Equal(t, []byte{1}, clearer.cleared[0].IssuerSignature)
+
+	settlement := storage.settlements[3]
+	assert.Equal(t, Submitted, settlement.State)
+	assert.Equal(t, 1, settlement.Attempts)
+	assert.Equal(t, common.BigToHash(common.Big1).Hex(), settlement.TxHash)
+	assert.Equal(t, now, settlement.SubmittedAt)
+
+	last, err := promiseStorage.GetLastPromise(issuerID)
+	assert.NoError(t, err)
+	assert.Equal(t, promise.StoredPromise{SequenceID: 4, UnconsumedAmount: money.NewUnits(20, money.CURRENCY_MYST)}, last)
+}
+
+func TestSettler_SkipsIssuerWithActiveSession(t *testing.T) {
+	promiseStorage := idlePromises()
+	promiseStorage.busy = map[identity.Identity]bool{issuerID: true}
+	clearer := &clearerFake{}
+	settler := newTestSettler(promiseStorage, &settlementStorageFake{settlements: map[uint64]Settlement{}}, clearer)
+	assert.NoError(t, settler.Start(receiverID))
+	defer settler.Stop()
+
+	assert.NoError(t, settler.Settle(issuerID))
+
+	assert.Len(t, clearer.cleared, 0)
+	assert.Len(t, promiseStorage.promises[issuerID], 1)
+}
+
+func TestSettler_DoesNotSettleSameSequenceTwice(t *testing.T) {
+	storage := &settlementStorageFake{settlements: map[uint64]Settlement{}}
+	clearer := &clearerFake{status: TxPending}
+	settler := newTestSettler(idlePromises(), storage, clearer)
+	assert.NoError(t, settler.Start(receiverID))
+	defer settler.Stop()
+
+	assert.NoError(t, settler.SettleAll())
+	assert.NoError(t, settler.SettleAll())
+	clearer.status = TxConfirmed
+	assert.NoError(t, settler.SettleAll())
+	assert.NoError(t, settler.SettleAll())
+
+	assert.Len(t, clearer.cleared, 1)
+	assert.Equal(t, Confirmed, storage.settlements[3].State)
+}
+
+func TestSettler_RetriesFailedClearing(t *testing.T) {
+	storage := &settlementStorageFake{settlements: map[uint64]Settlement{}}
+	clearer := &clearerFake{clearErr: errors.New("no connection")}
+	settler := newTestSettler(idlePromises(), storage, clearer)
+	assert.NoError(t, settler.Start(receiverID))
+	defer settler.Stop()
+
+	assert.Error(t, settler.SettleAll())
+	assert.Equal(t, Pending, storage.settlements[3].State)
+	assert.Equal(t, "no connection", storage.settlements[3].LastError)
+
+	clearer.clearErr = nil
+	assert.NoError(t, settler.SettleAll())
+
+	assert.Len(t, clearer.cleared, 1)
+	assert.Equal(t, uint64(3), clearer.cleared[0].SeqNo)
+	assert.Equal(t, Submitted, storage.settlements[3].State)
+	assert.Equal(t, 2, storage.settlements[3].Attempts)
+	assert.Equal(t, "", storage.settlements[3].LastError)
+}
+
+func TestSettler_DoesNotResubmitRevertedClearing(t *testing.T) {
+	storage := &settlementStorageFake{settlements: map[uint64]Settlement{}}
+	clearer := &clearerFake{status: TxFailed}
+	settler := newTestSettler(idlePromises(), storage, clearer)
+	assert.NoError(t, settler.Start(receiverID))
+	defer settler.Stop()
+
+	assert.NoError(t, settler.SettleAll())
+	assert.NoError(t, settler.SettleAll())
+	assert.NoError(t, settler.SettleAll())
+
+	assert.Len(t, clearer.cleared, 1)
+	assert.Equal(t, Failed, storage.settlements[3].State)
+	assert.Equal(t, 1, storage.settlements[3].Attempts)
+	assert.Equal(t, "clearing transaction reverted", storage.settlements[3].LastError)
+}
+
+func TestSettler_ResubmitsClearingNotMinedInTime(t *testing.T) {
+	storage := &settlementStorageFake{settlements: map[uint64]Settlement{}}
+	clearer := &clearerFake{status: TxPending}
+	settler := newTestSettler(idlePromises(), storage, clearer)
+	assert.NoError(t, settler.Start(receiverID))
+	defer settler.Stop()
+
+	assert.NoError(t, settler.SettleAll())
+	settler.now = func() time.Time { return now.Add(miningTimeout - time.Minute) }
+	assert.NoError(t, settler.SettleAll())
+	assert.Len(t, clearer.cleared, 1)
+
+	settler.now = func() time.Time { return now.Add(miningTimeout) }
+	assert.NoError(t, settler.SettleAll())
+
+	assert.Len(t, clearer.cleared, 2)
+	assert.Equal(t, Submitted, storage.settlements[3].State)
+	assert.Equal(t, 2, storage.settlements[3].Attempts)
+	assert.Equal(t, now.Add(miningTimeout), storage.settlements[3].SubmittedAt)
+}
+
+func TestSettler_GivesUpAfterMaxAttempts(t *testing.T) {
+	storage := &settlementStorageFake{settlements: map[uint64]Settlement{
+		3: {SequenceID: 3, Amount: 150, State: Pending, Attempts: maxAttempts},
+	}}
+	clearer := &clearerFake{}
+	settler := newTestSettler(idlePromises(), storage, clearer)
+	assert.NoError(t, settler.Start(receiverID))
+	defer settler.Stop()
+
+	assert.NoError(t, settler.SettleAll())
+
+	assert.Len(t, clearer.cleared, 0)
+	assert.Equal(t, Failed, storage.settlements[3].State)
+}
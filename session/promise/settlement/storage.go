@@ -0,0 +1,130 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package settlement
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+)
+
+const bucketPrefix = "promise-settlements-"
+
+// errBoltNotFound represents the bolts not found error
+var errBoltNotFound = errors.New("not found")
+
+// State represents the state of promise settlement
+type State string
+
+const (
+	// Pending means that clearing transaction is not sent yet or has to be sent again
+	Pending = State("Pending")
+	// Submitted means that clearing transaction was sent, but is not mined yet
+	Submitted = State("Submitted")
+	// Confirmed means that clearing transaction was mined successfully
+	Confirmed = State("Confirmed")
+	// Failed means that clearing transaction was reverted or could not be sent in all attempts, it is not retried
+	Failed = State("Failed")
+)
+
+// Storer allows to store and get settlements
+type Storer interface {
+	Store(bucket string, object interface{}) error
+	GetAllFrom(bucket string, array interface{}) error
+	GetBuckets() []string
+}
+
+// Settlement is a representation of a single promise clearing in storage
+type Settlement struct {
+	SequenceID  uint64 `storm:"id"`
+	Issuer      string
+	Amount      uint64
+	Signature   string
+	TxHash      string
+	State       State
+	Attempts    int
+	LastError   string
+	SubmittedAt time.Time
+	UpdatedAt   time.Time
+}
+
+// Storage keeps the settlements of promises, per every issuer
+type Storage struct {
+	storage Storer
+	lock    sync.Mutex
+}
+
+// NewStorage returns a new instance of settlement storage
+func NewStorage(storage Storer) *Storage {
+	return &Storage{
+		storage: storage,
+	}
+}
+
+// Save stores the given settlement of issuer promise, replacing the previous one of the same sequence
+func (s *Storage) Save(issuerID identity.Identity, settlement Settlement) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	settlement.Issuer = issuerID.Address
+	settlement.UpdatedAt = time.Now().UTC()
+	return s.storage.Store(getBucketName(issuerID), &settlement)
+}
+
+// GetAllOfIssuer returns all settlements of the given issuer promises
+func (s *Storage) GetAllOfIssuer(issuerID identity.Identity) ([]Settlement, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.getAllOfIssuer(issuerID)
+}
+
+// GetAll returns settlements of all known issuers
+func (s *Storage) GetAll() ([]Settlement, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	res := make([]Settlement, 0)
+	for _, bucket := range s.storage.GetBuckets() {
+		if !strings.HasPrefix(bucket, bucketPrefix) {
+			continue
+		}
+		settlements, err := s.getAllOfIssuer(identity.FromAddress(strings.TrimPrefix(bucket, bucketPrefix)))
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, settlements...)
+	}
+	return res, nil
+}
+
+func (s *Storage) getAllOfIssuer(issuerID identity.Identity) ([]Settlement, error) {
+	var settlements []Settlement
+	err := s.storage.GetAllFrom(getBucketName(issuerID), &settlements)
+	if err != nil && err.Error() == errBoltNotFound.Error() {
+		return []Settlement{}, nil
+	}
+	return settlements, err
+}
+
+func getBucketName(issuerID identity.Identity) string {
+	return bucketPrefix + issuerID.Address
+}
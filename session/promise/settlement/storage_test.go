@@ -0,0 +1,80 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package settlement
+
+import (
+	"testing"
+
+	"github.com/mysteriumnetwork/node/core/storage/boltdb"
+	"github.com/mysteriumnetwork/node/core/storage/boltdb/boltdbtest"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session/promise"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_KeepsSettlementsPerIssuer(t *testing.T) {
+	dir := boltdbtest.CreateTempDir(t)
+	defer boltdbtest.RemoveTempDir(t, dir)
+	bolt, err := boltdb.NewStorage(dir)
+	assert.NoError(t, err)
+	defer bolt.Close()
+
+	storage := NewStorage(bolt)
+	otherIssuer := identity.FromAddress("0x000000000000000000000000000000000000000c")
+
+	settlements, err := storage.GetAllOfIssuer(issuerID)
+	assert.NoError(t, err)
+	assert.Len(t, settlements, 0)
+
+	assert.NoError(t, storage.Save(issuerID, Settlement{SequenceID: 1, Amount: 100, State: Submitted}))
+	assert.NoError(t, storage.Save(issuerID, Settlement{SequenceID: 1, Amount: 100, State: Confirmed}))
+	assert.NoError(t, storage.Save(issuerID, Settlement{SequenceID: 2, Amount: 50, State: Failed}))
+	assert.NoError(t, storage.Save(otherIssuer, Settlement{SequenceID: 1, Amount: 10, State: Submitted}))
+
+	settlements, err = storage.GetAllOfIssuer(issuerID)
+	assert.NoError(t, err)
+	assert.Len(t, settlements, 2)
+	assert.Equal(t, Confirmed, settlements[0].State)
+	assert.Equal(t, issuerID.Address, settlements[0].Issuer)
+	assert.Equal(t, Failed, settlements[1].State)
+
+	settlements, err = storage.GetAll()
+	assert.NoError(t, err)
+	assert.Len(t, settlements, 3)
+}
+
+func TestStorage_KnownIssuersAreListedFromBolt(t *testing.T) {
+	dir := boltdbtest.CreateTempDir(t)
+	defer boltdbtest.RemoveTempDir(t, dir)
+	bolt, err := boltdb.NewStorage(dir)
+	assert.NoError(t, err)
+	defer bolt.Close()
+
+	promises := promise.NewStorage(bolt)
+	otherIssuer := identity.FromAddress("0x000000000000000000000000000000000000000c")
+	assert.Len(t, promises.GetAllKnownIssuers(), 0)
+
+	assert.NoError(t, promises.Store(issuerID, promise.StoredPromise{SequenceID: 1}))
+	assert.NoError(t, promises.Store(otherIssuer, promise.StoredPromise{SequenceID: 1}))
+	assert.NoError(t, NewStorage(bolt).Save(issuerID, Settlement{SequenceID: 1, Amount: 100, State: Submitted}))
+
+	issuers := promises.GetAllKnownIssuers()
+	assert.Len(t, issuers, 2)
+	assert.Contains(t, issuers, issuerID)
+	assert.Contains(t, issuers, otherIssuer)
+}
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
 )
 
 const promiseBucketPrefix = "stored-promise-"
@@ -32,6 +33,8 @@ const firstPromiseID = uint64(1)
 var (
 	// ErrPromiseNotFound represents the error we return when trying to update a non existing promise
 	ErrPromiseNotFound = errors.New("promise not found")
+	// ErrIssuerBusy represents the error we return when promises of the issuer are used by an active session
+	ErrIssuerBusy = errors.New("issuer has an active session")
 	// errBoltNotFound represents the bolts not found error
 	errBoltNotFound = errors.New("not found")
 )
@@ -50,6 +53,7 @@ type Storer interface {
 // It's designed to be used as a singleton for promise storage.
 type Storage struct {
 	storage Storer
+	active  map[identity.Identity]int
 	sync.Mutex
 }
 
@@ -57,16 +61,18 @@ type Storage struct {
 func NewStorage(storage Storer) *Storage {
 	return &Storage{
 		storage: storage,
+		active:  make(map[identity.Identity]int),
 	}
 }
 
 // StoredPromise is a representation of a promise in storage. It stores the message that the consumer sent.
 type StoredPromise struct {
-	SequenceID       uint64 `storm:"id"`
-	Message          *Message
-	AddedAt          time.Time
-	UpdatedAt        time.Time
-	UnconsumedAmount uint64
+	SequenceID uint64 `storm:"id"`
+	Message    *Message
+	AddedAt    time.Time
+	UpdatedAt  time.Time
+	// UnconsumedAmount is the part of promised amount which was not charged yet
+	UnconsumedAmount money.Money
 }
 
 // GetNewSeqIDForIssuer returns a new sequenceID for the provided issuer.
@@ -117,6 +123,52 @@ func (s *Storage) Update(issuerID identity.Identity, sp StoredPromise) error {
 	return s.update(issuerID, sp)
 }
 
+// Acquire marks promises of the issuer as used by an active session, they are not settled until released
+func (s *Storage) Acquire(issuerID identity.Identity) {
+	s.Lock()
+	defer s.Unlock()
+	s.active[issuerID]++
+}
+
+// Release marks that the session which acquired promises of the issuer has ended
+func (s *Storage) Release(issuerID identity.Identity) {
+	s.Lock()
+	defer s.Unlock()
+	if s.active[issuerID] <= 1 {
+		delete(s.active, issuerID)
+		return
+	}
+	s.active[issuerID]--
+}
+
+// SettleLastPromise passes the last promise of the issuer to settle while no session of the issuer is active,
+// so no promise of the issuer can be received meanwhile. Returns ErrIssuerBusy if a session is active.
+// Once settle takes the promise, a new sequence is started, as the taken one can not be extended anymore.
+func (s *Storage) SettleLastPromise(issuerID identity.Identity, settle func(last StoredPromise) (taken bool, err error)) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.active[issuerID] > 0 {
+		return ErrIssuerBusy
+	}
+	last, err := s.getLastPromise(issuerID)
+	if err != nil {
+		return err
+	}
+
+	taken, err := settle(last)
+	if !taken {
+		return err
+	}
+	if storeErr := s.store(issuerID, StoredPromise{
+		SequenceID:       last.SequenceID + 1,
+		UnconsumedAmount: last.UnconsumedAmount,
+	}); storeErr != nil {
+		return storeErr
+	}
+	return err
+}
+
 // GetLastPromise fetches the last promise for the provider
 func (s *Storage) GetLastPromise(issuerID identity.Identity) (StoredPromise, error) {
 	s.Lock()
@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -211,6 +212,60 @@ func Test_Storage_DoesAtomicIncrementsUnderConcurrentLoad(t *testing.T) {
 	}
 }
 
+func Test_Storage_SettlesLastPromiseAndStartsNewSequence(t *testing.T) {
+	s := NewStorage(newMockStorage(nil))
+	assert.Nil(t, s.Store(id, StoredPromise{SequenceID: 3, Message: &Message{Amount: 10}, UnconsumedAmount: money.NewUnits(5, money.CURRENCY_MYST)}))
+
+	var settled StoredPromise
+	err := s.SettleLastPromise(id, func(last StoredPromise) (bool, error) {
+		settled = last
+		return true, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), settled.SequenceID)
+
+	last, err := s.GetLastPromise(id)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), last.SequenceID)
+	assert.Nil(t, last.Message)
+	assert.Equal(t, money.NewUnits(5, money.CURRENCY_MYST), last.UnconsumedAmount)
+}
+
+func Test_Storage_KeepsSequenceWhenPromiseIsNotTaken(t *testing.T) {
+	s := NewStorage(newMockStorage(nil))
+	assert.Nil(t, s.Store(id, StoredPromise{SequenceID: 3}))
+
+	settleErr := errors.New("failed to save settlement")
+	err := s.SettleLastPromise(id, func(last StoredPromise) (bool, error) {
+		return false, settleErr
+	})
+	assert.Equal(t, settleErr, err)
+
+	last, err := s.GetLastPromise(id)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), last.SequenceID)
+}
+
+func Test_Storage_DoesNotSettleWhileIssuerHasActiveSession(t *testing.T) {
+	s := NewStorage(newMockStorage(nil))
+	assert.Nil(t, s.Store(id, StoredPromise{SequenceID: 3, Message: &Message{Amount: 10}}))
+	settle := func(last StoredPromise) (bool, error) {
+		return true, nil
+	}
+
+	s.Acquire(id)
+	s.Acquire(id)
+	assert.Equal(t, ErrIssuerBusy, s.SettleLastPromise(id, settle))
+	s.Release(id)
+	assert.Equal(t, ErrIssuerBusy, s.SettleLastPromise(id, settle))
+	s.Release(id)
+	assert.Nil(t, s.SettleLastPromise(id, settle))
+
+	last, err := s.GetLastPromise(id)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), last.SequenceID)
+}
+
 func Test_MockStorage(t *testing.T) {
 	ms := newMockStorage(nil)
 
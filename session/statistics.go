@@ -0,0 +1,33 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+// StatisticsEventTopic represents the provider session statistics topic
+const StatisticsEventTopic = "SessionStatistics"
+
+// DataTransfer represents the amount of data transferred through the session tunnel
+type DataTransfer struct {
+	BytesSent     uint64
+	BytesReceived uint64
+}
+
+// StatisticsEvent is emitted by services on StatisticsEventTopic with the latest data transfer of a session
+type StatisticsEvent struct {
+	SessionID  ID
+	Statistics DataTransfer
+}
@@ -45,14 +45,39 @@ func (storage *StorageMemory) Add(sessionInstance Session) {
 
 // Find returns underlying session instance
 func (storage *StorageMemory) Find(id ID) (Session, bool) {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
 	sessionInstance, found := storage.sessionMap[id]
 	return sessionInstance, found
 }
 
-// Remove removes given session from underlying storage
+// Remove removes given session from underlying storage and signals its end by closing the Done channel
 func (storage *StorageMemory) Remove(id ID) {
 	storage.lock.Lock()
 	defer storage.lock.Unlock()
 
+	sessionInstance, found := storage.sessionMap[id]
+	if !found {
+		return
+	}
+
 	delete(storage.sessionMap, id)
+	if sessionInstance.Done != nil {
+		close(sessionInstance.Done)
+	}
+}
+
+// ConsumeStatisticsEvent updates the data transfer of the session the event belongs to
+func (storage *StorageMemory) ConsumeStatisticsEvent(event StatisticsEvent) {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	sessionInstance, found := storage.sessionMap[event.SessionID]
+	if !found {
+		return
+	}
+
+	sessionInstance.DataTransfer = event.Statistics
+	storage.sessionMap[event.SessionID] = sessionInstance
 }
@@ -63,6 +63,30 @@ func TestStorage_Remove(t *testing.T) {
 	assert.Len(t, storage.sessionMap, 0)
 }
 
+func TestStorage_Remove_ClosesDone(t *testing.T) {
+	sessionInstance := Session{ID: ID("done-id"), Done: make(chan struct{})}
+	storage := mockStorage(sessionInstance)
+
+	storage.Remove(sessionInstance.ID)
+	storage.Remove(sessionInstance.ID)
+
+	_, open := <-sessionInstance.Done
+	assert.False(t, open)
+}
+
+func TestStorage_ConsumeStatisticsEvent(t *testing.T) {
+	storage := mockStorage(sessionExisting)
+	stats := DataTransfer{BytesSent: 10, BytesReceived: 20}
+
+	storage.ConsumeStatisticsEvent(StatisticsEvent{SessionID: sessionExisting.ID, Statistics: stats})
+	storage.ConsumeStatisticsEvent(StatisticsEvent{SessionID: ID("unknown-id"), Statistics: stats})
+
+	sessionInstance, found := storage.Find(sessionExisting.ID)
+	assert.True(t, found)
+	assert.Equal(t, stats, sessionInstance.DataTransfer)
+	assert.Len(t, storage.sessionMap, 1)
+}
+
 func mockStorage(sessionInstance Session) *StorageMemory {
 	return &StorageMemory{
 		sessionMap: map[ID]Session{
@@ -0,0 +1,61 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import "github.com/mysteriumnetwork/node/datasize"
+
+// sessionFinder finds the session which data transfer is tracked
+type sessionFinder interface {
+	Find(id ID) (Session, bool)
+}
+
+// TrafficTracker tracks data transferred through the session tunnel, as reported by the service
+// it's passive (no internal go routines) and simply reads the latest data transfer of the session
+type TrafficTracker struct {
+	sessionID ID
+	sessions  sessionFinder
+
+	started bool
+	last    DataTransfer
+}
+
+// NewTrafficTracker initializes TrafficTracker of the given session
+func NewTrafficTracker(sessions sessionFinder, sessionID ID) *TrafficTracker {
+	return &TrafficTracker{
+		sessionID: sessionID,
+		sessions:  sessions,
+	}
+}
+
+// StartTracking starts tracking the data transfer
+func (tt *TrafficTracker) StartTracking() {
+	tt.started = true
+}
+
+// Transferred gets the total data sent and received through the session tunnel since the session was created,
+// including traffic which went through before tracking started. Nothing is reported until tracking starts.
+// The last known data transfer is kept once the session is gone.
+func (tt *TrafficTracker) Transferred() datasize.BitSize {
+	if !tt.started {
+		return 0
+	}
+	if sessionInstance, found := tt.sessions.Find(tt.sessionID); found {
+		tt.last = sessionInstance.DataTransfer
+	}
+	return datasize.BitSize(tt.last.BytesSent+tt.last.BytesReceived) * datasize.Byte
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"testing"
+
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrafficTracker_Transferred(t *testing.T) {
+	storage := NewStorageMemory()
+	storage.Add(Session{ID: "session-1"})
+	tracker := NewTrafficTracker(storage, "session-1")
+
+	storage.ConsumeStatisticsEvent(StatisticsEvent{
+		SessionID:  "session-1",
+		Statistics: DataTransfer{BytesSent: 10, BytesReceived: 20},
+	})
+	assert.Equal(t, datasize.BitSize(0), tracker.Transferred())
+
+	tracker.StartTracking()
+	assert.Equal(t, 30*datasize.Byte, tracker.Transferred())
+
+	storage.ConsumeStatisticsEvent(StatisticsEvent{
+		SessionID:  "session-1",
+		Statistics: DataTransfer{BytesSent: 1024, BytesReceived: 1024},
+	})
+	assert.Equal(t, 2*datasize.KB, tracker.Transferred())
+
+	storage.Remove("session-1")
+	assert.Equal(t, 2*datasize.KB, tracker.Transferred())
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"errors"
+
+	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/identity"
+)
+
+// ErrorUpdateNotSupported is returned when the service can not update the config of the running session
+var ErrorUpdateNotSupported = errors.New("session update is not supported by the service")
+
+// updateConsumer processes session update requests from communication channel.
+type updateConsumer struct {
+	sessionFinder Finder
+	peerID        identity.Identity
+	configUpdater ConfigUpdater
+}
+
+// Finder defines method for looking up the session of given consumer
+type Finder interface {
+	Find(consumerID identity.Identity, sessionID ID) (Session, error)
+}
+
+// GetRequestEndpoint returns endpoint where to receive messages
+func (consumer *updateConsumer) GetRequestEndpoint() communication.RequestEndpoint {
+	return endpointSessionUpdate
+}
+
+// NewRequest creates struct where request from endpoint will be serialized
+func (consumer *updateConsumer) NewRequest() (requestPtr interface{}) {
+	return &UpdateRequest{}
+}
+
+// Consume handles requests from endpoint and replies with response
+func (consumer *updateConsumer) Consume(requestPtr interface{}) (response interface{}, err error) {
+	request := requestPtr.(*UpdateRequest)
+
+	if consumer.configUpdater == nil {
+		return updateResponse(ErrorUpdateNotSupported), nil
+	}
+
+	sessionInstance, err := consumer.sessionFinder.Find(consumer.peerID, ID(request.SessionID))
+	if err != nil {
+		return updateResponse(err), nil
+	}
+
+	err = consumer.configUpdater(sessionInstance.ID, request.Config)
+	return updateResponse(err), err
+}
+
+func updateResponse(err error) UpdateResponse {
+	if err != nil {
+		return UpdateResponse{Success: false, Message: err.Error()}
+	}
+	return UpdateResponse{Success: true}
+}
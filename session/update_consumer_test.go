@@ -0,0 +1,98 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateConsumer_Success(t *testing.T) {
+	var updatedID ID
+	var updatedConfig json.RawMessage
+	consumer := updateConsumer{
+		sessionFinder: &sessionFinderFake{returnSession: Session{ID: "session-id"}},
+		peerID:        identity.FromAddress("peer-id"),
+		configUpdater: func(sessionID ID, consumerConfig json.RawMessage) error {
+			updatedID = sessionID
+			updatedConfig = consumerConfig
+			return nil
+		},
+	}
+
+	request := consumer.NewRequest().(*UpdateRequest)
+	request.SessionID = "session-id"
+	request.Config = config
+	response, err := consumer.Consume(request)
+
+	assert.NoError(t, err)
+	assert.Exactly(t, UpdateResponse{Success: true}, response)
+	assert.Equal(t, ID("session-id"), updatedID)
+	assert.Equal(t, config, updatedConfig)
+}
+
+func TestUpdateConsumer_ErrorNotSessionOwner(t *testing.T) {
+	consumer := updateConsumer{
+		sessionFinder: &sessionFinderFake{returnError: ErrorWrongSessionOwner},
+		configUpdater: func(ID, json.RawMessage) error {
+			t.Fatal("config should not be updated")
+			return nil
+		},
+	}
+
+	response, err := consumer.Consume(consumer.NewRequest())
+
+	assert.NoError(t, err)
+	assert.Exactly(t, UpdateResponse{Success: false, Message: ErrorWrongSessionOwner.Error()}, response)
+}
+
+func TestUpdateConsumer_ErrorUpdateFailed(t *testing.T) {
+	consumer := updateConsumer{
+		sessionFinder: &sessionFinderFake{},
+		configUpdater: func(ID, json.RawMessage) error {
+			return errors.New("bad key")
+		},
+	}
+
+	response, err := consumer.Consume(consumer.NewRequest())
+
+	assert.EqualError(t, err, "bad key")
+	assert.Exactly(t, UpdateResponse{Success: false, Message: "bad key"}, response)
+}
+
+func TestUpdateConsumer_ErrorNotSupported(t *testing.T) {
+	consumer := updateConsumer{sessionFinder: &sessionFinderFake{}}
+
+	response, err := consumer.Consume(consumer.NewRequest())
+
+	assert.NoError(t, err)
+	assert.Exactly(t, UpdateResponse{Success: false, Message: ErrorUpdateNotSupported.Error()}, response)
+}
+
+type sessionFinderFake struct {
+	returnSession Session
+	returnError   error
+}
+
+func (finder *sessionFinderFake) Find(consumerID identity.Identity, sessionID ID) (Session, error) {
+	return finder.returnSession, finder.returnError
+}
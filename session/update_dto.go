@@ -0,0 +1,38 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"encoding/json"
+
+	"github.com/mysteriumnetwork/node/communication"
+)
+
+const endpointSessionUpdate = communication.RequestEndpoint("session-update")
+
+// UpdateRequest structure represents message from service consumer to update configuration of the running session
+type UpdateRequest struct {
+	SessionID string          `json:"session_id"`
+	Config    json.RawMessage `json:"config"`
+}
+
+// UpdateResponse structure represents service provider response to given session update request from consumer
+type UpdateResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
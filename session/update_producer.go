@@ -0,0 +1,68 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/mysteriumnetwork/node/communication"
+)
+
+type updateProducer struct {
+	SessionID string
+	Config    json.RawMessage
+}
+
+func (producer *updateProducer) GetRequestEndpoint() communication.RequestEndpoint {
+	return endpointSessionUpdate
+}
+
+func (producer *updateProducer) NewResponse() (responsePtr interface{}) {
+	return &UpdateResponse{}
+}
+
+func (producer *updateProducer) Produce() (requestPtr interface{}) {
+	return &UpdateRequest{
+		SessionID: producer.SessionID,
+		Config:    producer.Config,
+	}
+}
+
+// RequestSessionUpdate requests provider to apply updated consumer config to the running session
+func RequestSessionUpdate(sender communication.Sender, sessionID ID, config interface{}) error {
+	sessionUpdateConfigJSON, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	responsePtr, err := sender.Request(&updateProducer{
+		SessionID: string(sessionID),
+		Config:    sessionUpdateConfigJSON,
+	})
+	if err != nil {
+		return err
+	}
+
+	response := responsePtr.(*UpdateResponse)
+	if !response.Success {
+		return errors.New("Session update failed. " + response.Message)
+	}
+
+	return nil
+}
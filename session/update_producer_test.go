@@ -0,0 +1,62 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/communication"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProducer_RequestSessionUpdate(t *testing.T) {
+	sender := &fakeUpdateSender{response: &UpdateResponse{Success: true}}
+
+	err := RequestSessionUpdate(sender, "session-id", fakeSessionConfig{Param1: "string-param", Param2: 123})
+	assert.NoError(t, err)
+	assert.Exactly(
+		t,
+		&UpdateRequest{
+			SessionID: "session-id",
+			Config:    json.RawMessage(`{"Param1":"string-param","Param2":123}`),
+		},
+		sender.lastRequest.Produce(),
+	)
+}
+
+func TestProducer_RequestSessionUpdate_Failed(t *testing.T) {
+	sender := &fakeUpdateSender{response: &UpdateResponse{Success: false, Message: "no such session"}}
+
+	err := RequestSessionUpdate(sender, "session-id", fakeSessionConfig{})
+	assert.EqualError(t, err, "Session update failed. no such session")
+}
+
+type fakeUpdateSender struct {
+	lastRequest communication.RequestProducer
+	response    *UpdateResponse
+}
+
+func (sender *fakeUpdateSender) Send(producer communication.MessageProducer) error {
+	return nil
+}
+
+func (sender *fakeUpdateSender) Request(producer communication.RequestProducer) (responsePtr interface{}, err error) {
+	sender.lastRequest = producer
+	return sender.response, nil
+}
@@ -83,6 +83,32 @@ func (client *Client) IdentityRegistrationStatus(address string) (RegistrationDa
 	return status, err
 }
 
+// IdentityBalance returns MYST balance of identity on blockchain
+func (client *Client) IdentityBalance(address string) (IdentityBalanceDTO, error) {
+	response, err := client.http.Get("identities/"+address+"/balance", url.Values{})
+	if err != nil {
+		return IdentityBalanceDTO{}, err
+	}
+	defer response.Body.Close()
+
+	balance := IdentityBalanceDTO{}
+	err = parseResponseJSON(response, &balance)
+	return balance, err
+}
+
+// IdentityStatus returns registration status of identity in payments contract
+func (client *Client) IdentityStatus(address string) (IdentityStatusDTO, error) {
+	response, err := client.http.Get("identities/"+address+"/status", url.Values{})
+	if err != nil {
+		return IdentityStatusDTO{}, err
+	}
+	defer response.Body.Close()
+
+	status := IdentityStatusDTO{}
+	err = parseResponseJSON(response, &status)
+	return status, err
+}
+
 // Connect initiates a new connection to a host identified by providerID
 func (client *Client) Connect(consumerID, providerID, serviceType string, options ConnectOptions) (status StatusDTO, err error) {
 	payload := struct {
@@ -239,6 +265,168 @@ func (client *Client) Stop() error {
 	return nil
 }
 
+// WireguardCleanup removes abandoned wireguard interfaces of the running wireguard service
+func (client *Client) WireguardCleanup() (WireguardCleanupDTO, error) {
+	report := WireguardCleanupDTO{}
+	response, err := client.http.Post("wireguard/cleanup", struct{}{})
+	if err != nil {
+		return report, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &report)
+	return report, err
+}
+
+// ExportSession creates session with provider and returns its config for a standard tunnel client
+func (client *Client) ExportSession(consumerID, providerID, serviceType string) (ExportedSessionDTO, error) {
+	exported := ExportedSessionDTO{}
+	payload := struct {
+		Identity    string `json:"consumerId"`
+		ProviderID  string `json:"providerId"`
+		ServiceType string `json:"serviceType"`
+	}{
+		Identity:    consumerID,
+		ProviderID:  providerID,
+		ServiceType: serviceType,
+	}
+	response, err := client.http.Post("exports", payload)
+	if err != nil {
+		return exported, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &exported)
+	return exported, err
+}
+
+// ExportedSessions returns sessions kept alive for external tunnel clients
+func (client *Client) ExportedSessions() (ExportedSessionsDTO, error) {
+	sessions := ExportedSessionsDTO{}
+	response, err := client.http.Get("exports", url.Values{})
+	if err != nil {
+		return sessions, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &sessions)
+	return sessions, err
+}
+
+// StopExportedSession destroys exported session
+func (client *Client) StopExportedSession(sessionID string) error {
+	response, err := client.http.Delete("exports/"+sessionID, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	return nil
+}
+
+// ProviderPreferences returns favourite and blocked providers of consumer identity
+func (client *Client) ProviderPreferences(consumerID string) ([]ProviderPreferenceDTO, error) {
+	list := ProviderPreferenceListDTO{}
+	response, err := client.http.Get("identities/"+consumerID+"/providers", url.Values{})
+	if err != nil {
+		return list.Providers, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &list)
+	return list.Providers, err
+}
+
+// SetProviderPreference marks provider as favourite or blocked for consumer identity
+func (client *Client) SetProviderPreference(consumerID, providerID, kind string) error {
+	payload := struct {
+		Kind string `json:"kind"`
+	}{
+		Kind: kind,
+	}
+	response, err := client.http.Put("identities/"+consumerID+"/providers/"+providerID, payload)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	return nil
+}
+
+// RemoveProviderPreference removes provider from favourite or blocked providers of consumer identity
+func (client *Client) RemoveProviderPreference(consumerID, providerID string) error {
+	response, err := client.http.Delete("identities/"+consumerID+"/providers/"+providerID, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	return nil
+}
+
+// Spending returns current spending and remaining budget of consumer identity
+func (client *Client) Spending(consumerID string) (SpendingDTO, error) {
+	spending := SpendingDTO{}
+	response, err := client.http.Get("identities/"+consumerID+"/spending", url.Values{})
+	if err != nil {
+		return spending, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &spending)
+	return spending, err
+}
+
+// Settlements returns settlements of promises received by provider
+func (client *Client) Settlements() (SettlementListDTO, error) {
+	settlements := SettlementListDTO{}
+	response, err := client.http.Get("settlements", url.Values{})
+	if err != nil {
+		return settlements, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &settlements)
+	return settlements, err
+}
+
+// Settle clears the latest received promises with the payments contract and returns the settlements
+func (client *Client) Settle() (SettlementListDTO, error) {
+	settlements := SettlementListDTO{}
+	response, err := client.http.Post("settlements", struct{}{})
+	if err != nil {
+		return settlements, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &settlements)
+	return settlements, err
+}
+
+// Ledger returns earnings and spending within the given period, aggregated when groupBy is given
+func (client *Client) Ledger(from, to, groupBy string) (LedgerDTO, error) {
+	params := url.Values{}
+	if from != "" {
+		params.Add("from", from)
+	}
+	if to != "" {
+		params.Add("to", to)
+	}
+	if groupBy != "" {
+		params.Add("groupBy", groupBy)
+	}
+
+	ledger := LedgerDTO{}
+	response, err := client.http.Get("ledger", params)
+	if err != nil {
+		return ledger, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &ledger)
+	return ledger, err
+}
+
 // GetSessions returns all sessions from history
 func (client *Client) GetSessions() (SessionsDTO, error) {
 	sessions := SessionsDTO{}
@@ -17,13 +17,19 @@
 
 package client
 
-import "fmt"
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/mysteriumnetwork/node/money"
+)
 
 // StatusDTO holds connection status and session id
 type StatusDTO struct {
 	Status    string      `json:"status"`
 	SessionID string      `json:"sessionId"`
 	Proposal  ProposalDTO `json:"proposal"`
+	Reason    string      `json:"reason"`
 }
 
 // StatisticsDTO holds statistics about connection
@@ -36,6 +42,9 @@ type StatisticsDTO struct {
 // ProposalList describes list of proposals
 type ProposalList struct {
 	Proposals []ProposalDTO `json:"proposals"`
+	Total     int           `json:"total"`
+	UpdatedAt string        `json:"updatedAt"`
+	Stale     bool          `json:"stale"`
 }
 
 // ProposalDTO describes service proposal
@@ -71,10 +80,49 @@ type IdentityList struct {
 
 // HealthcheckDTO holds returned healthcheck response
 type HealthcheckDTO struct {
-	Uptime    string       `json:"uptime"`
-	Process   int          `json:"process"`
-	Version   string       `json:"version"`
-	BuildInfo BuildInfoDTO `json:"buildInfo"`
+	Uptime    string            `json:"uptime"`
+	Process   int               `json:"process"`
+	Version   string            `json:"version"`
+	BuildInfo BuildInfoDTO      `json:"buildInfo"`
+	Wireguard *WireguardInfoDTO `json:"wireguard,omitempty"`
+}
+
+// WireguardInfoDTO holds info about wireguard implementation in use
+type WireguardInfoDTO struct {
+	Mode string `json:"mode"`
+}
+
+// WireguardCleanupDTO holds abandoned wireguard resources removed by cleanup
+type WireguardCleanupDTO struct {
+	Interfaces []string `json:"interfaces"`
+	NATRules   []string `json:"natRules"`
+}
+
+// ExportedSessionDTO describes session exported for a standard tunnel client
+type ExportedSessionDTO struct {
+	SessionID   string `json:"sessionId"`
+	ConsumerID  string `json:"consumerId"`
+	ProviderID  string `json:"providerId"`
+	ServiceType string `json:"serviceType"`
+	Format      string `json:"format"`
+	Config      string `json:"config"`
+}
+
+// ExportedSessionsDTO holds list of exported sessions
+type ExportedSessionsDTO struct {
+	Sessions []ExportedSessionDTO `json:"sessions"`
+}
+
+// ProviderPreferenceDTO describes consumer preference for a provider
+type ProviderPreferenceDTO struct {
+	ProviderID string `json:"providerId"`
+	Kind       string `json:"kind"`
+	AddedAt    string `json:"addedAt"`
+}
+
+// ProviderPreferenceListDTO holds favourite and blocked providers of consumer
+type ProviderPreferenceListDTO struct {
+	Providers []ProviderPreferenceDTO `json:"providers"`
 }
 
 // BuildInfoDTO holds info about build
@@ -104,9 +152,98 @@ type SignatureDTO struct {
 	V uint8  `json:"v"`
 }
 
+// IdentityBalanceDTO holds MYST balance of identity in token and payments contracts
+type IdentityBalanceDTO struct {
+	Token     money.Money `json:"token"`
+	Payments  money.Money `json:"payments"`
+	UpdatedAt string      `json:"updatedAt"`
+}
+
+// IdentityStatusDTO holds registration status of identity in payments contract
+type IdentityStatusDTO struct {
+	Registered bool   `json:"registered"`
+	UpdatedAt  string `json:"updatedAt"`
+}
+
 // ConnectOptions copied from tequilapi endpoint
 type ConnectOptions struct {
-	DisableKillSwitch bool `json:"killSwitch"`
+	DisableKillSwitch bool               `json:"killSwitch"`
+	SpendingLimits    *SpendingLimitsDTO `json:"spendingLimits,omitempty"`
+}
+
+// SpendingLimitsDTO caps spending of a connection, zero means no limit
+type SpendingLimitsDTO struct {
+	PerSession  uint64 `json:"perSession"`
+	PerProvider uint64 `json:"perProvider"`
+	PerDay      uint64 `json:"perDay"`
+	PerMonth    uint64 `json:"perMonth"`
+}
+
+// SpendingUsageDTO describes amount spent against a single limit
+type SpendingUsageDTO struct {
+	Spent     *big.Int `json:"spent"`
+	Limit     *big.Int `json:"limit"`
+	Remaining *big.Int `json:"remaining"`
+}
+
+// SessionSpendingDTO describes spending of a single active session
+type SessionSpendingDTO struct {
+	SessionID  string           `json:"sessionId"`
+	ProviderID string           `json:"providerId"`
+	Session    SpendingUsageDTO `json:"session"`
+	Provider   SpendingUsageDTO `json:"provider"`
+}
+
+// SpendingDTO holds current spending of consumer identity
+type SpendingDTO struct {
+	Sessions []SessionSpendingDTO `json:"sessions"`
+	Day      SpendingUsageDTO     `json:"day"`
+	Month    SpendingUsageDTO     `json:"month"`
+}
+
+// SettlementDTO describes clearing of a single received promise
+type SettlementDTO struct {
+	Issuer     string `json:"issuer"`
+	SequenceID uint64 `json:"sequenceId"`
+	Amount     uint64 `json:"amount"`
+	TxHash     string `json:"txHash"`
+	State      string `json:"state"`
+	Attempts   int    `json:"attempts"`
+	LastError  string `json:"lastError"`
+	UpdatedAt  string `json:"updatedAt"`
+}
+
+// SettlementListDTO holds settlements of received promises
+type SettlementListDTO struct {
+	Settlements []SettlementDTO `json:"settlements"`
+}
+
+// LedgerEntryDTO is a single amount earned or spent
+type LedgerEntryDTO struct {
+	Direction string   `json:"direction"`
+	Identity  string   `json:"identity"`
+	Peer      string   `json:"peer"`
+	SessionID string   `json:"sessionId"`
+	Amount    *big.Int `json:"amount"`
+	Currency  string   `json:"currency"`
+	Formatted string   `json:"formatted"`
+	Timestamp string   `json:"timestamp"`
+}
+
+// LedgerTotalDTO is the aggregated amount earned or spent
+type LedgerTotalDTO struct {
+	Group     string   `json:"group"`
+	Direction string   `json:"direction"`
+	Amount    *big.Int `json:"amount"`
+	Currency  string   `json:"currency"`
+	Formatted string   `json:"formatted"`
+	Entries   int      `json:"entries"`
+}
+
+// LedgerDTO holds either itemised or aggregated earnings and spending
+type LedgerDTO struct {
+	Entries []LedgerEntryDTO `json:"entries"`
+	Totals  []LedgerTotalDTO `json:"totals"`
 }
 
 // SessionsDTO copied from tequilapi endpoint
@@ -26,9 +26,11 @@ import (
 	log "github.com/cihub/seelog"
 	"github.com/julienschmidt/httprouter"
 	"github.com/mysteriumnetwork/node/consumer"
+	"github.com/mysteriumnetwork/node/consumer/spending"
 	"github.com/mysteriumnetwork/node/core/connection"
 	"github.com/mysteriumnetwork/node/core/ip"
 	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/tequilapi/utils"
 	"github.com/mysteriumnetwork/node/tequilapi/validation"
 )
@@ -44,6 +46,23 @@ type ConnectOptions struct {
 	// required: false
 	// example: true
 	DisableKillSwitch bool `json:"killSwitch"`
+
+	// spending limits of the connection, global limits are enforced as well
+	// required: false
+	SpendingLimits *SpendingLimits `json:"spendingLimits,omitempty"`
+}
+
+// SpendingLimits holds tequilapi connection spending limits, in the smallest units of MYST. Zero means no limit.
+// swagger:model SpendingLimitsDTO
+type SpendingLimits struct {
+	// example: 100000000
+	PerSession uint64 `json:"perSession"`
+	// example: 0
+	PerProvider uint64 `json:"perProvider"`
+	// example: 0
+	PerDay uint64 `json:"perDay"`
+	// example: 0
+	PerMonth uint64 `json:"perMonth"`
 }
 
 // swagger:model ConnectionRequestDTO
@@ -79,6 +98,10 @@ type statusResponse struct {
 
 	// example: {"id":1,"providerId":"0x71ccbdee7f6afe85a5bc7106323518518cd23b94","serviceType":"openvpn","serviceDefinition":{"locationOriginate":{"asn":"","country":"CA"}}}
 	Proposal *proposalRes `json:"proposal,omitempty"`
+
+	// reason of the last disconnect, if it was not requested
+	// example: spending limit per session of 100000000 reached
+	Reason string `json:"reason,omitempty"`
 }
 
 // swagger:model IPDTO
@@ -113,18 +136,18 @@ type ConnectionEndpoint struct {
 	ipResolver        ip.Resolver
 	statisticsTracker SessionStatisticsTracker
 	//TODO connection should use concrete proposal from connection params and avoid going to marketplace
-	proposalProvider ProposalProvider
+	proposals preferredProposals
 }
 
 const connectionLogPrefix = "[Connection] "
 
 // NewConnectionEndpoint creates and returns connection endpoint
-func NewConnectionEndpoint(manager connection.Manager, ipResolver ip.Resolver, statsKeeper SessionStatisticsTracker, proposalProvider ProposalProvider) *ConnectionEndpoint {
+func NewConnectionEndpoint(manager connection.Manager, ipResolver ip.Resolver, statsKeeper SessionStatisticsTracker, proposalProvider ProposalProvider, providerKinds ProviderKinds) *ConnectionEndpoint {
 	return &ConnectionEndpoint{
 		manager:           manager,
 		ipResolver:        ipResolver,
 		statisticsTracker: statsKeeper,
-		proposalProvider:  proposalProvider,
+		proposals:         preferredProposals{proposals: proposalProvider, kinds: providerKinds},
 	}
 }
 
@@ -196,7 +219,11 @@ func (ce *ConnectionEndpoint) Create(resp http.ResponseWriter, req *http.Request
 		return
 	}
 
-	proposals, err := ce.proposalProvider.FindProposals(cr.ProviderID, cr.ServiceType)
+	proposals, _, err := ce.proposals.find(cr.ConsumerID, cr.ProviderID, cr.ServiceType)
+	if err == errProviderBlocked {
+		utils.SendError(resp, err, http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		utils.SendError(resp, err, http.StatusInternalServerError)
 		return
@@ -319,8 +346,8 @@ func (ce *ConnectionEndpoint) GetStatistics(writer http.ResponseWriter, request
 
 // AddRoutesForConnection adds connections routes to given router
 func AddRoutesForConnection(router *httprouter.Router, manager connection.Manager, ipResolver ip.Resolver,
-	statsKeeper SessionStatisticsTracker, proposalProvider ProposalProvider) {
-	connectionEndpoint := NewConnectionEndpoint(manager, ipResolver, statsKeeper, proposalProvider)
+	statsKeeper SessionStatisticsTracker, proposalProvider ProposalProvider, providerKinds ProviderKinds) {
+	connectionEndpoint := NewConnectionEndpoint(manager, ipResolver, statsKeeper, proposalProvider, providerKinds)
 	router.GET("/connection", connectionEndpoint.Status)
 	router.PUT("/connection", connectionEndpoint.Create)
 	router.DELETE("/connection", connectionEndpoint.Kill)
@@ -342,7 +369,16 @@ func toConnectionRequest(req *http.Request) (*connectionRequest, error) {
 }
 
 func getConnectOptions(cr *connectionRequest) connection.ConnectParams {
-	return connection.ConnectParams{DisableKillSwitch: cr.ConnectOptions.DisableKillSwitch}
+	params := connection.ConnectParams{DisableKillSwitch: cr.ConnectOptions.DisableKillSwitch}
+	if limits := cr.ConnectOptions.SpendingLimits; limits != nil {
+		params.SpendingLimits = spending.Limits{
+			PerSession:  money.NewUnits(limits.PerSession, money.CURRENCY_MYST),
+			PerProvider: money.NewUnits(limits.PerProvider, money.CURRENCY_MYST),
+			PerDay:      money.NewUnits(limits.PerDay, money.CURRENCY_MYST),
+			PerMonth:    money.NewUnits(limits.PerMonth, money.CURRENCY_MYST),
+		}
+	}
+	return params
 }
 
 func validateConnectionRequest(cr *connectionRequest) *validation.FieldErrorMap {
@@ -360,6 +396,7 @@ func toStatusResponse(status connection.Status) statusResponse {
 	response := statusResponse{
 		Status:    string(status.State),
 		SessionID: string(status.SessionID),
+		Reason:    status.Reason,
 	}
 
 	if status.Proposal.ProviderID != "" {
@@ -27,10 +27,13 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/mysteriumnetwork/node/consumer"
+	"github.com/mysteriumnetwork/node/consumer/preferences"
+	"github.com/mysteriumnetwork/node/consumer/spending"
 	"github.com/mysteriumnetwork/node/core/connection"
 	"github.com/mysteriumnetwork/node/core/ip"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/money"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,12 +45,14 @@ type fakeManager struct {
 	requestedConsumerID  identity.Identity
 	requestedProvider    identity.Identity
 	requestedServiceType string
+	requestedParams      connection.ConnectParams
 }
 
 func (fm *fakeManager) Connect(consumerID identity.Identity, proposal market.ServiceProposal, options connection.ConnectParams) error {
 	fm.requestedConsumerID = consumerID
 	fm.requestedProvider = identity.FromAddress(proposal.ProviderID)
 	fm.requestedServiceType = proposal.ServiceType
+	fm.requestedParams = options
 	return fm.onConnectReturn
 }
 
@@ -100,7 +105,7 @@ func TestAddRoutesForConnectionAddsRoutes(t *testing.T) {
 	ipResolver := ip.NewResolverFake("123.123.123.123")
 
 	mockedProposalProvider := getMockProposalProviderWithSpecifiedProposal("node1", "noop")
-	AddRoutesForConnection(router, &fakeManager, ipResolver, statsKeeper, mockedProposalProvider)
+	AddRoutesForConnection(router, &fakeManager, ipResolver, statsKeeper, mockedProposalProvider, &providerKindsFake{})
 
 	tests := []struct {
 		method         string
@@ -155,7 +160,7 @@ func TestDisconnectingState(t *testing.T) {
 		SessionID: "",
 	}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, &providerKindsFake{})
 	req := httptest.NewRequest(http.MethodGet, "/irrelevant", nil)
 	resp := httptest.NewRecorder()
 
@@ -177,7 +182,7 @@ func TestNotConnectedStateIsReturnedWhenNoConnection(t *testing.T) {
 		SessionID: "",
 	}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, &providerKindsFake{})
 	req := httptest.NewRequest(http.MethodGet, "/irrelevant", nil)
 	resp := httptest.NewRecorder()
 
@@ -199,7 +204,7 @@ func TestStateConnectingIsReturnedWhenIsConnectionInProgress(t *testing.T) {
 		State: connection.Connecting,
 	}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, &providerKindsFake{})
 	req := httptest.NewRequest(http.MethodGet, "/irrelevant", nil)
 	resp := httptest.NewRecorder()
 
@@ -222,7 +227,7 @@ func TestConnectedStateAndSessionIdIsReturnedWhenIsConnected(t *testing.T) {
 		SessionID: "My-super-session",
 	}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, &providerKindsFake{})
 	req := httptest.NewRequest(http.MethodGet, "/irrelevant", nil)
 	resp := httptest.NewRecorder()
 
@@ -242,7 +247,7 @@ func TestConnectedStateAndSessionIdIsReturnedWhenIsConnected(t *testing.T) {
 func TestPutReturns400ErrorIfRequestBodyIsNotJSON(t *testing.T) {
 	fakeManager := fakeManager{}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, &providerKindsFake{})
 	req := httptest.NewRequest(http.MethodPut, "/irrelevant", strings.NewReader("a"))
 	resp := httptest.NewRecorder()
 
@@ -261,7 +266,7 @@ func TestPutReturns400ErrorIfRequestBodyIsNotJSON(t *testing.T) {
 func TestPutReturns422ErrorIfRequestBodyIsMissingFieldValues(t *testing.T) {
 	fakeManager := fakeManager{}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, &providerKindsFake{})
 	req := httptest.NewRequest(http.MethodPut, "/irrelevant", strings.NewReader("{}"))
 	resp := httptest.NewRecorder()
 
@@ -284,7 +289,7 @@ func TestPutWithValidBodyCreatesConnection(t *testing.T) {
 	fakeManager := fakeManager{}
 
 	proposalProvider := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, proposalProvider)
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, proposalProvider, &providerKindsFake{})
 	req := httptest.NewRequest(
 		http.MethodPut,
 		"/irrelevant",
@@ -304,11 +309,35 @@ func TestPutWithValidBodyCreatesConnection(t *testing.T) {
 	assert.Equal(t, "openvpn", fakeManager.requestedServiceType)
 }
 
+func TestPutWithBlockedProviderDoesNotConnect(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	proposalProvider := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
+	kinds := &providerKindsFake{kinds: map[string]preferences.Kind{"required-node": preferences.Blocked}}
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, proposalProvider, kinds)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node"
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.JSONEq(t, `{"message": "provider is blocked by consumer"}`, resp.Body.String())
+	assert.Equal(t, identity.FromAddress("my-identity"), kinds.consumerID)
+	assert.Equal(t, identity.Identity{}, fakeManager.requestedConsumerID)
+}
+
 func TestPutWithServiceTypeOverridesDefault(t *testing.T) {
 	fakeManager := fakeManager{}
 
 	mystAPI := getMockProposalProviderWithSpecifiedProposal("required-node", "noop")
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, mystAPI)
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, mystAPI, &providerKindsFake{})
 	req := httptest.NewRequest(
 		http.MethodPut,
 		"/irrelevant",
@@ -329,10 +358,65 @@ func TestPutWithServiceTypeOverridesDefault(t *testing.T) {
 	assert.Equal(t, "noop", fakeManager.requestedServiceType)
 }
 
+func TestPutWithSpendingLimitsPassesThemToManager(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	mystAPI := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, mystAPI, &providerKindsFake{})
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"connectOptions": {"spendingLimits": {"perSession": 100, "perDay": 1000}}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(
+		t,
+		spending.Limits{
+			PerSession:  money.NewUnits(100, money.CURRENCY_MYST),
+			PerProvider: money.NewUnits(0, money.CURRENCY_MYST),
+			PerDay:      money.NewUnits(1000, money.CURRENCY_MYST),
+			PerMonth:    money.NewUnits(0, money.CURRENCY_MYST),
+		},
+		fakeManager.requestedParams.SpendingLimits,
+	)
+}
+
+func TestNotConnectedStatusContainsDisconnectReason(t *testing.T) {
+	fakeManager := fakeManager{}
+	fakeManager.onStatusReturn = connection.Status{
+		State:  connection.NotConnected,
+		Reason: "spending limit per session of 100 reached",
+	}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, &providerKindsFake{})
+	req := httptest.NewRequest(http.MethodGet, "/irrelevant", nil)
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Status(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"status": "NotConnected",
+			"reason": "spending limit per session of 100 reached"
+		}`,
+		resp.Body.String(),
+	)
+}
+
 func TestDeleteCallsDisconnect(t *testing.T) {
 	fakeManager := fakeManager{}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, &providerKindsFake{})
 	req := httptest.NewRequest(http.MethodDelete, "/irrelevant", nil)
 	resp := httptest.NewRecorder()
 
@@ -346,7 +430,7 @@ func TestDeleteCallsDisconnect(t *testing.T) {
 func TestGetIPEndpointSucceeds(t *testing.T) {
 	manager := fakeManager{}
 	ipResolver := ip.NewResolverFake("123.123.123.123")
-	connEndpoint := NewConnectionEndpoint(&manager, ipResolver, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&manager, ipResolver, nil, &mockProposalProvider{}, &providerKindsFake{})
 	resp := httptest.NewRecorder()
 
 	connEndpoint.GetIP(resp, nil, nil)
@@ -364,7 +448,7 @@ func TestGetIPEndpointSucceeds(t *testing.T) {
 func TestGetIPEndpointReturnsErrorWhenIPDetectionFails(t *testing.T) {
 	manager := fakeManager{}
 	ipResolver := ip.NewResolverFakeFailing(errors.New("fake error"))
-	connEndpoint := NewConnectionEndpoint(&manager, ipResolver, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&manager, ipResolver, nil, &mockProposalProvider{}, &providerKindsFake{})
 	resp := httptest.NewRecorder()
 
 	connEndpoint.GetIP(resp, nil, nil)
@@ -386,7 +470,7 @@ func TestGetStatisticsEndpointReturnsStatistics(t *testing.T) {
 	}
 
 	manager := fakeManager{}
-	connEndpoint := NewConnectionEndpoint(&manager, nil, statsKeeper, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&manager, nil, statsKeeper, &mockProposalProvider{}, &providerKindsFake{})
 
 	resp := httptest.NewRecorder()
 	connEndpoint.GetStatistics(resp, nil, nil)
@@ -407,7 +491,7 @@ func TestGetStatisticsEndpointReturnsStatisticsWhenSessionIsNotStarted(t *testin
 	}
 
 	manager := fakeManager{}
-	connEndpoint := NewConnectionEndpoint(&manager, nil, statsKeeper, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&manager, nil, statsKeeper, &mockProposalProvider{}, &providerKindsFake{})
 
 	resp := httptest.NewRecorder()
 	connEndpoint.GetStatistics(resp, nil, nil)
@@ -427,7 +511,7 @@ func TestEndpointReturnsConflictStatusIfConnectionAlreadyExists(t *testing.T) {
 	manager.onConnectReturn = connection.ErrAlreadyExists
 
 	mystAPI := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
-	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, mystAPI)
+	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, mystAPI, &providerKindsFake{})
 
 	req := httptest.NewRequest(
 		http.MethodPut,
@@ -455,7 +539,7 @@ func TestDisconnectReturnsConflictStatusIfConnectionDoesNotExist(t *testing.T) {
 	manager := fakeManager{}
 	manager.onDisconnectReturn = connection.ErrNoConnection
 
-	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, &mockProposalProvider{})
+	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, &mockProposalProvider{}, &providerKindsFake{})
 
 	req := httptest.NewRequest(
 		http.MethodDelete,
@@ -481,7 +565,7 @@ func TestConnectReturnsConnectCancelledStatusWhenErrConnectionCancelledIsEncount
 	manager.onConnectReturn = connection.ErrConnectionCancelled
 
 	mockProposalProvider := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
-	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, mockProposalProvider)
+	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, mockProposalProvider, &providerKindsFake{})
 	req := httptest.NewRequest(
 		http.MethodPut,
 		"/irrelevant",
@@ -508,7 +592,7 @@ func TestConnectReturnsErrorIfNoProposals(t *testing.T) {
 	manager := fakeManager{}
 	manager.onConnectReturn = connection.ErrConnectionCancelled
 
-	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, &mockProposalProvider{proposals: make([]market.ServiceProposal, 0)})
+	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, &mockProposalProvider{proposals: make([]market.ServiceProposal, 0)}, &providerKindsFake{})
 	req := httptest.NewRequest(
 		http.MethodPut,
 		"/irrelevant",
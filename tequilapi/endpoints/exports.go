@@ -0,0 +1,290 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	log "github.com/cihub/seelog"
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+	"github.com/mysteriumnetwork/node/tequilapi/validation"
+)
+
+const exportsLogPrefix = "[Exports] "
+
+// swagger:model ExportRequestDTO
+type exportRequest struct {
+	// consumer identity paying for the exported session
+	// required: true
+	// example: 0x0000000000000000000000000000000000000001
+	ConsumerID string `json:"consumerId"`
+
+	// provider identity
+	// required: true
+	// example: 0x0000000000000000000000000000000000000002
+	ProviderID string `json:"providerId"`
+
+	// service type. Possible values are "openvpn" and "wireguard"
+	// required: false
+	// default: openvpn
+	// example: openvpn
+	ServiceType string `json:"serviceType"`
+}
+
+// swagger:model ExportedSessionDTO
+type exportedSessionResponse struct {
+	// example: 4cfb0324-daf6-4ad8-448b-e61fe0a1f918
+	SessionID string `json:"sessionId"`
+
+	// example: 0x0000000000000000000000000000000000000001
+	ConsumerID string `json:"consumerId"`
+
+	// example: 0x0000000000000000000000000000000000000002
+	ProviderID string `json:"providerId"`
+
+	// example: openvpn
+	ServiceType string `json:"serviceType"`
+
+	// format of the exported config. Possible values are "ovpn" and "wg-quick"
+	// example: ovpn
+	Format string `json:"format"`
+
+	// exported config, only returned on session export
+	Config string `json:"config,omitempty"`
+}
+
+// swagger:model ExportedSessionListDTO
+type exportedSessionList struct {
+	Sessions []exportedSessionResponse `json:"sessions"`
+}
+
+// SessionExporter exports sessions for external tunnel clients
+type SessionExporter interface {
+	Export(consumerID identity.Identity, proposal market.ServiceProposal) (connection.ExportedSession, error)
+	Sessions() []connection.ExportedSession
+	Stop(sessionID session.ID) error
+}
+
+// ExportsEndpoint struct represents /exports resource and it's subresources
+type ExportsEndpoint struct {
+	exporter  SessionExporter
+	proposals preferredProposals
+}
+
+// NewExportsEndpoint creates and returns exports endpoint
+func NewExportsEndpoint(exporter SessionExporter, proposalProvider ProposalProvider, providerKinds ProviderKinds) *ExportsEndpoint {
+	return &ExportsEndpoint{
+		exporter:  exporter,
+		proposals: preferredProposals{proposals: proposalProvider, kinds: providerKinds},
+	}
+}
+
+// Create exports new session
+// swagger:operation POST /exports Exports createExport
+// ---
+// summary: Exports new session
+// description: Creates session with provider and returns its config for a standard tunnel client. The node keeps the session alive and pays for it until it is stopped.
+// parameters:
+//   - in: body
+//     name: body
+//     description: Parameters in body (consumerId, providerId, serviceType) required for exporting new session
+//     schema:
+//       $ref: "#/definitions/ExportRequestDTO"
+// responses:
+//   201:
+//     description: Session exported
+//     schema:
+//       "$ref": "#/definitions/ExportedSessionDTO"
+//   400:
+//     description: Bad request
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   422:
+//     description: Parameters validation error
+//     schema:
+//       "$ref": "#/definitions/ValidationErrorDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (ee *ExportsEndpoint) Create(resp http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	er, err := toExportRequest(req)
+	if err != nil {
+		utils.SendError(resp, err, http.StatusBadRequest)
+		return
+	}
+
+	errorMap := validateExportRequest(er)
+	if errorMap.HasErrors() {
+		utils.SendValidationErrorMessage(resp, errorMap)
+		return
+	}
+
+	proposals, _, err := ee.proposals.find(er.ConsumerID, er.ProviderID, er.ServiceType)
+	if err == errProviderBlocked {
+		utils.SendError(resp, err, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+	proposal, ok := selectExportedProposal(proposals, er.ProviderID, er.ServiceType)
+	if !ok {
+		utils.SendError(resp, errors.New("provider has no supported "+er.ServiceType+" service proposal"), http.StatusBadRequest)
+		return
+	}
+
+	exported, err := ee.exporter.Export(identity.FromAddress(er.ConsumerID), proposal)
+	if err != nil {
+		switch err {
+		case connection.ErrUnsupportedServiceType:
+			utils.SendError(resp, err, http.StatusBadRequest)
+		default:
+			log.Error(exportsLogPrefix, err)
+			utils.SendError(resp, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := toExportedSessionResponse(exported)
+	response.Config = exported.Config.Content
+
+	resp.WriteHeader(http.StatusCreated)
+	utils.WriteAsJSON(response, resp)
+}
+
+// List returns exported sessions
+// swagger:operation GET /exports Exports listExports
+// ---
+// summary: Returns exported sessions
+// description: Returns sessions which are kept alive for external tunnel clients
+// responses:
+//   200:
+//     description: List of exported sessions
+//     schema:
+//       "$ref": "#/definitions/ExportedSessionListDTO"
+func (ee *ExportsEndpoint) List(resp http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	response := exportedSessionList{Sessions: []exportedSessionResponse{}}
+	for _, exported := range ee.exporter.Sessions() {
+		response.Sessions = append(response.Sessions, toExportedSessionResponse(exported))
+	}
+	utils.WriteAsJSON(response, resp)
+}
+
+// Stop destroys exported session
+// swagger:operation DELETE /exports/{id} Exports stopExport
+// ---
+// summary: Stops exported session
+// description: Destroys exported session and stops paying for it
+// parameters:
+// - name: id
+//   in: path
+//   description: Session ID
+//   type: string
+//   required: true
+// responses:
+//   202:
+//     description: Exported session stopped
+//   404:
+//     description: Exported session not found
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (ee *ExportsEndpoint) Stop(resp http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+	err := ee.exporter.Stop(session.ID(params.ByName("id")))
+	if err != nil {
+		switch err {
+		case connection.ErrNoExportedSession:
+			utils.SendError(resp, err, http.StatusNotFound)
+		default:
+			utils.SendError(resp, err, http.StatusInternalServerError)
+		}
+		return
+	}
+	resp.WriteHeader(http.StatusAccepted)
+}
+
+// AddRoutesForExports adds exports routes to given router
+func AddRoutesForExports(router *httprouter.Router, exporter SessionExporter, proposalProvider ProposalProvider, providerKinds ProviderKinds) {
+	exportsEndpoint := NewExportsEndpoint(exporter, proposalProvider, providerKinds)
+	router.POST("/exports", exportsEndpoint.Create)
+	router.GET("/exports", exportsEndpoint.List)
+	router.DELETE("/exports/:id", exportsEndpoint.Stop)
+}
+
+func toExportRequest(req *http.Request) (*exportRequest, error) {
+	var exportRequest = exportRequest{
+		ServiceType: "openvpn",
+	}
+	err := json.NewDecoder(req.Body).Decode(&exportRequest)
+	if err != nil {
+		return nil, err
+	}
+	return &exportRequest, nil
+}
+
+func validateExportRequest(er *exportRequest) *validation.FieldErrorMap {
+	errors := validation.NewErrorMap()
+	if len(er.ConsumerID) == 0 {
+		errors.ForField("consumerId").AddError("required", "Field is required")
+	}
+	if len(er.ProviderID) == 0 {
+		errors.ForField("providerId").AddError("required", "Field is required")
+	}
+	return errors
+}
+
+// selectExportedProposal picks the proposal of given provider and service type
+// which passes signature check and can be used by this consumer
+func selectExportedProposal(proposals []market.ServiceProposal, providerID, serviceType string) (market.ServiceProposal, bool) {
+	for _, proposal := range proposals {
+		if proposal.ProviderID != providerID || proposal.ServiceType != serviceType {
+			continue
+		}
+		if !proposal.IsSupported() {
+			continue
+		}
+		if err := proposal.VerifyProviderSignature(); err != nil {
+			log.Warn(exportsLogPrefix, "skipping proposal of provider ", proposal.ProviderID, ": ", err)
+			continue
+		}
+		return proposal, true
+	}
+	return market.ServiceProposal{}, false
+}
+
+func toExportedSessionResponse(exported connection.ExportedSession) exportedSessionResponse {
+	return exportedSessionResponse{
+		SessionID:   string(exported.SessionID),
+		ConsumerID:  exported.ConsumerID.Address,
+		ProviderID:  exported.Proposal.ProviderID,
+		ServiceType: exported.Proposal.ServiceType,
+		Format:      exported.Config.Format,
+	}
+}
@@ -0,0 +1,230 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/consumer/preferences"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSessionExporter struct {
+	sessions          []connection.ExportedSession
+	requestedConsumer identity.Identity
+	stoppedSession    session.ID
+	onStopReturn      error
+}
+
+func (fe *fakeSessionExporter) Export(consumerID identity.Identity, proposal market.ServiceProposal) (connection.ExportedSession, error) {
+	fe.requestedConsumer = consumerID
+	exported := connection.ExportedSession{
+		SessionID:  "session-1",
+		ConsumerID: consumerID,
+		Proposal:   proposal,
+		Config:     connection.ExportedConfig{Format: "ovpn", Content: "client"},
+	}
+	fe.sessions = append(fe.sessions, exported)
+	return exported, nil
+}
+
+func (fe *fakeSessionExporter) Sessions() []connection.ExportedSession {
+	return fe.sessions
+}
+
+func (fe *fakeSessionExporter) Stop(sessionID session.ID) error {
+	fe.stoppedSession = sessionID
+	return fe.onStopReturn
+}
+
+func exportableProposal(providerID, serviceType string) market.ServiceProposal {
+	return market.ServiceProposal{
+		ID:                1,
+		ServiceType:       serviceType,
+		ServiceDefinition: TestServiceDefinition{},
+		ProviderID:        providerID,
+		ProviderContacts:  market.ContactList{{Type: "test", Definition: struct{}{}}},
+	}
+}
+
+func TestAddRoutesForExportsAddsRoutes(t *testing.T) {
+	router := httprouter.New()
+	exporter := &fakeSessionExporter{}
+	AddRoutesForExports(router, exporter, &mockProposalProvider{
+		proposals: []market.ServiceProposal{exportableProposal("node1", "openvpn")},
+	}, &providerKindsFake{})
+
+	tests := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedJSON   string
+	}{
+		{
+			http.MethodGet, "/exports", "",
+			http.StatusOK, `{"sessions": []}`,
+		},
+		{
+			http.MethodPost, "/exports", `{"consumerId": "me", "providerId": "node1"}`,
+			http.StatusCreated, `{
+				"sessionId": "session-1",
+				"consumerId": "me",
+				"providerId": "node1",
+				"serviceType": "openvpn",
+				"format": "ovpn",
+				"config": "client"
+			}`,
+		},
+		{
+			http.MethodGet, "/exports", "",
+			http.StatusOK, `{"sessions": [{
+				"sessionId": "session-1",
+				"consumerId": "me",
+				"providerId": "node1",
+				"serviceType": "openvpn",
+				"format": "ovpn"
+			}]}`,
+		},
+		{
+			http.MethodDelete, "/exports/session-1", "",
+			http.StatusAccepted, "",
+		},
+	}
+
+	for _, test := range tests {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, test.expectedStatus, resp.Code)
+		if test.expectedJSON != "" {
+			assert.JSONEq(t, test.expectedJSON, resp.Body.String())
+		} else {
+			assert.Equal(t, "", resp.Body.String())
+		}
+	}
+	assert.Equal(t, identity.FromAddress("me"), exporter.requestedConsumer)
+	assert.Equal(t, session.ID("session-1"), exporter.stoppedSession)
+}
+
+func TestExportRequiresIdentities(t *testing.T) {
+	endpoint := NewExportsEndpoint(&fakeSessionExporter{}, &mockProposalProvider{}, &providerKindsFake{})
+	req := httptest.NewRequest(http.MethodPost, "/exports", strings.NewReader(`{}`))
+	resp := httptest.NewRecorder()
+
+	endpoint.Create(resp, req, nil)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message" : "validation_error",
+			"errors" : {
+				"consumerId" : [ { "code" : "required" , "message" : "Field is required" } ],
+				"providerId" : [ { "code" : "required" , "message" : "Field is required" } ]
+			}
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func TestStopUnknownExportReturnsNotFound(t *testing.T) {
+	router := httprouter.New()
+	AddRoutesForExports(router, &fakeSessionExporter{onStopReturn: connection.ErrNoExportedSession}, &mockProposalProvider{}, &providerKindsFake{})
+	req := httptest.NewRequest(http.MethodDelete, "/exports/unknown", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.JSONEq(t, `{"message": "no exported session exists"}`, resp.Body.String())
+}
+
+func TestExportSelectsProposalOfRequestedServiceType(t *testing.T) {
+	exporter := &fakeSessionExporter{}
+	unsupported := exportableProposal("node1", "openvpn")
+	unsupported.ProviderContacts = market.ContactList{{Type: "unknown", Definition: market.UnsupportedContactType{}}}
+	endpoint := NewExportsEndpoint(exporter, &mockProposalProvider{
+		proposals: []market.ServiceProposal{
+			exportableProposal("node1", "wireguard"),
+			unsupported,
+			exportableProposal("node2", "openvpn"),
+			exportableProposal("node1", "openvpn"),
+		},
+	}, &providerKindsFake{})
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/exports",
+		strings.NewReader(`{"consumerId": "me", "providerId": "node1", "serviceType": "openvpn"}`),
+	)
+	resp := httptest.NewRecorder()
+
+	endpoint.Create(resp, req, nil)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Len(t, exporter.sessions, 1)
+	assert.Equal(t, exportableProposal("node1", "openvpn"), exporter.sessions[0].Proposal)
+}
+
+func TestExportWithoutMatchingProposalReturnsBadRequest(t *testing.T) {
+	exporter := &fakeSessionExporter{}
+	endpoint := NewExportsEndpoint(exporter, &mockProposalProvider{
+		proposals: []market.ServiceProposal{exportableProposal("node1", "openvpn")},
+	}, &providerKindsFake{})
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/exports",
+		strings.NewReader(`{"consumerId": "me", "providerId": "node1", "serviceType": "wireguard"}`),
+	)
+	resp := httptest.NewRecorder()
+
+	endpoint.Create(resp, req, nil)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.JSONEq(t, `{"message": "provider has no supported wireguard service proposal"}`, resp.Body.String())
+	assert.Len(t, exporter.sessions, 0)
+}
+
+func TestExportOfBlockedProviderReturnsBadRequest(t *testing.T) {
+	exporter := &fakeSessionExporter{}
+	kinds := &providerKindsFake{kinds: map[string]preferences.Kind{"node1": preferences.Blocked}}
+	endpoint := NewExportsEndpoint(exporter, &mockProposalProvider{
+		proposals: []market.ServiceProposal{exportableProposal("node1", "openvpn")},
+	}, kinds)
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/exports",
+		strings.NewReader(`{"consumerId": "me", "providerId": "node1", "serviceType": "openvpn"}`),
+	)
+	resp := httptest.NewRecorder()
+
+	endpoint.Create(resp, req, nil)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.JSONEq(t, `{"message": "provider is blocked by consumer"}`, resp.Body.String())
+	assert.Equal(t, identity.FromAddress("me"), kinds.consumerID)
+	assert.Len(t, exporter.sessions, 0)
+}
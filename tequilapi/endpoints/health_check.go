@@ -37,6 +37,15 @@ type healthCheckData struct {
 	// example: 0.0.6
 	Version   string    `json:"version"`
 	BuildInfo buildInfo `json:"buildInfo"`
+
+	Wireguard *wireguardInfo `json:"wireguard,omitempty"`
+}
+
+// swagger:model WireguardInfoDTO
+type wireguardInfo struct {
+	// wireguard implementation selected on first use, "not yet resolved" before it
+	// example: kernelspace
+	Mode string `json:"mode"`
 }
 
 // swagger:model BuildInfoDTO
@@ -55,18 +64,20 @@ type healthCheckEndpoint struct {
 	startTime       time.Time
 	currentTimeFunc func() time.Time
 	processNumber   int
+	wireguardMode   func() string
 }
 
 /*
 HealthCheckEndpointFactory creates a structure with single HealthCheck method for healthcheck serving as http,
-currentTimeFunc is injected for easier testing
+currentTimeFunc is injected for easier testing, wireguard info is omitted when wireguardMode reports empty string
 */
-func HealthCheckEndpointFactory(currentTimeFunc func() time.Time, procID func() int) *healthCheckEndpoint {
+func HealthCheckEndpointFactory(currentTimeFunc func() time.Time, procID func() int, wireguardMode func() string) *healthCheckEndpoint {
 	startTime := currentTimeFunc()
 	return &healthCheckEndpoint{
 		startTime,
 		currentTimeFunc,
 		procID(),
+		wireguardMode,
 	}
 }
 
@@ -94,5 +105,8 @@ func (hce *healthCheckEndpoint) HealthCheck(writer http.ResponseWriter, request
 			metadata.BuildNumber,
 		},
 	}
+	if mode := hce.wireguardMode(); mode != "" {
+		status.Wireguard = &wireguardInfo{Mode: mode}
+	}
 	utils.WriteAsJSON(status, writer)
 }
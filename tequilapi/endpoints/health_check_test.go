@@ -18,6 +18,7 @@
 package endpoints
 
 import (
+	"encoding/json"
 	"net/http/httptest"
 	"testing"
 	"time"
@@ -42,6 +43,7 @@ func TestHealthCheckReturnsExpectedJSONObject(t *testing.T) {
 	handlerFunc := HealthCheckEndpointFactory(
 		newMockTimer([]time.Time{tick1, tick2}).Now,
 		func() int { return 1 },
+		func() string { return "" },
 	).HealthCheck
 	handlerFunc(resp, req, httprouter.Params{})
 
@@ -60,6 +62,22 @@ func TestHealthCheckReturnsExpectedJSONObject(t *testing.T) {
 		resp.Body.String())
 }
 
+func TestHealthCheckReportsWireguardMode(t *testing.T) {
+	req := httptest.NewRequest("GET", "/irrelevant", nil)
+	resp := httptest.NewRecorder()
+
+	handlerFunc := HealthCheckEndpointFactory(
+		time.Now,
+		func() int { return 1 },
+		func() string { return "userspace" },
+	).HealthCheck
+	handlerFunc(resp, req, httprouter.Params{})
+
+	var status healthCheckData
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &status))
+	assert.Equal(t, &wireguardInfo{Mode: "userspace"}, status.Wireguard)
+}
+
 type mockTimer struct {
 	values  []time.Time
 	current int
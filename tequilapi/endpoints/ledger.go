@@ -0,0 +1,300 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"bytes"
+	"math/big"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/session/ledger"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+	"github.com/mysteriumnetwork/node/tequilapi/validation"
+)
+
+// swagger:model LedgerEntryDTO
+type ledgerEntryRes struct {
+	// earned or spent
+	// example: earned
+	Direction string `json:"direction"`
+
+	// identity which earned or spent the amount
+	// example: 0x0000000000000000000000000000000000000001
+	Identity string `json:"identity"`
+
+	// the other side of the session
+	// example: 0x0000000000000000000000000000000000000002
+	Peer string `json:"peer"`
+
+	// example: 4cfb0324-daf6-4ad8-448b-e61fe0a1f918
+	SessionID string `json:"sessionId"`
+
+	// amount in the smallest money units
+	// example: 50000
+	Amount *big.Int `json:"amount"`
+
+	// example: MYST
+	Currency string `json:"currency"`
+
+	// amount in whole units of currency, for displaying
+	// example: 0.0005 MYST
+	Formatted string `json:"formatted"`
+
+	// example: 2019-06-06T11:04:43Z
+	Timestamp string `json:"timestamp"`
+}
+
+// swagger:model LedgerTotalDTO
+type ledgerTotalRes struct {
+	// day, month, peer or session the total is of
+	// example: 2019-06-06
+	Group string `json:"group"`
+
+	// earned or spent
+	// example: earned
+	Direction string `json:"direction"`
+
+	// amount in the smallest money units
+	// example: 150000
+	Amount *big.Int `json:"amount"`
+
+	// example: MYST
+	Currency string `json:"currency"`
+
+	// amount in whole units of currency, for displaying
+	// example: 0.0015 MYST
+	Formatted string `json:"formatted"`
+
+	// count of entries summed up
+	// example: 3
+	Entries int `json:"entries"`
+}
+
+// swagger:model LedgerDTO
+type ledgerRes struct {
+	// itemised entries, present when no grouping is requested
+	Entries []ledgerEntryRes `json:"entries,omitempty"`
+
+	// aggregated totals, present when grouping is requested
+	Totals []ledgerTotalRes `json:"totals,omitempty"`
+}
+
+// Ledger provides the recorded earnings and spending
+type Ledger interface {
+	Entries(from, to time.Time) ([]ledger.Entry, error)
+}
+
+type ledgerQuery struct {
+	from    time.Time
+	to      time.Time
+	groupBy ledger.GroupBy
+}
+
+type ledgerEndpoint struct {
+	ledger Ledger
+}
+
+// NewLedgerEndpoint creates and returns ledger endpoint
+func NewLedgerEndpoint(ledger Ledger) *ledgerEndpoint {
+	return &ledgerEndpoint{ledger: ledger}
+}
+
+// swagger:operation GET /ledger Ledger getLedger
+// ---
+// summary: Returns earnings and spending
+// description: Returns the amounts earned by providers and spent by consumers of this node, itemised or aggregated
+// parameters:
+//   - in: query
+//     name: from
+//     description: start of the period, inclusive. RFC3339 time or date (YYYY-MM-DD)
+//     type: string
+//   - in: query
+//     name: to
+//     description: end of the period, exclusive. RFC3339 time or date (YYYY-MM-DD)
+//     type: string
+//   - in: query
+//     name: groupBy
+//     description: aggregates the entries, when given. Possible values are "day", "month", "peer" and "session"
+//     type: string
+// responses:
+//   200:
+//     description: Earnings and spending
+//     schema:
+//       "$ref": "#/definitions/LedgerDTO"
+//   422:
+//     description: Parameters validation error
+//     schema:
+//       "$ref": "#/definitions/ValidationErrorDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *ledgerEndpoint) Get(resp http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	query, entries, ok := endpoint.query(resp, req)
+	if !ok {
+		return
+	}
+
+	response := ledgerRes{}
+	if query.groupBy == "" {
+		response.Entries = []ledgerEntryRes{}
+		for _, entry := range entries {
+			response.Entries = append(response.Entries, ledgerEntryRes{
+				Direction: string(entry.Direction),
+				Identity:  entry.Identity,
+				Peer:      entry.Peer,
+				SessionID: entry.SessionID,
+				Amount:    entry.Amount.Units(),
+				Currency:  string(entry.Amount.Currency),
+				Formatted: entry.Amount.String(),
+				Timestamp: entry.Timestamp.UTC().Format(time.RFC3339),
+			})
+		}
+	} else {
+		totals, err := ledger.Aggregate(entries, query.groupBy)
+		if err != nil {
+			utils.SendError(resp, err, http.StatusInternalServerError)
+			return
+		}
+		response.Totals = []ledgerTotalRes{}
+		for _, total := range totals {
+			response.Totals = append(response.Totals, ledgerTotalRes{
+				Group:     total.Group,
+				Direction: string(total.Direction),
+				Amount:    total.Amount.Units(),
+				Currency:  string(total.Amount.Currency),
+				Formatted: total.Amount.String(),
+				Entries:   total.Entries,
+			})
+		}
+	}
+	utils.WriteAsJSON(response, resp)
+}
+
+// swagger:operation GET /ledger/csv Ledger exportLedger
+// ---
+// summary: Exports earnings and spending as CSV
+// description: Returns the same view as GET /ledger, formatted as CSV for accounting
+// produces:
+// - text/csv
+// parameters:
+//   - in: query
+//     name: from
+//     description: start of the period, inclusive. RFC3339 time or date (YYYY-MM-DD)
+//     type: string
+//   - in: query
+//     name: to
+//     description: end of the period, exclusive. RFC3339 time or date (YYYY-MM-DD)
+//     type: string
+//   - in: query
+//     name: groupBy
+//     description: aggregates the entries, when given. Possible values are "day", "month", "peer" and "session"
+//     type: string
+// responses:
+//   200:
+//     description: Earnings and spending in CSV
+//   422:
+//     description: Parameters validation error
+//     schema:
+//       "$ref": "#/definitions/ValidationErrorDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *ledgerEndpoint) ExportCSV(resp http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	query, entries, ok := endpoint.query(resp, req)
+	if !ok {
+		return
+	}
+
+	var csv bytes.Buffer
+	var err error
+	if query.groupBy == "" {
+		err = ledger.WriteEntriesCSV(&csv, entries)
+	} else {
+		var totals []ledger.Total
+		if totals, err = ledger.Aggregate(entries, query.groupBy); err == nil {
+			err = ledger.WriteTotalsCSV(&csv, totals)
+		}
+	}
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+
+	resp.Header().Set("Content-Type", "text/csv")
+	resp.Header().Set("Content-Disposition", `attachment; filename="ledger.csv"`)
+	resp.Write(csv.Bytes())
+}
+
+func (endpoint *ledgerEndpoint) query(resp http.ResponseWriter, req *http.Request) (ledgerQuery, []ledger.Entry, bool) {
+	query, errorMap := parseLedgerQuery(req.URL.Query())
+	if errorMap.HasErrors() {
+		utils.SendValidationErrorMessage(resp, errorMap)
+		return query, nil, false
+	}
+
+	entries, err := endpoint.ledger.Entries(query.from, query.to)
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return query, nil, false
+	}
+	return query, entries, true
+}
+
+func parseLedgerQuery(values url.Values) (ledgerQuery, *validation.FieldErrorMap) {
+	errors := validation.NewErrorMap()
+	query := ledgerQuery{
+		from: parseLedgerTime(values, "from", errors),
+		to:   parseLedgerTime(values, "to", errors),
+	}
+
+	if value := values.Get("groupBy"); value != "" {
+		groupBy, err := ledger.ParseGroupBy(value)
+		if err != nil {
+			errors.ForField("groupBy").AddError("invalid", "Supported values are: day, month, peer, session")
+		}
+		query.groupBy = groupBy
+	}
+	return query, errors
+}
+
+func parseLedgerTime(values url.Values, field string, errors *validation.FieldErrorMap) time.Time {
+	value := values.Get(field)
+	if value == "" {
+		return time.Time{}
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t
+	}
+	errors.ForField(field).AddError("invalid", "Value must be RFC3339 time or date (YYYY-MM-DD)")
+	return time.Time{}
+}
+
+// AddRoutesForLedger attaches ledger endpoints to router
+func AddRoutesForLedger(router *httprouter.Router, ledger Ledger) {
+	endpoint := NewLedgerEndpoint(ledger)
+	router.GET("/ledger", endpoint.Get)
+	router.GET("/ledger/csv", endpoint.ExportCSV)
+}
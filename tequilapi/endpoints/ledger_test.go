@@ -0,0 +1,123 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/session/ledger"
+	"github.com/stretchr/testify/assert"
+)
+
+type ledgerFake struct {
+	from, to time.Time
+	entries  []ledger.Entry
+}
+
+func (lf *ledgerFake) Entries(from, to time.Time) ([]ledger.Entry, error) {
+	lf.from, lf.to = from, to
+	return lf.entries, nil
+}
+
+func newLedgerFake() *ledgerFake {
+	timestamp := time.Date(2019, 6, 6, 11, 4, 43, 0, time.UTC)
+	return &ledgerFake{entries: []ledger.Entry{
+		{Direction: ledger.Earned, Identity: "0xprovider", Peer: "0xconsumer", SessionID: "session1", Amount: money.NewUnits(100, money.CURRENCY_MYST), Timestamp: timestamp},
+		{Direction: ledger.Earned, Identity: "0xprovider", Peer: "0xconsumer", SessionID: "session1", Amount: money.NewUnits(50, money.CURRENCY_MYST), Timestamp: timestamp.Add(time.Minute)},
+	}}
+}
+
+func TestLedgerEndpointReturnsEntries(t *testing.T) {
+	fake := newLedgerFake()
+	router := httprouter.New()
+	AddRoutesForLedger(router, fake)
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ledger?from=2019-06-01&to=2019-07-01T00:00:00Z", nil)
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"entries": [
+				{"direction": "earned", "identity": "0xprovider", "peer": "0xconsumer", "sessionId": "session1", "amount": 100, "currency": "MYST", "formatted": "0.000001 MYST", "timestamp": "2019-06-06T11:04:43Z"},
+				{"direction": "earned", "identity": "0xprovider", "peer": "0xconsumer", "sessionId": "session1", "amount": 50, "currency": "MYST", "formatted": "0.0000005 MYST", "timestamp": "2019-06-06T11:05:43Z"}
+			]
+		}`,
+		resp.Body.String(),
+	)
+	assert.Equal(t, time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC), fake.from)
+	assert.Equal(t, time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC), fake.to)
+}
+
+func TestLedgerEndpointReturnsTotals(t *testing.T) {
+	router := httprouter.New()
+	AddRoutesForLedger(router, newLedgerFake())
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ledger?groupBy=session", nil)
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(
+		t,
+		`{"totals": [{"group": "session1", "direction": "earned", "amount": 150, "currency": "MYST", "formatted": "0.0000015 MYST", "entries": 2}]}`,
+		resp.Body.String(),
+	)
+}
+
+func TestLedgerEndpointValidatesQuery(t *testing.T) {
+	router := httprouter.New()
+	AddRoutesForLedger(router, newLedgerFake())
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ledger?from=yesterday&groupBy=year", nil)
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message": "validation_error",
+			"errors": {
+				"from": [{"code": "invalid", "message": "Value must be RFC3339 time or date (YYYY-MM-DD)"}],
+				"groupBy": [{"code": "invalid", "message": "Supported values are: day, month, peer, session"}]
+			}
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func TestLedgerEndpointExportsCSV(t *testing.T) {
+	router := httprouter.New()
+	AddRoutesForLedger(router, newLedgerFake())
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ledger/csv?groupBy=day", nil)
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "text/csv", resp.Header().Get("Content-Type"))
+	assert.Equal(t, "group,direction,amount,currency,entries\n2019-06-06,earned,150,MYST,2\n", resp.Body.String())
+}
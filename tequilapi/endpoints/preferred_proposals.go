@@ -0,0 +1,58 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"errors"
+
+	"github.com/mysteriumnetwork/node/consumer/preferences"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+)
+
+// errProviderBlocked is returned when consumer asks for proposals of the provider it has blocked
+var errProviderBlocked = errors.New("provider is blocked by consumer")
+
+// preferredProposals applies the provider preferences of consumer to the found proposals:
+// proposals of blocked providers are dropped and the ones of favourite providers go first.
+// Connect, export and list all find proposals through it, so that the rules are the same everywhere.
+type preferredProposals struct {
+	proposals ProposalProvider
+	kinds     ProviderKinds
+}
+
+// find returns the proposals of given provider and service type preferred by the consumer together with consumer preferences.
+// Preferences are not applied when consumer is not given.
+func (pp preferredProposals) find(consumerID, providerID, serviceType string) ([]market.ServiceProposal, map[string]preferences.Kind, error) {
+	proposals, err := pp.proposals.FindProposals(providerID, serviceType)
+	if err != nil {
+		return nil, nil, err
+	}
+	if consumerID == "" {
+		return proposals, nil, nil
+	}
+
+	kinds, err := pp.kinds.Kinds(identity.FromAddress(consumerID))
+	if err != nil {
+		return nil, nil, err
+	}
+	if providerID != "" && kinds[providerID] == preferences.Blocked {
+		return nil, kinds, errProviderBlocked
+	}
+	return preferences.Prefer(proposals, kinds), kinds, nil
+}
@@ -19,17 +19,34 @@ package endpoints
 
 import (
 	"encoding/json"
+	"math/big"
 	"net/http"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/consumer/preferences"
+	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/market/metrics"
+	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/tequilapi/utils"
 )
 
 // swagger:model ProposalsList
 type proposalsRes struct {
 	Proposals []proposalRes `json:"proposals"`
+
+	// count of proposals matching filters before pagination
+	// example: 120
+	Total int `json:"total"`
+
+	// time of the last successful proposals refresh from discovery
+	// example: 2019-06-06T11:04:43.910035Z
+	UpdatedAt string `json:"updatedAt,omitempty"`
+
+	// true when discovery is unreachable and the last known proposals are returned
+	// example: false
+	Stale bool `json:"stale,omitempty"`
 }
 
 // swagger:model ServiceLocationDTO
@@ -43,6 +60,10 @@ type locationRes struct {
 
 	// example: Amsterdam
 	City string `json:"city,omitempty"`
+
+	// connectivity class of provider. Possible values are "none" and "nat"
+	// example: nat
+	NATType string `json:"natType,omitempty"`
 }
 
 // swagger:model ServiceDefinitionDTO
@@ -50,6 +71,52 @@ type serviceDefinitionRes struct {
 	LocationOriginate locationRes `json:"locationOriginate"`
 }
 
+// swagger:model MoneyDTO
+type moneyRes struct {
+	// amount in smallest units of currency
+	// example: 50000
+	Amount *big.Int `json:"amount"`
+
+	// example: MYST
+	Currency string `json:"currency"`
+
+	// amount in whole units of currency, for displaying
+	// example: 0.0005 MYST
+	Formatted string `json:"formatted"`
+}
+
+func newMoneyRes(value money.Money) moneyRes {
+	return moneyRes{
+		Amount:    value.Units(),
+		Currency:  string(value.Currency),
+		Formatted: value.String(),
+	}
+}
+
+// swagger:model PaymentMethodDTO
+type paymentMethodRes struct {
+	// example: PER_TIME
+	Type string `json:"type"`
+
+	// price per unit of payment method
+	Price moneyRes `json:"price"`
+}
+
+// swagger:model FreeCreditDTO
+type freeCreditRes struct {
+	// free service time in seconds, for services priced per time
+	// example: 600
+	Duration uint64 `json:"duration,omitempty"`
+
+	// free data in bytes, for services priced per transferred data
+	// example: 104857600
+	Bytes uint64 `json:"bytes,omitempty"`
+
+	// period in seconds after which the free allowance of consumer is renewed, 0 if it is given only once
+	// example: 86400
+	Period uint64 `json:"period"`
+}
+
 // swagger:model ProposalDTO
 type proposalRes struct {
 	// per provider unique serial number of service description provided
@@ -67,12 +134,24 @@ type proposalRes struct {
 	// qualitative service definition
 	ServiceDefinition serviceDefinitionRes `json:"serviceDefinition"`
 
+	// payment method and price of the service
+	PaymentMethod *paymentMethodRes `json:"paymentMethod,omitempty"`
+
+	// free allowance offered to every consumer
+	FreeCredit *freeCreditRes `json:"freeCredit,omitempty"`
+
+	// true when provider is a favourite of consumer given in query
+	Favourite bool `json:"favourite,omitempty"`
+
 	// Metrics of the service
 	Metrics json.RawMessage `json:"metrics,omitempty"`
+
+	// price per time or data unit, used for sorting
+	unitPrice *big.Rat
 }
 
 func proposalToRes(p market.ServiceProposal) proposalRes {
-	return proposalRes{
+	res := proposalRes{
 		ID:          p.ID,
 		ProviderID:  p.ProviderID,
 		ServiceType: p.ServiceType,
@@ -81,9 +160,25 @@ func proposalToRes(p market.ServiceProposal) proposalRes {
 				ASN:     p.ServiceDefinition.GetLocation().ASN,
 				Country: p.ServiceDefinition.GetLocation().Country,
 				City:    p.ServiceDefinition.GetLocation().City,
+				NATType: p.ServiceDefinition.GetLocation().NATType,
 			},
 		},
 	}
+	if price, ok := proposalPrice(p); ok {
+		res.PaymentMethod = &paymentMethodRes{
+			Type:  p.PaymentMethodType,
+			Price: newMoneyRes(price),
+		}
+		res.unitPrice, _, _ = unitPrice(p)
+	}
+	if p.FreeCredit != nil {
+		res.FreeCredit = &freeCreditRes{
+			Duration: uint64(p.FreeCredit.Duration / time.Second),
+			Bytes:    uint64(p.FreeCredit.Bytes.Bytes()),
+			Period:   uint64(p.FreeCredit.Period / time.Second),
+		}
+	}
+	return res
 }
 
 func mapProposalsToRes(
@@ -103,20 +198,32 @@ type ProposalProvider interface {
 	FindProposals(providerID string, serviceType string) ([]market.ServiceProposal, error)
 }
 
+// ProposalRepository allows to fetch cached proposals and tells how fresh they are
+type ProposalRepository interface {
+	ProposalProvider
+	Freshness() (updatedAt time.Time, stale bool)
+}
+
+// ProviderKinds returns favourite and blocked providers of consumer
+type ProviderKinds interface {
+	Kinds(consumerID identity.Identity) (map[string]preferences.Kind, error)
+}
+
 type proposalsEndpoint struct {
-	proposalProvider     ProposalProvider
+	proposalProvider     ProposalRepository
 	mysteriumMorqaClient metrics.QualityOracle
+	providerKinds        ProviderKinds
 }
 
 // NewProposalsEndpoint creates and returns proposal creation endpoint
-func NewProposalsEndpoint(proposalProvider ProposalProvider, morqaClient metrics.QualityOracle) *proposalsEndpoint {
-	return &proposalsEndpoint{proposalProvider, morqaClient}
+func NewProposalsEndpoint(proposalProvider ProposalRepository, morqaClient metrics.QualityOracle, providerKinds ProviderKinds) *proposalsEndpoint {
+	return &proposalsEndpoint{proposalProvider, morqaClient, providerKinds}
 }
 
 // swagger:operation GET /proposals Proposal listProposals
 // ---
 // summary: Returns proposals
-// description: Returns list of proposals filtered by provider id
+// description: Returns list of proposals filtered, sorted and paginated by given params. Proposals are served from local cache which is refreshed in the background, stale flag is set when discovery is unreachable
 // parameters:
 //   - in: query
 //     name: providerId
@@ -130,43 +237,130 @@ func NewProposalsEndpoint(proposalProvider ProposalProvider, morqaClient metrics
 //     name: fetchConnectCounts
 //     description: if set to true, fetches the connection success metrics for nodes. False by default.
 //     type: boolean
+//   - in: query
+//     name: consumerId
+//     description: consumer identity whose blocked providers are excluded and favourite ones are flagged and listed first
+//     type: string
+//   - in: query
+//     name: country
+//     description: country code of provider location
+//     type: string
+//   - in: query
+//     name: natType
+//     description: connectivity class of provider. Possible values are "none" and "nat"
+//     type: string
+//   - in: query
+//     name: paymentMethod
+//     description: payment method type of the proposal
+//     type: string
+//   - in: query
+//     name: priceMin
+//     description: minimal price per hour of service or per gigabyte of data, either in smallest units of currency or with currency, e.g. "0.5 MYST". Requires paymentMethod
+//     type: string
+//   - in: query
+//     name: priceMax
+//     description: maximal price per hour of service or per gigabyte of data, either in smallest units of currency or with currency, e.g. "0.5 MYST". Requires paymentMethod
+//     type: string
+//   - in: query
+//     name: minConnectSuccess
+//     description: minimal share of successful connects to provider, from 0 to 1
+//     type: number
+//   - in: query
+//     name: sortBy
+//     description: sort key. Possible values are "providerId", "country", "price" and "connectSuccessRate". Proposals are sorted by price within their payment method
+//     type: string
+//   - in: query
+//     name: sortOrder
+//     description: sort order. Possible values are "asc" and "desc"
+//     type: string
+//   - in: query
+//     name: limit
+//     description: maximal count of returned proposals
+//     type: integer
+//   - in: query
+//     name: offset
+//     description: count of proposals to skip
+//     type: integer
 // responses:
 //   200:
 //     description: List of proposals
 //     schema:
 //       "$ref": "#/definitions/ProposalsList"
+//   422:
+//     description: Parameters validation error
+//     schema:
+//       "$ref": "#/definitions/ValidationErrorDTO"
 //   500:
 //     description: Internal server error
 //     schema:
 //       "$ref": "#/definitions/ErrorMessageDTO"
 func (pe *proposalsEndpoint) List(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
-	providerID := req.URL.Query().Get("providerId")
-	serviceType := req.URL.Query().Get("serviceType")
-	fetchConnectCounts := req.URL.Query().Get("fetchConnectCounts")
-	proposals, err := pe.proposalProvider.FindProposals(providerID, serviceType)
-	if err != nil {
+	query, errorMap := parseProposalsQuery(req.URL.Query())
+	if errorMap.HasErrors() {
+		utils.SendValidationErrorMessage(resp, errorMap)
+		return
+	}
+
+	preferred := preferredProposals{proposals: pe.proposalProvider, kinds: pe.providerKinds}
+	proposals, kinds, err := preferred.find(query.consumerID, query.providerID, query.serviceType)
+	// proposals of blocked provider are not listed, which is not an error
+	if err != nil && err != errProviderBlocked {
 		utils.SendError(resp, err, http.StatusInternalServerError)
 		return
 	}
 
+	var matching []market.ServiceProposal
+	for _, proposal := range proposals {
+		if query.matches(proposal) {
+			matching = append(matching, proposal)
+		}
+	}
+
+	var proposalsMetrics map[string]json.RawMessage
+	if query.needsMetrics() {
+		proposalsMetrics = fetchMetrics(pe.mysteriumMorqaClient)
+	}
+
 	addMetricsToRes := noMetrics
-	if fetchConnectCounts == "true" {
-		addMetricsToRes = addMetrics(pe.mysteriumMorqaClient)
+	if query.fetchConnectCounts {
+		addMetricsToRes = addMetrics(proposalsMetrics)
 	}
 
-	proposalsRes := proposalsRes{mapProposalsToRes(proposals, proposalToRes, addMetricsToRes)}
+	rates := make(map[string]float64, len(proposalsMetrics))
+	for key, metrics := range proposalsMetrics {
+		if rate, ok := connectSuccessRate(metrics); ok {
+			rates[key] = rate
+		}
+	}
+
+	results := query.filterByMetrics(mapProposalsToRes(matching, proposalToRes, addMetricsToRes), rates)
+	for i := range results {
+		results[i].Favourite = kinds[results[i].ProviderID] == preferences.Favourite
+	}
+	query.sort(results, rates)
+
+	proposalsRes := proposalsRes{
+		Proposals: query.paginate(results),
+		Total:     len(results),
+	}
+	updatedAt, stale := pe.proposalProvider.Freshness()
+	if !updatedAt.IsZero() {
+		proposalsRes.UpdatedAt = updatedAt.UTC().Format(time.RFC3339)
+	}
+	proposalsRes.Stale = stale
 	utils.WriteAsJSON(proposalsRes, resp)
 }
 
 // AddRoutesForProposals attaches proposals endpoints to router
-func AddRoutesForProposals(router *httprouter.Router, proposalProvider ProposalProvider, morqaClient metrics.QualityOracle) {
-	pe := NewProposalsEndpoint(proposalProvider, morqaClient)
+func AddRoutesForProposals(router *httprouter.Router, proposalProvider ProposalRepository, morqaClient metrics.QualityOracle, providerKinds ProviderKinds) {
+	pe := NewProposalsEndpoint(proposalProvider, morqaClient, providerKinds)
 	router.GET("/proposals", pe.List)
 }
 
 func noMetrics(p proposalRes) proposalRes { return p }
 
-func addMetrics(mc metrics.QualityOracle) func(p proposalRes) proposalRes {
+// fetchMetrics returns proposal metrics from quality oracle keyed by provider and service type
+func fetchMetrics(mc metrics.QualityOracle) map[string]json.RawMessage {
 	receivedMetrics := mc.ProposalsMetrics()
 	proposalsMetrics := make(map[string]json.RawMessage, len(receivedMetrics))
 	var proposal struct{ ProposalID proposalRes }
@@ -174,14 +368,20 @@ func addMetrics(mc metrics.QualityOracle) func(p proposalRes) proposalRes {
 	for _, m := range receivedMetrics {
 		json, err := metrics.Parse(m, &proposal)
 		if err != nil {
-			return noMetrics
+			return nil
 		}
-		p := proposal.ProposalID
-		proposalsMetrics[p.ProviderID+"-"+p.ServiceType] = json
+		proposalsMetrics[proposalMetricsKey(proposal.ProposalID)] = json
+	}
+	return proposalsMetrics
+}
+
+func addMetrics(proposalsMetrics map[string]json.RawMessage) func(p proposalRes) proposalRes {
+	if proposalsMetrics == nil {
+		return noMetrics
 	}
 
 	return func(p proposalRes) proposalRes {
-		if metrics, ok := proposalsMetrics[p.ProviderID+"-"+p.ServiceType]; ok {
+		if metrics, ok := proposalsMetrics[proposalMetricsKey(p)]; ok {
 			p.Metrics = metrics
 			return p
 		}
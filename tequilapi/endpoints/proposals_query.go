@@ -0,0 +1,336 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
+	"github.com/mysteriumnetwork/node/tequilapi/validation"
+)
+
+// Proposal sort keys
+const (
+	sortByProviderID         = "providerId"
+	sortByCountry            = "country"
+	sortByPrice              = "price"
+	sortByConnectSuccessRate = "connectSuccessRate"
+)
+
+// Prices are compared per hour of service for proposals priced per time
+// and per gigabyte of data for proposals priced per bytes
+const (
+	priceTimeUnit = time.Hour
+	priceDataUnit = datasize.GB
+)
+
+// proposalsQuery holds filters, sorting and pagination of proposals list
+type proposalsQuery struct {
+	providerID         string
+	consumerID         string
+	serviceType        string
+	fetchConnectCounts bool
+
+	country           string
+	natType           string
+	paymentMethodType string
+	priceMin          *money.Money
+	priceMax          *money.Money
+	minConnectSuccess *float64
+
+	sortBy   string
+	sortDesc bool
+	limit    int
+	offset   int
+}
+
+// needsMetrics tells whether quality metrics are required to answer the query
+func (q *proposalsQuery) needsMetrics() bool {
+	return q.fetchConnectCounts || q.minConnectSuccess != nil || q.sortBy == sortByConnectSuccessRate
+}
+
+func parseProposalsQuery(values url.Values) (*proposalsQuery, *validation.FieldErrorMap) {
+	errors := validation.NewErrorMap()
+	query := &proposalsQuery{
+		providerID:         values.Get("providerId"),
+		consumerID:         values.Get("consumerId"),
+		serviceType:        values.Get("serviceType"),
+		fetchConnectCounts: values.Get("fetchConnectCounts") == "true",
+		country:            values.Get("country"),
+		natType:            values.Get("natType"),
+		paymentMethodType:  values.Get("paymentMethod"),
+		sortBy:             values.Get("sortBy"),
+	}
+
+	// provider location is detected by country only
+	for _, field := range []string{"city", "asn"} {
+		if values.Get(field) != "" {
+			errors.ForField(field).AddError("unsupported", "Filtering by "+field+" is not supported")
+		}
+	}
+
+	query.priceMin = parseAmount(values, "priceMin", errors)
+	query.priceMax = parseAmount(values, "priceMax", errors)
+	if (query.priceMin != nil || query.priceMax != nil) && query.paymentMethodType == "" {
+		errors.ForField("paymentMethod").AddError("required", "Price filters require payment method, as prices of different methods are per different units")
+	}
+
+	if value := values.Get("minConnectSuccess"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			errors.ForField("minConnectSuccess").AddError("invalid", "Value must be a number between 0 and 1")
+		} else {
+			query.minConnectSuccess = &rate
+		}
+	}
+
+	switch query.sortBy {
+	case "", sortByProviderID, sortByCountry, sortByPrice, sortByConnectSuccessRate:
+	default:
+		errors.ForField("sortBy").AddError("invalid", "Supported values are: providerId, country, price, connectSuccessRate")
+	}
+
+	switch values.Get("sortOrder") {
+	case "", "asc":
+	case "desc":
+		query.sortDesc = true
+	default:
+		errors.ForField("sortOrder").AddError("invalid", "Supported values are: asc, desc")
+	}
+
+	query.limit = parseCount(values, "limit", errors)
+	query.offset = parseCount(values, "offset", errors)
+
+	return query, errors
+}
+
+// parseAmount accepts plain smallest units of currency as well as amounts with currency, e.g. "0.5 MYST"
+func parseAmount(values url.Values, field string, errors *validation.FieldErrorMap) *money.Money {
+	value := values.Get(field)
+	if value == "" {
+		return nil
+	}
+	if units, err := strconv.ParseUint(value, 10, 64); err == nil {
+		amount := money.NewUnits(units, "")
+		return &amount
+	}
+	amount, err := money.Parse(value)
+	if err != nil {
+		errors.ForField(field).AddError("invalid", "Value must be a non negative integer or an amount with currency, e.g. 0.5 MYST")
+		return nil
+	}
+	return &amount
+}
+
+func parseCount(values url.Values, field string, errors *validation.FieldErrorMap) int {
+	value := values.Get(field)
+	if value == "" {
+		return 0
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		errors.ForField(field).AddError("invalid", "Value must be a non negative integer")
+		return 0
+	}
+	return count
+}
+
+// matches checks proposal against filters which do not depend on quality metrics
+func (q *proposalsQuery) matches(proposal market.ServiceProposal) bool {
+	location := proposal.ServiceDefinition.GetLocation()
+	if q.country != "" && !strings.EqualFold(location.Country, q.country) {
+		return false
+	}
+	if q.natType != "" && location.NATType != q.natType {
+		return false
+	}
+	if q.paymentMethodType != "" && proposal.PaymentMethodType != q.paymentMethodType {
+		return false
+	}
+	if q.priceMin != nil || q.priceMax != nil {
+		price, currency, ok := unitPrice(proposal)
+		if !ok {
+			return false
+		}
+		if q.priceMin != nil {
+			if cmp, ok := cmpPrice(price, currency, *q.priceMin); !ok || cmp < 0 {
+				return false
+			}
+		}
+		if q.priceMax != nil {
+			if cmp, ok := cmpPrice(price, currency, *q.priceMax); !ok || cmp > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// filterByMetrics drops proposals below requested connect success rate
+func (q *proposalsQuery) filterByMetrics(proposals []proposalRes, rates map[string]float64) []proposalRes {
+	if q.minConnectSuccess == nil {
+		return proposals
+	}
+
+	filtered := make([]proposalRes, 0, len(proposals))
+	for _, proposal := range proposals {
+		if rate, ok := rates[proposalMetricsKey(proposal)]; ok && rate >= *q.minConnectSuccess {
+			filtered = append(filtered, proposal)
+		}
+	}
+	return filtered
+}
+
+func (q *proposalsQuery) sort(proposals []proposalRes, rates map[string]float64) {
+	var less func(a, b proposalRes) bool
+	switch q.sortBy {
+	case sortByProviderID:
+		less = func(a, b proposalRes) bool { return a.ProviderID < b.ProviderID }
+	case sortByCountry:
+		less = func(a, b proposalRes) bool {
+			return a.ServiceDefinition.LocationOriginate.Country < b.ServiceDefinition.LocationOriginate.Country
+		}
+	case sortByPrice:
+		// prices of different payment methods are per different units, so proposals are sorted within their method
+		less = func(a, b proposalRes) bool {
+			if resPaymentMethod(a) != resPaymentMethod(b) {
+				return resPaymentMethod(a) < resPaymentMethod(b)
+			}
+			return resUnitPrice(a).Cmp(resUnitPrice(b)) < 0
+		}
+	case sortByConnectSuccessRate:
+		less = func(a, b proposalRes) bool {
+			return rates[proposalMetricsKey(a)] < rates[proposalMetricsKey(b)]
+		}
+	default:
+		return
+	}
+
+	sort.SliceStable(proposals, func(i, j int) bool {
+		if q.sortDesc {
+			return less(proposals[j], proposals[i])
+		}
+		return less(proposals[i], proposals[j])
+	})
+}
+
+func (q *proposalsQuery) paginate(proposals []proposalRes) []proposalRes {
+	if q.offset >= len(proposals) {
+		return []proposalRes{}
+	}
+	proposals = proposals[q.offset:]
+	if q.limit > 0 && q.limit < len(proposals) {
+		proposals = proposals[:q.limit]
+	}
+	return proposals
+}
+
+func proposalPrice(proposal market.ServiceProposal) (money.Money, bool) {
+	if proposal.PaymentMethod == nil {
+		return money.Money{}, false
+	}
+	if _, unsupported := proposal.PaymentMethod.(market.UnsupportedPaymentMethod); unsupported {
+		return money.Money{}, false
+	}
+	return proposal.PaymentMethod.GetPrice(), true
+}
+
+// unitPrice returns proposal price per time or data unit, so that the prices for different durations
+// or amounts of data can be compared. Prices of other payment methods are returned as they are.
+func unitPrice(proposal market.ServiceProposal) (*big.Rat, money.Currency, bool) {
+	price, ok := proposalPrice(proposal)
+	if !ok {
+		return nil, "", false
+	}
+
+	amount := new(big.Rat)
+	if price.Amount != nil {
+		amount.SetInt(price.Amount)
+	}
+	switch method := proposal.PaymentMethod.(type) {
+	case dto.PaymentPerTime:
+		if method.Duration > 0 {
+			amount.Mul(amount, big.NewRat(int64(priceTimeUnit), int64(method.Duration)))
+		}
+	case dto.PaymentPerBytes:
+		if method.Bytes > 0 {
+			amount.Mul(amount, new(big.Rat).SetFloat64(float64(priceDataUnit/method.Bytes)))
+		}
+	}
+	return amount, price.Currency, true
+}
+
+// cmpPrice compares unit price with the limit given in query, limit without currency is in the currency of price
+func cmpPrice(price *big.Rat, currency money.Currency, limit money.Money) (int, bool) {
+	if limit.Currency != "" && limit.Currency != currency {
+		return 0, false
+	}
+	amount := new(big.Rat)
+	if limit.Amount != nil {
+		amount.SetInt(limit.Amount)
+	}
+	return price.Cmp(amount), true
+}
+
+func resPaymentMethod(proposal proposalRes) string {
+	if proposal.PaymentMethod == nil {
+		return ""
+	}
+	return proposal.PaymentMethod.Type
+}
+
+func resUnitPrice(proposal proposalRes) *big.Rat {
+	if proposal.unitPrice == nil {
+		return new(big.Rat)
+	}
+	return proposal.unitPrice
+}
+
+func proposalMetricsKey(proposal proposalRes) string {
+	return proposal.ProviderID + "-" + proposal.ServiceType
+}
+
+// connectSuccessRate calculates share of successful connects from proposal metrics
+func connectSuccessRate(metrics json.RawMessage) (float64, bool) {
+	var parsed struct {
+		ConnectCount struct {
+			Success int `json:"success"`
+			Fail    int `json:"fail"`
+			Timeout int `json:"timeout"`
+		} `json:"connectCount"`
+	}
+	if err := json.Unmarshal(metrics, &parsed); err != nil {
+		return 0, false
+	}
+
+	counts := parsed.ConnectCount
+	total := counts.Success + counts.Fail + counts.Timeout
+	if total == 0 {
+		return 0, false
+	}
+	return float64(counts.Success) / float64(total), true
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+type locatedServiceDefinition struct {
+	location market.Location
+}
+
+func (service locatedServiceDefinition) GetLocation() market.Location {
+	return service.location
+}
+
+type pricedPaymentMethod struct {
+	amount uint64
+}
+
+func (method pricedPaymentMethod) GetPrice() money.Money {
+	return money.NewUnits(method.amount, money.CURRENCY_MYST)
+}
+
+func newQueryTestProposal(providerID, country, natType string, price uint64) market.ServiceProposal {
+	return market.ServiceProposal{
+		ID:                1,
+		ProviderID:        providerID,
+		ServiceType:       "testprotocol",
+		ServiceDefinition: locatedServiceDefinition{market.Location{Country: country, NATType: natType}},
+		PaymentMethodType: "PER_TIME",
+		PaymentMethod:     pricedPaymentMethod{price},
+	}
+}
+
+var queryTestProposals = []market.ServiceProposal{
+	newQueryTestProposal("0x1", "LT", market.NATTypeNone, 300),
+	newQueryTestProposal("0x2", "NL", market.NATTypeNAT, 100),
+	newQueryTestProposal("0x3", "LT", market.NATTypeNAT, 200),
+}
+
+type morqaMetricsFake struct {
+	metrics []json.RawMessage
+}
+
+func (m *morqaMetricsFake) ProposalsMetrics() []json.RawMessage {
+	return m.metrics
+}
+
+func connectMetric(providerID string, success, fail int) json.RawMessage {
+	return json.RawMessage(`{
+		"proposalID": {"providerID": "` + providerID + `", "serviceType": "testprotocol"},
+		"connectCount": {"success": ` + strconv.Itoa(success) + `, "fail": ` + strconv.Itoa(fail) + `, "timeout": 0}
+	}`)
+}
+
+func listProposals(t *testing.T, query string, morqa *morqaMetricsFake) (int, proposalsRes) {
+	endpoint := NewProposalsEndpoint(&mockProposalProvider{proposals: queryTestProposals}, morqa, &providerKindsFake{})
+	req := httptest.NewRequest(http.MethodGet, "/proposals?"+query, nil)
+	resp := httptest.NewRecorder()
+
+	endpoint.List(resp, req, nil)
+
+	var res proposalsRes
+	if resp.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &res))
+	}
+	return resp.Code, res
+}
+
+func providerIDs(res proposalsRes) []string {
+	ids := make([]string, len(res.Proposals))
+	for i, proposal := range res.Proposals {
+		ids[i] = proposal.ProviderID
+	}
+	return ids
+}
+
+func TestProposalsEndpointListReturnsPaymentMethod(t *testing.T) {
+	_, res := listProposals(t, "country=nl", &morqaMetricsFake{})
+
+	assert.Equal(t, 1, res.Total)
+	assert.Equal(
+		t,
+		&paymentMethodRes{Type: "PER_TIME", Price: moneyRes{Amount: big.NewInt(100), Currency: "MYST", Formatted: "0.000001 MYST"}},
+		res.Proposals[0].PaymentMethod,
+	)
+	assert.Equal(t, market.NATTypeNAT, res.Proposals[0].ServiceDefinition.LocationOriginate.NATType)
+}
+
+func TestProposalsEndpointListFilters(t *testing.T) {
+	morqa := &morqaMetricsFake{metrics: []json.RawMessage{
+		connectMetric("0x1", 9, 1),
+		connectMetric("0x2", 1, 9),
+		connectMetric("0x3", 5, 5),
+	}}
+
+	tests := []struct {
+		query       string
+		expectedIDs []string
+	}{
+		{"country=LT", []string{"0x1", "0x3"}},
+		{"natType=nat", []string{"0x2", "0x3"}},
+		{"paymentMethod=PER_TIME&priceMin=150&priceMax=250", []string{"0x3"}},
+		{"paymentMethod=PER_TIME&priceMin=0.0000015+MYST&priceMax=0.0000025MYST", []string{"0x3"}},
+		{"paymentMethod=PER_BYTES", []string{}},
+		{"minConnectSuccess=0.5", []string{"0x1", "0x3"}},
+		{"country=LT&minConnectSuccess=0.6", []string{"0x1"}},
+	}
+
+	for _, test := range tests {
+		code, res := listProposals(t, test.query, morqa)
+		assert.Equal(t, http.StatusOK, code, test.query)
+		assert.Equal(t, test.expectedIDs, providerIDs(res), test.query)
+		assert.Equal(t, len(test.expectedIDs), res.Total, test.query)
+	}
+}
+
+func TestProposalsEndpointListSortsAndPaginates(t *testing.T) {
+	morqa := &morqaMetricsFake{metrics: []json.RawMessage{
+		connectMetric("0x1", 5, 5),
+		connectMetric("0x2", 9, 1),
+		connectMetric("0x3", 1, 9),
+	}}
+
+	tests := []struct {
+		query         string
+		expectedIDs   []string
+		expectedTotal int
+	}{
+		{"sortBy=price", []string{"0x2", "0x3", "0x1"}, 3},
+		{"sortBy=price&sortOrder=desc", []string{"0x1", "0x3", "0x2"}, 3},
+		{"sortBy=country", []string{"0x1", "0x3", "0x2"}, 3},
+		{"sortBy=connectSuccessRate&sortOrder=desc", []string{"0x2", "0x1", "0x3"}, 3},
+		{"sortBy=price&limit=2", []string{"0x2", "0x3"}, 3},
+		{"sortBy=price&limit=2&offset=2", []string{"0x1"}, 3},
+		{"offset=5", []string{}, 3},
+	}
+
+	for _, test := range tests {
+		code, res := listProposals(t, test.query, morqa)
+		assert.Equal(t, http.StatusOK, code, test.query)
+		assert.Equal(t, test.expectedIDs, providerIDs(res), test.query)
+		assert.Equal(t, test.expectedTotal, res.Total, test.query)
+	}
+}
+
+func newPricedProposal(providerID, paymentMethodType string, paymentMethod market.PaymentMethod) market.ServiceProposal {
+	proposal := newQueryTestProposal(providerID, "LT", market.NATTypeNone, 0)
+	proposal.PaymentMethodType = paymentMethodType
+	proposal.PaymentMethod = paymentMethod
+	return proposal
+}
+
+func TestProposalsEndpointListComparesPricesPerUnitWithinPaymentMethod(t *testing.T) {
+	myst := func(amount uint64) money.Money { return money.NewUnits(amount, money.CURRENCY_MYST) }
+	proposals := []market.ServiceProposal{
+		// 600 per hour
+		newPricedProposal("0x1", dto.PaymentMethodPerTime, dto.PaymentPerTime{Price: myst(10), Duration: time.Minute}),
+		// 500 per hour
+		newPricedProposal("0x2", dto.PaymentMethodPerTime, dto.PaymentPerTime{Price: myst(500), Duration: time.Hour}),
+		// 400 per gigabyte
+		newPricedProposal("0x3", dto.PaymentMethodPerBytes, dto.PaymentPerBytes{Price: myst(100), Bytes: 256 * datasize.MB}),
+		// 50 per gigabyte
+		newPricedProposal("0x4", dto.PaymentMethodPerBytes, dto.PaymentPerBytes{Price: myst(50), Bytes: datasize.GB}),
+	}
+
+	tests := []struct {
+		query       string
+		expectedIDs []string
+	}{
+		{"sortBy=price", []string{"0x4", "0x3", "0x2", "0x1"}},
+		{"sortBy=price&paymentMethod=PER_TIME", []string{"0x2", "0x1"}},
+		{"paymentMethod=PER_TIME&priceMax=550", []string{"0x2"}},
+		{"paymentMethod=PER_BYTES&priceMin=100", []string{"0x3"}},
+	}
+
+	for _, test := range tests {
+		endpoint := NewProposalsEndpoint(&mockProposalProvider{proposals: proposals}, &morqaMetricsFake{}, &providerKindsFake{})
+		req := httptest.NewRequest(http.MethodGet, "/proposals?"+test.query, nil)
+		resp := httptest.NewRecorder()
+
+		endpoint.List(resp, req, nil)
+
+		assert.Equal(t, http.StatusOK, resp.Code, test.query)
+		var res proposalsRes
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &res))
+		assert.Equal(t, test.expectedIDs, providerIDs(res), test.query)
+	}
+}
+
+func TestProposalsEndpointListValidatesQuery(t *testing.T) {
+	for _, query := range []string{
+		"paymentMethod=PER_TIME&priceMin=-1",
+		"paymentMethod=PER_TIME&priceMax=0.000000001+MYST",
+		"paymentMethod=PER_TIME&priceMax=1+BTC",
+		"priceMin=100",
+		"city=Vilnius",
+		"asn=AS8764",
+		"minConnectSuccess=2",
+		"sortBy=unknown",
+		"sortOrder=up",
+		"limit=x",
+		"offset=-3",
+	} {
+		code, _ := listProposals(t, query, &morqaMetricsFake{})
+		assert.Equal(t, http.StatusUnprocessableEntity, code, query)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/stretchr/testify/assert"
@@ -66,7 +67,7 @@ func TestProposalsEndpointListByNodeId(t *testing.T) {
 	req.URL.RawQuery = query.Encode()
 
 	resp := httptest.NewRecorder()
-	handlerFunc := NewProposalsEndpoint(mockProposalProvider, &mysteriumMorqaFake{}).List
+	handlerFunc := NewProposalsEndpoint(mockProposalProvider, &mysteriumMorqaFake{}, &providerKindsFake{}).List
 	handlerFunc(resp, req, nil)
 
 	assert.JSONEq(
@@ -85,7 +86,8 @@ func TestProposalsEndpointListByNodeId(t *testing.T) {
                         }
                     }
                 }
-            ]
+            ],
+            "total": 1
         }`,
 		resp.Body.String(),
 	)
@@ -105,7 +107,7 @@ func TestProposalsEndpointList(t *testing.T) {
 	assert.Nil(t, err)
 
 	resp := httptest.NewRecorder()
-	handlerFunc := NewProposalsEndpoint(proposalProvider, &mysteriumMorqaFake{}).List
+	handlerFunc := NewProposalsEndpoint(proposalProvider, &mysteriumMorqaFake{}, &providerKindsFake{}).List
 	handlerFunc(resp, req, nil)
 
 	assert.JSONEq(
@@ -136,7 +138,49 @@ func TestProposalsEndpointList(t *testing.T) {
                         }
                     }
                 }
-            ]
+            ],
+            "total": 2
+        }`,
+		resp.Body.String(),
+	)
+}
+
+func TestProposalsEndpointListShowsFreeCredit(t *testing.T) {
+	proposal := serviceProposals[0]
+	proposal.FreeCredit = &market.FreeCredit{Duration: 10 * time.Minute, Period: 24 * time.Hour}
+	proposalProvider := &mockProposalProvider{
+		proposals: []market.ServiceProposal{proposal},
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/irrelevant", nil)
+	assert.Nil(t, err)
+
+	resp := httptest.NewRecorder()
+	handlerFunc := NewProposalsEndpoint(proposalProvider, &mysteriumMorqaFake{}, &providerKindsFake{}).List
+	handlerFunc(resp, req, nil)
+
+	assert.JSONEq(
+		t,
+		`{
+            "proposals": [
+                {
+                    "id": 1,
+                    "providerId": "0xProviderId",
+                    "serviceType": "testprotocol",
+                    "serviceDefinition": {
+                        "locationOriginate": {
+                            "asn": "LT",
+                            "country": "Lithuania",
+                            "city": "Vilnius"
+                        }
+                    },
+                    "freeCredit": {
+                        "duration": 600,
+                        "period": 86400
+                    }
+                }
+            ],
+            "total": 1
         }`,
 		resp.Body.String(),
 	)
@@ -154,7 +198,7 @@ func TestProposalsEndpointListFetchConnectCounts(t *testing.T) {
 	assert.Nil(t, err)
 
 	resp := httptest.NewRecorder()
-	handlerFunc := NewProposalsEndpoint(proposalProvider, &mysteriumMorqaFake{}).List
+	handlerFunc := NewProposalsEndpoint(proposalProvider, &mysteriumMorqaFake{}, &providerKindsFake{}).List
 	handlerFunc(resp, req, nil)
 
 	assert.JSONEq(
@@ -193,7 +237,8 @@ func TestProposalsEndpointListFetchConnectCounts(t *testing.T) {
 					},
 					"metrics": {}
 				}
-			]
+			],
+			"total": 2
 		}`,
 		resp.Body.String(),
 	)
@@ -219,10 +264,36 @@ func (m *mysteriumMorqaFake) ProposalsMetrics() []json.RawMessage {
 	return nil
 }
 
+func TestProposalsEndpointListReportsStaleProposals(t *testing.T) {
+	proposalProvider := &mockProposalProvider{
+		proposals: []market.ServiceProposal{},
+		updatedAt: time.Date(2019, 6, 6, 11, 4, 43, 0, time.UTC),
+		stale:     true,
+	}
+	req := httptest.NewRequest(http.MethodGet, "/irrelevant", nil)
+
+	resp := httptest.NewRecorder()
+	handlerFunc := NewProposalsEndpoint(proposalProvider, &mysteriumMorqaFake{}, &providerKindsFake{}).List
+	handlerFunc(resp, req, nil)
+
+	assert.JSONEq(
+		t,
+		`{
+			"proposals": [],
+			"total": 0,
+			"updatedAt": "2019-06-06T11:04:43Z",
+			"stale": true
+		}`,
+		resp.Body.String(),
+	)
+}
+
 type mockProposalProvider struct {
 	recordedProviderId  string
 	recordedServiceType string
 	proposals           []market.ServiceProposal
+	updatedAt           time.Time
+	stale               bool
 }
 
 func (mpp *mockProposalProvider) FindProposals(providerID string, serviceType string) ([]market.ServiceProposal, error) {
@@ -231,4 +302,8 @@ func (mpp *mockProposalProvider) FindProposals(providerID string, serviceType st
 	return mpp.proposals, nil
 }
 
-var _ ProposalProvider = &mockProposalProvider{}
+func (mpp *mockProposalProvider) Freshness() (time.Time, bool) {
+	return mpp.updatedAt, mpp.stale
+}
+
+var _ ProposalRepository = &mockProposalProvider{}
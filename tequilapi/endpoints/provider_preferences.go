@@ -0,0 +1,227 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/consumer/preferences"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+	"github.com/mysteriumnetwork/node/tequilapi/validation"
+)
+
+// swagger:model ProviderPreferenceDTO
+type providerPreferenceRes struct {
+	// example: 0x0000000000000000000000000000000000000002
+	ProviderID string `json:"providerId"`
+
+	// possible values are "favourite" and "blocked"
+	// example: favourite
+	Kind string `json:"kind"`
+
+	// example: 2019-06-06T11:04:43Z
+	AddedAt string `json:"addedAt"`
+}
+
+// swagger:model ProviderPreferenceListDTO
+type providerPreferencesRes struct {
+	Providers []providerPreferenceRes `json:"providers"`
+}
+
+// swagger:model ProviderPreferenceRequestDTO
+type providerPreferenceRequest struct {
+	// possible values are "favourite" and "blocked"
+	// required: true
+	// example: blocked
+	Kind string `json:"kind"`
+}
+
+// ProviderPreferences keeps favourite and blocked providers of consumer identities
+type ProviderPreferences interface {
+	List(consumerID identity.Identity) ([]preferences.ProviderPreference, error)
+	Set(consumerID identity.Identity, providerID string, kind preferences.Kind) error
+	Remove(consumerID identity.Identity, providerID string) error
+}
+
+type providerPreferencesEndpoint struct {
+	preferences ProviderPreferences
+}
+
+// NewProviderPreferencesEndpoint creates and returns provider preferences endpoint
+func NewProviderPreferencesEndpoint(preferences ProviderPreferences) *providerPreferencesEndpoint {
+	return &providerPreferencesEndpoint{preferences: preferences}
+}
+
+// swagger:operation GET /identities/{id}/providers Identity listProviderPreferences
+// ---
+// summary: Returns favourite and blocked providers
+// description: Returns favourite and blocked providers of consumer identity
+// parameters:
+// - in: path
+//   name: id
+//   description: Consumer identity
+//   type: string
+//   required: true
+// responses:
+//   200:
+//     description: List of provider preferences
+//     schema:
+//       "$ref": "#/definitions/ProviderPreferenceListDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *providerPreferencesEndpoint) List(resp http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+	list, err := endpoint.preferences.List(identity.FromAddress(params.ByName("id")))
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := providerPreferencesRes{Providers: []providerPreferenceRes{}}
+	for _, preference := range list {
+		response.Providers = append(response.Providers, providerPreferenceRes{
+			ProviderID: preference.ProviderID,
+			Kind:       string(preference.Kind),
+			AddedAt:    preference.AddedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	utils.WriteAsJSON(response, resp)
+}
+
+// swagger:operation PUT /identities/{id}/providers/{providerId} Identity setProviderPreference
+// ---
+// summary: Marks provider as favourite or blocked
+// description: Marks provider as favourite or blocked for consumer identity, replacing previous preference
+// parameters:
+// - in: path
+//   name: id
+//   description: Consumer identity
+//   type: string
+//   required: true
+// - in: path
+//   name: providerId
+//   description: Provider identity
+//   type: string
+//   required: true
+// - in: body
+//   name: body
+//   description: Parameter in body (kind) required for marking provider
+//   schema:
+//     $ref: "#/definitions/ProviderPreferenceRequestDTO"
+// responses:
+//   202:
+//     description: Provider preference saved
+//   400:
+//     description: Body parsing error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   422:
+//     description: Parameters validation error
+//     schema:
+//       "$ref": "#/definitions/ValidationErrorDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *providerPreferencesEndpoint) Set(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	var preferenceReq providerPreferenceRequest
+	if err := json.NewDecoder(req.Body).Decode(&preferenceReq); err != nil {
+		utils.SendError(resp, err, http.StatusBadRequest)
+		return
+	}
+
+	errorMap := validateProviderPreferenceRequest(preferenceReq)
+	if errorMap.HasErrors() {
+		utils.SendValidationErrorMessage(resp, errorMap)
+		return
+	}
+
+	err := endpoint.preferences.Set(
+		identity.FromAddress(params.ByName("id")),
+		params.ByName("providerId"),
+		preferences.Kind(preferenceReq.Kind),
+	)
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+	resp.WriteHeader(http.StatusAccepted)
+}
+
+// swagger:operation DELETE /identities/{id}/providers/{providerId} Identity removeProviderPreference
+// ---
+// summary: Forgets provider preference
+// description: Removes provider from favourite or blocked providers of consumer identity
+// parameters:
+// - in: path
+//   name: id
+//   description: Consumer identity
+//   type: string
+//   required: true
+// - in: path
+//   name: providerId
+//   description: Provider identity
+//   type: string
+//   required: true
+// responses:
+//   202:
+//     description: Provider preference removed
+//   404:
+//     description: Provider preference not found
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *providerPreferencesEndpoint) Remove(resp http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+	err := endpoint.preferences.Remove(identity.FromAddress(params.ByName("id")), params.ByName("providerId"))
+	switch err {
+	case nil:
+		resp.WriteHeader(http.StatusAccepted)
+	case preferences.ErrPreferenceNotFound:
+		utils.SendError(resp, err, http.StatusNotFound)
+	default:
+		utils.SendError(resp, err, http.StatusInternalServerError)
+	}
+}
+
+func validateProviderPreferenceRequest(preferenceReq providerPreferenceRequest) *validation.FieldErrorMap {
+	errors := validation.NewErrorMap()
+	switch preferences.Kind(preferenceReq.Kind) {
+	case preferences.Favourite, preferences.Blocked:
+	case "":
+		errors.ForField("kind").AddError("required", "Field is required")
+	default:
+		errors.ForField("kind").AddError("invalid", "Supported values are: favourite, blocked")
+	}
+	return errors
+}
+
+// AddRoutesForProviderPreferences attaches provider preferences endpoints to router
+func AddRoutesForProviderPreferences(router *httprouter.Router, preferences ProviderPreferences) {
+	endpoint := NewProviderPreferencesEndpoint(preferences)
+	router.GET("/identities/:id/providers", endpoint.List)
+	router.PUT("/identities/:id/providers/:providerId", endpoint.Set)
+	router.DELETE("/identities/:id/providers/:providerId", endpoint.Remove)
+}
@@ -0,0 +1,172 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/consumer/preferences"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/stretchr/testify/assert"
+)
+
+type providerKindsFake struct {
+	consumerID identity.Identity
+	kinds      map[string]preferences.Kind
+}
+
+func (pk *providerKindsFake) List(consumerID identity.Identity) ([]preferences.ProviderPreference, error) {
+	pk.consumerID = consumerID
+	var list []preferences.ProviderPreference
+	for providerID, kind := range pk.kinds {
+		list = append(list, preferences.ProviderPreference{
+			ProviderID: providerID,
+			Kind:       kind,
+			AddedAt:    time.Date(2019, 6, 6, 11, 4, 43, 0, time.UTC),
+		})
+	}
+	return list, nil
+}
+
+func (pk *providerKindsFake) Set(consumerID identity.Identity, providerID string, kind preferences.Kind) error {
+	pk.consumerID = consumerID
+	if pk.kinds == nil {
+		pk.kinds = make(map[string]preferences.Kind)
+	}
+	pk.kinds[providerID] = kind
+	return nil
+}
+
+func (pk *providerKindsFake) Remove(consumerID identity.Identity, providerID string) error {
+	pk.consumerID = consumerID
+	if _, found := pk.kinds[providerID]; !found {
+		return preferences.ErrPreferenceNotFound
+	}
+	delete(pk.kinds, providerID)
+	return nil
+}
+
+func (pk *providerKindsFake) Kinds(consumerID identity.Identity) (map[string]preferences.Kind, error) {
+	pk.consumerID = consumerID
+	return pk.kinds, nil
+}
+
+func TestAddRoutesForProviderPreferencesAddsRoutes(t *testing.T) {
+	router := httprouter.New()
+	fake := &providerKindsFake{}
+	AddRoutesForProviderPreferences(router, fake)
+
+	tests := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedJSON   string
+	}{
+		{
+			http.MethodGet, "/identities/0xconsumer/providers", "",
+			http.StatusOK, `{"providers": []}`,
+		},
+		{
+			http.MethodPut, "/identities/0xconsumer/providers/0xprovider", `{"kind": "blocked"}`,
+			http.StatusAccepted, "",
+		},
+		{
+			http.MethodGet, "/identities/0xconsumer/providers", "",
+			http.StatusOK, `{"providers": [{"providerId": "0xprovider", "kind": "blocked", "addedAt": "2019-06-06T11:04:43Z"}]}`,
+		},
+		{
+			http.MethodPut, "/identities/0xconsumer/providers/0xprovider", `{"kind": "liked"}`,
+			http.StatusUnprocessableEntity, `{
+				"message": "validation_error",
+				"errors": {"kind": [{"code": "invalid", "message": "Supported values are: favourite, blocked"}]}
+			}`,
+		},
+		{
+			http.MethodDelete, "/identities/0xconsumer/providers/0xprovider", "",
+			http.StatusAccepted, "",
+		},
+		{
+			http.MethodDelete, "/identities/0xconsumer/providers/0xprovider", "",
+			http.StatusNotFound, `{"message": "provider preference not found"}`,
+		},
+	}
+
+	for _, test := range tests {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, test.expectedStatus, resp.Code, test.method+" "+test.path)
+		if test.expectedJSON != "" {
+			assert.JSONEq(t, test.expectedJSON, resp.Body.String())
+		} else {
+			assert.Equal(t, "", resp.Body.String())
+		}
+	}
+	assert.Equal(t, identity.FromAddress("0xconsumer"), fake.consumerID)
+}
+
+func TestProposalsEndpointListAppliesProviderPreferences(t *testing.T) {
+	kinds := &providerKindsFake{kinds: map[string]preferences.Kind{
+		"0x1": preferences.Blocked,
+		"0x3": preferences.Favourite,
+	}}
+	proposals := []market.ServiceProposal{
+		newQueryTestProposal("0x1", "LT", market.NATTypeNone, 300),
+		newQueryTestProposal("0x2", "NL", market.NATTypeNAT, 100),
+		newQueryTestProposal("0x3", "LT", market.NATTypeNAT, 200),
+	}
+	endpoint := NewProposalsEndpoint(&mockProposalProvider{proposals: proposals}, &morqaMetricsFake{}, kinds)
+	req := httptest.NewRequest(http.MethodGet, "/proposals?consumerId=0xconsumer", nil)
+	resp := httptest.NewRecorder()
+
+	endpoint.List(resp, req, nil)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, identity.FromAddress("0xconsumer"), kinds.consumerID)
+	assert.JSONEq(
+		t,
+		`{
+			"proposals": [
+				{
+					"id": 1,
+					"providerId": "0x3",
+					"serviceType": "testprotocol",
+					"serviceDefinition": {"locationOriginate": {"asn": "", "country": "LT", "natType": "nat"}},
+					"paymentMethod": {"type": "PER_TIME", "price": {"amount": 200, "currency": "MYST", "formatted": "0.000002 MYST"}},
+					"favourite": true
+				},
+				{
+					"id": 1,
+					"providerId": "0x2",
+					"serviceType": "testprotocol",
+					"serviceDefinition": {"locationOriginate": {"asn": "", "country": "NL", "natType": "nat"}},
+					"paymentMethod": {"type": "PER_TIME", "price": {"amount": 100, "currency": "MYST", "formatted": "0.000001 MYST"}}
+				}
+			],
+			"total": 2
+		}`,
+		resp.Body.String(),
+	)
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/session/promise/settlement"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+)
+
+// swagger:model SettlementDTO
+type settlementResponse struct {
+	// issuer of the settled promise
+	// example: 0x0000000000000000000000000000000000000001
+	Issuer string `json:"issuer"`
+
+	// sequence number of the settled promise
+	// example: 2
+	SequenceID uint64 `json:"sequenceId"`
+
+	// amount of the settled promise, in the smallest money units
+	// example: 50000
+	Amount uint64 `json:"amount"`
+
+	// hash of the last clearing transaction, omitted if none was sent
+	TxHash string `json:"txHash,omitempty"`
+
+	// settlement state: Pending, Submitted, Confirmed or Failed
+	// example: Confirmed
+	State string `json:"state"`
+
+	// number of clearing attempts
+	// example: 1
+	Attempts int `json:"attempts"`
+
+	// error of the last failed attempt
+	LastError string `json:"lastError,omitempty"`
+
+	// example: 2019-06-06T11:04:43Z
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// swagger:model SettlementListDTO
+type settlementList struct {
+	Settlements []settlementResponse `json:"settlements"`
+}
+
+// Settler clears the received promises with the payments contract
+type Settler interface {
+	SettleAll() error
+	Settlements() ([]settlement.Settlement, error)
+}
+
+type settlementEndpoint struct {
+	settler Settler
+}
+
+// NewSettlementEndpoint creates and returns settlement endpoint
+func NewSettlementEndpoint(settler Settler) *settlementEndpoint {
+	return &settlementEndpoint{settler: settler}
+}
+
+// swagger:operation GET /settlements Settlement listSettlements
+// ---
+// summary: Returns promise settlements
+// description: Returns settlements of promises received by provider
+// responses:
+//   200:
+//     description: List of settlements
+//     schema:
+//       "$ref": "#/definitions/SettlementListDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *settlementEndpoint) List(resp http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	endpoint.writeSettlements(resp)
+}
+
+// swagger:operation POST /settlements Settlement settle
+// ---
+// summary: Settles promises
+// description: Clears the latest promises of all issuers with the payments contract without waiting for the next periodic settlement
+// responses:
+//   200:
+//     description: List of settlements
+//     schema:
+//       "$ref": "#/definitions/SettlementListDTO"
+//   409:
+//     description: Settlement is not started, as no service is running
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *settlementEndpoint) Settle(resp http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	err := endpoint.settler.SettleAll()
+	if err == settlement.ErrNotStarted {
+		utils.SendError(resp, err, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+	endpoint.writeSettlements(resp)
+}
+
+func (endpoint *settlementEndpoint) writeSettlements(resp http.ResponseWriter) {
+	settlements, err := endpoint.settler.Settlements()
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := settlementList{Settlements: []settlementResponse{}}
+	for _, s := range settlements {
+		response.Settlements = append(response.Settlements, settlementResponse{
+			Issuer:     s.Issuer,
+			SequenceID: s.SequenceID,
+			Amount:     s.Amount,
+			TxHash:     s.TxHash,
+			State:      string(s.State),
+			Attempts:   s.Attempts,
+			LastError:  s.LastError,
+			UpdatedAt:  s.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	utils.WriteAsJSON(response, resp)
+}
+
+// AddRoutesForSettlement attaches settlement endpoints to router
+func AddRoutesForSettlement(router *httprouter.Router, settler Settler) {
+	endpoint := NewSettlementEndpoint(settler)
+	router.GET("/settlements", endpoint.List)
+	router.POST("/settlements", endpoint.Settle)
+}
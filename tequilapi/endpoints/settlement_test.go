@@ -0,0 +1,121 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/session/promise/settlement"
+	"github.com/stretchr/testify/assert"
+)
+
+type settlerFake struct {
+	settled     bool
+	settleErr   error
+	settlements []settlement.Settlement
+}
+
+func (sf *settlerFake) SettleAll() error {
+	sf.settled = true
+	return sf.settleErr
+}
+
+func (sf *settlerFake) Settlements() ([]settlement.Settlement, error) {
+	return sf.settlements, nil
+}
+
+var settlerWithSettlements = &settlerFake{
+	settlements: []settlement.Settlement{
+		{
+			Issuer:     "0xissuer",
+			SequenceID: 2,
+			Amount:     500,
+			TxHash:     "0xhash",
+			State:      settlement.Confirmed,
+			Attempts:   1,
+			UpdatedAt:  time.Date(2019, 6, 6, 11, 4, 43, 0, time.UTC),
+		},
+	},
+}
+
+func TestSettlementEndpointListsSettlements(t *testing.T) {
+	router := httprouter.New()
+	AddRoutesForSettlement(router, settlerWithSettlements)
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/settlements", nil)
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"settlements": [{
+				"issuer": "0xissuer",
+				"sequenceId": 2,
+				"amount": 500,
+				"txHash": "0xhash",
+				"state": "Confirmed",
+				"attempts": 1,
+				"updatedAt": "2019-06-06T11:04:43Z"
+			}]
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func TestSettlementEndpointSettlesOnDemand(t *testing.T) {
+	settler := &settlerFake{}
+	router := httprouter.New()
+	AddRoutesForSettlement(router, settler)
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/settlements", nil)
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"settlements": []}`, resp.Body.String())
+	assert.True(t, settler.settled)
+}
+
+func TestSettlementEndpointSettleFails(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{settlement.ErrNotStarted, http.StatusConflict},
+		{errors.New("no connection"), http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		router := httprouter.New()
+		AddRoutesForSettlement(router, &settlerFake{settleErr: test.err})
+
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/settlements", nil)
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, test.code, resp.Code)
+		assert.JSONEq(t, `{"message": "`+test.err.Error()+`"}`, resp.Body.String())
+	}
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"math/big"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/consumer/spending"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+)
+
+// swagger:model SpendingUsageDTO
+type spendingUsageRes struct {
+	// amount spent, in the smallest money units
+	// example: 50000
+	Spent *big.Int `json:"spent"`
+
+	// spending limit, omitted when there is no limit
+	// example: 200000
+	Limit *big.Int `json:"limit,omitempty"`
+
+	// amount which can still be spent, omitted when there is no limit
+	// example: 150000
+	Remaining *big.Int `json:"remaining,omitempty"`
+}
+
+// swagger:model SessionSpendingDTO
+type sessionSpendingRes struct {
+	// example: 4cfb0324-daf6-4ad8-448b-e61fe0a1f918
+	SessionID string `json:"sessionId"`
+
+	// example: 0x0000000000000000000000000000000000000002
+	ProviderID string `json:"providerId"`
+
+	// spending of the session
+	Session spendingUsageRes `json:"session"`
+
+	// spending with the provider of the session
+	Provider spendingUsageRes `json:"provider"`
+}
+
+// swagger:model SpendingDTO
+type spendingRes struct {
+	// spending of every active session, connected or exported
+	Sessions []sessionSpendingRes `json:"sessions"`
+
+	Day   spendingUsageRes `json:"day"`
+	Month spendingUsageRes `json:"month"`
+}
+
+// SpendingTracker keeps track of consumer spending
+type SpendingTracker interface {
+	Summary(consumer identity.Identity) (spending.Summary, error)
+}
+
+type spendingEndpoint struct {
+	tracker SpendingTracker
+}
+
+// NewSpendingEndpoint creates and returns spending endpoint
+func NewSpendingEndpoint(tracker SpendingTracker) *spendingEndpoint {
+	return &spendingEndpoint{tracker: tracker}
+}
+
+// swagger:operation GET /identities/{id}/spending Identity getSpending
+// ---
+// summary: Returns consumer spending
+// description: Returns current spending and remaining budget of consumer identity
+// parameters:
+// - in: path
+//   name: id
+//   description: Consumer identity
+//   type: string
+//   required: true
+// responses:
+//   200:
+//     description: Current spending
+//     schema:
+//       "$ref": "#/definitions/SpendingDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *spendingEndpoint) Get(resp http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+	summary, err := endpoint.tracker.Summary(identity.FromAddress(params.ByName("id")))
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := spendingRes{
+		Sessions: []sessionSpendingRes{},
+		Day:      toSpendingUsageRes(summary.Day),
+		Month:    toSpendingUsageRes(summary.Month),
+	}
+	for _, session := range summary.Sessions {
+		response.Sessions = append(response.Sessions, sessionSpendingRes{
+			SessionID:  string(session.SessionID),
+			ProviderID: session.ProviderID.Address,
+			Session:    toSpendingUsageRes(session.Session),
+			Provider:   toSpendingUsageRes(session.Provider),
+		})
+	}
+	utils.WriteAsJSON(response, resp)
+}
+
+// AddRoutesForSpending attaches spending endpoints to router
+func AddRoutesForSpending(router *httprouter.Router, tracker SpendingTracker) {
+	endpoint := NewSpendingEndpoint(tracker)
+	router.GET("/identities/:id/spending", endpoint.Get)
+}
+
+func toSpendingUsageRes(usage spending.Usage) spendingUsageRes {
+	res := spendingUsageRes{Spent: usage.Spent.Units()}
+	if remaining, limited := usage.Remaining(); limited {
+		res.Limit = usage.Limit.Units()
+		res.Remaining = remaining.Units()
+	}
+	return res
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/consumer/spending"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/stretchr/testify/assert"
+)
+
+type spendingTrackerFake struct {
+	consumerID identity.Identity
+	summary    spending.Summary
+}
+
+func (stf *spendingTrackerFake) Summary(consumer identity.Identity) (spending.Summary, error) {
+	stf.consumerID = consumer
+	return stf.summary, nil
+}
+
+func TestSpendingEndpointReturnsSummary(t *testing.T) {
+	tracker := &spendingTrackerFake{summary: spending.Summary{
+		Sessions: []spending.SessionSummary{{
+			SessionID:  "session-1",
+			ProviderID: identity.FromAddress("0xprovider"),
+			Session:    spending.Usage{Spent: money.NewUnits(100, money.CURRENCY_MYST), Limit: money.NewUnits(300, money.CURRENCY_MYST)},
+			Provider:   spending.Usage{Spent: money.NewUnits(500, money.CURRENCY_MYST)},
+		}},
+		Day:   spending.Usage{Spent: money.NewUnits(600, money.CURRENCY_MYST), Limit: money.NewUnits(500, money.CURRENCY_MYST)},
+		Month: spending.Usage{Spent: money.NewUnits(600, money.CURRENCY_MYST)},
+	}}
+	router := httprouter.New()
+	AddRoutesForSpending(router, tracker)
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/identities/0xconsumer/spending", nil)
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"sessions": [{
+				"sessionId": "session-1",
+				"providerId": "0xprovider",
+				"session": {"spent": 100, "limit": 300, "remaining": 200},
+				"provider": {"spent": 500}
+			}],
+			"day": {"spent": 600, "limit": 500, "remaining": 0},
+			"month": {"spent": 600}
+		}`,
+		resp.Body.String(),
+	)
+	assert.Equal(t, identity.FromAddress("0xconsumer"), tracker.consumerID)
+}
+
+func TestSpendingEndpointReturnsAmountsBeyondUint64(t *testing.T) {
+	spent, err := money.NewUnits(math.MaxUint64, money.CURRENCY_MYST).Add(money.NewUnits(1, money.CURRENCY_MYST))
+	assert.NoError(t, err)
+	router := httprouter.New()
+	AddRoutesForSpending(router, &spendingTrackerFake{summary: spending.Summary{Day: spending.Usage{Spent: spent}}})
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/identities/0xconsumer/spending", nil)
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"sessions": [], "day": {"spent": 18446744073709551616}, "month": {"spent": 0}}`, resp.Body.String())
+}
+
+func TestSpendingEndpointOmitsSessionWhenNotConnected(t *testing.T) {
+	router := httprouter.New()
+	AddRoutesForSpending(router, &spendingTrackerFake{})
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/identities/0xconsumer/spending", nil)
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"sessions": [], "day": {"spent": 0}, "month": {"spent": 0}}`, resp.Body.String())
+}
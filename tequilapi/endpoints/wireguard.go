@@ -0,0 +1,68 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+)
+
+// swagger:model WireguardCleanupDTO
+type WireguardCleanupDTO struct {
+	// abandoned wireguard interfaces removed from the system
+	// example: ["mystp0"]
+	Interfaces []string `json:"interfaces"`
+
+	// NAT forwarding rules removed together with the interfaces
+	// example: ["10.182.0.0/24 -> 192.168.1.10"]
+	NATRules []string `json:"natRules"`
+}
+
+// WireguardCleaner removes abandoned wireguard interfaces and reports what was removed
+type WireguardCleaner func() (WireguardCleanupDTO, error)
+
+// AddRoutesForWireguard attaches wireguard actions to given router
+func AddRoutesForWireguard(router *httprouter.Router, cleaner WireguardCleaner) {
+	router.POST("/wireguard/cleanup", newWireguardCleanupHandler(cleaner))
+}
+
+// swagger:operation POST /wireguard/cleanup Wireguard wireguardCleanup
+// ---
+// summary: Removes abandoned wireguard interfaces
+// description: Removes wireguard interfaces left by previous runs together with their NAT forwarding rules
+// responses:
+//   200:
+//     description: Removed interfaces and NAT rules
+//     schema:
+//       "$ref": "#/definitions/WireguardCleanupDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func newWireguardCleanupHandler(cleaner WireguardCleaner) httprouter.Handle {
+	return func(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+		report, err := cleaner()
+		if err != nil {
+			utils.SendError(writer, err, http.StatusInternalServerError)
+			return
+		}
+		utils.WriteAsJSON(report, writer)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddRoutesForWireguardCleanup(t *testing.T) {
+	router := httprouter.New()
+	AddRoutesForWireguard(router, func() (WireguardCleanupDTO, error) {
+		return WireguardCleanupDTO{
+			Interfaces: []string{"myst0"},
+			NATRules:   []string{"10.182.0.0/24 -> 192.168.1.10"},
+		}, nil
+	})
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/wireguard/cleanup", nil)
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(
+		t,
+		`{"interfaces": ["myst0"], "natRules": ["10.182.0.0/24 -> 192.168.1.10"]}`,
+		resp.Body.String(),
+	)
+}
+
+func TestAddRoutesForWireguardCleanupFails(t *testing.T) {
+	router := httprouter.New()
+	AddRoutesForWireguard(router, func() (WireguardCleanupDTO, error) {
+		return WireguardCleanupDTO{}, errors.New("wireguard service is not running")
+	})
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/wireguard/cleanup", nil)
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.JSONEq(t, `{"message": "wireguard service is not running"}`, resp.Body.String())
+}
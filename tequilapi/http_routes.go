@@ -26,11 +26,11 @@ import (
 )
 
 // NewAPIRouter returns new api router with status endpoints
-func NewAPIRouter() *httprouter.Router {
+func NewAPIRouter(wireguardMode func() string) *httprouter.Router {
 	router := httprouter.New()
 	router.HandleMethodNotAllowed = true
 
-	router.GET("/healthcheck", endpoints.HealthCheckEndpointFactory(time.Now, os.Getpid).HealthCheck)
+	router.GET("/healthcheck", endpoints.HealthCheckEndpointFactory(time.Now, os.Getpid, wireguardMode).HealthCheck)
 
 	return router
 }